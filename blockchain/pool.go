@@ -286,6 +286,17 @@ func (pool *BlockPool) SetPeerHeight(peerID p2p.ID, height int64) {
 	}
 }
 
+// SetPeerRTT sets the round-trip time to the peer, used to prefer the closest
+// peers when requesting blocks.
+func (pool *BlockPool) SetPeerRTT(peerID p2p.ID, rtt time.Duration) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	if peer := pool.peers[peerID]; peer != nil {
+		peer.rtt = rtt
+	}
+}
+
 func (pool *BlockPool) RemovePeer(peerID p2p.ID) {
 	pool.mtx.Lock()
 	defer pool.mtx.Unlock()
@@ -302,12 +313,16 @@ func (pool *BlockPool) removePeer(peerID p2p.ID) {
 	delete(pool.peers, peerID)
 }
 
-// Pick an available peer with at least the given minHeight.
+// Pick an available peer with at least the given minHeight, preferring the
+// ones with the lowest round-trip time. The peers whose round-trip time is
+// unknown (until their first pong) come last, and the requests are spread
+// between the peers which rank the same.
 // If no peers are available, returns nil.
 func (pool *BlockPool) pickIncrAvailablePeer(minHeight int64) *bpPeer {
 	pool.mtx.Lock()
 	defer pool.mtx.Unlock()
 
+	var picked *bpPeer
 	for _, peer := range pool.peers {
 		if peer.didTimeout {
 			pool.removePeer(peer.id)
@@ -319,10 +334,30 @@ func (pool *BlockPool) pickIncrAvailablePeer(minHeight int64) *bpPeer {
 		if peer.height < minHeight {
 			continue
 		}
-		peer.incrPending()
-		return peer
+		if picked == nil || peer.ranksBefore(picked) {
+			picked = peer
+		}
+	}
+	if picked != nil {
+		picked.incrPending()
+	}
+	return picked
+}
+
+// ranksBefore returns true if requests should go to peer rather than to
+// other: the peer with the lowest known round-trip time, or else with the
+// fewest pending requests.
+func (peer *bpPeer) ranksBefore(other *bpPeer) bool {
+	switch {
+	case peer.rtt == other.rtt:
+		return peer.numPending < other.numPending
+	case peer.rtt == 0:
+		return false
+	case other.rtt == 0:
+		return true
+	default:
+		return peer.rtt < other.rtt
 	}
-	return nil
 }
 
 func (pool *BlockPool) makeNextRequester() {
@@ -389,6 +424,7 @@ type bpPeer struct {
 	recvMonitor *flow.Monitor
 
 	height     int64
+	rtt        time.Duration // 0 if unknown
 	numPending int32
 	timeout    *time.Timer
 	didTimeout bool
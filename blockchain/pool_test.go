@@ -180,3 +180,57 @@ func TestTimeout(t *testing.T) {
 		}
 	}
 }
+
+func TestPickPeerPrefersLowestRTT(t *testing.T) {
+	pool := NewBlockPool(1, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+
+	pool.SetPeerHeight("unknown", 100)
+	pool.SetPeerHeight("slow", 100)
+	pool.SetPeerRTT("slow", 200*time.Millisecond)
+	pool.SetPeerHeight("fast", 100)
+	pool.SetPeerRTT("fast", 20*time.Millisecond)
+	pool.SetPeerHeight("short", 10)
+	pool.SetPeerRTT("short", time.Millisecond)
+
+	// the closest peer is picked until it has too many pending requests
+	for i := 0; i < maxPendingRequestsPerPeer; i++ {
+		peer := pool.pickIncrAvailablePeer(50)
+		if peer == nil || peer.id != "fast" {
+			t.Fatalf("expected to pick fast, got %v", peer)
+		}
+	}
+	if peer := pool.pickIncrAvailablePeer(50); peer == nil || peer.id != "slow" {
+		t.Fatalf("expected to pick slow, got %v", peer)
+	}
+}
+
+func TestPickPeerSpreadsUnmeasured(t *testing.T) {
+	pool := NewBlockPool(1, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+
+	// no round-trip time is known before the first pongs
+	ids := []p2p.ID{"a", "b", "c"}
+	for _, id := range ids {
+		pool.SetPeerHeight(id, 100)
+	}
+	picks := make(map[p2p.ID]int)
+	for i := 0; i < 3*len(ids); i++ {
+		peer := pool.pickIncrAvailablePeer(50)
+		if peer == nil {
+			t.Fatal("expected to pick a peer")
+		}
+		picks[peer.id]++
+	}
+	for _, id := range ids {
+		if picks[id] != 3 {
+			t.Fatalf("expected the requests to be spread evenly, got %v", picks)
+		}
+	}
+
+	// a measured peer is preferred
+	pool.SetPeerRTT("b", 10*time.Millisecond)
+	if peer := pool.pickIncrAvailablePeer(50); peer == nil || peer.id != "b" {
+		t.Fatalf("expected to pick b, got %v", peer)
+	}
+}
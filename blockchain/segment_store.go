@@ -0,0 +1,333 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
+	sm "github.com/tendermint/tendermint/state"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// DefaultMaxSegmentSize is the size after which a SegmentBlockStore
+	// stops appending to the current segment file and starts a new one.
+	DefaultMaxSegmentSize = 256 * 1024 * 1024 // 256MB
+
+	segmentFilePerm = 0600
+)
+
+var _ sm.BlockStore = (*SegmentBlockStore)(nil)
+
+/*
+SegmentBlockStore is a BlockStore that keeps block parts in append-only
+segment files on disk, and only a small index in the DB.
+
+Block parts make up the bulk of the data written for every block. Writing them
+sequentially to a flat file, rather than as individual keys in the DB, avoids
+the write amplification caused by compaction in LSM-tree backed databases
+like leveldb.
+
+The following is stored in the DB:
+ - BlockMeta:      Meta information about each block
+ - Part location:  Segment, offset and length of each block part
+ - Commit:         The commit part of each block
+ - Store state:    The height and the end of the last committed segment write
+
+The segment position is only recorded after the parts have been synced to
+disk, so any bytes past it (left over from an unclean shutdown) are truncated
+on the next start.
+
+// NOTE: SegmentBlockStore methods will panic if they encounter errors
+// reading or deserializing loaded data, indicating probable corruption on disk.
+*/
+type SegmentBlockStore struct {
+	db             dbm.DB
+	dir            string
+	maxSegmentSize int64
+
+	mtx     sync.RWMutex
+	height  int64
+	segment int64
+	offset  int64
+	file    *os.File // current segment, opened for appending
+}
+
+// NewSegmentBlockStore returns a new SegmentBlockStore which stores its index
+// in the given DB and its segment files in dir. It is initialized to the last
+// height that was committed to the DB.
+func NewSegmentBlockStore(db dbm.DB, dir string) (*SegmentBlockStore, error) {
+	if err := cmn.EnsureDir(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	ssjson := LoadSegmentStoreStateJSON(db)
+	bs := &SegmentBlockStore{
+		db:             db,
+		dir:            dir,
+		maxSegmentSize: DefaultMaxSegmentSize,
+		height:         ssjson.Height,
+		segment:        ssjson.Segment,
+		offset:         ssjson.Offset,
+	}
+	if err := bs.openSegment(); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// SetMaxSegmentSize sets the size after which a new segment file is started.
+func (bs *SegmentBlockStore) SetMaxSegmentSize(size int64) {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	bs.maxSegmentSize = size
+}
+
+// Height returns the last known contiguous block height.
+func (bs *SegmentBlockStore) Height() int64 {
+	bs.mtx.RLock()
+	defer bs.mtx.RUnlock()
+	return bs.height
+}
+
+// LoadBlock returns the block with the given height.
+// If no block is found for that height, it returns nil.
+func (bs *SegmentBlockStore) LoadBlock(height int64) *types.Block {
+	return loadBlock(bs, height)
+}
+
+// LoadBlockPart returns the Part at the given index
+// from the block at the given height.
+// If no part is found for the given height and index, it returns nil.
+func (bs *SegmentBlockStore) LoadBlockPart(height int64, index int) *types.Part {
+	bz := bs.db.Get(calcBlockPartKey(height, index))
+	if len(bz) == 0 {
+		return nil
+	}
+	var loc segmentLocation
+	err := cdc.UnmarshalBinaryBare(bz, &loc)
+	if err != nil {
+		panic(cmn.ErrorWrap(err, "Error reading block part location"))
+	}
+
+	partBytes, err := bs.readSegment(loc)
+	if err != nil {
+		panic(cmn.ErrorWrap(err, "Error reading block part"))
+	}
+	var part = new(types.Part)
+	err = cdc.UnmarshalBinaryBare(partBytes, part)
+	if err != nil {
+		panic(cmn.ErrorWrap(err, "Error reading block part"))
+	}
+	return part
+}
+
+// LoadBlockMeta returns the BlockMeta for the given height.
+// If no block is found for the given height, it returns nil.
+func (bs *SegmentBlockStore) LoadBlockMeta(height int64) *types.BlockMeta {
+	var blockMeta = new(types.BlockMeta)
+	bz := bs.db.Get(calcBlockMetaKey(height))
+	if len(bz) == 0 {
+		return nil
+	}
+	err := cdc.UnmarshalBinaryBare(bz, blockMeta)
+	if err != nil {
+		panic(cmn.ErrorWrap(err, "Error reading block meta"))
+	}
+	return blockMeta
+}
+
+// LoadBlockCommit returns the Commit for the given height.
+// If no commit is found for the given height, it returns nil.
+func (bs *SegmentBlockStore) LoadBlockCommit(height int64) *types.Commit {
+	var commit = new(types.Commit)
+	bz := bs.db.Get(calcBlockCommitKey(height))
+	if len(bz) == 0 {
+		return nil
+	}
+	err := cdc.UnmarshalBinaryBare(bz, commit)
+	if err != nil {
+		panic(cmn.ErrorWrap(err, "Error reading block commit"))
+	}
+	return commit
+}
+
+// LoadSeenCommit returns the locally seen Commit for the given height.
+// If no commit is found for the given height, it returns nil.
+func (bs *SegmentBlockStore) LoadSeenCommit(height int64) *types.Commit {
+	var commit = new(types.Commit)
+	bz := bs.db.Get(calcSeenCommitKey(height))
+	if len(bz) == 0 {
+		return nil
+	}
+	err := cdc.UnmarshalBinaryBare(bz, commit)
+	if err != nil {
+		panic(cmn.ErrorWrap(err, "Error reading block seen commit"))
+	}
+	return commit
+}
+
+// SaveBlock appends the block parts to the current segment file, syncs it,
+// and then atomically persists the block meta, part locations, commits and
+// the new store state to the DB.
+// See BlockStore.SaveBlock for a description of the arguments.
+func (bs *SegmentBlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	if block == nil {
+		cmn.PanicSanity("SegmentBlockStore can only save a non-nil block")
+	}
+	height := block.Height
+	if g, w := height, bs.Height()+1; g != w {
+		cmn.PanicSanity(fmt.Sprintf("SegmentBlockStore can only save contiguous blocks. Wanted %v, got %v", w, g))
+	}
+	if !blockParts.IsComplete() {
+		cmn.PanicSanity(fmt.Sprintf("SegmentBlockStore can only save complete block part sets"))
+	}
+
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+
+	if bs.offset >= bs.maxSegmentSize {
+		if err := bs.rollSegment(); err != nil {
+			panic(cmn.ErrorWrap(err, "Error starting new block store segment"))
+		}
+	}
+
+	batch := bs.db.NewBatch()
+	defer batch.Close()
+
+	// Append block parts to the segment
+	offset := bs.offset
+	for i := 0; i < blockParts.Total(); i++ {
+		partBytes := cdc.MustMarshalBinaryBare(blockParts.GetPart(i))
+		if _, err := bs.file.WriteAt(partBytes, offset); err != nil {
+			panic(cmn.ErrorWrap(err, "Error writing block part"))
+		}
+		loc := segmentLocation{
+			Segment: bs.segment,
+			Offset:  offset,
+			Length:  int64(len(partBytes)),
+		}
+		batch.Set(calcBlockPartKey(height, i), cdc.MustMarshalBinaryBare(loc))
+		offset += loc.Length
+	}
+	if err := bs.file.Sync(); err != nil {
+		panic(cmn.ErrorWrap(err, "Error syncing block store segment"))
+	}
+
+	// Save block meta
+	blockMeta := types.NewBlockMeta(block, blockParts)
+	batch.Set(calcBlockMetaKey(height), cdc.MustMarshalBinaryBare(blockMeta))
+
+	// Save block commit (duplicate and separate from the Block)
+	batch.Set(calcBlockCommitKey(height-1), cdc.MustMarshalBinaryBare(block.LastCommit))
+
+	// Save seen commit (seen +2/3 precommits for block)
+	batch.Set(calcSeenCommitKey(height), cdc.MustMarshalBinaryBare(seenCommit))
+
+	// Save new SegmentStoreStateJSON descriptor
+	ssjson := SegmentStoreStateJSON{Height: height, Segment: bs.segment, Offset: offset}
+	batch.Set(segmentStoreKey, ssjson.Bytes())
+
+	batch.WriteSync()
+
+	bs.height = height
+	bs.offset = offset
+}
+
+// Close closes the current segment file.
+func (bs *SegmentBlockStore) Close() error {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	return bs.file.Close()
+}
+
+// openSegment opens the current segment for appending, discarding anything
+// written past the last committed offset.
+// CONTRACT: caller must hold the lock or have exclusive access to bs.
+func (bs *SegmentBlockStore) openSegment() error {
+	f, err := os.OpenFile(bs.segmentPath(bs.segment), os.O_RDWR|os.O_CREATE, segmentFilePerm)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(bs.offset); err != nil {
+		f.Close() // nolint: errcheck
+		return err
+	}
+	bs.file = f
+	return nil
+}
+
+// rollSegment closes the current segment and starts a new one.
+// CONTRACT: caller must hold the lock.
+func (bs *SegmentBlockStore) rollSegment() error {
+	if err := bs.file.Close(); err != nil {
+		return err
+	}
+	bs.segment++
+	bs.offset = 0
+	return bs.openSegment()
+}
+
+func (bs *SegmentBlockStore) readSegment(loc segmentLocation) ([]byte, error) {
+	f, err := os.Open(bs.segmentPath(loc.Segment))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint: errcheck
+
+	buf := make([]byte, loc.Length)
+	if _, err := f.ReadAt(buf, loc.Offset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+func (bs *SegmentBlockStore) segmentPath(segment int64) string {
+	return filepath.Join(bs.dir, fmt.Sprintf("%06d.seg", segment))
+}
+
+// segmentLocation is the position of a block part in the segment files.
+type segmentLocation struct {
+	Segment int64 `json:"segment"`
+	Offset  int64 `json:"offset"`
+	Length  int64 `json:"length"`
+}
+
+//-----------------------------------------------------------------------------
+
+var segmentStoreKey = []byte("segmentBlockStore")
+
+// SegmentStoreStateJSON is the persisted state of a SegmentBlockStore.
+type SegmentStoreStateJSON struct {
+	Height  int64 `json:"height"`
+	Segment int64 `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// Bytes returns the JSON encoding of the state.
+func (ssj SegmentStoreStateJSON) Bytes() []byte {
+	bytes, err := cdc.MarshalJSON(ssj)
+	if err != nil {
+		cmn.PanicSanity(fmt.Sprintf("Could not marshal state bytes: %v", err))
+	}
+	return bytes
+}
+
+// LoadSegmentStoreStateJSON returns the SegmentStoreStateJSON as loaded from disk.
+// If no SegmentStoreStateJSON was previously persisted, it returns the zero value.
+func LoadSegmentStoreStateJSON(db dbm.DB) SegmentStoreStateJSON {
+	bytes := db.Get(segmentStoreKey)
+	if len(bytes) == 0 {
+		return SegmentStoreStateJSON{}
+	}
+	ssj := SegmentStoreStateJSON{}
+	err := cdc.UnmarshalJSON(bytes, &ssj)
+	if err != nil {
+		panic(fmt.Sprintf("Could not unmarshal bytes: %X", bytes))
+	}
+	return ssj
+}
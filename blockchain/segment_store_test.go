@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+)
+
+func freshSegmentBlockStore(t *testing.T) (*SegmentBlockStore, dbm.DB, string) {
+	dir, err := ioutil.TempDir("", "segment_store_test")
+	require.NoError(t, err)
+	db := dbm.NewMemDB()
+	bs, err := NewSegmentBlockStore(db, dir)
+	require.NoError(t, err)
+	return bs, db, dir
+}
+
+func TestSegmentBlockStoreSaveLoadBlock(t *testing.T) {
+	bs, _, dir := freshSegmentBlockStore(t)
+	defer os.RemoveAll(dir)
+	defer bs.Close()
+	require.Equal(t, int64(0), bs.Height(), "initially the height should be zero")
+
+	// use a tiny segment size so every block starts a new segment
+	bs.SetMaxSegmentSize(1)
+
+	var blocks []*types.Block
+	for h := int64(1); h <= 3; h++ {
+		block := makeBlock(h, state, new(types.Commit))
+		partSet := block.MakePartSet(2)
+		seenCommit := &types.Commit{Precommits: []*types.Vote{{Height: h,
+			Timestamp: tmtime.Now()}}}
+		bs.SaveBlock(block, partSet, seenCommit)
+		require.Equal(t, h, bs.Height(), "expecting the new height to be changed")
+		blocks = append(blocks, block)
+	}
+
+	for _, block := range blocks {
+		got := bs.LoadBlock(block.Height)
+		require.NotNil(t, got)
+		assert.Equal(t, block.Hash(), got.Hash())
+		assert.Equal(t, cdc.MustMarshalBinaryBare(block), cdc.MustMarshalBinaryBare(got))
+
+		meta := bs.LoadBlockMeta(block.Height)
+		require.NotNil(t, meta)
+		assert.Equal(t, block.Hash(), meta.BlockID.Hash)
+		assert.NotNil(t, bs.LoadSeenCommit(block.Height))
+	}
+	assert.Nil(t, bs.LoadBlock(4), "expecting an unsuccessful load of Height()+1")
+	assert.Equal(t, int64(2), LoadSegmentStoreStateJSON(bs.db).Segment)
+}
+
+func TestSegmentBlockStoreReopenTruncatesUncommittedWrites(t *testing.T) {
+	bs, db, dir := freshSegmentBlockStore(t)
+	defer os.RemoveAll(dir)
+
+	block := makeBlock(1, state, new(types.Commit))
+	bs.SaveBlock(block, block.MakePartSet(2), seenCommit1)
+	committed := LoadSegmentStoreStateJSON(db)
+
+	// simulate a crash after the parts were appended but before the index was written
+	_, err := bs.file.WriteAt([]byte("garbage"), committed.Offset)
+	require.NoError(t, err)
+	require.NoError(t, bs.Close())
+
+	bs, err = NewSegmentBlockStore(db, dir)
+	require.NoError(t, err)
+	defer bs.Close()
+
+	info, err := os.Stat(bs.segmentPath(committed.Segment))
+	require.NoError(t, err)
+	assert.Equal(t, committed.Offset, info.Size())
+	assert.Equal(t, int64(1), bs.Height())
+
+	next := makeBlock(2, state, new(types.Commit))
+	bs.SaveBlock(next, next.MakePartSet(2), seenCommit1)
+	assert.Equal(t, block.Hash(), bs.LoadBlock(1).Hash())
+	assert.Equal(t, next.Hash(), bs.LoadBlock(2).Hash())
+}
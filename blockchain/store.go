@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"sync"
 
+	cfg "github.com/tendermint/tendermint/config"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	dbm "github.com/tendermint/tendermint/libs/db"
+	sm "github.com/tendermint/tendermint/state"
 
 	"github.com/tendermint/tendermint/types"
 )
 
+var _ sm.BlockStore = (*BlockStore)(nil)
+
 /*
 BlockStore is a simple low level store for blocks.
 
@@ -32,6 +36,37 @@ type BlockStore struct {
 	height int64
 }
 
+// CreateBlockStore returns the block store backend selected in the config,
+// using db for its data or, in the case of the segment backend, its index.
+func CreateBlockStore(config cfg.BaseConfig, db dbm.DB) (sm.BlockStore, error) {
+	switch config.BlockStoreBackend {
+	case cfg.BlockStoreBackendSegment:
+		return NewSegmentBlockStore(db, config.BlockStoreSegmentsDir())
+	case cfg.BlockStoreBackendDB, "":
+		return NewBlockStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown block store backend %q", config.BlockStoreBackend)
+	}
+}
+
+// ResetBlockStoreHeight overwrites the height persisted by the block store
+// backend selected in the config, so that blocks above height are ignored and
+// will be overwritten when saved again. It must not be called while the block
+// store is in use, and is meant for repairing a store after an unclean shutdown.
+func ResetBlockStoreHeight(config cfg.BaseConfig, db dbm.DB, height int64) error {
+	switch config.BlockStoreBackend {
+	case cfg.BlockStoreBackendSegment:
+		ssjson := LoadSegmentStoreStateJSON(db)
+		ssjson.Height = height
+		db.SetSync(segmentStoreKey, ssjson.Bytes())
+	case cfg.BlockStoreBackendDB, "":
+		BlockStoreStateJSON{Height: height}.Save(db)
+	default:
+		return fmt.Errorf("unknown block store backend %q", config.BlockStoreBackend)
+	}
+	return nil
+}
+
 // NewBlockStore returns a new BlockStore with the given DB,
 // initialized to the last height that was committed to the DB.
 func NewBlockStore(db dbm.DB) *BlockStore {
@@ -52,24 +87,7 @@ func (bs *BlockStore) Height() int64 {
 // LoadBlock returns the block with the given height.
 // If no block is found for that height, it returns nil.
 func (bs *BlockStore) LoadBlock(height int64) *types.Block {
-	var blockMeta = bs.LoadBlockMeta(height)
-	if blockMeta == nil {
-		return nil
-	}
-
-	var block = new(types.Block)
-	buf := []byte{}
-	for i := 0; i < blockMeta.BlockID.PartsHeader.Total; i++ {
-		part := bs.LoadBlockPart(height, i)
-		buf = append(buf, part.Bytes...)
-	}
-	err := cdc.UnmarshalBinaryLengthPrefixed(buf, block)
-	if err != nil {
-		// NOTE: The existence of meta should imply the existence of the
-		// block. So, make sure meta is only saved after blocks are saved.
-		panic(cmn.ErrorWrap(err, "Error reading block"))
-	}
-	return block
+	return loadBlock(bs, height)
 }
 
 // LoadBlockPart returns the Part at the given index
@@ -194,6 +212,29 @@ func (bs *BlockStore) saveBlockPart(height int64, index int, part *types.Part) {
 	bs.db.Set(calcBlockPartKey(height, index), partBytes)
 }
 
+// loadBlock reassembles the block at the given height from the parts held
+// by bs. It returns nil if no block meta is found for that height.
+func loadBlock(bs sm.BlockStoreRPC, height int64) *types.Block {
+	var blockMeta = bs.LoadBlockMeta(height)
+	if blockMeta == nil {
+		return nil
+	}
+
+	var block = new(types.Block)
+	buf := []byte{}
+	for i := 0; i < blockMeta.BlockID.PartsHeader.Total; i++ {
+		part := bs.LoadBlockPart(height, i)
+		buf = append(buf, part.Bytes...)
+	}
+	err := cdc.UnmarshalBinaryLengthPrefixed(buf, block)
+	if err != nil {
+		// NOTE: The existence of meta should imply the existence of the
+		// block. So, make sure meta is only saved after blocks are saved.
+		panic(cmn.ErrorWrap(err, "Error reading block"))
+	}
+	return block
+}
+
 //-----------------------------------------------------------------------------
 
 func calcBlockMetaKey(height int64) []byte {
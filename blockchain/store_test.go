@@ -398,3 +398,20 @@ func newBlock(hdr types.Header, lastCommit *types.Commit) *types.Block {
 		LastCommit: lastCommit,
 	}
 }
+
+func TestResetBlockStoreHeight(t *testing.T) {
+	config := cfg.TestBaseConfig()
+	db := dbm.NewMemDB()
+	BlockStoreStateJSON{Height: 10}.Save(db)
+
+	require.NoError(t, ResetBlockStoreHeight(config, db, 8))
+	assert.Equal(t, int64(8), NewBlockStore(db).Height())
+
+	config.BlockStoreBackend = cfg.BlockStoreBackendSegment
+	db.Set(segmentStoreKey, SegmentStoreStateJSON{Height: 10, Segment: 2, Offset: 100}.Bytes())
+	require.NoError(t, ResetBlockStoreHeight(config, db, 9))
+	assert.Equal(t, SegmentStoreStateJSON{Height: 9, Segment: 2, Offset: 100}, LoadSegmentStoreStateJSON(db))
+
+	config.BlockStoreBackend = "unknown"
+	assert.Error(t, ResetBlockStoreHeight(config, db, 1))
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"encoding/hex"
 	"flag"
+	"fmt"
 	"os"
 
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
 
 	"github.com/tendermint/tendermint/privval"
 )
@@ -16,6 +20,8 @@ func main() {
 		addr        = flag.String("addr", ":26659", "Address of client to connect to")
 		chainID     = flag.String("chain-id", "mychain", "chain id")
 		privValPath = flag.String("priv", "", "priv val file path")
+		keyPath     = flag.String("key", "", "Path to the key authenticating the signer to the node (generated if missing; random if empty)")
+		nodeID      = flag.String("node-id", "", "ID of the node key to pin (only this node is served)")
 
 		logger = log.NewTMLogger(
 			log.NewSyncWriter(os.Stdout),
@@ -23,6 +29,15 @@ func main() {
 	)
 	flag.Parse()
 
+	var nodeAddr crypto.Address
+	if *nodeID != "" {
+		var err error
+		nodeAddr, err = hex.DecodeString(*nodeID)
+		if err != nil || len(nodeAddr) != crypto.AddressSize {
+			cmn.Exit(fmt.Sprintf("Invalid -node-id %q: must be %d bytes in hex", *nodeID, crypto.AddressSize))
+		}
+	}
+
 	logger.Info(
 		"Starting private validator",
 		"addr", *addr,
@@ -32,13 +47,29 @@ func main() {
 
 	pv := privval.LoadFilePV(*privValPath)
 
+	privKey := ed25519.GenPrivKey()
+	if *keyPath != "" {
+		key, err := p2p.LoadOrGenNodeKey(*keyPath)
+		if err != nil {
+			panic(err)
+		}
+		var ok bool
+		if privKey, ok = key.PrivKey.(ed25519.PrivKeyEd25519); !ok {
+			panic("the signer key must be an ed25519 key")
+		}
+	}
+	logger.Info("Signer ID (priv_validator_allowed_signers of the node)", "id", p2p.PubKeyToID(privKey.PubKey()))
+
 	rs := privval.NewRemoteSigner(
 		logger,
 		*chainID,
 		*addr,
 		pv,
-		ed25519.GenPrivKey(),
+		privKey,
 	)
+	if nodeAddr != nil {
+		privval.RemoteSignerPinnedNodeKey(nodeAddr)(rs)
+	}
 	err := rs.Start()
 	if err != nil {
 		panic(err)
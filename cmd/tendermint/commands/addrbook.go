@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	dbm "github.com/tendermint/tendermint/libs/db"
+	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// AddrBookCmd groups the commands used to inspect and edit the address book
+// of a stopped node.
+var AddrBookCmd = &cobra.Command{
+	Use:   "addrbook",
+	Short: "Inspect, prune and import the addresses of the address book",
+	Long: `Inspect, prune and import the addresses of the address book, using the
+backend selected by [p2p] addr_book_backend. The node must not be running.`,
+}
+
+var listAddrBookCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "List the addresses of the address book",
+	RunE:         listAddrBook,
+	SilenceUsage: true,
+}
+
+var pruneAddrBookCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove addresses from the address book",
+	Long: `Remove the addresses selected by the flags from the address book. An
+address is removed if it matches any of them.`,
+	RunE:         pruneAddrBook,
+	SilenceUsage: true,
+}
+
+var importAddrBookCmd = &cobra.Command{
+	Use:   "import [file | id@host:port ...]",
+	Short: "Add addresses to the address book",
+	Long: `Add the addresses of a JSON address book file, or the given
+id@host:port addresses, to the address book. Addresses which are already
+known are left unchanged.`,
+	Args:         cobra.MinimumNArgs(1),
+	RunE:         importAddrBook,
+	SilenceUsage: true,
+}
+
+var (
+	listAddrBookJSON bool
+
+	pruneBad        bool
+	pruneNotSeenFor time.Duration
+	pruneNetwork    string
+)
+
+func init() {
+	listAddrBookCmd.Flags().BoolVar(&listAddrBookJSON, "json", false, "Print the addresses and their metadata as JSON")
+
+	pruneAddrBookCmd.Flags().BoolVar(&pruneBad, "bad", false, "Remove the addresses considered bad")
+	pruneAddrBookCmd.Flags().DurationVar(&pruneNotSeenFor, "not-seen-for", 0, "Remove the addresses not connected to for longer than this (e.g. 720h)")
+	pruneAddrBookCmd.Flags().StringVar(&pruneNetwork, "network", "", "Remove the addresses of peers of another network (chain ID)")
+
+	AddrBookCmd.AddCommand(listAddrBookCmd)
+	AddrBookCmd.AddCommand(pruneAddrBookCmd)
+	AddrBookCmd.AddCommand(importAddrBookCmd)
+}
+
+// withAddrBook loads the address book, runs fn, then saves the book and
+// closes its database, if any.
+func withAddrBook(fn func(book pex.AddrBook) error) error {
+	var addrBookDB dbm.DB
+	dbProvider := func(ctx *nm.DBContext) (dbm.DB, error) {
+		db, err := nm.DefaultDBProvider(ctx)
+		addrBookDB = db
+		return db, err
+	}
+	book, err := nm.CreateAddrBook(config, dbProvider)
+	if err != nil {
+		return err
+	}
+	if addrBookDB != nil {
+		defer addrBookDB.Close()
+	}
+	book.SetLogger(logger.With("module", "addrbook"))
+	if err := book.Start(); err != nil {
+		return err
+	}
+	err = fn(book)
+	book.Stop() // nolint: errcheck
+	book.Wait()
+	return err
+}
+
+func listAddrBook(cmd *cobra.Command, args []string) error {
+	return withAddrBook(func(book pex.AddrBook) error {
+		addrs := book.ListOfKnownAddresses()
+		sort.Slice(addrs, func(i, j int) bool { return addrs[i].Addr.String() < addrs[j].Addr.String() })
+
+		if listAddrBookJSON {
+			bz, err := json.MarshalIndent(addrs, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(bz))
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ADDRESS\tBUCKET\tATTEMPTS\tLAST SUCCESS\tLATENCY\tNETWORK\tVERSION")
+		for _, ka := range addrs {
+			bucket := "new"
+			if book.IsGood(ka.Addr) {
+				bucket = "old"
+			}
+			lastSuccess := "-"
+			if !ka.LastSuccess.IsZero() {
+				lastSuccess = ka.LastSuccess.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%v\t%s\t%d\t%s\t%v\t%s\t%s\n",
+				ka.Addr, bucket, ka.Attempts, lastSuccess, ka.Latency, orDash(ka.Network), orDash(ka.Version))
+		}
+		return w.Flush()
+	})
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func pruneAddrBook(cmd *cobra.Command, args []string) error {
+	opts := pex.PruneOptions{
+		Bad:        pruneBad,
+		NotSeenFor: pruneNotSeenFor,
+		Network:    pruneNetwork,
+	}
+	if opts == (pex.PruneOptions{}) {
+		return fmt.Errorf("nothing to prune: set --bad, --not-seen-for or --network")
+	}
+	return withAddrBook(func(book pex.AddrBook) error {
+		pruned := book.Prune(opts)
+		for _, addr := range pruned {
+			fmt.Println(addr)
+		}
+		logger.Info("Pruned address book", "removed", len(pruned))
+		return nil
+	})
+}
+
+func importAddrBook(cmd *cobra.Command, args []string) error {
+	// a single argument without '@' is a file
+	if len(args) == 1 && !strings.Contains(args[0], "@") {
+		return withAddrBook(func(book pex.AddrBook) error {
+			n, err := book.ImportFile(args[0])
+			if err != nil {
+				return err
+			}
+			logger.Info("Imported addresses", "file", args[0], "added", n)
+			return nil
+		})
+	}
+
+	addrs, errs := p2p.NewNetAddressStrings(args)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return withAddrBook(func(book pex.AddrBook) error {
+		n := 0
+		for _, addr := range addrs {
+			if book.HasAddress(addr) {
+				continue
+			}
+			if err := book.AddAddress(addr, addr); err != nil {
+				logger.Error("Failed to import address", "addr", addr, "err", err)
+				continue
+			}
+			n++
+		}
+		logger.Info("Imported addresses", "added", n)
+		return nil
+	})
+}
@@ -0,0 +1,121 @@
+package commands
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// CaptureCmd prints the messages recorded in the capture of the p2p messages.
+var CaptureCmd = &cobra.Command{
+	Use:   "capture [head-file]",
+	Short: "Print the captured p2p messages",
+	Long: `Print the messages sent to and received from the peers, as recorded in
+the capture enabled by [p2p] capture_file (or in the capture with the given
+head file), oldest first. The flags select the messages to print.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         printCapture,
+	SilenceUsage: true,
+}
+
+var (
+	capturePeer      string
+	captureDirection string
+	captureChannels  []string
+	captureType      string
+	captureSince     string
+	captureUntil     string
+	captureJSON      bool
+	captureBytes     bool
+)
+
+func init() {
+	CaptureCmd.Flags().StringVar(&capturePeer, "peer", "", "Only print the messages of the peer with this ID")
+	CaptureCmd.Flags().StringVar(&captureDirection, "direction", "", "Only print the messages sent (\"send\") or received (\"recv\")")
+	CaptureCmd.Flags().StringSliceVar(&captureChannels, "channel", nil, "Only print the messages of these channels (e.g. 0x20,0x21)")
+	CaptureCmd.Flags().StringVar(&captureType, "type", "", "Only print the messages whose type contains this (e.g. VoteMessage)")
+	CaptureCmd.Flags().StringVar(&captureSince, "since", "", "Only print the messages recorded at or after this RFC3339 time")
+	CaptureCmd.Flags().StringVar(&captureUntil, "until", "", "Only print the messages recorded at or before this RFC3339 time")
+	CaptureCmd.Flags().BoolVar(&captureJSON, "json", false, "Print the records as JSON, one per line")
+	CaptureCmd.Flags().BoolVar(&captureBytes, "bytes", false, "Print the bytes of the messages in hex")
+}
+
+func printCapture(cmd *cobra.Command, args []string) error {
+	headPath := config.P2P.CaptureFile()
+	if len(args) == 1 {
+		headPath = args[0]
+	}
+	if headPath == "" {
+		return fmt.Errorf("no capture: set [p2p] capture_file or give the head file")
+	}
+
+	filter, err := captureFilter()
+	if err != nil {
+		return err
+	}
+
+	return p2p.ReadCapture(headPath, func(rec p2p.CaptureRecord) error {
+		if !filter.Match(rec) {
+			return nil
+		}
+		if captureJSON {
+			bz, err := json.Marshal(rec)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(bz))
+			return nil
+		}
+
+		typ := rec.Type
+		if typ == "" {
+			typ = "-"
+		}
+		fmt.Printf("%s %s %s %#x %s %dB\n",
+			rec.Time.Format(time.RFC3339Nano), rec.Direction, rec.Peer, rec.ChID, typ, rec.Size)
+		if captureBytes {
+			fmt.Println(hex.EncodeToString(rec.Msg))
+		}
+		return nil
+	})
+}
+
+// captureFilter returns the filter selected by the flags.
+func captureFilter() (p2p.CaptureFilter, error) {
+	filter := p2p.CaptureFilter{
+		Peer:      p2p.ID(capturePeer),
+		Direction: captureDirection,
+		Type:      captureType,
+	}
+	switch captureDirection {
+	case "", p2p.CaptureSend, p2p.CaptureRecv:
+	default:
+		return filter, fmt.Errorf("invalid --direction %q, expected %q or %q",
+			captureDirection, p2p.CaptureSend, p2p.CaptureRecv)
+	}
+	for _, ch := range captureChannels {
+		chID, err := strconv.ParseUint(ch, 0, 8)
+		if err != nil {
+			return filter, fmt.Errorf("invalid --channel %q: %v", ch, err)
+		}
+		filter.ChIDs = append(filter.ChIDs, byte(chID))
+	}
+	var err error
+	if captureSince != "" {
+		if filter.Since, err = time.Parse(time.RFC3339Nano, captureSince); err != nil {
+			return filter, fmt.Errorf("invalid --since: %v", err)
+		}
+	}
+	if captureUntil != "" {
+		if filter.Until, err = time.Parse(time.RFC3339Nano, captureUntil); err != nil {
+			return filter, fmt.Errorf("invalid --until: %v", err)
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,384 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	"github.com/tendermint/tendermint/evidence"
+	dbm "github.com/tendermint/tendermint/libs/db"
+	"github.com/tendermint/tendermint/libs/log"
+	nm "github.com/tendermint/tendermint/node"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/txindex/null"
+	"github.com/tendermint/tendermint/types"
+)
+
+// CheckDBCmd checks the databases of a stopped node for inconsistencies,
+// like the ones left behind by an unclean shutdown.
+var CheckDBCmd = &cobra.Command{
+	Use:   "check-db",
+	Short: "Check the blockstore, state, evidence and tx index databases for inconsistencies",
+	Long: `Walk the blockstore, state, evidence and tx index databases and check
+that they reference each other consistently: store heights, block part set
+completeness, commit, validators and results hashes, committed evidence and
+indexed transactions.
+
+With --repair, the following inconsistencies are fixed:
+ - blocks past the state height which are incomplete or can't be replayed
+   are dropped from the blockstore
+ - evidence included in a block is marked as committed
+ - transactions missing from the tx index are indexed again
+
+The node must not be running.`,
+	RunE:         checkDB,
+	SilenceUsage: true,
+}
+
+var repairDB bool
+
+func init() {
+	CheckDBCmd.Flags().BoolVar(&repairDB, "repair", false, "Repair the inconsistencies that can be fixed safely")
+}
+
+func checkDB(cmd *cobra.Command, args []string) error {
+	dbType := dbm.DBBackendType(config.DBBackend)
+
+	blockStoreDB := dbm.NewDB("blockstore", dbType, config.DBDir())
+	defer blockStoreDB.Close()
+	blockStore, err := bc.CreateBlockStore(config.BaseConfig, blockStoreDB)
+	if err != nil {
+		return err
+	}
+	if closer, ok := blockStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	stateDB := dbm.NewDB("state", dbType, config.DBDir())
+	defer stateDB.Close()
+
+	evidenceDB := dbm.NewDB("evidence", dbType, config.DBDir())
+	defer evidenceDB.Close()
+
+	txIndexer, err := nm.CreateTxIndexer(config, nm.DefaultDBProvider)
+	if err != nil {
+		return err
+	}
+	if closer, ok := txIndexer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if _, ok := txIndexer.(*null.TxIndex); ok {
+		txIndexer = nil
+	}
+
+	checker := &dbChecker{
+		blockStore:    blockStore,
+		stateDB:       stateDB,
+		evidenceStore: evidence.NewEvidenceStore(evidenceDB),
+		txIndexer:     txIndexer,
+		repair:        repairDB,
+		logger:        logger.With("module", "check-db"),
+	}
+	resetHeight := checker.Check()
+
+	if resetHeight >= 0 {
+		if err := bc.ResetBlockStoreHeight(config.BaseConfig, blockStoreDB, resetHeight); err != nil {
+			return err
+		}
+		checker.logger.Info("Repaired: reset blockstore height", "height", resetHeight)
+	}
+
+	if checker.unrepaired > 0 {
+		return fmt.Errorf("found %d inconsistencies that were not repaired", checker.unrepaired)
+	}
+	checker.logger.Info("Check complete", "repaired", checker.repaired)
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// dbChecker walks the databases of a node and reports the inconsistencies
+// it finds, repairing them if asked to.
+type dbChecker struct {
+	blockStore    sm.BlockStore
+	stateDB       dbm.DB
+	evidenceStore *evidence.EvidenceStore
+	txIndexer     txindex.TxIndexer // nil if txs are not indexed
+
+	repair bool
+	logger log.Logger
+
+	repaired        int // number of heights with repairs
+	repairedHeights map[int64]struct{}
+	unrepaired      int
+}
+
+// Check runs all checks. If the blockstore has to be rolled back, it returns
+// the height to reset it to, and -1 otherwise.
+func (c *dbChecker) Check() (resetHeight int64) {
+	resetHeight = -1
+
+	state := sm.LoadState(c.stateDB)
+	if state.IsEmpty() {
+		c.report(0, "no state found in the state DB")
+		return
+	}
+	storeHeight := c.blockStore.Height()
+	stateHeight := state.LastBlockHeight
+
+	// The blockstore may be one block ahead of the state: the handshake
+	// replays that block on start. Anything else is an inconsistency.
+	if storeHeight < stateHeight {
+		c.report(storeHeight, fmt.Sprintf("blockstore height %d is behind state height %d", storeHeight, stateHeight))
+	}
+
+	// Blocks past the state height haven't been executed yet, so an incomplete
+	// one can be dropped and fetched again from peers.
+	lastComplete := int64(0)
+	for height := int64(1); height <= storeHeight; height++ {
+		if err := c.checkBlock(height, storeHeight); err != nil {
+			if !c.repair || height <= stateHeight {
+				c.report(height, err.Error())
+			} else {
+				c.reportRepaired(height, err.Error())
+			}
+			break
+		}
+		lastComplete = height
+	}
+
+	validHeight := lastComplete
+	if validHeight > stateHeight+1 {
+		validHeight = stateHeight + 1
+	}
+	if validHeight < storeHeight {
+		msg := fmt.Sprintf("blockstore height %d is past the last usable height %d (state height %d)",
+			storeHeight, validHeight, stateHeight)
+		if c.repair && validHeight >= stateHeight {
+			// the blocks from validHeight+1 are dropped
+			c.reportRepaired(validHeight+1, msg)
+			resetHeight = validHeight
+		} else {
+			c.report(storeHeight, msg)
+		}
+	}
+
+	c.checkState(state, storeHeight)
+
+	for height := int64(1); height <= stateHeight && height <= lastComplete; height++ {
+		c.checkExecuted(height)
+	}
+	return
+}
+
+// checkBlock checks that the block at height is complete and linked to the
+// previous block and to its commit. It returns an error if the block can't be
+// loaded.
+func (c *dbChecker) checkBlock(height, storeHeight int64) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("error loading block: %v", r)
+		}
+	}()
+
+	meta := c.blockStore.LoadBlockMeta(height)
+	if meta == nil {
+		return errors.New("missing block meta")
+	}
+
+	total := meta.BlockID.PartsHeader.Total
+	partSet := types.NewPartSetFromHeader(meta.BlockID.PartsHeader)
+	for i := 0; i < total; i++ {
+		part := c.blockStore.LoadBlockPart(height, i)
+		if part == nil {
+			return fmt.Errorf("missing block part %d/%d", i, total)
+		}
+		if _, err := partSet.AddPart(part); err != nil {
+			return fmt.Errorf("invalid block part %d/%d: %v", i, total, err)
+		}
+	}
+
+	block := c.blockStore.LoadBlock(height)
+	if !bytes.Equal(block.Hash(), meta.BlockID.Hash) {
+		return fmt.Errorf("block hash %X does not match block meta %X", block.Hash(), meta.BlockID.Hash)
+	}
+
+	if height > 1 {
+		prevMeta := c.blockStore.LoadBlockMeta(height - 1)
+		if prevMeta != nil && !block.LastBlockID.Equals(prevMeta.BlockID) {
+			c.report(height, fmt.Sprintf("last block ID %v does not match block %d", block.LastBlockID, height-1))
+		}
+	}
+
+	var commit *types.Commit
+	if height < storeHeight {
+		commit = c.blockStore.LoadBlockCommit(height)
+	} else {
+		commit = c.blockStore.LoadSeenCommit(height)
+	}
+	if commit == nil {
+		c.report(height, "missing commit")
+	} else if !commit.BlockID.Equals(meta.BlockID) {
+		c.report(height, fmt.Sprintf("commit is for block %v, not %v", commit.BlockID, meta.BlockID))
+	}
+	return nil
+}
+
+// checkState checks the latest state against the blockstore.
+func (c *dbChecker) checkState(state sm.State, storeHeight int64) {
+	height := state.LastBlockHeight
+	if height == 0 || height > storeHeight {
+		return
+	}
+	if meta := c.blockStore.LoadBlockMeta(height); meta != nil && !state.LastBlockID.Equals(meta.BlockID) {
+		c.report(height, fmt.Sprintf("state last block ID %v does not match block meta %v", state.LastBlockID, meta.BlockID))
+	}
+	if height == storeHeight {
+		return
+	}
+	next := c.blockStore.LoadBlockMeta(height + 1)
+	if next == nil {
+		return
+	}
+	if !bytes.Equal(next.Header.AppHash, state.AppHash) {
+		c.report(height, fmt.Sprintf("state app hash %X does not match block %d app hash %X",
+			state.AppHash, height+1, next.Header.AppHash))
+	}
+	if !bytes.Equal(next.Header.LastResultsHash, state.LastResultsHash) {
+		c.report(height, fmt.Sprintf("state last results hash %X does not match block %d last results hash %X",
+			state.LastResultsHash, height+1, next.Header.LastResultsHash))
+	}
+}
+
+// checkExecuted checks the data stored when executing the block at height:
+// validator sets, ABCI responses, committed evidence and indexed txs.
+func (c *dbChecker) checkExecuted(height int64) {
+	block := c.blockStore.LoadBlock(height)
+
+	vals, err := sm.LoadValidators(c.stateDB, height)
+	if err != nil {
+		c.report(height, err.Error())
+	} else if !bytes.Equal(vals.Hash(), block.ValidatorsHash) {
+		c.report(height, fmt.Sprintf("validators hash %X does not match block validators hash %X",
+			vals.Hash(), block.ValidatorsHash))
+	}
+	nextVals, err := sm.LoadValidators(c.stateDB, height+1)
+	if err != nil {
+		c.report(height, err.Error())
+	} else if !bytes.Equal(nextVals.Hash(), block.NextValidatorsHash) {
+		c.report(height, fmt.Sprintf("next validators hash %X does not match block next validators hash %X",
+			nextVals.Hash(), block.NextValidatorsHash))
+	}
+
+	if height > 1 {
+		prevResponses, err := sm.LoadABCIResponses(c.stateDB, height-1)
+		if err != nil {
+			c.report(height, err.Error())
+		} else if !bytes.Equal(prevResponses.ResultsHash(), block.LastResultsHash) {
+			c.report(height, fmt.Sprintf("results hash of block %d %X does not match last results hash %X",
+				height-1, prevResponses.ResultsHash(), block.LastResultsHash))
+		}
+	}
+
+	for _, ev := range block.Evidence.Evidence {
+		ei := c.evidenceStore.GetEvidence(ev.Height(), ev.Hash())
+		if ei != nil && ei.Committed {
+			continue
+		}
+		msg := fmt.Sprintf("evidence %X is not marked as committed", ev.Hash())
+		if !c.repair {
+			c.report(height, msg)
+			continue
+		}
+		if ei == nil || ei.Evidence == nil {
+			c.evidenceStore.AddNewEvidence(ev, 0)
+		}
+		c.evidenceStore.MarkEvidenceAsCommitted(ev)
+		c.reportRepaired(height, msg)
+	}
+
+	if c.txIndexer != nil && len(block.Data.Txs) > 0 {
+		c.checkTxIndex(block)
+	}
+}
+
+func (c *dbChecker) checkTxIndex(block *types.Block) {
+	height := block.Height
+	var missing []int
+	for i, tx := range block.Data.Txs {
+		res, err := c.txIndexer.Get(tx.Hash())
+		if err != nil {
+			c.report(height, fmt.Sprintf("error reading tx %X from index: %v", tx.Hash(), err))
+			continue
+		}
+		if res == nil {
+			missing = append(missing, i)
+			continue
+		}
+		// a later block may include the same tx again, overwriting the entry
+		if res.Height < height || (res.Height == height && res.Index != uint32(i)) {
+			c.report(height, fmt.Sprintf("tx %X is indexed at height %d index %d, want index %d",
+				tx.Hash(), res.Height, res.Index, i))
+		}
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	msg := fmt.Sprintf("%d txs missing from the tx index", len(missing))
+	if !c.repair {
+		c.report(height, msg)
+		return
+	}
+	abciResponses, err := sm.LoadABCIResponses(c.stateDB, height)
+	if err != nil {
+		c.report(height, msg)
+		return
+	}
+	if len(abciResponses.DeliverTx) != len(block.Data.Txs) {
+		c.report(height, fmt.Sprintf("%s, can't index them: block has %d txs but %d results",
+			msg, len(block.Data.Txs), len(abciResponses.DeliverTx)))
+		return
+	}
+	batch := txindex.NewBatch(int64(len(missing)))
+	for _, i := range missing {
+		if abciResponses.DeliverTx[i] == nil {
+			c.report(height, fmt.Sprintf("%s, can't index them: missing result of tx %d", msg, i))
+			return
+		}
+		batch.Add(&types.TxResult{
+			Height: height,
+			Index:  uint32(i),
+			Tx:     block.Data.Txs[i],
+			Result: *(abciResponses.DeliverTx[i]),
+		})
+	}
+	if err := c.txIndexer.AddBatch(batch); err != nil {
+		c.report(height, fmt.Sprintf("%s, failed to index them: %v", msg, err))
+		return
+	}
+	c.reportRepaired(height, msg)
+}
+
+// reportRepaired logs an inconsistency which was repaired. Repairs are
+// counted once per height.
+func (c *dbChecker) reportRepaired(height int64, msg string) {
+	if c.repairedHeights == nil {
+		c.repairedHeights = make(map[int64]struct{})
+	}
+	if _, ok := c.repairedHeights[height]; !ok {
+		c.repairedHeights[height] = struct{}{}
+		c.repaired++
+	}
+	c.logger.Error(msg, "height", height, "repaired", true)
+}
+
+func (c *dbChecker) report(height int64, msg string) {
+	c.unrepaired++
+	c.logger.Error(msg, "height", height)
+}
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	bc "github.com/tendermint/tendermint/blockchain"
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/evidence"
+	dbm "github.com/tendermint/tendermint/libs/db"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/txindex/kv"
+	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+)
+
+// testChain holds the databases of a node which stored blocks up to
+// storeHeight and executed them up to stateHeight with the kvstore app.
+type testChain struct {
+	config       cfg.BaseConfig
+	blockStoreDB dbm.DB
+	blockStore   sm.BlockStore
+	stateDB      dbm.DB
+	evidenceDB   dbm.DB
+	txIndexer    txindex.TxIndexer
+}
+
+func newTestChain(t *testing.T, storeHeight, stateHeight int64) *testChain {
+	val, privVal := types.RandValidator(false, 10)
+	genDoc := &types.GenesisDoc{
+		GenesisTime: tmtime.Now(),
+		ChainID:     "check-db-test",
+		Validators:  []types.GenesisValidator{{PubKey: val.PubKey, Power: val.VotingPower}},
+	}
+	c := &testChain{
+		config:       cfg.TestBaseConfig(),
+		blockStoreDB: dbm.NewMemDB(),
+		stateDB:      dbm.NewMemDB(),
+		evidenceDB:   dbm.NewMemDB(),
+		txIndexer:    kv.NewTxIndex(dbm.NewMemDB()),
+	}
+	c.blockStore = bc.NewBlockStore(c.blockStoreDB)
+
+	state, err := sm.LoadStateFromDBOrGenesisDoc(c.stateDB, genDoc)
+	require.NoError(t, err)
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication()))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+	blockExec := sm.NewBlockExecutor(c.stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		sm.MockMempool{}, sm.MockEvidencePool{})
+
+	lastCommit := &types.Commit{}
+	for height := int64(1); height <= storeHeight; height++ {
+		txs := []types.Tx{[]byte(fmt.Sprintf("a%d=1", height)), []byte(fmt.Sprintf("b%d=2", height))}
+		block, parts := state.MakeBlock(height, txs, lastCommit, nil, state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+
+		vote := &types.Vote{
+			ValidatorAddress: privVal.GetAddress(),
+			ValidatorIndex:   0,
+			Height:           height,
+			Timestamp:        block.Time.Add(time.Second),
+			Type:             types.PrecommitType,
+			BlockID:          blockID,
+		}
+		require.NoError(t, privVal.SignVote(genDoc.ChainID, vote))
+		lastCommit = &types.Commit{BlockID: blockID, Precommits: []*types.Vote{vote}}
+		c.blockStore.SaveBlock(block, parts, lastCommit)
+
+		if height > stateHeight {
+			continue
+		}
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+		txResults, err := sm.LoadTxResults(c.stateDB, c.blockStore, height)
+		require.NoError(t, err)
+		batch := txindex.NewBatch(int64(len(txResults)))
+		for _, txResult := range txResults {
+			require.NoError(t, batch.Add(txResult))
+		}
+		require.NoError(t, c.txIndexer.AddBatch(batch))
+	}
+	return c
+}
+
+func (c *testChain) checker(repair bool) *dbChecker {
+	return &dbChecker{
+		blockStore:    c.blockStore,
+		stateDB:       c.stateDB,
+		evidenceStore: evidence.NewEvidenceStore(c.evidenceDB),
+		txIndexer:     c.txIndexer,
+		repair:        repair,
+		logger:        log.TestingLogger(),
+	}
+}
+
+func TestDBChecker(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		storeHeight          int64
+		stateHeight          int64
+		corrupt              func(t *testing.T, c *testChain)
+		repair               bool
+		resetHeight          int64
+		repaired, unrepaired int
+	}{
+		{"consistent", 4, 4, nil, false, -1, 0, 0},
+		{"consistent, block to replay", 5, 4, nil, false, -1, 0, 0},
+		{"missing state", 4, 4, func(t *testing.T, c *testChain) {
+			c.stateDB = dbm.NewMemDB()
+		}, false, -1, 0, 1},
+		{"missing block", 4, 4, func(t *testing.T, c *testChain) {
+			c.blockStoreDB.Delete([]byte("P:2:0")) // the only part of block 2
+		}, true, -1, 0, 2},
+		{"truncated store", 4, 4, func(t *testing.T, c *testChain) {
+			require.NoError(t, bc.ResetBlockStoreHeight(c.config, c.blockStoreDB, 3))
+			c.blockStore = bc.NewBlockStore(c.blockStoreDB)
+		}, true, -1, 0, 1},
+		{"missing tx index", 4, 4, func(t *testing.T, c *testChain) {
+			c.txIndexer = kv.NewTxIndex(dbm.NewMemDB())
+		}, false, -1, 0, 4},
+		{"repair missing tx index", 4, 4, func(t *testing.T, c *testChain) {
+			c.txIndexer = kv.NewTxIndex(dbm.NewMemDB())
+		}, true, -1, 4, 0},
+		{"repair missing tx index without results", 4, 4, func(t *testing.T, c *testChain) {
+			c.txIndexer = kv.NewTxIndex(dbm.NewMemDB())
+			abciResponses, err := sm.LoadABCIResponses(c.stateDB, 2)
+			require.NoError(t, err)
+			abciResponses.DeliverTx = abciResponses.DeliverTx[:1]
+			c.stateDB.Set([]byte("abciResponsesKey:2"), abciResponses.Bytes())
+		}, true, -1, 3, 2},
+		{"repair incomplete block to replay", 5, 4, func(t *testing.T, c *testChain) {
+			c.blockStoreDB.Delete([]byte("P:5:0"))
+		}, true, 4, 1, 0},
+		{"incomplete block to replay", 5, 4, func(t *testing.T, c *testChain) {
+			c.blockStoreDB.Delete([]byte("P:5:0"))
+		}, false, -1, 0, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestChain(t, tc.storeHeight, tc.stateHeight)
+			if tc.corrupt != nil {
+				tc.corrupt(t, c)
+			}
+			checker := c.checker(tc.repair)
+			assert.Equal(t, tc.resetHeight, checker.Check())
+			assert.Equal(t, tc.repaired, checker.repaired, "repaired")
+			assert.Equal(t, tc.unrepaired, checker.unrepaired, "unrepaired")
+		})
+	}
+}
+
+func TestDBCheckerRepairTxIndex(t *testing.T) {
+	c := newTestChain(t, 3, 3)
+	c.txIndexer = kv.NewTxIndex(dbm.NewMemDB())
+	checker := c.checker(true)
+	checker.Check()
+	require.Equal(t, 0, checker.unrepaired)
+
+	// a second run finds nothing left to repair
+	checker = c.checker(false)
+	checker.Check()
+	assert.Equal(t, 0, checker.unrepaired)
+	assert.Equal(t, 0, checker.repaired)
+}
@@ -0,0 +1,18 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// DebugCmd groups the commands used to inspect and repair the data of a
+// stopped node.
+var DebugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Inspect and repair the data of a stopped node",
+}
+
+func init() {
+	DebugCmd.AddCommand(CheckDBCmd)
+	DebugCmd.AddCommand(AddrBookCmd)
+	DebugCmd.AddCommand(CaptureCmd)
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
+	nm "github.com/tendermint/tendermint/node"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/txindex/null"
+)
+
+// ReindexCmd rebuilds the tx index of a stopped node from its blockstore and
+// state databases.
+var ReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuild the tx index from the blockstore and state databases",
+	Long: `Index again the transactions of the blocks between --start-height and
+--end-height (the last executed block by default), using their results from
+the state database and the current [tx_index] settings. Entries of tags which
+are no longer indexed are deleted.
+
+Use it after changing the tags to index, or to make the history searchable
+after enabling indexing. The node must not be running.`,
+	RunE:         reindex,
+	SilenceUsage: true,
+}
+
+var (
+	reindexStartHeight int64
+	reindexEndHeight   int64
+)
+
+func init() {
+	ReindexCmd.Flags().Int64Var(&reindexStartHeight, "start-height", 1, "Height of the first block to index")
+	ReindexCmd.Flags().Int64Var(&reindexEndHeight, "end-height", 0, "Height of the last block to index (0 means the last executed block)")
+}
+
+// reindexer is implemented by the tx indexers which have to remove stale
+// entries when indexing txs again. Others just overwrite them with AddBatch.
+type reindexer interface {
+	Reindex(b *txindex.Batch) error
+}
+
+func reindex(cmd *cobra.Command, args []string) error {
+	txIndexer, err := nm.CreateTxIndexer(config, nm.DefaultDBProvider)
+	if err != nil {
+		return err
+	}
+	if closer, ok := txIndexer.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if _, ok := txIndexer.(*null.TxIndex); ok {
+		return errors.New(`tx indexing is disabled (set [tx_index] indexer = "kv" or "sql" in the config)`)
+	}
+
+	dbType := dbm.DBBackendType(config.DBBackend)
+
+	blockStoreDB := dbm.NewDB("blockstore", dbType, config.DBDir())
+	defer blockStoreDB.Close()
+	blockStore, err := bc.CreateBlockStore(config.BaseConfig, blockStoreDB)
+	if err != nil {
+		return err
+	}
+	if closer, ok := blockStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	stateDB := dbm.NewDB("state", dbType, config.DBDir())
+	defer stateDB.Close()
+
+	// only executed blocks have results
+	state := sm.LoadState(stateDB)
+	lastHeight := cmn.MinInt64(state.LastBlockHeight, blockStore.Height())
+	startHeight, endHeight := reindexStartHeight, reindexEndHeight
+	if endHeight == 0 {
+		endHeight = lastHeight
+	}
+	switch {
+	case startHeight < 1:
+		return fmt.Errorf("--start-height must be at least 1, got %d", startHeight)
+	case endHeight > lastHeight:
+		return fmt.Errorf("--end-height %d is past the last executed block %d", endHeight, lastHeight)
+	case startHeight > endHeight:
+		return fmt.Errorf("--start-height %d is past --end-height %d", startHeight, endHeight)
+	}
+
+	logger := logger.With("module", "reindex")
+	logger.Info("Reindexing txs", "from", startHeight, "to", endHeight)
+
+	numTxs := 0
+	for height := startHeight; height <= endHeight; height++ {
+		n, err := reindexBlock(txIndexer, blockStore, stateDB, height)
+		if err != nil {
+			return err
+		}
+		numTxs += n
+		if height%1000 == 0 {
+			logger.Info("Reindexed txs", "height", height, "txs", numTxs)
+		}
+	}
+
+	logger.Info("Reindex complete", "from", startHeight, "to", endHeight, "txs", numTxs)
+	return nil
+}
+
+// reindexBlock indexes the txs of the block at the given height again and
+// returns their number.
+func reindexBlock(txIndexer txindex.TxIndexer, blockStore sm.BlockStore, stateDB dbm.DB, height int64) (int, error) {
+	txResults, err := sm.LoadTxResults(stateDB, blockStore, height)
+	if err != nil {
+		return 0, err
+	}
+	if len(txResults) == 0 {
+		return 0, nil
+	}
+
+	batch := txindex.NewBatch(int64(len(txResults)))
+	for _, txResult := range txResults {
+		batch.Add(txResult)
+	}
+
+	if r, ok := txIndexer.(reindexer); ok {
+		err = r.Reindex(batch)
+	} else {
+		err = txIndexer.AddBatch(batch)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to index the txs of block %d: %v", height, err)
+	}
+	return batch.Size(), nil
+}
@@ -18,7 +18,7 @@ func AddNodeFlags(cmd *cobra.Command) {
 	cmd.Flags().String("moniker", config.Moniker, "Node Name")
 
 	// priv val flags
-	cmd.Flags().String("priv_validator_laddr", config.PrivValidatorListenAddr, "Socket address to listen on for connections from external priv_validator process")
+	cmd.Flags().String("priv_validator_laddr", config.PrivValidatorListenAddr, "Socket address to listen on for connections from external priv_validator process (comma separated to fail over between several)")
 
 	// node flags
 	cmd.Flags().Bool("fast_sync", config.FastSync, "Fast blockchain syncing")
@@ -33,7 +33,7 @@ func AddNodeFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("rpc.unsafe", config.RPC.Unsafe, "Enabled unsafe rpc methods")
 
 	// p2p flags
-	cmd.Flags().String("p2p.laddr", config.P2P.ListenAddress, "Node listen address. (0.0.0.0:0 means any interface, any port)")
+	cmd.Flags().String("p2p.laddr", config.P2P.ListenAddress, "Comma separated list of node listen addresses. (0.0.0.0:0 means any interface, any port)")
 	cmd.Flags().String("p2p.seeds", config.P2P.Seeds, "Comma-delimited ID@host:port seed nodes")
 	cmd.Flags().String("p2p.persistent_peers", config.P2P.PersistentPeers, "Comma-delimited ID@host:port persistent peers")
 	cmd.Flags().Bool("p2p.upnp", config.P2P.UPNP, "Enable/disable UPNP port forwarding")
@@ -70,6 +70,19 @@ func NewRunNodeCmd(nodeProvider nm.NodeProvider) *cobra.Command {
 				}
 			}()
 
+			// Reload the allow list upon receiving SIGHUP
+			if n.Switch().AllowList() != nil {
+				hupc := make(chan os.Signal, 1)
+				signal.Notify(hupc, syscall.SIGHUP)
+				go func() {
+					for range hupc {
+						if err := n.ReloadAllowList(); err != nil {
+							logger.Error("Error reloading allow list", "err", err)
+						}
+					}
+				}()
+			}
+
 			if err := n.Start(); err != nil {
 				return fmt.Errorf("Failed to start node: %v", err)
 			}
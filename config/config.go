@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -19,6 +21,17 @@ const (
 	LogFormatPlain = "plain"
 	// LogFormatJSON is a format for json output
 	LogFormatJSON = "json"
+
+	// BlockStoreBackendDB stores whole blocks in the database
+	BlockStoreBackendDB = "db"
+	// BlockStoreBackendSegment stores block parts in append-only segment
+	// files, with only an index kept in the database
+	BlockStoreBackendSegment = "segment"
+
+	// AddrBookBackendDB saves the address book in the database
+	AddrBookBackendDB = "db"
+	// AddrBookBackendFile saves the address book in a JSON file
+	AddrBookBackendFile = "file"
 )
 
 // NOTE: Most of the structs & relevant comments + the
@@ -35,15 +48,19 @@ var (
 	defaultConfigFileName  = "config.toml"
 	defaultGenesisJSONName = "genesis.json"
 
+	defaultBlockStoreSegmentsName = "blockstore.seg"
+
 	defaultPrivValName  = "priv_validator.json"
 	defaultNodeKeyName  = "node_key.json"
 	defaultAddrBookName = "addrbook.json"
+	defaultBanListName  = "banlist.json"
 
 	defaultConfigFilePath  = filepath.Join(defaultConfigDir, defaultConfigFileName)
 	defaultGenesisJSONPath = filepath.Join(defaultConfigDir, defaultGenesisJSONName)
 	defaultPrivValPath     = filepath.Join(defaultConfigDir, defaultPrivValName)
 	defaultNodeKeyPath     = filepath.Join(defaultConfigDir, defaultNodeKeyName)
 	defaultAddrBookPath    = filepath.Join(defaultConfigDir, defaultAddrBookName)
+	defaultBanListPath     = filepath.Join(defaultConfigDir, defaultBanListName)
 )
 
 // Config defines the top level configuration for a Tendermint node
@@ -114,6 +131,9 @@ func (cfg *Config) ValidateBasic() error {
 	if err := cfg.Consensus.ValidateBasic(); err != nil {
 		return errors.Wrap(err, "Error in [consensus] section")
 	}
+	if err := cfg.TxIndex.ValidateBasic(); err != nil {
+		return errors.Wrap(err, "Error in [tx_index] section")
+	}
 	return errors.Wrap(
 		cfg.Instrumentation.ValidateBasic(),
 		"Error in [instrumentation] section",
@@ -150,6 +170,11 @@ type BaseConfig struct {
 	// Database directory
 	DBPath string `mapstructure:"db_dir"`
 
+	// Block store backend: db | segment
+	// "segment" keeps block parts in append-only files under db_dir,
+	// reducing write amplification of the database
+	BlockStoreBackend string `mapstructure:"block_store_backend"`
+
 	// Output level for logging
 	LogLevel string `mapstructure:"log_level"`
 
@@ -163,9 +188,17 @@ type BaseConfig struct {
 	PrivValidator string `mapstructure:"priv_validator_file"`
 
 	// TCP or UNIX socket address for Tendermint to listen on for
-	// connections from an external PrivValidator process
+	// connections from an external PrivValidator process. With a comma
+	// separated list of addresses, one signer of the validator connects to
+	// each of them and the node fails over between them
 	PrivValidatorListenAddr string `mapstructure:"priv_validator_laddr"`
 
+	// Comma separated list of the IDs (addresses of the keys) of the external
+	// PrivValidator processes allowed to connect to a TCP
+	// priv_validator_laddr. If empty, any process is accepted (and an error is
+	// logged)
+	PrivValidatorAllowedSigners string `mapstructure:"priv_validator_allowed_signers"`
+
 	// A JSON file containing the private key to use for p2p authenticated encryption
 	NodeKey string `mapstructure:"node_key_file"`
 
@@ -196,6 +229,7 @@ func DefaultBaseConfig() BaseConfig {
 		FilterPeers:       false,
 		DBBackend:         "leveldb",
 		DBPath:            "data",
+		BlockStoreBackend: BlockStoreBackendDB,
 	}
 }
 
@@ -233,6 +267,12 @@ func (cfg BaseConfig) DBDir() string {
 	return rootify(cfg.DBPath, cfg.RootDir)
 }
 
+// BlockStoreSegmentsDir returns the full path to the directory holding
+// the segment files of the "segment" block store backend
+func (cfg BaseConfig) BlockStoreSegmentsDir() string {
+	return filepath.Join(cfg.DBDir(), defaultBlockStoreSegmentsName)
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg BaseConfig) ValidateBasic() error {
@@ -241,6 +281,11 @@ func (cfg BaseConfig) ValidateBasic() error {
 	default:
 		return errors.New("unknown log_format (must be 'plain' or 'json')")
 	}
+	switch cfg.BlockStoreBackend {
+	case BlockStoreBackendDB, BlockStoreBackendSegment:
+	default:
+		return errors.New("unknown block_store_backend (must be 'db' or 'segment')")
+	}
 	return nil
 }
 
@@ -349,22 +394,49 @@ func (cfg *RPCConfig) IsCorsEnabled() bool {
 type P2PConfig struct {
 	RootDir string `mapstructure:"home"`
 
-	// Address to listen for incoming connections
+	// Comma separated list of addresses to listen for incoming connections,
+	// e.g. an IPv4 and an IPv6 one
 	ListenAddress string `mapstructure:"laddr"`
 
-	// Address to advertise to peers for them to dial
+	// Comma separated list of addresses to advertise to peers for them to dial
 	ExternalAddress string `mapstructure:"external_address"`
 
+	// How to multiplex the channels of the reactors over a peer connection:
+	//   1) "mconn" (default) - all the channels share a single stream
+	//   2) "stream" - each channel has its own stream, so that large messages
+	//   (e.g. block parts) don't hold back the others (e.g. votes). It falls
+	//   back to "mconn" with the peers which don't support it.
+	Transport string `mapstructure:"transport"`
+
+	// Handshake securing the connections we dial:
+	//   1) "sts" (default) - the original station-to-station handshake
+	//   2) "noise" - the Noise_XX_25519_ChaChaPoly_SHA256 handshake. The
+	//   peers which don't support it are dialed again with "sts".
+	// The connections of the peers which dial us use the handshake they chose.
+	SecretHandshake string `mapstructure:"secret_handshake"`
+
 	// Comma separated list of seed nodes to connect to
 	// We only use these if we can’t connect to peers in the addrbook
 	Seeds string `mapstructure:"seeds"`
 
+	// Comma separated list of DNS names listing seed nodes in their TXT
+	// records (ID@host:port addresses) or SRV records (the first label of
+	// the target being the node ID). They are resolved on start, and again
+	// when dialing the seeds
+	DNSSeeds string `mapstructure:"dns_seeds"`
+
 	// Comma separated list of nodes to keep persistent connections to
 	PersistentPeers string `mapstructure:"persistent_peers"`
 
 	// UPNP port forwarding
 	UPNP bool `mapstructure:"upnp"`
 
+	// Address book backend: db | file
+	// "db" saves the address book in the addrbook database under db_dir,
+	// writing only the changed addresses. On first start, it imports the
+	// addresses of addr_book_file
+	AddrBookBackend string `mapstructure:"addr_book_backend"`
+
 	// Path to address book
 	AddrBook string `mapstructure:"addr_book_file"`
 
@@ -372,6 +444,19 @@ type P2PConfig struct {
 	// Set false for private or local networks
 	AddrBookStrict bool `mapstructure:"addr_book_strict"`
 
+	// Path to the list of banned peers
+	BanList string `mapstructure:"ban_list_file"`
+
+	// How long to ban peers which send invalid messages. 0 (the default)
+	// disables banning. The persistent, private and validator peers are never
+	// banned
+	BanDuration time.Duration `mapstructure:"ban_duration"`
+
+	// Path to the list of the node IDs allowed to connect, one per line. If
+	// set, inbound connections from the other nodes are rejected. The list is
+	// reloaded on SIGHUP and with the unsafe /reload_allow_list RPC endpoint
+	AllowList string `mapstructure:"allow_list_file"`
+
 	// Maximum number of inbound peers
 	MaxNumInboundPeers int `mapstructure:"max_num_inbound_peers"`
 
@@ -390,6 +475,20 @@ type P2PConfig struct {
 	// Rate at which packets can be received, in bytes/second
 	RecvRate int64 `mapstructure:"recv_rate"`
 
+	// Rate at which packets can be sent to all the peers together, in
+	// bytes/second. 0 means no limit
+	TotalSendRate int64 `mapstructure:"total_send_rate"`
+
+	// Rate at which packets can be received from all the peers together, in
+	// bytes/second. 0 means no limit
+	TotalRecvRate int64 `mapstructure:"total_recv_rate"`
+
+	// Caps on the rates at which individual channels send and receive
+	// packets, as a comma separated list of <channel ID>:<send rate>:<recv rate>
+	// in bytes/second (0 means no cap), e.g. "0x30:102400:102400" for the
+	// mempool channel
+	ChannelRates string `mapstructure:"channel_rates"`
+
 	// Set true to enable the peer-exchange reactor
 	PexReactor bool `mapstructure:"pex"`
 
@@ -399,6 +498,14 @@ type P2PConfig struct {
 	// Does not work if the peer-exchange reactor is disabled.
 	SeedMode bool `mapstructure:"seed_mode"`
 
+	// Sentry mode, in which the node guards the validators in ValidatorPeers:
+	// it keeps persistent connections to them, reconnecting with exponential
+	// backoff for as long as it runs, and never gossips their addresses.
+	SentryMode bool `mapstructure:"sentry_mode"`
+
+	// Comma separated list of the validator nodes guarded in sentry mode
+	ValidatorPeers string `mapstructure:"validator_peers"`
+
 	// Comma separated list of peer IDs to keep private (will not be gossiped to
 	// other peers)
 	PrivatePeerIDs string `mapstructure:"private_peer_ids"`
@@ -410,12 +517,24 @@ type P2PConfig struct {
 	HandshakeTimeout time.Duration `mapstructure:"handshake_timeout"`
 	DialTimeout      time.Duration `mapstructure:"dial_timeout"`
 
+	// Path to the head file of the capture of the messages sent to and
+	// received from the peers, rotated every 10MB and limited to 1GB. The
+	// messages are captured only if set. Read it with "tendermint debug
+	// capture"
+	Capture string `mapstructure:"capture_file"`
+
 	// Testing params.
 	// Force dial to fail
 	TestDialFail bool `mapstructure:"test_dial_fail"`
 	// FUzz connection
 	TestFuzz       bool            `mapstructure:"test_fuzz"`
 	TestFuzzConfig *FuzzConnConfig `mapstructure:"test_fuzz_config"`
+	// Enable the unsafe RPC endpoints injecting faults (partitions, dropped
+	// and delayed messages) into the connections to the peers
+	TestFaultInjection bool `mapstructure:"test_fault_injection"`
+	// Seed of the messages dropped at random by the injected faults, to
+	// reproduce a run. A random seed, logged on start, if 0
+	TestFaultInjectionSeed int64 `mapstructure:"test_fault_injection_seed"`
 }
 
 // DefaultP2PConfig returns a default configuration for the peer-to-peer layer
@@ -423,9 +542,14 @@ func DefaultP2PConfig() *P2PConfig {
 	return &P2PConfig{
 		ListenAddress:           "tcp://0.0.0.0:26656",
 		ExternalAddress:         "",
+		Transport:               "mconn",
+		SecretHandshake:         "sts",
 		UPNP:                    false,
+		AddrBookBackend:         AddrBookBackendDB,
 		AddrBook:                defaultAddrBookPath,
 		AddrBookStrict:          true,
+		BanList:                 defaultBanListPath,
+		BanDuration:             0,
 		MaxNumInboundPeers:      40,
 		MaxNumOutboundPeers:     10,
 		FlushThrottleTimeout:    100 * time.Millisecond,
@@ -440,6 +564,8 @@ func DefaultP2PConfig() *P2PConfig {
 		TestDialFail:            false,
 		TestFuzz:                false,
 		TestFuzzConfig:          DefaultFuzzConnConfig(),
+		TestFaultInjection:      false,
+		TestFaultInjectionSeed:  0,
 	}
 }
 
@@ -457,6 +583,68 @@ func (cfg *P2PConfig) AddrBookFile() string {
 	return rootify(cfg.AddrBook, cfg.RootDir)
 }
 
+// BanListFile returns the full path to the list of banned peers
+func (cfg *P2PConfig) BanListFile() string {
+	return rootify(cfg.BanList, cfg.RootDir)
+}
+
+// AllowListFile returns the full path to the list of the peers allowed to
+// connect, or "" if every peer is.
+func (cfg *P2PConfig) AllowListFile() string {
+	if cfg.AllowList == "" {
+		return ""
+	}
+	return rootify(cfg.AllowList, cfg.RootDir)
+}
+
+// CaptureFile returns the full path to the head file of the capture of the
+// messages, or "" if they aren't captured.
+func (cfg *P2PConfig) CaptureFile() string {
+	if cfg.Capture == "" {
+		return ""
+	}
+	return rootify(cfg.Capture, cfg.RootDir)
+}
+
+// ChannelRate holds the caps on the rates at which a channel sends and
+// receives packets, in bytes/second. 0 means no cap.
+type ChannelRate struct {
+	SendRate int64
+	RecvRate int64
+}
+
+// ParseChannelRates returns the caps set in ChannelRates, by channel ID.
+func (cfg *P2PConfig) ParseChannelRates() (map[byte]ChannelRate, error) {
+	rates := make(map[byte]ChannelRate)
+	for _, rate := range strings.Split(cfg.ChannelRates, ",") {
+		rate = strings.TrimSpace(rate)
+		if rate == "" {
+			continue
+		}
+		fields := strings.Split(rate, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%q must be <channel ID>:<send rate>:<recv rate>", rate)
+		}
+		id, err := strconv.ParseUint(fields[0], 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid channel ID in %q", rate)
+		}
+		if _, ok := rates[byte(id)]; ok {
+			return nil, fmt.Errorf("channel %#x is capped more than once", id)
+		}
+		sendRate, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil || sendRate < 0 {
+			return nil, fmt.Errorf("invalid send rate in %q", rate)
+		}
+		recvRate, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || recvRate < 0 {
+			return nil, fmt.Errorf("invalid recv rate in %q", rate)
+		}
+		rates[byte(id)] = ChannelRate{SendRate: sendRate, RecvRate: recvRate}
+	}
+	return rates, nil
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg *P2PConfig) ValidateBasic() error {
@@ -478,6 +666,41 @@ func (cfg *P2PConfig) ValidateBasic() error {
 	if cfg.RecvRate < 0 {
 		return errors.New("recv_rate can't be negative")
 	}
+	if cfg.TotalSendRate < 0 {
+		return errors.New("total_send_rate can't be negative")
+	}
+	if cfg.TotalRecvRate < 0 {
+		return errors.New("total_recv_rate can't be negative")
+	}
+	if _, err := cfg.ParseChannelRates(); err != nil {
+		return errors.Wrap(err, "invalid channel_rates")
+	}
+	if cfg.BanDuration < 0 {
+		return errors.New("ban_duration can't be negative")
+	}
+	switch cfg.AddrBookBackend {
+	case AddrBookBackendDB, AddrBookBackendFile:
+	default:
+		return errors.New("unknown addr_book_backend (must be 'db' or 'file')")
+	}
+	if cfg.SentryMode {
+		if cfg.SeedMode {
+			return errors.New("sentry_mode and seed_mode can't be both enabled")
+		}
+		if cfg.ValidatorPeers == "" {
+			return errors.New("sentry_mode requires validator_peers")
+		}
+	}
+	switch cfg.Transport {
+	case "mconn", "stream":
+	default:
+		return fmt.Errorf("unknown transport %q, expected \"mconn\" or \"stream\"", cfg.Transport)
+	}
+	switch cfg.SecretHandshake {
+	case "sts", "noise":
+	default:
+		return fmt.Errorf("unknown secret_handshake %q, expected \"sts\" or \"noise\"", cfg.SecretHandshake)
+	}
 	return nil
 }
 
@@ -729,8 +952,21 @@ type TxIndexConfig struct {
 	// Options:
 	//   1) "null"
 	//   2) "kv" (default) - the simplest possible indexer, backed by key-value storage (defaults to levelDB; see DBBackend).
+	//   3) "sql" - txs, results and tags are written into a relational database (see SQLDriver and SQLConn).
 	Indexer string `mapstructure:"indexer"`
 
+	// Database driver used by the "sql" indexer
+	//
+	// Options:
+	//   1) "sqlite3" (default) - requires tendermint to be built with cgo enabled (the default with a C compiler)
+	//   2) "postgres"
+	SQLDriver string `mapstructure:"sql_driver"`
+
+	// Connection string passed to the database driver by the "sql" indexer.
+	// When empty and using "sqlite3", the database is created in the data
+	// directory (tx_index.sqlite).
+	SQLConn string `mapstructure:"sql_conn"`
+
 	// Comma-separated list of tags to index (by default the only tag is "tx.hash")
 	//
 	// You can also index transactions by height by adding "tx.height" tag here.
@@ -753,6 +989,8 @@ type TxIndexConfig struct {
 func DefaultTxIndexConfig() *TxIndexConfig {
 	return &TxIndexConfig{
 		Indexer:      "kv",
+		SQLDriver:    "sqlite3",
+		SQLConn:      "",
 		IndexTags:    "",
 		IndexAllTags: false,
 	}
@@ -763,6 +1001,25 @@ func TestTxIndexConfig() *TxIndexConfig {
 	return DefaultTxIndexConfig()
 }
 
+// ValidateBasic performs basic validation (checking param bounds, etc.) and
+// returns an error if any check fails.
+func (cfg *TxIndexConfig) ValidateBasic() error {
+	switch cfg.Indexer {
+	case "null", "kv":
+	case "sql":
+		switch cfg.SQLDriver {
+		case "sqlite3":
+		case "postgres":
+			if cfg.SQLConn == "" {
+				return errors.New("sql_conn is required with the postgres driver")
+			}
+		default:
+			return fmt.Errorf("unknown sql_driver %q", cfg.SQLDriver)
+		}
+	}
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 // InstrumentationConfig
 
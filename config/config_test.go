@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -36,3 +37,49 @@ func TestConfigValidateBasic(t *testing.T) {
 	cfg.Consensus.TimeoutPropose = -10 * time.Second
 	assert.Error(t, cfg.ValidateBasic())
 }
+
+func TestTxIndexConfigValidateBasic(t *testing.T) {
+	cfg := DefaultTxIndexConfig()
+	cfg.Indexer = "sql"
+	assert.NoError(t, cfg.ValidateBasic())
+
+	// postgres needs a connection string
+	cfg.SQLDriver = "postgres"
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.SQLConn = "postgres://localhost/tendermint"
+	assert.NoError(t, cfg.ValidateBasic())
+
+	cfg.SQLDriver = "mysql"
+	assert.Error(t, cfg.ValidateBasic())
+}
+
+func TestP2PConfigValidateBasic(t *testing.T) {
+	cfg := DefaultP2PConfig()
+	assert.NoError(t, cfg.ValidateBasic())
+
+	// sentry mode needs the validators to guard
+	cfg.SentryMode = true
+	assert.Error(t, cfg.ValidateBasic())
+	cfg.ValidatorPeers = "75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b@10.0.0.2:26656"
+	assert.NoError(t, cfg.ValidateBasic())
+
+	cfg.SeedMode = true
+	assert.Error(t, cfg.ValidateBasic())
+
+	cfg = DefaultP2PConfig()
+	cfg.ChannelRates = "0x30:102400:0, 64:0:51200"
+	rates, err := cfg.ParseChannelRates()
+	require.NoError(t, err)
+	assert.Equal(t, map[byte]ChannelRate{0x30: {102400, 0}, 0x40: {0, 51200}}, rates)
+	assert.NoError(t, cfg.ValidateBasic())
+	for _, invalid := range []string{"0x30:1", "0x100:1:1", "0x30:-1:0", "0x30:1:1,0x30:2:2"} {
+		cfg.ChannelRates = invalid
+		assert.Error(t, cfg.ValidateBasic(), invalid)
+	}
+
+	cfg = DefaultP2PConfig()
+	cfg.SecretHandshake = "noise"
+	assert.NoError(t, cfg.ValidateBasic())
+	cfg.SecretHandshake = "tls"
+	assert.Error(t, cfg.ValidateBasic())
+}
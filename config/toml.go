@@ -83,6 +83,11 @@ db_backend = "{{ .BaseConfig.DBBackend }}"
 # Database directory
 db_dir = "{{ js .BaseConfig.DBPath }}"
 
+# Block store backend: db | segment
+# "segment" keeps block parts in append-only files under db_dir,
+# reducing write amplification of the database
+block_store_backend = "{{ .BaseConfig.BlockStoreBackend }}"
+
 # Output level for logging, including package level options
 log_level = "{{ .BaseConfig.LogLevel }}"
 
@@ -98,9 +103,22 @@ genesis_file = "{{ js .BaseConfig.Genesis }}"
 priv_validator_file = "{{ js .BaseConfig.PrivValidator }}"
 
 # TCP or UNIX socket address for Tendermint to listen on for
-# connections from an external PrivValidator process
+# connections from an external PrivValidator process.
+# With a comma separated list of addresses, one signer of the validator
+# connects to each of them, and the node signs with one of them and fails
+# over to the next one when it disconnects. The signers share the last
+# height/round/step they signed to never double sign: the node only fails
+# over when it can read the state of the previous signer or of a majority of
+# the signers, so run at least three signers to fail over when one is down.
 priv_validator_laddr = "{{ .BaseConfig.PrivValidatorListenAddr }}"
 
+# Comma separated list of the IDs (addresses of the keys) of the external
+# PrivValidator processes allowed to connect to a TCP priv_validator_laddr.
+# If empty, any process is accepted (and an error is logged). The node
+# authenticates itself to the external PrivValidator with its node key (see
+# node_key_file).
+priv_validator_allowed_signers = "{{ .BaseConfig.PrivValidatorAllowedSigners }}"
+
 # Path to the JSON file containing the private key to use for node authentication in the p2p protocol
 node_key_file = "{{ js .BaseConfig.NodeKey }}"
 
@@ -161,24 +179,53 @@ max_open_connections = {{ .RPC.MaxOpenConnections }}
 ##### peer to peer configuration options #####
 [p2p]
 
-# Address to listen for incoming connections
+# Comma separated list of addresses to listen for incoming connections,
+# e.g. "tcp://0.0.0.0:26656,tcp://[::]:26656" to listen on IPv4 and IPv6
 laddr = "{{ .P2P.ListenAddress }}"
 
-# Address to advertise to peers for them to dial
+# Comma separated list of addresses to advertise to peers for them to dial,
+# e.g. an IPv4 and an IPv6 one. The first one is the main address, but the
+# peers we connect to over IPv6 (IPv4) remember the first IPv6 (IPv4) one.
 # If empty, will use the same port as the laddr,
 # and will introspect on the listener or use UPnP
 # to figure out the address.
 external_address = "{{ .P2P.ExternalAddress }}"
 
+# How to multiplex the channels of the reactors over a peer connection:
+#   1) "mconn" (default) - all the channels share a single stream
+#   2) "stream" - each channel has its own stream, so that large messages
+#   (e.g. block parts) don't hold back the others (e.g. votes). It falls
+#   back to "mconn" with the peers which don't support it.
+transport = "{{ .P2P.Transport }}"
+
+# Handshake securing the connections we dial:
+#   1) "sts" (default) - the original station-to-station handshake
+#   2) "noise" - the Noise_XX_25519_ChaChaPoly_SHA256 handshake. The
+#   peers which don't support it are dialed again with "sts".
+# The connections of the peers which dial us use the handshake they chose.
+secret_handshake = "{{ .P2P.SecretHandshake }}"
+
 # Comma separated list of seed nodes to connect to
 seeds = "{{ .P2P.Seeds }}"
 
+# Comma separated list of DNS names listing seed nodes in their TXT
+# records (ID@host:port addresses) or SRV records (the first label of
+# the target being the node ID). They are resolved on start, and again
+# when dialing the seeds
+dns_seeds = "{{ .P2P.DNSSeeds }}"
+
 # Comma separated list of nodes to keep persistent connections to
 persistent_peers = "{{ .P2P.PersistentPeers }}"
 
 # UPNP port forwarding
 upnp = {{ .P2P.UPNP }}
 
+# Address book backend: db | file
+# "db" saves the address book in the addrbook database under db_dir,
+# writing only the changed addresses. On first start, it imports the
+# addresses of addr_book_file
+addr_book_backend = "{{ .P2P.AddrBookBackend }}"
+
 # Path to address book
 addr_book_file = "{{ js .P2P.AddrBook }}"
 
@@ -186,6 +233,18 @@ addr_book_file = "{{ js .P2P.AddrBook }}"
 # Set false for private or local networks
 addr_book_strict = {{ .P2P.AddrBookStrict }}
 
+# Path to the list of banned peers
+ban_list_file = "{{ js .P2P.BanList }}"
+
+# How long to ban peers which send invalid messages. 0 disables banning.
+# The persistent, private and validator peers are never banned
+ban_duration = "{{ .P2P.BanDuration }}"
+
+# Path to the list of the node IDs allowed to connect, one per line. If set,
+# inbound connections from the other nodes are rejected. The list is reloaded
+# on SIGHUP and with the unsafe /reload_allow_list RPC endpoint
+allow_list_file = "{{ js .P2P.AllowList }}"
+
 # Maximum number of inbound peers
 max_num_inbound_peers = {{ .P2P.MaxNumInboundPeers }}
 
@@ -204,6 +263,21 @@ send_rate = {{ .P2P.SendRate }}
 # Rate at which packets can be received, in bytes/second
 recv_rate = {{ .P2P.RecvRate }}
 
+# Rate at which packets can be sent to all the peers together, in bytes/second.
+# 0 means no limit
+total_send_rate = {{ .P2P.TotalSendRate }}
+
+# Rate at which packets can be received from all the peers together, in
+# bytes/second. 0 means no limit
+total_recv_rate = {{ .P2P.TotalRecvRate }}
+
+# Caps on the rates at which individual channels send and receive packets, as a
+# comma separated list of <channel ID>:<send rate>:<recv rate> in bytes/second
+# (0 means no cap), e.g. "0x30:102400:102400" for the mempool channel. The IDs
+# of the channels are 0x00 (pex), 0x20-0x23 (consensus), 0x30 (mempool),
+# 0x38 (evidence) and 0x40 (blockchain)
+channel_rates = "{{ .P2P.ChannelRates }}"
+
 # Set true to enable the peer-exchange reactor
 pex = {{ .P2P.PexReactor }}
 
@@ -213,6 +287,14 @@ pex = {{ .P2P.PexReactor }}
 # Does not work if the peer-exchange reactor is disabled.
 seed_mode = {{ .P2P.SeedMode }}
 
+# Sentry mode, in which the node guards the validators in validator_peers:
+# it keeps persistent connections to them, reconnecting with exponential
+# backoff for as long as it runs, and never gossips their addresses.
+sentry_mode = {{ .P2P.SentryMode }}
+
+# Comma separated list of the validator nodes guarded in sentry mode
+validator_peers = "{{ .P2P.ValidatorPeers }}"
+
 # Comma separated list of peer IDs to keep private (will not be gossiped to other peers)
 private_peer_ids = "{{ .P2P.PrivatePeerIDs }}"
 
@@ -223,6 +305,20 @@ allow_duplicate_ip = {{ .P2P.AllowDuplicateIP }}
 handshake_timeout = "{{ .P2P.HandshakeTimeout }}"
 dial_timeout = "{{ .P2P.DialTimeout }}"
 
+# Path to the head file of the capture of the messages sent to and received
+# from the peers, rotated every 10MB and limited to 1GB. The messages are
+# captured only if set. Read it with "tendermint debug capture"
+capture_file = "{{ js .P2P.Capture }}"
+
+# Testing only! Enable the unsafe RPC endpoints which inject faults into the
+# connections to the peers: partitions, dropped and delayed messages. Never
+# enable it in production
+test_fault_injection = {{ .P2P.TestFaultInjection }}
+
+# Seed of the messages dropped at random by the injected faults, to reproduce
+# a run. If 0, a random seed is used and logged on start
+test_fault_injection_seed = {{ .P2P.TestFaultInjectionSeed }}
+
 ##### mempool configuration options #####
 [mempool]
 
@@ -274,8 +370,21 @@ blocktime_iota = "{{ .Consensus.BlockTimeIota }}"
 # Options:
 #   1) "null"
 #   2) "kv" (default) - the simplest possible indexer, backed by key-value storage (defaults to levelDB; see DBBackend).
+#   3) "sql" - txs, results and tags are written into a relational database (see sql_driver and sql_conn).
 indexer = "{{ .TxIndex.Indexer }}"
 
+# Database driver used by the "sql" indexer
+#
+# Options:
+#   1) "sqlite3" (default) - requires tendermint to be built with cgo enabled (the default with a C compiler)
+#   2) "postgres"
+sql_driver = "{{ .TxIndex.SQLDriver }}"
+
+# Connection string passed to the database driver by the "sql" indexer.
+# When empty and using "sqlite3", the database is created in the data
+# directory (tx_index.sqlite).
+sql_conn = "{{ .TxIndex.SQLConn }}"
+
 # Comma-separated list of tags to index (by default the only tag is "tx.hash")
 #
 # You can also index transactions by height by adding "tx.height" tag here.
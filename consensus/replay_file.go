@@ -305,7 +305,10 @@ func newConsensusStateForReplay(config cfg.BaseConfig, csConfig *cfg.ConsensusCo
 	dbType := dbm.DBBackendType(config.DBBackend)
 	// Get BlockStore
 	blockStoreDB := dbm.NewDB("blockstore", dbType, config.DBDir())
-	blockStore := bc.NewBlockStore(blockStoreDB)
+	blockStore, err := bc.CreateBlockStore(config, blockStoreDB)
+	if err != nil {
+		cmn.Exit(err.Error())
+	}
 
 	// Get State
 	stateDB := dbm.NewDB("state", dbType, config.DBDir())
@@ -65,19 +65,24 @@ func (evR *EvidenceReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
 	// nothing to do
 }
 
+// DecodeMessage implements p2p.MessageDecoder.
+func (evR *EvidenceReactor) DecodeMessage(chID byte, msgBytes []byte) (interface{}, error) {
+	return decodeMsg(msgBytes)
+}
+
 // Receive implements Reactor.
 // It adds any received evidence to the evpool.
 func (evR *EvidenceReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 	msg, err := decodeMsg(msgBytes)
 	if err != nil {
 		evR.Logger.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
-		evR.Switch.StopPeerForError(src, err)
+		evR.Switch.StopPeerForMisbehavior(src, err)
 		return
 	}
 
 	if err = msg.ValidateBasic(); err != nil {
 		evR.Logger.Error("Peer sent us invalid msg", "peer", src, "msg", msg, "err", err)
-		evR.Switch.StopPeerForError(src, err)
+		evR.Switch.StopPeerForMisbehavior(src, err)
 		return
 	}
 
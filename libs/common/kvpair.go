@@ -2,7 +2,11 @@ package common
 
 import (
 	"bytes"
+	"fmt"
+	"math"
 	"sort"
+	"strconv"
+	"time"
 )
 
 //----------------------------------------
@@ -14,9 +18,40 @@ Defined in types.proto
 type KVPair struct {
 	Key   []byte
 	Value []byte
+	Type  KVPair_Type
 }
 */
 
+// ValidateValue returns an error if the value is not a valid value of the
+// pair's type.
+func (pair KVPair) ValidateValue() error {
+	value := string(pair.Value)
+	switch pair.Type {
+	case KVPair_UNTYPED, KVPair_STRING:
+		return nil
+	case KVPair_INT:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("invalid INT value %q", value)
+		}
+	case KVPair_DECIMAL:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Errorf("invalid DECIMAL value %q", value)
+		}
+	case KVPair_TIME:
+		if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+			return fmt.Errorf("invalid TIME value %q (want RFC3339)", value)
+		}
+	case KVPair_BOOL:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("invalid BOOL value %q (want true or false)", value)
+		}
+	default:
+		return fmt.Errorf("unknown type %v", pair.Type)
+	}
+	return nil
+}
+
 type KVPairs []KVPair
 
 // Sorting
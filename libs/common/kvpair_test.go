@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKVPairValidateValue(t *testing.T) {
+	testCases := []struct {
+		typ   KVPair_Type
+		value string
+		valid bool
+	}{
+		{KVPair_UNTYPED, "anything", true},
+		{KVPair_STRING, "7", true},
+		{KVPair_INT, "-42", true},
+		{KVPair_INT, "4.2", false},
+		{KVPair_INT, "99999999999999999999", false},
+		{KVPair_DECIMAL, "3.14", true},
+		{KVPair_DECIMAL, "-1e-6", true},
+		{KVPair_DECIMAL, "NaN", false},
+		{KVPair_DECIMAL, "pi", false},
+		{KVPair_TIME, "2013-05-03T14:45:00Z", true},
+		{KVPair_TIME, "2013-05-03T14:45:00.123+02:00", true},
+		{KVPair_TIME, "2013-05-03", false},
+		{KVPair_BOOL, "true", true},
+		{KVPair_BOOL, "1", false},
+		{KVPair_Type(42), "", false},
+	}
+
+	for _, tc := range testCases {
+		err := KVPair{Key: []byte("k"), Value: []byte(tc.value), Type: tc.typ}.ValidateValue()
+		if tc.valid {
+			assert.NoError(t, err, "%v %q", tc.typ, tc.value)
+		} else {
+			assert.Error(t, err, "%v %q", tc.typ, tc.value)
+		}
+	}
+}
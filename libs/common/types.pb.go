@@ -26,20 +26,64 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.GoGoProtoPackageIsVersion2 // please upgrade the proto package
 
+// Type of a value, which determines how it is compared in queries.
+type KVPair_Type int32
+
+const (
+	// The value is compared as a number, a time or a string depending on
+	// what it looks like.
+	KVPair_UNTYPED KVPair_Type = 0
+	// Any bytes.
+	KVPair_STRING KVPair_Type = 1
+	// A base 10 integer which fits in 64 bits, like "-42".
+	KVPair_INT KVPair_Type = 2
+	// A decimal number, like "3.14" or "-1e-6".
+	KVPair_DECIMAL KVPair_Type = 3
+	// An RFC3339 time, like "2013-05-03T14:45:00Z".
+	KVPair_TIME KVPair_Type = 4
+	// "true" or "false".
+	KVPair_BOOL KVPair_Type = 5
+)
+
+var KVPair_Type_name = map[int32]string{
+	0: "UNTYPED",
+	1: "STRING",
+	2: "INT",
+	3: "DECIMAL",
+	4: "TIME",
+	5: "BOOL",
+}
+var KVPair_Type_value = map[string]int32{
+	"UNTYPED": 0,
+	"STRING":  1,
+	"INT":     2,
+	"DECIMAL": 3,
+	"TIME":    4,
+	"BOOL":    5,
+}
+
+func (x KVPair_Type) String() string {
+	return proto.EnumName(KVPair_Type_name, int32(x))
+}
+func (KVPair_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_types_b74cf2ab9457afd2, []int{0, 0}
+}
+
 // Define these here for compatibility but use libs/common.KVPair.
 type KVPair struct {
-	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key                  []byte      `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value                []byte      `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Type                 KVPair_Type `protobuf:"varint,3,opt,name=type,proto3,enum=common.KVPair_Type" json:"type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *KVPair) Reset()         { *m = KVPair{} }
 func (m *KVPair) String() string { return proto.CompactTextString(m) }
 func (*KVPair) ProtoMessage()    {}
 func (*KVPair) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_611b4364a8604338, []int{0}
+	return fileDescriptor_types_b74cf2ab9457afd2, []int{0}
 }
 func (m *KVPair) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -82,6 +126,13 @@ func (m *KVPair) GetValue() []byte {
 	return nil
 }
 
+func (m *KVPair) GetType() KVPair_Type {
+	if m != nil {
+		return m.Type
+	}
+	return KVPair_UNTYPED
+}
+
 // Define these here for compatibility but use libs/common.KI64Pair.
 type KI64Pair struct {
 	Key                  []byte   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
@@ -95,7 +146,7 @@ func (m *KI64Pair) Reset()         { *m = KI64Pair{} }
 func (m *KI64Pair) String() string { return proto.CompactTextString(m) }
 func (*KI64Pair) ProtoMessage()    {}
 func (*KI64Pair) Descriptor() ([]byte, []int) {
-	return fileDescriptor_types_611b4364a8604338, []int{1}
+	return fileDescriptor_types_b74cf2ab9457afd2, []int{1}
 }
 func (m *KI64Pair) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -143,6 +194,8 @@ func init() {
 	golang_proto.RegisterType((*KVPair)(nil), "common.KVPair")
 	proto.RegisterType((*KI64Pair)(nil), "common.KI64Pair")
 	golang_proto.RegisterType((*KI64Pair)(nil), "common.KI64Pair")
+	proto.RegisterEnum("common.KVPair_Type", KVPair_Type_name, KVPair_Type_value)
+	golang_proto.RegisterEnum("common.KVPair_Type", KVPair_Type_name, KVPair_Type_value)
 }
 func (this *KVPair) Equal(that interface{}) bool {
 	if that == nil {
@@ -169,6 +222,9 @@ func (this *KVPair) Equal(that interface{}) bool {
 	if !bytes.Equal(this.Value, that1.Value) {
 		return false
 	}
+	if this.Type != that1.Type {
+		return false
+	}
 	if !bytes.Equal(this.XXX_unrecognized, that1.XXX_unrecognized) {
 		return false
 	}
@@ -231,6 +287,11 @@ func (m *KVPair) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTypes(dAtA, i, uint64(len(m.Value)))
 		i += copy(dAtA[i:], m.Value)
 	}
+	if m.Type != 0 {
+		dAtA[i] = 0x18
+		i++
+		i = encodeVarintTypes(dAtA, i, uint64(m.Type))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -290,8 +351,9 @@ func NewPopulatedKVPair(r randyTypes, easy bool) *KVPair {
 	for i := 0; i < v2; i++ {
 		this.Value[i] = byte(r.Intn(256))
 	}
+	this.Type = KVPair_Type([]int32{0, 1, 2, 3, 4, 5}[r.Intn(6)])
 	if !easy && r.Intn(10) != 0 {
-		this.XXX_unrecognized = randUnrecognizedTypes(r, 3)
+		this.XXX_unrecognized = randUnrecognizedTypes(r, 4)
 	}
 	return this
 }
@@ -396,6 +458,9 @@ func (m *KVPair) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovTypes(uint64(l))
 	}
+	if m.Type != 0 {
+		n += 1 + sovTypes(uint64(m.Type))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -522,6 +587,25 @@ func (m *KVPair) Unmarshal(dAtA []byte) error {
 				m.Value = []byte{}
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			m.Type = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTypes
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Type |= (KVPair_Type(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTypes(dAtA[iNdEx:])
@@ -750,22 +834,28 @@ var (
 	ErrIntOverflowTypes   = fmt.Errorf("proto: integer overflow")
 )
 
-func init() { proto.RegisterFile("libs/common/types.proto", fileDescriptor_types_611b4364a8604338) }
+func init() { proto.RegisterFile("libs/common/types.proto", fileDescriptor_types_b74cf2ab9457afd2) }
 func init() {
-	golang_proto.RegisterFile("libs/common/types.proto", fileDescriptor_types_611b4364a8604338)
+	golang_proto.RegisterFile("libs/common/types.proto", fileDescriptor_types_b74cf2ab9457afd2)
 }
 
-var fileDescriptor_types_611b4364a8604338 = []byte{
-	// 174 bytes of a gzipped FileDescriptorProto
+var fileDescriptor_types_b74cf2ab9457afd2 = []byte{
+	// 269 bytes of a gzipped FileDescriptorProto
 	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0xcf, 0xc9, 0x4c, 0x2a,
 	0xd6, 0x4f, 0xce, 0xcf, 0xcd, 0xcd, 0xcf, 0xd3, 0x2f, 0xa9, 0x2c, 0x48, 0x2d, 0xd6, 0x2b, 0x28,
 	0xca, 0x2f, 0xc9, 0x17, 0x62, 0x83, 0x88, 0x49, 0xe9, 0xa6, 0x67, 0x96, 0x64, 0x94, 0x26, 0xe9,
 	0x25, 0xe7, 0xe7, 0xea, 0xa7, 0xe7, 0xa7, 0xe7, 0xeb, 0x83, 0xa5, 0x93, 0x4a, 0xd3, 0xc0, 0x3c,
-	0x30, 0x07, 0xcc, 0x82, 0x68, 0x53, 0x32, 0xe0, 0x62, 0xf3, 0x0e, 0x0b, 0x48, 0xcc, 0x2c, 0x12,
-	0x12, 0xe0, 0x62, 0xce, 0x4e, 0xad, 0x94, 0x60, 0x54, 0x60, 0xd4, 0xe0, 0x09, 0x02, 0x31, 0x85,
-	0x44, 0xb8, 0x58, 0xcb, 0x12, 0x73, 0x4a, 0x53, 0x25, 0x98, 0xc0, 0x62, 0x10, 0x8e, 0x92, 0x11,
-	0x17, 0x87, 0xb7, 0xa7, 0x99, 0x09, 0x31, 0x7a, 0x98, 0xa1, 0x7a, 0x9c, 0x64, 0x7e, 0x3c, 0x94,
-	0x63, 0x5c, 0xf1, 0x48, 0x8e, 0x71, 0xc7, 0x23, 0x39, 0xc6, 0x13, 0x8f, 0xe4, 0x18, 0x2f, 0x3c,
-	0x92, 0x63, 0x7c, 0xf0, 0x48, 0x8e, 0xf1, 0xc0, 0x63, 0x39, 0xc6, 0x24, 0x36, 0xb0, 0x53, 0x8c,
-	0x01, 0x01, 0x00, 0x00, 0xff, 0xff, 0xb1, 0x39, 0xe1, 0xef, 0xdc, 0x00, 0x00, 0x00,
+	0x30, 0x07, 0xcc, 0x82, 0x68, 0x53, 0x5a, 0xc2, 0xc8, 0xc5, 0xe6, 0x1d, 0x16, 0x90, 0x98, 0x59,
+	0x24, 0x24, 0xc0, 0xc5, 0x9c, 0x9d, 0x5a, 0x29, 0xc1, 0xa8, 0xc0, 0xa8, 0xc1, 0x13, 0x04, 0x62,
+	0x0a, 0x89, 0x70, 0xb1, 0x96, 0x25, 0xe6, 0x94, 0xa6, 0x4a, 0x30, 0x81, 0xc5, 0x20, 0x1c, 0x21,
+	0x75, 0x2e, 0x16, 0x90, 0xc5, 0x12, 0xcc, 0x0a, 0x8c, 0x1a, 0x7c, 0x46, 0xc2, 0x7a, 0x10, 0x8b,
+	0xf5, 0x20, 0xa6, 0xe8, 0x85, 0x54, 0x16, 0xa4, 0x06, 0x81, 0x15, 0x28, 0x79, 0x72, 0xb1, 0x80,
+	0x78, 0x42, 0xdc, 0x5c, 0xec, 0xa1, 0x7e, 0x21, 0x91, 0x01, 0xae, 0x2e, 0x02, 0x0c, 0x42, 0x5c,
+	0x5c, 0x6c, 0xc1, 0x21, 0x41, 0x9e, 0x7e, 0xee, 0x02, 0x8c, 0x42, 0xec, 0x5c, 0xcc, 0x9e, 0x7e,
+	0x21, 0x02, 0x4c, 0x20, 0x15, 0x2e, 0xae, 0xce, 0x9e, 0xbe, 0x8e, 0x3e, 0x02, 0xcc, 0x42, 0x1c,
+	0x5c, 0x2c, 0x21, 0x9e, 0xbe, 0xae, 0x02, 0x2c, 0x20, 0x96, 0x93, 0xbf, 0xbf, 0x8f, 0x00, 0xab,
+	0x92, 0x11, 0x17, 0x87, 0xb7, 0xa7, 0x99, 0x09, 0x31, 0xee, 0x64, 0x86, 0xba, 0xd3, 0x49, 0xe6,
+	0xc7, 0x43, 0x39, 0xc6, 0x15, 0x8f, 0xe4, 0x18, 0x77, 0x3c, 0x92, 0x63, 0x3c, 0xf1, 0x48, 0x8e,
+	0xf1, 0xc2, 0x23, 0x39, 0xc6, 0x07, 0x8f, 0xe4, 0x18, 0x0f, 0x3c, 0x96, 0x63, 0x4c, 0x62, 0x03,
+	0xfb, 0xdf, 0x18, 0x30, 0x00, 0x5d, 0x65, 0x97, 0x9b, 0x51, 0x01, 0x00, 0x00,
 }
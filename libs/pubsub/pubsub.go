@@ -138,6 +138,34 @@ func (ts tagMap) Len() int {
 	return len(ts)
 }
 
+// TypedTagMap is a TagMap which also knows the types of the values, so that
+// queries can compare them accordingly (see cmn.KVPair_Type).
+type TypedTagMap interface {
+	TagMap
+	// Type returns the type of the value for a key, or cmn.KVPair_UNTYPED if
+	// the value has no type or is not present.
+	Type(key string) cmn.KVPair_Type
+}
+
+type typedTagMap struct {
+	tagMap
+	types map[string]cmn.KVPair_Type
+}
+
+var _ TypedTagMap = typedTagMap{}
+
+// NewTypedTagMap constructs a new immutable tag set from a map of values and
+// a map of their types. The values missing from types are untyped.
+func NewTypedTagMap(data map[string]string, types map[string]cmn.KVPair_Type) TypedTagMap {
+	return typedTagMap{tagMap: tagMap(data), types: types}
+}
+
+// Type returns the type of the value for a key, or cmn.KVPair_UNTYPED if the
+// value has no type or is not present.
+func (ts typedTagMap) Type(key string) cmn.KVPair_Type {
+	return ts.types[key]
+}
+
 // NewServer returns a new server. See the commentary on the Option functions
 // for a detailed description of how to configure buffering. If no options are
 // provided, the resulting server's queue is unbuffered.
@@ -73,12 +73,51 @@ func TestParser(t *testing.T) {
 
 		{"account.balance=100", true},
 		{"account.balance >= 200", true},
-		{"account.balance >= -300", false},
+		{"account.balance >= -300", true},
+		{"account.balance >= -0.5", true},
+		{"account.balance >= 0.5", true},
+		{"account.balance >= - 300", false},
+		{"account.balance >= --300", false},
+		{"account.balance >= 01", false},
 		{"account.balance >>= 400", false},
 		{"account.balance=33.22.1", false},
 
 		{"hash='136E18F7E4C348B780CF873A0BF43922E5BAFA63'", true},
 		{"hash=136E18F7E4C348B780CF873A0BF43922E5BAFA63", false},
+
+		{"tm.events.type='NewBlock' OR abci.account.name='Igor'", true},
+		{"tm.events.type='NewBlock' OR", false},
+		{"OR tm.events.type='NewBlock'", false},
+		{"tm.events.type='NewBlock' AND abci.account.name='Igor' OR abci.account.name='Ivan'", true},
+
+		{"(tm.events.type='NewBlock')", true},
+		{"( tm.events.type='NewBlock' OR tx.gas > 7 ) AND abci.account.name='Igor'", true},
+		{"tm.events.type='NewBlock' AND (abci.account.name='Igor' OR (tx.gas > 7 AND tx.gas < 9))", true},
+		{"(tm.events.type='NewBlock'", false},
+		{"tm.events.type='NewBlock')", false},
+		{"()", false},
+
+		{"NOT tm.events.type='NewBlock'", true},
+		{"NOT (tm.events.type='NewBlock' OR tx.gas > 7)", true},
+		{"NOT(tm.events.type='NewBlock')", true},
+		{"NOT NOT tm.events.type='NewBlock'", true},
+		{"NOTE='NewBlock'", true},
+		{"NOT", false},
+		{"tm.events.type NOT 'NewBlock'", false},
+
+		{"abci.account.name IN ('Igor', 'Ivan')", true},
+		{"account.balance IN (100, 200.5)", true},
+		{"tx.date IN (DATE 2013-05-03, TIME 2013-05-03T14:45:00Z)", true},
+		{"abci.account.name IN ('Igor')", true},
+		{"abci.account.name IN ( 'Igor' ,'Ivan' )", true},
+		{"abci.account.name IN ()", false},
+		{"abci.account.name IN 'Igor'", false},
+		{"abci.account.name IN ('Igor',)", false},
+
+		{"abci.account.name EXISTS", true},
+		{"NOT abci.account.name EXISTS AND tx.gas > 7", true},
+		{"abci.account.name EXISTS 'Igor'", false},
+		{"EXISTS", false},
 	}
 
 	for _, c := range cases {
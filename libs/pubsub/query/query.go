@@ -2,12 +2,25 @@
 //
 //		abci.invoice.number=22 AND abci.invoice.owner=Ivan
 //
+// Conditions can be combined with AND, OR and NOT, and grouped with
+// parentheses. AND binds tighter than OR, and NOT tighter than both:
+//
+//		transfer.sender='A' OR (transfer.recipient='A' AND NOT transfer.fee EXISTS)
+//
+// Besides comparisons, a condition can check that a tag is set
+// (transfer.fee EXISTS) or is equal to one of several operands
+// (transfer.currency IN ('BTC', 'ETH')).
+//
 // See query.peg for the grammar, which is a https://en.wikipedia.org/wiki/Parsing_expression_grammar.
 // More: https://github.com/PhilippeSigaud/Pegged/wiki/PEG-Basics
 //
 // It has a support for numbers (integer and floating point), dates and times.
+// Values of typed tags are only compared with operands of their type, see
+// pubsub.TypedTagMap.
 package query
 
+//go:generate peg -inline -switch query.peg
+
 import (
 	"fmt"
 	"reflect"
@@ -15,23 +28,52 @@ import (
 	"strings"
 	"time"
 
+	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/pubsub"
 )
 
-// Query holds the query string and the query parser.
+// Query holds the query string and the parsed expression.
 type Query struct {
-	str    string
-	parser *QueryParser
+	str  string
+	expr *Expr
 }
 
 // Condition represents a single condition within a query and consists of tag
 // (e.g. "tx.gas"), operator (e.g. "=") and operand (e.g. "7").
+//
+// The operand of an IN condition is a []interface{} holding the operands
+// from the list; an EXISTS condition has no operand.
 type Condition struct {
 	Tag     string
 	Op      Operator
 	Operand interface{}
 }
 
+// ExprType is the type of a node of the expression tree.
+type ExprType uint8
+
+const (
+	// ExprCondition is a leaf holding a single condition.
+	ExprCondition ExprType = iota
+	// ExprAnd is true when all of its arguments are true.
+	ExprAnd
+	// ExprOr is true when any of its arguments is true.
+	ExprOr
+	// ExprNot is true when its only argument is false.
+	ExprNot
+)
+
+// Expr is a node of the expression tree of a query. Conditions are the
+// leaves, combined by AND, OR and NOT nodes.
+type Expr struct {
+	Type ExprType
+	// Condition is only set on ExprCondition nodes.
+	Condition Condition
+	// Args holds the operands of ExprAnd and ExprOr (two or more) and ExprNot
+	// (exactly one) nodes.
+	Args []*Expr
+}
+
 // New parses the given string and returns a query or error if the string is
 // invalid.
 func New(s string) (*Query, error) {
@@ -40,7 +82,11 @@ func New(s string) (*Query, error) {
 	if err := p.Parse(); err != nil {
 		return nil, err
 	}
-	return &Query{str: s, parser: p}, nil
+	expr, err := buildExpr(p.AST(), p.buffer)
+	if err != nil {
+		return nil, err
+	}
+	return &Query{str: s, expr: expr}, nil
 }
 
 // MustParse turns the given string into a query or panics; for tests or others
@@ -75,6 +121,10 @@ const (
 	OpEqual
 	// "CONTAINS"; used to check if a string contains a certain sub string.
 	OpContains
+	// "IN"; used to check if a value is equal to any operand of a list.
+	OpIn
+	// "EXISTS"; used to check if a tag is present.
+	OpExists
 )
 
 const (
@@ -84,154 +134,242 @@ const (
 	TimeLayout = time.RFC3339
 )
 
-// Conditions returns a list of conditions.
+// Expr returns the expression tree of the query.
+func (q *Query) Expr() *Expr {
+	return q.expr
+}
+
+// Conditions returns a list of conditions, in the order they appear in the
+// query.
+//
+// NOTE: the list does not tell how the conditions are combined. Use it as is
+// only if IsConjunction returns true, and walk Expr otherwise.
 func (q *Query) Conditions() []Condition {
 	conditions := make([]Condition, 0)
-
-	buffer, begin, end := q.parser.Buffer, 0, 0
-
-	var tag string
-	var op Operator
-
-	// tokens must be in the following order: tag ("tx.gas") -> operator ("=") -> operand ("7")
-	for _, token := range q.parser.Tokens() {
-		switch token.pegRule {
-
-		case rulePegText:
-			begin, end = int(token.begin), int(token.end)
-		case ruletag:
-			tag = buffer[begin:end]
-		case rulele:
-			op = OpLessEqual
-		case rulege:
-			op = OpGreaterEqual
-		case rulel:
-			op = OpLess
-		case ruleg:
-			op = OpGreater
-		case ruleequal:
-			op = OpEqual
-		case rulecontains:
-			op = OpContains
-		case rulevalue:
-			// strip single quotes from value (i.e. "'NewBlock'" -> "NewBlock")
-			valueWithoutSingleQuotes := buffer[begin+1 : end-1]
-			conditions = append(conditions, Condition{tag, op, valueWithoutSingleQuotes})
-		case rulenumber:
-			number := buffer[begin:end]
-			if strings.ContainsAny(number, ".") { // if it looks like a floating-point number
-				value, err := strconv.ParseFloat(number, 64)
-				if err != nil {
-					panic(fmt.Sprintf("got %v while trying to parse %s as float64 (should never happen if the grammar is correct)", err, number))
-				}
-				conditions = append(conditions, Condition{tag, op, value})
-			} else {
-				value, err := strconv.ParseInt(number, 10, 64)
-				if err != nil {
-					panic(fmt.Sprintf("got %v while trying to parse %s as int64 (should never happen if the grammar is correct)", err, number))
-				}
-				conditions = append(conditions, Condition{tag, op, value})
-			}
-		case ruletime:
-			value, err := time.Parse(TimeLayout, buffer[begin:end])
-			if err != nil {
-				panic(fmt.Sprintf("got %v while trying to parse %s as time.Time / RFC3339 (should never happen if the grammar is correct)", err, buffer[begin:end]))
-			}
-			conditions = append(conditions, Condition{tag, op, value})
-		case ruledate:
-			value, err := time.Parse("2006-01-02", buffer[begin:end])
-			if err != nil {
-				panic(fmt.Sprintf("got %v while trying to parse %s as time.Time / '2006-01-02' (should never happen if the grammar is correct)", err, buffer[begin:end]))
-			}
-			conditions = append(conditions, Condition{tag, op, value})
+	var walk func(e *Expr)
+	walk = func(e *Expr) {
+		if e.Type == ExprCondition {
+			conditions = append(conditions, e.Condition)
+			return
+		}
+		for _, arg := range e.Args {
+			walk(arg)
 		}
 	}
-
+	walk(q.expr)
 	return conditions
 }
 
+// IsConjunction returns true if the query is a single condition, or
+// conditions joined by AND only (i.e. the query matches when all of
+// Conditions() do).
+func (q *Query) IsConjunction() bool {
+	if q.expr.Type == ExprCondition {
+		return true
+	}
+	if q.expr.Type != ExprAnd {
+		return false
+	}
+	for _, arg := range q.expr.Args {
+		if arg.Type != ExprCondition {
+			return false
+		}
+	}
+	return true
+}
+
 // Matches returns true if the query matches the given set of tags, false otherwise.
 //
 // For example, query "name=John" matches tags = {"name": "John"}. More
 // examples could be found in parser_test.go and query_test.go.
 func (q *Query) Matches(tags pubsub.TagMap) bool {
-	if tags.Len() == 0 {
+	return q.expr.matches(tags)
+}
+
+func (e *Expr) matches(tags pubsub.TagMap) bool {
+	switch e.Type {
+	case ExprCondition:
+		return matchCondition(e.Condition, tags)
+	case ExprAnd:
+		for _, arg := range e.Args {
+			if !arg.matches(tags) {
+				return false
+			}
+		}
+		return true
+	case ExprOr:
+		for _, arg := range e.Args {
+			if arg.matches(tags) {
+				return true
+			}
+		}
 		return false
+	case ExprNot:
+		return !e.Args[0].matches(tags)
+	default:
+		panic(fmt.Sprintf("Unknown expression type %v", e.Type))
 	}
+}
 
-	buffer, begin, end := q.parser.Buffer, 0, 0
-
-	var tag string
-	var op Operator
-
-	// tokens must be in the following order: tag ("tx.gas") -> operator ("=") -> operand ("7")
-	for _, token := range q.parser.Tokens() {
-		switch token.pegRule {
-
-		case rulePegText:
-			begin, end = int(token.begin), int(token.end)
-		case ruletag:
-			tag = buffer[begin:end]
-		case rulele:
-			op = OpLessEqual
-		case rulege:
-			op = OpGreaterEqual
-		case rulel:
-			op = OpLess
-		case ruleg:
-			op = OpGreater
-		case ruleequal:
-			op = OpEqual
-		case rulecontains:
-			op = OpContains
-		case rulevalue:
-			// strip single quotes from value (i.e. "'NewBlock'" -> "NewBlock")
-			valueWithoutSingleQuotes := buffer[begin+1 : end-1]
-
-			// see if the triplet (tag, operator, operand) matches any tag
-			// "tx.gas", "=", "7", { "tx.gas": 7, "tx.ID": "4AE393495334" }
-			if !match(tag, op, reflect.ValueOf(valueWithoutSingleQuotes), tags) {
-				return false
+// Matches returns true if the condition matches the given tags.
+func (c Condition) Matches(tags pubsub.TagMap) bool {
+	return matchCondition(c, tags)
+}
+
+// matchCondition returns true if the given condition matches any tag.
+func matchCondition(c Condition, tags pubsub.TagMap) bool {
+	switch c.Op {
+	case OpExists:
+		_, ok := tags.Get(c.Tag)
+		return ok
+	case OpIn:
+		for _, operand := range c.Operand.([]interface{}) {
+			if match(c.Tag, OpEqual, reflect.ValueOf(operand), tags) {
+				return true
 			}
-		case rulenumber:
-			number := buffer[begin:end]
-			if strings.ContainsAny(number, ".") { // if it looks like a floating-point number
-				value, err := strconv.ParseFloat(number, 64)
-				if err != nil {
-					panic(fmt.Sprintf("got %v while trying to parse %s as float64 (should never happen if the grammar is correct)", err, number))
-				}
-				if !match(tag, op, reflect.ValueOf(value), tags) {
-					return false
-				}
-			} else {
-				value, err := strconv.ParseInt(number, 10, 64)
-				if err != nil {
-					panic(fmt.Sprintf("got %v while trying to parse %s as int64 (should never happen if the grammar is correct)", err, number))
-				}
-				if !match(tag, op, reflect.ValueOf(value), tags) {
-					return false
-				}
+		}
+		return false
+	default:
+		// see if the triplet (tag, operator, operand) matches any tag
+		// "tx.gas", "=", "7", { "tx.gas": 7, "tx.ID": "4AE393495334" }
+		return match(c.Tag, c.Op, reflect.ValueOf(c.Operand), tags)
+	}
+}
+
+// buildExpr turns the syntax tree produced by the parser into an expression
+// tree.
+func buildExpr(node *node32, buffer []rune) (*Expr, error) {
+	switch node.pegRule {
+	case rulee, rulegroup:
+		return buildExpr(node.up, buffer)
+	case ruleexpr, ruleterm:
+		// term (OR term)* / factor (AND factor)*
+		var args []*Expr
+		for n := node.up; n != nil; n = n.next {
+			if n.pegRule == ruleor || n.pegRule == ruleand {
+				continue
 			}
-		case ruletime:
-			value, err := time.Parse(TimeLayout, buffer[begin:end])
+			arg, err := buildExpr(n, buffer)
 			if err != nil {
-				panic(fmt.Sprintf("got %v while trying to parse %s as time.Time / RFC3339 (should never happen if the grammar is correct)", err, buffer[begin:end]))
+				return nil, err
 			}
-			if !match(tag, op, reflect.ValueOf(value), tags) {
-				return false
+			args = append(args, arg)
+		}
+		if len(args) == 1 {
+			return args[0], nil
+		}
+		if node.pegRule == ruleexpr {
+			return &Expr{Type: ExprOr, Args: args}, nil
+		}
+		return &Expr{Type: ExprAnd, Args: args}, nil
+	case rulefactor:
+		// NOT factor / NOT group / group / condition
+		if node.up.pegRule == rulenot {
+			arg, err := buildExpr(node.up.next, buffer)
+			if err != nil {
+				return nil, err
 			}
-		case ruledate:
-			value, err := time.Parse("2006-01-02", buffer[begin:end])
+			return &Expr{Type: ExprNot, Args: []*Expr{arg}}, nil
+		}
+		return buildExpr(node.up, buffer)
+	case rulecondition:
+		c, err := buildCondition(node, buffer)
+		if err != nil {
+			return nil, err
+		}
+		return &Expr{Type: ExprCondition, Condition: c}, nil
+	default:
+		panic(fmt.Sprintf("unexpected rule %v (should never happen if the grammar is correct)", rul3s[node.pegRule]))
+	}
+}
+
+// buildCondition parses the nodes of a condition, which must be in the
+// following order: tag ("tx.gas") -> operator ("=") -> operand(s) ("7").
+func buildCondition(node *node32, buffer []rune) (Condition, error) {
+	tag := node.up
+	c := Condition{Tag: text(tag, buffer)}
+
+	op := tag.next
+	switch op.pegRule {
+	case rulele:
+		c.Op = OpLessEqual
+	case rulege:
+		c.Op = OpGreaterEqual
+	case rulel:
+		c.Op = OpLess
+	case ruleg:
+		c.Op = OpGreater
+	case ruleequal:
+		c.Op = OpEqual
+	case rulecontains:
+		c.Op = OpContains
+	case rulein:
+		c.Op = OpIn
+		operands := make([]interface{}, 0)
+		for n := op.next; n != nil; n = n.next {
+			operand, err := parseOperand(n.up, buffer)
 			if err != nil {
-				panic(fmt.Sprintf("got %v while trying to parse %s as time.Time / '2006-01-02' (should never happen if the grammar is correct)", err, buffer[begin:end]))
+				return c, err
 			}
-			if !match(tag, op, reflect.ValueOf(value), tags) {
-				return false
+			operands = append(operands, operand)
+		}
+		c.Operand = operands
+		return c, nil
+	case ruleexists:
+		c.Op = OpExists
+		return c, nil
+	}
+
+	operand, err := parseOperand(op.next, buffer)
+	if err != nil {
+		return c, err
+	}
+	c.Operand = operand
+	return c, nil
+}
+
+// parseOperand returns the value of a value, number, time or date node.
+func parseOperand(node *node32, buffer []rune) (interface{}, error) {
+	switch node.pegRule {
+	case rulevalue:
+		// strip single quotes from value (i.e. "'NewBlock'" -> "NewBlock")
+		value := text(node, buffer)
+		return value[1 : len(value)-1], nil
+	case rulenumber:
+		number := text(node, buffer)
+		if strings.ContainsAny(number, ".") { // if it looks like a floating-point number
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return nil, fmt.Errorf("got %v while trying to parse %s as float64", err, number)
 			}
+			return value, nil
+		}
+		value, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("got %v while trying to parse %s as int64", err, number)
+		}
+		return value, nil
+	case ruletime:
+		s := text(node.up, buffer) // skip "TIME "
+		value, err := time.Parse(TimeLayout, s)
+		if err != nil {
+			return nil, fmt.Errorf("got %v while trying to parse %s as time.Time / RFC3339", err, s)
+		}
+		return value, nil
+	case ruledate:
+		s := text(node.up, buffer) // skip "DATE "
+		value, err := time.Parse(DateLayout, s)
+		if err != nil {
+			return nil, fmt.Errorf("got %v while trying to parse %s as time.Time / '2006-01-02'", err, s)
 		}
+		return value, nil
+	default:
+		panic(fmt.Sprintf("unexpected rule %v (should never happen if the grammar is correct)", rul3s[node.pegRule]))
 	}
+}
 
-	return true
+func text(node *node32, buffer []rune) string {
+	return string(buffer[node.begin:node.end])
 }
 
 // match returns true if the given triplet (tag, operator, operand) matches any tag.
@@ -240,27 +378,42 @@ func (q *Query) Matches(tags pubsub.TagMap) bool {
 // value from it to the operand using the operator.
 //
 // "tx.gas", "=", "7", { "tx.gas": 7, "tx.ID": "4AE393495334" }
+//
+// Typed values (see pubsub.TypedTagMap) only match operands of their type:
+// INT and DECIMAL values are compared with numbers, TIME values with times and
+// dates, and STRING and BOOL values with strings. Untyped values are compared
+// as whatever the operand is, and don't match if they can't be converted.
 func match(tag string, op Operator, operand reflect.Value, tags pubsub.TagMap) bool {
 	// look up the tag from the query in tags
 	value, ok := tags.Get(tag)
 	if !ok {
 		return false
 	}
+	typ := cmn.KVPair_UNTYPED
+	if typed, ok := tags.(pubsub.TypedTagMap); ok {
+		typ = typed.Type(tag)
+	}
+
 	switch operand.Kind() {
 	case reflect.Struct: // time
 		operandAsTime := operand.Interface().(time.Time)
-		// try our best to convert value from tags to time.Time
 		var (
 			v   time.Time
 			err error
 		)
-		if strings.ContainsAny(value, "T") {
+		switch {
+		case typ == cmn.KVPair_TIME:
+			v, err = time.Parse(time.RFC3339Nano, value)
+		case typ != cmn.KVPair_UNTYPED:
+			return false
+		// try our best to convert value from tags to time.Time
+		case strings.ContainsAny(value, "T"):
 			v, err = time.Parse(TimeLayout, value)
-		} else {
+		default:
 			v, err = time.Parse(DateLayout, value)
 		}
 		if err != nil {
-			panic(fmt.Sprintf("Failed to convert value %v from tag to time.Time: %v", value, err))
+			return false
 		}
 		switch op {
 		case OpLessEqual:
@@ -275,33 +428,33 @@ func match(tag string, op Operator, operand reflect.Value, tags pubsub.TagMap) b
 			return v.Equal(operandAsTime)
 		}
 	case reflect.Float64:
-		operandFloat64 := operand.Interface().(float64)
-		var v float64
+		if !isNumeric(typ) {
+			return false
+		}
 		// try our best to convert value from tags to float64
 		v, err := strconv.ParseFloat(value, 64)
 		if err != nil {
-			panic(fmt.Sprintf("Failed to convert value %v from tag to float64: %v", value, err))
-		}
-		switch op {
-		case OpLessEqual:
-			return v <= operandFloat64
-		case OpGreaterEqual:
-			return v >= operandFloat64
-		case OpLess:
-			return v < operandFloat64
-		case OpGreater:
-			return v > operandFloat64
-		case OpEqual:
-			return v == operandFloat64
+			return false
 		}
+		return compareFloat64(v, op, operand.Interface().(float64))
 	case reflect.Int64:
+		if !isNumeric(typ) {
+			return false
+		}
 		operandInt := operand.Interface().(int64)
+		if typ == cmn.KVPair_DECIMAL {
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return false
+			}
+			return compareFloat64(v, op, float64(operandInt))
+		}
 		var v int64
-		// if value looks like float, we try to parse it as float
-		if strings.ContainsAny(value, ".") {
+		// if an untyped value looks like float, we try to parse it as float
+		if typ == cmn.KVPair_UNTYPED && strings.ContainsAny(value, ".") {
 			v1, err := strconv.ParseFloat(value, 64)
 			if err != nil {
-				panic(fmt.Sprintf("Failed to convert value %v from tag to float64: %v", value, err))
+				return false
 			}
 			v = int64(v1)
 		} else {
@@ -309,7 +462,7 @@ func match(tag string, op Operator, operand reflect.Value, tags pubsub.TagMap) b
 			// try our best to convert value from tags to int64
 			v, err = strconv.ParseInt(value, 10, 64)
 			if err != nil {
-				panic(fmt.Sprintf("Failed to convert value %v from tag to int64: %v", value, err))
+				return false
 			}
 		}
 		switch op {
@@ -325,6 +478,11 @@ func match(tag string, op Operator, operand reflect.Value, tags pubsub.TagMap) b
 			return v == operandInt
 		}
 	case reflect.String:
+		switch typ {
+		case cmn.KVPair_UNTYPED, cmn.KVPair_STRING, cmn.KVPair_BOOL:
+		default:
+			return false
+		}
 		switch op {
 		case OpEqual:
 			return value == operand.String()
@@ -337,3 +495,23 @@ func match(tag string, op Operator, operand reflect.Value, tags pubsub.TagMap) b
 
 	return false
 }
+
+func isNumeric(typ cmn.KVPair_Type) bool {
+	return typ == cmn.KVPair_UNTYPED || typ == cmn.KVPair_INT || typ == cmn.KVPair_DECIMAL
+}
+
+func compareFloat64(v float64, op Operator, operand float64) bool {
+	switch op {
+	case OpLessEqual:
+		return v <= operand
+	case OpGreaterEqual:
+		return v >= operand
+	case OpLess:
+		return v < operand
+	case OpGreater:
+		return v > operand
+	case OpEqual:
+		return v == operand
+	}
+	return false
+}
@@ -1,13 +1,15 @@
 // nolint
 package query
 
-//go:generate peg -inline -switch query.peg
+// Code generated by peg -inline -switch query.peg DO NOT EDIT.
 
 import (
 	"fmt"
-	"math"
+	"io"
+	"os"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 const endSymbol rune = 1114112
@@ -18,7 +20,12 @@ type pegRule uint8
 const (
 	ruleUnknown pegRule = iota
 	rulee
+	ruleexpr
+	ruleterm
+	rulefactor
+	rulegroup
 	rulecondition
+	ruleoperand
 	ruletag
 	rulevalue
 	rulenumber
@@ -29,8 +36,12 @@ const (
 	rulemonth
 	ruleday
 	ruleand
+	ruleor
+	rulenot
 	ruleequal
 	rulecontains
+	rulein
+	ruleexists
 	rulele
 	rulege
 	rulel
@@ -41,7 +52,12 @@ const (
 var rul3s = [...]string{
 	"Unknown",
 	"e",
+	"expr",
+	"term",
+	"factor",
+	"group",
 	"condition",
+	"operand",
 	"tag",
 	"value",
 	"number",
@@ -52,8 +68,12 @@ var rul3s = [...]string{
 	"month",
 	"day",
 	"and",
+	"or",
+	"not",
 	"equal",
 	"contains",
+	"in",
+	"exists",
 	"le",
 	"ge",
 	"l",
@@ -75,19 +95,19 @@ type node32 struct {
 	up, next *node32
 }
 
-func (node *node32) print(pretty bool, buffer string) {
+func (node *node32) print(w io.Writer, pretty bool, buffer string) {
 	var print func(node *node32, depth int)
 	print = func(node *node32, depth int) {
 		for node != nil {
 			for c := 0; c < depth; c++ {
-				fmt.Printf(" ")
+				fmt.Fprintf(w, " ")
 			}
 			rule := rul3s[node.pegRule]
 			quote := strconv.Quote(string(([]rune(buffer)[node.begin:node.end])))
 			if !pretty {
-				fmt.Printf("%v %v\n", rule, quote)
+				fmt.Fprintf(w, "%v %v\n", rule, quote)
 			} else {
-				fmt.Printf("\x1B[34m%v\x1B[m %v\n", rule, quote)
+				fmt.Fprintf(w, "\x1B[36m%v\x1B[m %v\n", rule, quote)
 			}
 			if node.up != nil {
 				print(node.up, depth+1)
@@ -98,12 +118,12 @@ func (node *node32) print(pretty bool, buffer string) {
 	print(node, 0)
 }
 
-func (node *node32) Print(buffer string) {
-	node.print(false, buffer)
+func (node *node32) Print(w io.Writer, buffer string) {
+	node.print(w, false, buffer)
 }
 
-func (node *node32) PrettyPrint(buffer string) {
-	node.print(true, buffer)
+func (node *node32) PrettyPrint(w io.Writer, buffer string) {
+	node.print(w, true, buffer)
 }
 
 type tokens32 struct {
@@ -146,24 +166,24 @@ func (t *tokens32) AST() *node32 {
 }
 
 func (t *tokens32) PrintSyntaxTree(buffer string) {
-	t.AST().Print(buffer)
+	t.AST().Print(os.Stdout, buffer)
+}
+
+func (t *tokens32) WriteSyntaxTree(w io.Writer, buffer string) {
+	t.AST().Print(w, buffer)
 }
 
 func (t *tokens32) PrettyPrintSyntaxTree(buffer string) {
-	t.AST().PrettyPrint(buffer)
+	t.AST().PrettyPrint(os.Stdout, buffer)
 }
 
 func (t *tokens32) Add(rule pegRule, begin, end, index uint32) {
-	if tree := t.tree; int(index) >= len(tree) {
-		expanded := make([]token32, 2*len(tree))
-		copy(expanded, tree)
-		t.tree = expanded
-	}
-	t.tree[index] = token32{
-		pegRule: rule,
-		begin:   begin,
-		end:     end,
+	tree, i := t.tree, int(index)
+	if i >= len(tree) {
+		t.tree = append(tree, token32{pegRule: rule, begin: begin, end: end})
+		return
 	}
+	tree[i] = token32{pegRule: rule, begin: begin, end: end}
 }
 
 func (t *tokens32) Tokens() []token32 {
@@ -173,7 +193,7 @@ func (t *tokens32) Tokens() []token32 {
 type QueryParser struct {
 	Buffer string
 	buffer []rune
-	rules  [20]func() bool
+	rules  [29]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
@@ -225,7 +245,7 @@ type parseError struct {
 }
 
 func (e *parseError) Error() string {
-	tokens, error := []token32{e.max}, "\n"
+	tokens, err := []token32{e.max}, "\n"
 	positions, p := make([]int, 2*len(tokens)), 0
 	for _, token := range tokens {
 		positions[p], p = int(token.begin), p+1
@@ -238,14 +258,14 @@ func (e *parseError) Error() string {
 	}
 	for _, token := range tokens {
 		begin, end := int(token.begin), int(token.end)
-		error += fmt.Sprintf(format,
+		err += fmt.Sprintf(format,
 			rul3s[token.pegRule],
 			translations[begin].line, translations[begin].symbol,
 			translations[end].line, translations[end].symbol,
 			strconv.Quote(string(e.p.buffer[begin:end])))
 	}
 
-	return error
+	return err
 }
 
 func (p *QueryParser) PrintSyntaxTree() {
@@ -256,12 +276,41 @@ func (p *QueryParser) PrintSyntaxTree() {
 	}
 }
 
-func (p *QueryParser) Init() {
+func (p *QueryParser) WriteSyntaxTree(w io.Writer) {
+	p.tokens32.WriteSyntaxTree(w, p.Buffer)
+}
+
+func (p *QueryParser) SprintSyntaxTree() string {
+	var bldr strings.Builder
+	p.WriteSyntaxTree(&bldr)
+	return bldr.String()
+}
+
+func Pretty(pretty bool) func(*QueryParser) error {
+	return func(p *QueryParser) error {
+		p.Pretty = pretty
+		return nil
+	}
+}
+
+func Size(size int) func(*QueryParser) error {
+	return func(p *QueryParser) error {
+		p.tokens32 = tokens32{tree: make([]token32, 0, size)}
+		return nil
+	}
+}
+func (p *QueryParser) Init(options ...func(*QueryParser) error) error {
 	var (
 		max                  token32
 		position, tokenIndex uint32
 		buffer               []rune
 	)
+	for _, option := range options {
+		err := option(p)
+		if err != nil {
+			return err
+		}
+	}
 	p.reset = func() {
 		max = token32{}
 		position, tokenIndex = 0, 0
@@ -275,7 +324,7 @@ func (p *QueryParser) Init() {
 	p.reset()
 
 	_rules := p.rules
-	tree := tokens32{tree: make([]token32, math.MaxInt16)}
+	tree := p.tokens32
 	p.parse = func(rule ...int) error {
 		r := 1
 		if len(rule) > 0 {
@@ -324,7 +373,7 @@ func (p *QueryParser) Init() {
 
 	_rules = [...]func() bool{
 		nil,
-		/* 0 e <- <('"' condition (' '+ and ' '+ condition)* '"' !.)> */
+		/* 0 e <- <('"' expr '"' !.)> */
 		func() bool {
 			position0, tokenIndex0 := position, tokenIndex
 			{
@@ -333,1223 +382,1694 @@ func (p *QueryParser) Init() {
 					goto l0
 				}
 				position++
-				if !_rules[rulecondition]() {
+				if !_rules[ruleexpr]() {
 					goto l0
 				}
-			l2:
+				if buffer[position] != rune('"') {
+					goto l0
+				}
+				position++
+				{
+					position2, tokenIndex2 := position, tokenIndex
+					if !matchDot() {
+						goto l2
+					}
+					goto l0
+				l2:
+					position, tokenIndex = position2, tokenIndex2
+				}
+				add(rulee, position1)
+			}
+			return true
+		l0:
+			position, tokenIndex = position0, tokenIndex0
+			return false
+		},
+		/* 1 expr <- <(term (' '+ or ' '+ term)*)> */
+		func() bool {
+			position3, tokenIndex3 := position, tokenIndex
+			{
+				position4 := position
+				if !_rules[ruleterm]() {
+					goto l3
+				}
+			l5:
 				{
-					position3, tokenIndex3 := position, tokenIndex
+					position6, tokenIndex6 := position, tokenIndex
 					if buffer[position] != rune(' ') {
-						goto l3
+						goto l6
 					}
 					position++
-				l4:
+				l7:
 					{
-						position5, tokenIndex5 := position, tokenIndex
+						position8, tokenIndex8 := position, tokenIndex
 						if buffer[position] != rune(' ') {
-							goto l5
+							goto l8
 						}
 						position++
-						goto l4
-					l5:
-						position, tokenIndex = position5, tokenIndex5
+						goto l7
+					l8:
+						position, tokenIndex = position8, tokenIndex8
 					}
 					{
-						position6 := position
-						{
-							position7, tokenIndex7 := position, tokenIndex
-							if buffer[position] != rune('a') {
-								goto l8
-							}
-							position++
-							goto l7
-						l8:
-							position, tokenIndex = position7, tokenIndex7
-							if buffer[position] != rune('A') {
-								goto l3
-							}
-							position++
-						}
-					l7:
+						position9 := position
 						{
-							position9, tokenIndex9 := position, tokenIndex
-							if buffer[position] != rune('n') {
-								goto l10
+							position10, tokenIndex10 := position, tokenIndex
+							if buffer[position] != rune('o') {
+								goto l11
 							}
 							position++
-							goto l9
-						l10:
-							position, tokenIndex = position9, tokenIndex9
-							if buffer[position] != rune('N') {
-								goto l3
+							goto l10
+						l11:
+							position, tokenIndex = position10, tokenIndex10
+							if buffer[position] != rune('O') {
+								goto l6
 							}
 							position++
 						}
-					l9:
+					l10:
 						{
-							position11, tokenIndex11 := position, tokenIndex
-							if buffer[position] != rune('d') {
-								goto l12
+							position12, tokenIndex12 := position, tokenIndex
+							if buffer[position] != rune('r') {
+								goto l13
 							}
 							position++
-							goto l11
-						l12:
-							position, tokenIndex = position11, tokenIndex11
-							if buffer[position] != rune('D') {
-								goto l3
+							goto l12
+						l13:
+							position, tokenIndex = position12, tokenIndex12
+							if buffer[position] != rune('R') {
+								goto l6
 							}
 							position++
 						}
-					l11:
-						add(ruleand, position6)
+					l12:
+						add(ruleor, position9)
 					}
 					if buffer[position] != rune(' ') {
-						goto l3
+						goto l6
 					}
 					position++
-				l13:
+				l14:
 					{
-						position14, tokenIndex14 := position, tokenIndex
+						position15, tokenIndex15 := position, tokenIndex
 						if buffer[position] != rune(' ') {
-							goto l14
+							goto l15
 						}
 						position++
-						goto l13
-					l14:
-						position, tokenIndex = position14, tokenIndex14
-					}
-					if !_rules[rulecondition]() {
-						goto l3
+						goto l14
+					l15:
+						position, tokenIndex = position15, tokenIndex15
 					}
-					goto l2
-				l3:
-					position, tokenIndex = position3, tokenIndex3
-				}
-				if buffer[position] != rune('"') {
-					goto l0
-				}
-				position++
-				{
-					position15, tokenIndex15 := position, tokenIndex
-					if !matchDot() {
-						goto l15
+					if !_rules[ruleterm]() {
+						goto l6
 					}
-					goto l0
-				l15:
-					position, tokenIndex = position15, tokenIndex15
+					goto l5
+				l6:
+					position, tokenIndex = position6, tokenIndex6
 				}
-				add(rulee, position1)
+				add(ruleexpr, position4)
 			}
 			return true
-		l0:
-			position, tokenIndex = position0, tokenIndex0
+		l3:
+			position, tokenIndex = position3, tokenIndex3
 			return false
 		},
-		/* 1 condition <- <(tag ' '* ((le ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number))) / (ge ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number))) / ((&('=') (equal ' '* ((&('\'') value) | (&('D' | 'd') date) | (&('T' | 't') time) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number)))) | (&('>') (g ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number)))) | (&('<') (l ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number)))) | (&('C' | 'c') (contains ' '* value)))))> */
+		/* 2 term <- <(factor (' '+ and ' '+ factor)*)> */
 		func() bool {
 			position16, tokenIndex16 := position, tokenIndex
 			{
 				position17 := position
+				if !_rules[rulefactor]() {
+					goto l16
+				}
+			l18:
 				{
-					position18 := position
+					position19, tokenIndex19 := position, tokenIndex
+					if buffer[position] != rune(' ') {
+						goto l19
+					}
+					position++
+				l20:
+					{
+						position21, tokenIndex21 := position, tokenIndex
+						if buffer[position] != rune(' ') {
+							goto l21
+						}
+						position++
+						goto l20
+					l21:
+						position, tokenIndex = position21, tokenIndex21
+					}
 					{
-						position19 := position
+						position22 := position
 						{
-							position22, tokenIndex22 := position, tokenIndex
-							{
-								switch buffer[position] {
-								case '<':
-									if buffer[position] != rune('<') {
-										goto l22
-									}
-									position++
-									break
-								case '>':
-									if buffer[position] != rune('>') {
-										goto l22
-									}
-									position++
-									break
-								case '=':
-									if buffer[position] != rune('=') {
-										goto l22
-									}
-									position++
-									break
-								case '\'':
-									if buffer[position] != rune('\'') {
-										goto l22
-									}
-									position++
-									break
-								case '"':
-									if buffer[position] != rune('"') {
-										goto l22
-									}
-									position++
-									break
-								case ')':
-									if buffer[position] != rune(')') {
-										goto l22
-									}
-									position++
-									break
-								case '(':
-									if buffer[position] != rune('(') {
-										goto l22
-									}
-									position++
-									break
-								case '\\':
-									if buffer[position] != rune('\\') {
-										goto l22
-									}
-									position++
-									break
-								case '\r':
-									if buffer[position] != rune('\r') {
-										goto l22
-									}
-									position++
-									break
-								case '\n':
-									if buffer[position] != rune('\n') {
-										goto l22
-									}
-									position++
-									break
-								case '\t':
-									if buffer[position] != rune('\t') {
-										goto l22
-									}
-									position++
-									break
-								default:
-									if buffer[position] != rune(' ') {
-										goto l22
-									}
-									position++
-									break
-								}
+							position23, tokenIndex23 := position, tokenIndex
+							if buffer[position] != rune('a') {
+								goto l24
 							}
-
-							goto l16
-						l22:
-							position, tokenIndex = position22, tokenIndex22
+							position++
+							goto l23
+						l24:
+							position, tokenIndex = position23, tokenIndex23
+							if buffer[position] != rune('A') {
+								goto l19
+							}
+							position++
 						}
-						if !matchDot() {
-							goto l16
+					l23:
+						{
+							position25, tokenIndex25 := position, tokenIndex
+							if buffer[position] != rune('n') {
+								goto l26
+							}
+							position++
+							goto l25
+						l26:
+							position, tokenIndex = position25, tokenIndex25
+							if buffer[position] != rune('N') {
+								goto l19
+							}
+							position++
 						}
-					l20:
+					l25:
 						{
-							position21, tokenIndex21 := position, tokenIndex
-							{
-								position24, tokenIndex24 := position, tokenIndex
-								{
-									switch buffer[position] {
-									case '<':
-										if buffer[position] != rune('<') {
-											goto l24
-										}
-										position++
-										break
-									case '>':
-										if buffer[position] != rune('>') {
-											goto l24
-										}
-										position++
-										break
-									case '=':
-										if buffer[position] != rune('=') {
-											goto l24
-										}
-										position++
-										break
-									case '\'':
-										if buffer[position] != rune('\'') {
-											goto l24
-										}
-										position++
-										break
-									case '"':
-										if buffer[position] != rune('"') {
-											goto l24
-										}
-										position++
-										break
-									case ')':
-										if buffer[position] != rune(')') {
-											goto l24
-										}
-										position++
-										break
-									case '(':
-										if buffer[position] != rune('(') {
-											goto l24
-										}
-										position++
-										break
-									case '\\':
-										if buffer[position] != rune('\\') {
-											goto l24
-										}
-										position++
-										break
-									case '\r':
-										if buffer[position] != rune('\r') {
-											goto l24
-										}
-										position++
-										break
-									case '\n':
-										if buffer[position] != rune('\n') {
-											goto l24
-										}
-										position++
-										break
-									case '\t':
-										if buffer[position] != rune('\t') {
-											goto l24
-										}
-										position++
-										break
-									default:
-										if buffer[position] != rune(' ') {
-											goto l24
-										}
-										position++
-										break
-									}
-								}
-
-								goto l21
-							l24:
-								position, tokenIndex = position24, tokenIndex24
+							position27, tokenIndex27 := position, tokenIndex
+							if buffer[position] != rune('d') {
+								goto l28
 							}
-							if !matchDot() {
-								goto l21
+							position++
+							goto l27
+						l28:
+							position, tokenIndex = position27, tokenIndex27
+							if buffer[position] != rune('D') {
+								goto l19
 							}
-							goto l20
-						l21:
-							position, tokenIndex = position21, tokenIndex21
+							position++
 						}
-						add(rulePegText, position19)
+					l27:
+						add(ruleand, position22)
 					}
-					add(ruletag, position18)
-				}
-			l26:
-				{
-					position27, tokenIndex27 := position, tokenIndex
 					if buffer[position] != rune(' ') {
-						goto l27
+						goto l19
 					}
 					position++
-					goto l26
-				l27:
-					position, tokenIndex = position27, tokenIndex27
-				}
-				{
-					position28, tokenIndex28 := position, tokenIndex
-					{
-						position30 := position
-						if buffer[position] != rune('<') {
-							goto l29
-						}
-						position++
-						if buffer[position] != rune('=') {
-							goto l29
-						}
-						position++
-						add(rulele, position30)
-					}
-				l31:
+				l29:
 					{
-						position32, tokenIndex32 := position, tokenIndex
+						position30, tokenIndex30 := position, tokenIndex
 						if buffer[position] != rune(' ') {
-							goto l32
+							goto l30
 						}
 						position++
-						goto l31
-					l32:
-						position, tokenIndex = position32, tokenIndex32
+						goto l29
+					l30:
+						position, tokenIndex = position30, tokenIndex30
 					}
+					if !_rules[rulefactor]() {
+						goto l19
+					}
+					goto l18
+				l19:
+					position, tokenIndex = position19, tokenIndex19
+				}
+				add(ruleterm, position17)
+			}
+			return true
+		l16:
+			position, tokenIndex = position16, tokenIndex16
+			return false
+		},
+		/* 3 factor <- <((not ((' '+ factor) / (' '* group))) / group / condition)> */
+		func() bool {
+			position31, tokenIndex31 := position, tokenIndex
+			{
+				position32 := position
+				{
+					position33, tokenIndex33 := position, tokenIndex
 					{
-						switch buffer[position] {
-						case 'D', 'd':
-							if !_rules[ruledate]() {
-								goto l29
-							}
-							break
-						case 'T', 't':
-							if !_rules[ruletime]() {
-								goto l29
+						position35 := position
+						{
+							position36, tokenIndex36 := position, tokenIndex
+							if buffer[position] != rune('n') {
+								goto l37
 							}
-							break
-						default:
-							if !_rules[rulenumber]() {
-								goto l29
+							position++
+							goto l36
+						l37:
+							position, tokenIndex = position36, tokenIndex36
+							if buffer[position] != rune('N') {
+								goto l34
 							}
-							break
+							position++
 						}
-					}
-
-					goto l28
-				l29:
-					position, tokenIndex = position28, tokenIndex28
-					{
-						position35 := position
-						if buffer[position] != rune('>') {
-							goto l34
+					l36:
+						{
+							position38, tokenIndex38 := position, tokenIndex
+							if buffer[position] != rune('o') {
+								goto l39
+							}
+							position++
+							goto l38
+						l39:
+							position, tokenIndex = position38, tokenIndex38
+							if buffer[position] != rune('O') {
+								goto l34
+							}
+							position++
 						}
-						position++
-						if buffer[position] != rune('=') {
-							goto l34
+					l38:
+						{
+							position40, tokenIndex40 := position, tokenIndex
+							if buffer[position] != rune('t') {
+								goto l41
+							}
+							position++
+							goto l40
+						l41:
+							position, tokenIndex = position40, tokenIndex40
+							if buffer[position] != rune('T') {
+								goto l34
+							}
+							position++
 						}
-						position++
-						add(rulege, position35)
+					l40:
+						add(rulenot, position35)
 					}
-				l36:
 					{
-						position37, tokenIndex37 := position, tokenIndex
+						position42, tokenIndex42 := position, tokenIndex
 						if buffer[position] != rune(' ') {
-							goto l37
+							goto l43
 						}
 						position++
-						goto l36
-					l37:
-						position, tokenIndex = position37, tokenIndex37
-					}
-					{
-						switch buffer[position] {
-						case 'D', 'd':
-							if !_rules[ruledate]() {
-								goto l34
-							}
-							break
-						case 'T', 't':
-							if !_rules[ruletime]() {
-								goto l34
+					l44:
+						{
+							position45, tokenIndex45 := position, tokenIndex
+							if buffer[position] != rune(' ') {
+								goto l45
 							}
-							break
-						default:
-							if !_rules[rulenumber]() {
-								goto l34
+							position++
+							goto l44
+						l45:
+							position, tokenIndex = position45, tokenIndex45
+						}
+						if !_rules[rulefactor]() {
+							goto l43
+						}
+						goto l42
+					l43:
+						position, tokenIndex = position42, tokenIndex42
+					l46:
+						{
+							position47, tokenIndex47 := position, tokenIndex
+							if buffer[position] != rune(' ') {
+								goto l47
 							}
-							break
+							position++
+							goto l46
+						l47:
+							position, tokenIndex = position47, tokenIndex47
+						}
+						if !_rules[rulegroup]() {
+							goto l34
 						}
 					}
-
-					goto l28
+				l42:
+					goto l33
 				l34:
-					position, tokenIndex = position28, tokenIndex28
+					position, tokenIndex = position33, tokenIndex33
+					if !_rules[rulegroup]() {
+						goto l48
+					}
+					goto l33
+				l48:
+					position, tokenIndex = position33, tokenIndex33
 					{
-						switch buffer[position] {
-						case '=':
+						position49 := position
+						{
+							position50 := position
 							{
-								position40 := position
-								if buffer[position] != rune('=') {
-									goto l16
+								position51 := position
+								{
+									position54, tokenIndex54 := position, tokenIndex
+									{
+										switch buffer[position] {
+										case '<':
+											if buffer[position] != rune('<') {
+												goto l54
+											}
+											position++
+										case '>':
+											if buffer[position] != rune('>') {
+												goto l54
+											}
+											position++
+										case '=':
+											if buffer[position] != rune('=') {
+												goto l54
+											}
+											position++
+										case '\'':
+											if buffer[position] != rune('\'') {
+												goto l54
+											}
+											position++
+										case '"':
+											if buffer[position] != rune('"') {
+												goto l54
+											}
+											position++
+										case ')':
+											if buffer[position] != rune(')') {
+												goto l54
+											}
+											position++
+										case '(':
+											if buffer[position] != rune('(') {
+												goto l54
+											}
+											position++
+										case '\\':
+											if buffer[position] != rune('\\') {
+												goto l54
+											}
+											position++
+										case '\r':
+											if buffer[position] != rune('\r') {
+												goto l54
+											}
+											position++
+										case '\n':
+											if buffer[position] != rune('\n') {
+												goto l54
+											}
+											position++
+										case '\t':
+											if buffer[position] != rune('\t') {
+												goto l54
+											}
+											position++
+										default:
+											if buffer[position] != rune(' ') {
+												goto l54
+											}
+											position++
+										}
+									}
+
+									goto l31
+								l54:
+									position, tokenIndex = position54, tokenIndex54
 								}
-								position++
-								add(ruleequal, position40)
-							}
-						l41:
-							{
-								position42, tokenIndex42 := position, tokenIndex
-								if buffer[position] != rune(' ') {
-									goto l42
+								if !matchDot() {
+									goto l31
 								}
-								position++
-								goto l41
-							l42:
-								position, tokenIndex = position42, tokenIndex42
-							}
-							{
-								switch buffer[position] {
-								case '\'':
-									if !_rules[rulevalue]() {
-										goto l16
-									}
-									break
-								case 'D', 'd':
-									if !_rules[ruledate]() {
-										goto l16
-									}
-									break
-								case 'T', 't':
-									if !_rules[ruletime]() {
-										goto l16
+							l52:
+								{
+									position53, tokenIndex53 := position, tokenIndex
+									{
+										position56, tokenIndex56 := position, tokenIndex
+										{
+											switch buffer[position] {
+											case '<':
+												if buffer[position] != rune('<') {
+													goto l56
+												}
+												position++
+											case '>':
+												if buffer[position] != rune('>') {
+													goto l56
+												}
+												position++
+											case '=':
+												if buffer[position] != rune('=') {
+													goto l56
+												}
+												position++
+											case '\'':
+												if buffer[position] != rune('\'') {
+													goto l56
+												}
+												position++
+											case '"':
+												if buffer[position] != rune('"') {
+													goto l56
+												}
+												position++
+											case ')':
+												if buffer[position] != rune(')') {
+													goto l56
+												}
+												position++
+											case '(':
+												if buffer[position] != rune('(') {
+													goto l56
+												}
+												position++
+											case '\\':
+												if buffer[position] != rune('\\') {
+													goto l56
+												}
+												position++
+											case '\r':
+												if buffer[position] != rune('\r') {
+													goto l56
+												}
+												position++
+											case '\n':
+												if buffer[position] != rune('\n') {
+													goto l56
+												}
+												position++
+											case '\t':
+												if buffer[position] != rune('\t') {
+													goto l56
+												}
+												position++
+											default:
+												if buffer[position] != rune(' ') {
+													goto l56
+												}
+												position++
+											}
+										}
+
+										goto l53
+									l56:
+										position, tokenIndex = position56, tokenIndex56
 									}
-									break
-								default:
-									if !_rules[rulenumber]() {
-										goto l16
+									if !matchDot() {
+										goto l53
 									}
-									break
+									goto l52
+								l53:
+									position, tokenIndex = position53, tokenIndex53
 								}
+								add(rulePegText, position51)
 							}
-
-							break
-						case '>':
+							add(ruletag, position50)
+						}
+					l58:
+						{
+							position59, tokenIndex59 := position, tokenIndex
+							if buffer[position] != rune(' ') {
+								goto l59
+							}
+							position++
+							goto l58
+						l59:
+							position, tokenIndex = position59, tokenIndex59
+						}
+						{
+							position60, tokenIndex60 := position, tokenIndex
 							{
-								position44 := position
-								if buffer[position] != rune('>') {
-									goto l16
+								position62 := position
+								if buffer[position] != rune('<') {
+									goto l61
+								}
+								position++
+								if buffer[position] != rune('=') {
+									goto l61
 								}
 								position++
-								add(ruleg, position44)
+								add(rulele, position62)
 							}
-						l45:
+						l63:
 							{
-								position46, tokenIndex46 := position, tokenIndex
+								position64, tokenIndex64 := position, tokenIndex
 								if buffer[position] != rune(' ') {
-									goto l46
+									goto l64
 								}
 								position++
-								goto l45
-							l46:
-								position, tokenIndex = position46, tokenIndex46
+								goto l63
+							l64:
+								position, tokenIndex = position64, tokenIndex64
 							}
 							{
 								switch buffer[position] {
 								case 'D', 'd':
 									if !_rules[ruledate]() {
-										goto l16
+										goto l61
 									}
-									break
 								case 'T', 't':
 									if !_rules[ruletime]() {
-										goto l16
+										goto l61
 									}
-									break
 								default:
 									if !_rules[rulenumber]() {
-										goto l16
+										goto l61
 									}
-									break
 								}
 							}
 
-							break
-						case '<':
+							goto l60
+						l61:
+							position, tokenIndex = position60, tokenIndex60
 							{
-								position48 := position
-								if buffer[position] != rune('<') {
-									goto l16
+								position67 := position
+								if buffer[position] != rune('>') {
+									goto l66
+								}
+								position++
+								if buffer[position] != rune('=') {
+									goto l66
 								}
 								position++
-								add(rulel, position48)
+								add(rulege, position67)
 							}
-						l49:
+						l68:
 							{
-								position50, tokenIndex50 := position, tokenIndex
+								position69, tokenIndex69 := position, tokenIndex
 								if buffer[position] != rune(' ') {
-									goto l50
+									goto l69
 								}
 								position++
-								goto l49
-							l50:
-								position, tokenIndex = position50, tokenIndex50
+								goto l68
+							l69:
+								position, tokenIndex = position69, tokenIndex69
 							}
 							{
 								switch buffer[position] {
 								case 'D', 'd':
 									if !_rules[ruledate]() {
-										goto l16
+										goto l66
 									}
-									break
 								case 'T', 't':
 									if !_rules[ruletime]() {
-										goto l16
+										goto l66
 									}
-									break
 								default:
 									if !_rules[rulenumber]() {
-										goto l16
+										goto l66
 									}
-									break
 								}
 							}
 
-							break
-						default:
+							goto l60
+						l66:
+							position, tokenIndex = position60, tokenIndex60
 							{
-								position52 := position
-								{
-									position53, tokenIndex53 := position, tokenIndex
-									if buffer[position] != rune('c') {
-										goto l54
+								switch buffer[position] {
+								case 'E', 'e':
+									{
+										position72 := position
+										{
+											position73, tokenIndex73 := position, tokenIndex
+											if buffer[position] != rune('e') {
+												goto l74
+											}
+											position++
+											goto l73
+										l74:
+											position, tokenIndex = position73, tokenIndex73
+											if buffer[position] != rune('E') {
+												goto l31
+											}
+											position++
+										}
+									l73:
+										{
+											position75, tokenIndex75 := position, tokenIndex
+											if buffer[position] != rune('x') {
+												goto l76
+											}
+											position++
+											goto l75
+										l76:
+											position, tokenIndex = position75, tokenIndex75
+											if buffer[position] != rune('X') {
+												goto l31
+											}
+											position++
+										}
+									l75:
+										{
+											position77, tokenIndex77 := position, tokenIndex
+											if buffer[position] != rune('i') {
+												goto l78
+											}
+											position++
+											goto l77
+										l78:
+											position, tokenIndex = position77, tokenIndex77
+											if buffer[position] != rune('I') {
+												goto l31
+											}
+											position++
+										}
+									l77:
+										{
+											position79, tokenIndex79 := position, tokenIndex
+											if buffer[position] != rune('s') {
+												goto l80
+											}
+											position++
+											goto l79
+										l80:
+											position, tokenIndex = position79, tokenIndex79
+											if buffer[position] != rune('S') {
+												goto l31
+											}
+											position++
+										}
+									l79:
+										{
+											position81, tokenIndex81 := position, tokenIndex
+											if buffer[position] != rune('t') {
+												goto l82
+											}
+											position++
+											goto l81
+										l82:
+											position, tokenIndex = position81, tokenIndex81
+											if buffer[position] != rune('T') {
+												goto l31
+											}
+											position++
+										}
+									l81:
+										{
+											position83, tokenIndex83 := position, tokenIndex
+											if buffer[position] != rune('s') {
+												goto l84
+											}
+											position++
+											goto l83
+										l84:
+											position, tokenIndex = position83, tokenIndex83
+											if buffer[position] != rune('S') {
+												goto l31
+											}
+											position++
+										}
+									l83:
+										add(ruleexists, position72)
 									}
-									position++
-									goto l53
-								l54:
-									position, tokenIndex = position53, tokenIndex53
-									if buffer[position] != rune('C') {
-										goto l16
+								case 'I', 'i':
+									{
+										position85 := position
+										{
+											position86, tokenIndex86 := position, tokenIndex
+											if buffer[position] != rune('i') {
+												goto l87
+											}
+											position++
+											goto l86
+										l87:
+											position, tokenIndex = position86, tokenIndex86
+											if buffer[position] != rune('I') {
+												goto l31
+											}
+											position++
+										}
+									l86:
+										{
+											position88, tokenIndex88 := position, tokenIndex
+											if buffer[position] != rune('n') {
+												goto l89
+											}
+											position++
+											goto l88
+										l89:
+											position, tokenIndex = position88, tokenIndex88
+											if buffer[position] != rune('N') {
+												goto l31
+											}
+											position++
+										}
+									l88:
+										add(rulein, position85)
 									}
-									position++
-								}
-							l53:
-								{
-									position55, tokenIndex55 := position, tokenIndex
-									if buffer[position] != rune('o') {
-										goto l56
+								l90:
+									{
+										position91, tokenIndex91 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l91
+										}
+										position++
+										goto l90
+									l91:
+										position, tokenIndex = position91, tokenIndex91
 									}
-									position++
-									goto l55
-								l56:
-									position, tokenIndex = position55, tokenIndex55
-									if buffer[position] != rune('O') {
-										goto l16
+									if buffer[position] != rune('(') {
+										goto l31
 									}
 									position++
-								}
-							l55:
-								{
-									position57, tokenIndex57 := position, tokenIndex
-									if buffer[position] != rune('n') {
-										goto l58
+								l92:
+									{
+										position93, tokenIndex93 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l93
+										}
+										position++
+										goto l92
+									l93:
+										position, tokenIndex = position93, tokenIndex93
 									}
-									position++
-									goto l57
-								l58:
-									position, tokenIndex = position57, tokenIndex57
-									if buffer[position] != rune('N') {
-										goto l16
+									if !_rules[ruleoperand]() {
+										goto l31
 									}
-									position++
-								}
-							l57:
-								{
-									position59, tokenIndex59 := position, tokenIndex
-									if buffer[position] != rune('t') {
-										goto l60
+								l94:
+									{
+										position95, tokenIndex95 := position, tokenIndex
+									l96:
+										{
+											position97, tokenIndex97 := position, tokenIndex
+											if buffer[position] != rune(' ') {
+												goto l97
+											}
+											position++
+											goto l96
+										l97:
+											position, tokenIndex = position97, tokenIndex97
+										}
+										if buffer[position] != rune(',') {
+											goto l95
+										}
+										position++
+									l98:
+										{
+											position99, tokenIndex99 := position, tokenIndex
+											if buffer[position] != rune(' ') {
+												goto l99
+											}
+											position++
+											goto l98
+										l99:
+											position, tokenIndex = position99, tokenIndex99
+										}
+										if !_rules[ruleoperand]() {
+											goto l95
+										}
+										goto l94
+									l95:
+										position, tokenIndex = position95, tokenIndex95
 									}
-									position++
-									goto l59
-								l60:
-									position, tokenIndex = position59, tokenIndex59
-									if buffer[position] != rune('T') {
-										goto l16
+								l100:
+									{
+										position101, tokenIndex101 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l101
+										}
+										position++
+										goto l100
+									l101:
+										position, tokenIndex = position101, tokenIndex101
 									}
-									position++
-								}
-							l59:
-								{
-									position61, tokenIndex61 := position, tokenIndex
-									if buffer[position] != rune('a') {
-										goto l62
+									if buffer[position] != rune(')') {
+										goto l31
 									}
 									position++
-									goto l61
-								l62:
-									position, tokenIndex = position61, tokenIndex61
-									if buffer[position] != rune('A') {
-										goto l16
+								case '=':
+									{
+										position102 := position
+										if buffer[position] != rune('=') {
+											goto l31
+										}
+										position++
+										add(ruleequal, position102)
 									}
-									position++
-								}
-							l61:
-								{
-									position63, tokenIndex63 := position, tokenIndex
-									if buffer[position] != rune('i') {
-										goto l64
+								l103:
+									{
+										position104, tokenIndex104 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l104
+										}
+										position++
+										goto l103
+									l104:
+										position, tokenIndex = position104, tokenIndex104
 									}
-									position++
-									goto l63
-								l64:
-									position, tokenIndex = position63, tokenIndex63
-									if buffer[position] != rune('I') {
-										goto l16
+									{
+										switch buffer[position] {
+										case '\'':
+											if !_rules[rulevalue]() {
+												goto l31
+											}
+										case 'D', 'd':
+											if !_rules[ruledate]() {
+												goto l31
+											}
+										case 'T', 't':
+											if !_rules[ruletime]() {
+												goto l31
+											}
+										default:
+											if !_rules[rulenumber]() {
+												goto l31
+											}
+										}
 									}
-									position++
-								}
-							l63:
-								{
-									position65, tokenIndex65 := position, tokenIndex
-									if buffer[position] != rune('n') {
-										goto l66
+
+								case '>':
+									{
+										position106 := position
+										if buffer[position] != rune('>') {
+											goto l31
+										}
+										position++
+										add(ruleg, position106)
 									}
-									position++
-									goto l65
-								l66:
-									position, tokenIndex = position65, tokenIndex65
-									if buffer[position] != rune('N') {
-										goto l16
+								l107:
+									{
+										position108, tokenIndex108 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l108
+										}
+										position++
+										goto l107
+									l108:
+										position, tokenIndex = position108, tokenIndex108
 									}
-									position++
-								}
-							l65:
-								{
-									position67, tokenIndex67 := position, tokenIndex
-									if buffer[position] != rune('s') {
-										goto l68
+									{
+										switch buffer[position] {
+										case 'D', 'd':
+											if !_rules[ruledate]() {
+												goto l31
+											}
+										case 'T', 't':
+											if !_rules[ruletime]() {
+												goto l31
+											}
+										default:
+											if !_rules[rulenumber]() {
+												goto l31
+											}
+										}
 									}
-									position++
-									goto l67
-								l68:
-									position, tokenIndex = position67, tokenIndex67
-									if buffer[position] != rune('S') {
-										goto l16
+
+								case '<':
+									{
+										position110 := position
+										if buffer[position] != rune('<') {
+											goto l31
+										}
+										position++
+										add(rulel, position110)
+									}
+								l111:
+									{
+										position112, tokenIndex112 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l112
+										}
+										position++
+										goto l111
+									l112:
+										position, tokenIndex = position112, tokenIndex112
+									}
+									{
+										switch buffer[position] {
+										case 'D', 'd':
+											if !_rules[ruledate]() {
+												goto l31
+											}
+										case 'T', 't':
+											if !_rules[ruletime]() {
+												goto l31
+											}
+										default:
+											if !_rules[rulenumber]() {
+												goto l31
+											}
+										}
+									}
+
+								default:
+									{
+										position114 := position
+										{
+											position115, tokenIndex115 := position, tokenIndex
+											if buffer[position] != rune('c') {
+												goto l116
+											}
+											position++
+											goto l115
+										l116:
+											position, tokenIndex = position115, tokenIndex115
+											if buffer[position] != rune('C') {
+												goto l31
+											}
+											position++
+										}
+									l115:
+										{
+											position117, tokenIndex117 := position, tokenIndex
+											if buffer[position] != rune('o') {
+												goto l118
+											}
+											position++
+											goto l117
+										l118:
+											position, tokenIndex = position117, tokenIndex117
+											if buffer[position] != rune('O') {
+												goto l31
+											}
+											position++
+										}
+									l117:
+										{
+											position119, tokenIndex119 := position, tokenIndex
+											if buffer[position] != rune('n') {
+												goto l120
+											}
+											position++
+											goto l119
+										l120:
+											position, tokenIndex = position119, tokenIndex119
+											if buffer[position] != rune('N') {
+												goto l31
+											}
+											position++
+										}
+									l119:
+										{
+											position121, tokenIndex121 := position, tokenIndex
+											if buffer[position] != rune('t') {
+												goto l122
+											}
+											position++
+											goto l121
+										l122:
+											position, tokenIndex = position121, tokenIndex121
+											if buffer[position] != rune('T') {
+												goto l31
+											}
+											position++
+										}
+									l121:
+										{
+											position123, tokenIndex123 := position, tokenIndex
+											if buffer[position] != rune('a') {
+												goto l124
+											}
+											position++
+											goto l123
+										l124:
+											position, tokenIndex = position123, tokenIndex123
+											if buffer[position] != rune('A') {
+												goto l31
+											}
+											position++
+										}
+									l123:
+										{
+											position125, tokenIndex125 := position, tokenIndex
+											if buffer[position] != rune('i') {
+												goto l126
+											}
+											position++
+											goto l125
+										l126:
+											position, tokenIndex = position125, tokenIndex125
+											if buffer[position] != rune('I') {
+												goto l31
+											}
+											position++
+										}
+									l125:
+										{
+											position127, tokenIndex127 := position, tokenIndex
+											if buffer[position] != rune('n') {
+												goto l128
+											}
+											position++
+											goto l127
+										l128:
+											position, tokenIndex = position127, tokenIndex127
+											if buffer[position] != rune('N') {
+												goto l31
+											}
+											position++
+										}
+									l127:
+										{
+											position129, tokenIndex129 := position, tokenIndex
+											if buffer[position] != rune('s') {
+												goto l130
+											}
+											position++
+											goto l129
+										l130:
+											position, tokenIndex = position129, tokenIndex129
+											if buffer[position] != rune('S') {
+												goto l31
+											}
+											position++
+										}
+									l129:
+										add(rulecontains, position114)
+									}
+								l131:
+									{
+										position132, tokenIndex132 := position, tokenIndex
+										if buffer[position] != rune(' ') {
+											goto l132
+										}
+										position++
+										goto l131
+									l132:
+										position, tokenIndex = position132, tokenIndex132
+									}
+									if !_rules[rulevalue]() {
+										goto l31
 									}
-									position++
-								}
-							l67:
-								add(rulecontains, position52)
-							}
-						l69:
-							{
-								position70, tokenIndex70 := position, tokenIndex
-								if buffer[position] != rune(' ') {
-									goto l70
 								}
-								position++
-								goto l69
-							l70:
-								position, tokenIndex = position70, tokenIndex70
-							}
-							if !_rules[rulevalue]() {
-								goto l16
 							}
-							break
+
 						}
+					l60:
+						add(rulecondition, position49)
 					}
-
 				}
-			l28:
-				add(rulecondition, position17)
+			l33:
+				add(rulefactor, position32)
 			}
 			return true
-		l16:
-			position, tokenIndex = position16, tokenIndex16
+		l31:
+			position, tokenIndex = position31, tokenIndex31
 			return false
 		},
-		/* 2 tag <- <<(!((&('<') '<') | (&('>') '>') | (&('=') '=') | (&('\'') '\'') | (&('"') '"') | (&(')') ')') | (&('(') '(') | (&('\\') '\\') | (&('\r') '\r') | (&('\n') '\n') | (&('\t') '\t') | (&(' ') ' ')) .)+>> */
+		/* 4 group <- <('(' ' '* expr ' '* ')')> */
+		func() bool {
+			position133, tokenIndex133 := position, tokenIndex
+			{
+				position134 := position
+				if buffer[position] != rune('(') {
+					goto l133
+				}
+				position++
+			l135:
+				{
+					position136, tokenIndex136 := position, tokenIndex
+					if buffer[position] != rune(' ') {
+						goto l136
+					}
+					position++
+					goto l135
+				l136:
+					position, tokenIndex = position136, tokenIndex136
+				}
+				if !_rules[ruleexpr]() {
+					goto l133
+				}
+			l137:
+				{
+					position138, tokenIndex138 := position, tokenIndex
+					if buffer[position] != rune(' ') {
+						goto l138
+					}
+					position++
+					goto l137
+				l138:
+					position, tokenIndex = position138, tokenIndex138
+				}
+				if buffer[position] != rune(')') {
+					goto l133
+				}
+				position++
+				add(rulegroup, position134)
+			}
+			return true
+		l133:
+			position, tokenIndex = position133, tokenIndex133
+			return false
+		},
+		/* 5 condition <- <(tag ' '* ((le ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number))) / (ge ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number))) / ((&('E' | 'e') exists) | (&('I' | 'i') (in ' '* '(' ' '* operand (' '* ',' ' '* operand)* ' '* ')')) | (&('=') (equal ' '* ((&('\'') value) | (&('D' | 'd') date) | (&('T' | 't') time) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number)))) | (&('>') (g ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number)))) | (&('<') (l ' '* ((&('D' | 'd') date) | (&('T' | 't') time) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number)))) | (&('C' | 'c') (contains ' '* value)))))> */
 		nil,
-		/* 3 value <- <<('\'' (!('"' / '\'') .)* '\'')>> */
+		/* 6 operand <- <((&('\'') value) | (&('D' | 'd') date) | (&('T' | 't') time) | (&('-' | '0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') number))> */
 		func() bool {
-			position72, tokenIndex72 := position, tokenIndex
+			position140, tokenIndex140 := position, tokenIndex
 			{
-				position73 := position
+				position141 := position
 				{
-					position74 := position
+					switch buffer[position] {
+					case '\'':
+						if !_rules[rulevalue]() {
+							goto l140
+						}
+					case 'D', 'd':
+						if !_rules[ruledate]() {
+							goto l140
+						}
+					case 'T', 't':
+						if !_rules[ruletime]() {
+							goto l140
+						}
+					default:
+						if !_rules[rulenumber]() {
+							goto l140
+						}
+					}
+				}
+
+				add(ruleoperand, position141)
+			}
+			return true
+		l140:
+			position, tokenIndex = position140, tokenIndex140
+			return false
+		},
+		/* 7 tag <- <<(!((&('<') '<') | (&('>') '>') | (&('=') '=') | (&('\'') '\'') | (&('"') '"') | (&(')') ')') | (&('(') '(') | (&('\\') '\\') | (&('\r') '\r') | (&('\n') '\n') | (&('\t') '\t') | (&(' ') ' ')) .)+>> */
+		nil,
+		/* 8 value <- <<('\'' (!('"' / '\'') .)* '\'')>> */
+		func() bool {
+			position144, tokenIndex144 := position, tokenIndex
+			{
+				position145 := position
+				{
+					position146 := position
 					if buffer[position] != rune('\'') {
-						goto l72
+						goto l144
 					}
 					position++
-				l75:
+				l147:
 					{
-						position76, tokenIndex76 := position, tokenIndex
+						position148, tokenIndex148 := position, tokenIndex
 						{
-							position77, tokenIndex77 := position, tokenIndex
+							position149, tokenIndex149 := position, tokenIndex
 							{
-								position78, tokenIndex78 := position, tokenIndex
+								position150, tokenIndex150 := position, tokenIndex
 								if buffer[position] != rune('"') {
-									goto l79
+									goto l151
 								}
 								position++
-								goto l78
-							l79:
-								position, tokenIndex = position78, tokenIndex78
+								goto l150
+							l151:
+								position, tokenIndex = position150, tokenIndex150
 								if buffer[position] != rune('\'') {
-									goto l77
+									goto l149
 								}
 								position++
 							}
-						l78:
-							goto l76
-						l77:
-							position, tokenIndex = position77, tokenIndex77
+						l150:
+							goto l148
+						l149:
+							position, tokenIndex = position149, tokenIndex149
 						}
 						if !matchDot() {
-							goto l76
+							goto l148
 						}
-						goto l75
-					l76:
-						position, tokenIndex = position76, tokenIndex76
+						goto l147
+					l148:
+						position, tokenIndex = position148, tokenIndex148
 					}
 					if buffer[position] != rune('\'') {
-						goto l72
+						goto l144
 					}
 					position++
-					add(rulePegText, position74)
+					add(rulePegText, position146)
 				}
-				add(rulevalue, position73)
+				add(rulevalue, position145)
 			}
 			return true
-		l72:
-			position, tokenIndex = position72, tokenIndex72
+		l144:
+			position, tokenIndex = position144, tokenIndex144
 			return false
 		},
-		/* 4 number <- <<('0' / ([1-9] digit* ('.' digit*)?))>> */
+		/* 9 number <- <<('-'? ('0' / ([1-9] digit*)) ('.' digit*)?)>> */
 		func() bool {
-			position80, tokenIndex80 := position, tokenIndex
+			position152, tokenIndex152 := position, tokenIndex
 			{
-				position81 := position
+				position153 := position
 				{
-					position82 := position
+					position154 := position
 					{
-						position83, tokenIndex83 := position, tokenIndex
+						position155, tokenIndex155 := position, tokenIndex
+						if buffer[position] != rune('-') {
+							goto l155
+						}
+						position++
+						goto l156
+					l155:
+						position, tokenIndex = position155, tokenIndex155
+					}
+				l156:
+					{
+						position157, tokenIndex157 := position, tokenIndex
 						if buffer[position] != rune('0') {
-							goto l84
+							goto l158
 						}
 						position++
-						goto l83
-					l84:
-						position, tokenIndex = position83, tokenIndex83
+						goto l157
+					l158:
+						position, tokenIndex = position157, tokenIndex157
 						if c := buffer[position]; c < rune('1') || c > rune('9') {
-							goto l80
+							goto l152
 						}
 						position++
-					l85:
+					l159:
 						{
-							position86, tokenIndex86 := position, tokenIndex
+							position160, tokenIndex160 := position, tokenIndex
 							if !_rules[ruledigit]() {
-								goto l86
+								goto l160
 							}
-							goto l85
-						l86:
-							position, tokenIndex = position86, tokenIndex86
+							goto l159
+						l160:
+							position, tokenIndex = position160, tokenIndex160
 						}
+					}
+				l157:
+					{
+						position161, tokenIndex161 := position, tokenIndex
+						if buffer[position] != rune('.') {
+							goto l161
+						}
+						position++
+					l163:
 						{
-							position87, tokenIndex87 := position, tokenIndex
-							if buffer[position] != rune('.') {
-								goto l87
-							}
-							position++
-						l89:
-							{
-								position90, tokenIndex90 := position, tokenIndex
-								if !_rules[ruledigit]() {
-									goto l90
-								}
-								goto l89
-							l90:
-								position, tokenIndex = position90, tokenIndex90
+							position164, tokenIndex164 := position, tokenIndex
+							if !_rules[ruledigit]() {
+								goto l164
 							}
-							goto l88
-						l87:
-							position, tokenIndex = position87, tokenIndex87
+							goto l163
+						l164:
+							position, tokenIndex = position164, tokenIndex164
 						}
-					l88:
+						goto l162
+					l161:
+						position, tokenIndex = position161, tokenIndex161
 					}
-				l83:
-					add(rulePegText, position82)
+				l162:
+					add(rulePegText, position154)
 				}
-				add(rulenumber, position81)
+				add(rulenumber, position153)
 			}
 			return true
-		l80:
-			position, tokenIndex = position80, tokenIndex80
+		l152:
+			position, tokenIndex = position152, tokenIndex152
 			return false
 		},
-		/* 5 digit <- <[0-9]> */
+		/* 10 digit <- <[0-9]> */
 		func() bool {
-			position91, tokenIndex91 := position, tokenIndex
+			position165, tokenIndex165 := position, tokenIndex
 			{
-				position92 := position
+				position166 := position
 				if c := buffer[position]; c < rune('0') || c > rune('9') {
-					goto l91
+					goto l165
 				}
 				position++
-				add(ruledigit, position92)
+				add(ruledigit, position166)
 			}
 			return true
-		l91:
-			position, tokenIndex = position91, tokenIndex91
+		l165:
+			position, tokenIndex = position165, tokenIndex165
 			return false
 		},
-		/* 6 time <- <(('t' / 'T') ('i' / 'I') ('m' / 'M') ('e' / 'E') ' ' <(year '-' month '-' day 'T' digit digit ':' digit digit ':' digit digit ((('-' / '+') digit digit ':' digit digit) / 'Z'))>)> */
+		/* 11 time <- <(('t' / 'T') ('i' / 'I') ('m' / 'M') ('e' / 'E') ' ' <(year '-' month '-' day 'T' digit digit ':' digit digit ':' digit digit ((('-' / '+') digit digit ':' digit digit) / 'Z'))>)> */
 		func() bool {
-			position93, tokenIndex93 := position, tokenIndex
+			position167, tokenIndex167 := position, tokenIndex
 			{
-				position94 := position
+				position168 := position
 				{
-					position95, tokenIndex95 := position, tokenIndex
+					position169, tokenIndex169 := position, tokenIndex
 					if buffer[position] != rune('t') {
-						goto l96
+						goto l170
 					}
 					position++
-					goto l95
-				l96:
-					position, tokenIndex = position95, tokenIndex95
+					goto l169
+				l170:
+					position, tokenIndex = position169, tokenIndex169
 					if buffer[position] != rune('T') {
-						goto l93
+						goto l167
 					}
 					position++
 				}
-			l95:
+			l169:
 				{
-					position97, tokenIndex97 := position, tokenIndex
+					position171, tokenIndex171 := position, tokenIndex
 					if buffer[position] != rune('i') {
-						goto l98
+						goto l172
 					}
 					position++
-					goto l97
-				l98:
-					position, tokenIndex = position97, tokenIndex97
+					goto l171
+				l172:
+					position, tokenIndex = position171, tokenIndex171
 					if buffer[position] != rune('I') {
-						goto l93
+						goto l167
 					}
 					position++
 				}
-			l97:
+			l171:
 				{
-					position99, tokenIndex99 := position, tokenIndex
+					position173, tokenIndex173 := position, tokenIndex
 					if buffer[position] != rune('m') {
-						goto l100
+						goto l174
 					}
 					position++
-					goto l99
-				l100:
-					position, tokenIndex = position99, tokenIndex99
+					goto l173
+				l174:
+					position, tokenIndex = position173, tokenIndex173
 					if buffer[position] != rune('M') {
-						goto l93
+						goto l167
 					}
 					position++
 				}
-			l99:
+			l173:
 				{
-					position101, tokenIndex101 := position, tokenIndex
+					position175, tokenIndex175 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l102
+						goto l176
 					}
 					position++
-					goto l101
-				l102:
-					position, tokenIndex = position101, tokenIndex101
+					goto l175
+				l176:
+					position, tokenIndex = position175, tokenIndex175
 					if buffer[position] != rune('E') {
-						goto l93
+						goto l167
 					}
 					position++
 				}
-			l101:
+			l175:
 				if buffer[position] != rune(' ') {
-					goto l93
+					goto l167
 				}
 				position++
 				{
-					position103 := position
+					position177 := position
 					if !_rules[ruleyear]() {
-						goto l93
+						goto l167
 					}
 					if buffer[position] != rune('-') {
-						goto l93
+						goto l167
 					}
 					position++
 					if !_rules[rulemonth]() {
-						goto l93
+						goto l167
 					}
 					if buffer[position] != rune('-') {
-						goto l93
+						goto l167
 					}
 					position++
 					if !_rules[ruleday]() {
-						goto l93
+						goto l167
 					}
 					if buffer[position] != rune('T') {
-						goto l93
+						goto l167
 					}
 					position++
 					if !_rules[ruledigit]() {
-						goto l93
+						goto l167
 					}
 					if !_rules[ruledigit]() {
-						goto l93
+						goto l167
 					}
 					if buffer[position] != rune(':') {
-						goto l93
+						goto l167
 					}
 					position++
 					if !_rules[ruledigit]() {
-						goto l93
+						goto l167
 					}
 					if !_rules[ruledigit]() {
-						goto l93
+						goto l167
 					}
 					if buffer[position] != rune(':') {
-						goto l93
+						goto l167
 					}
 					position++
 					if !_rules[ruledigit]() {
-						goto l93
+						goto l167
 					}
 					if !_rules[ruledigit]() {
-						goto l93
+						goto l167
 					}
 					{
-						position104, tokenIndex104 := position, tokenIndex
+						position178, tokenIndex178 := position, tokenIndex
 						{
-							position106, tokenIndex106 := position, tokenIndex
+							position180, tokenIndex180 := position, tokenIndex
 							if buffer[position] != rune('-') {
-								goto l107
+								goto l181
 							}
 							position++
-							goto l106
-						l107:
-							position, tokenIndex = position106, tokenIndex106
+							goto l180
+						l181:
+							position, tokenIndex = position180, tokenIndex180
 							if buffer[position] != rune('+') {
-								goto l105
+								goto l179
 							}
 							position++
 						}
-					l106:
+					l180:
 						if !_rules[ruledigit]() {
-							goto l105
+							goto l179
 						}
 						if !_rules[ruledigit]() {
-							goto l105
+							goto l179
 						}
 						if buffer[position] != rune(':') {
-							goto l105
+							goto l179
 						}
 						position++
 						if !_rules[ruledigit]() {
-							goto l105
+							goto l179
 						}
 						if !_rules[ruledigit]() {
-							goto l105
+							goto l179
 						}
-						goto l104
-					l105:
-						position, tokenIndex = position104, tokenIndex104
+						goto l178
+					l179:
+						position, tokenIndex = position178, tokenIndex178
 						if buffer[position] != rune('Z') {
-							goto l93
+							goto l167
 						}
 						position++
 					}
-				l104:
-					add(rulePegText, position103)
+				l178:
+					add(rulePegText, position177)
 				}
-				add(ruletime, position94)
+				add(ruletime, position168)
 			}
 			return true
-		l93:
-			position, tokenIndex = position93, tokenIndex93
+		l167:
+			position, tokenIndex = position167, tokenIndex167
 			return false
 		},
-		/* 7 date <- <(('d' / 'D') ('a' / 'A') ('t' / 'T') ('e' / 'E') ' ' <(year '-' month '-' day)>)> */
+		/* 12 date <- <(('d' / 'D') ('a' / 'A') ('t' / 'T') ('e' / 'E') ' ' <(year '-' month '-' day)>)> */
 		func() bool {
-			position108, tokenIndex108 := position, tokenIndex
+			position182, tokenIndex182 := position, tokenIndex
 			{
-				position109 := position
+				position183 := position
 				{
-					position110, tokenIndex110 := position, tokenIndex
+					position184, tokenIndex184 := position, tokenIndex
 					if buffer[position] != rune('d') {
-						goto l111
+						goto l185
 					}
 					position++
-					goto l110
-				l111:
-					position, tokenIndex = position110, tokenIndex110
+					goto l184
+				l185:
+					position, tokenIndex = position184, tokenIndex184
 					if buffer[position] != rune('D') {
-						goto l108
+						goto l182
 					}
 					position++
 				}
-			l110:
+			l184:
 				{
-					position112, tokenIndex112 := position, tokenIndex
+					position186, tokenIndex186 := position, tokenIndex
 					if buffer[position] != rune('a') {
-						goto l113
+						goto l187
 					}
 					position++
-					goto l112
-				l113:
-					position, tokenIndex = position112, tokenIndex112
+					goto l186
+				l187:
+					position, tokenIndex = position186, tokenIndex186
 					if buffer[position] != rune('A') {
-						goto l108
+						goto l182
 					}
 					position++
 				}
-			l112:
+			l186:
 				{
-					position114, tokenIndex114 := position, tokenIndex
+					position188, tokenIndex188 := position, tokenIndex
 					if buffer[position] != rune('t') {
-						goto l115
+						goto l189
 					}
 					position++
-					goto l114
-				l115:
-					position, tokenIndex = position114, tokenIndex114
+					goto l188
+				l189:
+					position, tokenIndex = position188, tokenIndex188
 					if buffer[position] != rune('T') {
-						goto l108
+						goto l182
 					}
 					position++
 				}
-			l114:
+			l188:
 				{
-					position116, tokenIndex116 := position, tokenIndex
+					position190, tokenIndex190 := position, tokenIndex
 					if buffer[position] != rune('e') {
-						goto l117
+						goto l191
 					}
 					position++
-					goto l116
-				l117:
-					position, tokenIndex = position116, tokenIndex116
+					goto l190
+				l191:
+					position, tokenIndex = position190, tokenIndex190
 					if buffer[position] != rune('E') {
-						goto l108
+						goto l182
 					}
 					position++
 				}
-			l116:
+			l190:
 				if buffer[position] != rune(' ') {
-					goto l108
+					goto l182
 				}
 				position++
 				{
-					position118 := position
+					position192 := position
 					if !_rules[ruleyear]() {
-						goto l108
+						goto l182
 					}
 					if buffer[position] != rune('-') {
-						goto l108
+						goto l182
 					}
 					position++
 					if !_rules[rulemonth]() {
-						goto l108
+						goto l182
 					}
 					if buffer[position] != rune('-') {
-						goto l108
+						goto l182
 					}
 					position++
 					if !_rules[ruleday]() {
-						goto l108
+						goto l182
 					}
-					add(rulePegText, position118)
+					add(rulePegText, position192)
 				}
-				add(ruledate, position109)
+				add(ruledate, position183)
 			}
 			return true
-		l108:
-			position, tokenIndex = position108, tokenIndex108
+		l182:
+			position, tokenIndex = position182, tokenIndex182
 			return false
 		},
-		/* 8 year <- <(('1' / '2') digit digit digit)> */
+		/* 13 year <- <(('1' / '2') digit digit digit)> */
 		func() bool {
-			position119, tokenIndex119 := position, tokenIndex
+			position193, tokenIndex193 := position, tokenIndex
 			{
-				position120 := position
+				position194 := position
 				{
-					position121, tokenIndex121 := position, tokenIndex
+					position195, tokenIndex195 := position, tokenIndex
 					if buffer[position] != rune('1') {
-						goto l122
+						goto l196
 					}
 					position++
-					goto l121
-				l122:
-					position, tokenIndex = position121, tokenIndex121
+					goto l195
+				l196:
+					position, tokenIndex = position195, tokenIndex195
 					if buffer[position] != rune('2') {
-						goto l119
+						goto l193
 					}
 					position++
 				}
-			l121:
+			l195:
 				if !_rules[ruledigit]() {
-					goto l119
+					goto l193
 				}
 				if !_rules[ruledigit]() {
-					goto l119
+					goto l193
 				}
 				if !_rules[ruledigit]() {
-					goto l119
+					goto l193
 				}
-				add(ruleyear, position120)
+				add(ruleyear, position194)
 			}
 			return true
-		l119:
-			position, tokenIndex = position119, tokenIndex119
+		l193:
+			position, tokenIndex = position193, tokenIndex193
 			return false
 		},
-		/* 9 month <- <(('0' / '1') digit)> */
+		/* 14 month <- <(('0' / '1') digit)> */
 		func() bool {
-			position123, tokenIndex123 := position, tokenIndex
+			position197, tokenIndex197 := position, tokenIndex
 			{
-				position124 := position
+				position198 := position
 				{
-					position125, tokenIndex125 := position, tokenIndex
+					position199, tokenIndex199 := position, tokenIndex
 					if buffer[position] != rune('0') {
-						goto l126
+						goto l200
 					}
 					position++
-					goto l125
-				l126:
-					position, tokenIndex = position125, tokenIndex125
+					goto l199
+				l200:
+					position, tokenIndex = position199, tokenIndex199
 					if buffer[position] != rune('1') {
-						goto l123
+						goto l197
 					}
 					position++
 				}
-			l125:
+			l199:
 				if !_rules[ruledigit]() {
-					goto l123
+					goto l197
 				}
-				add(rulemonth, position124)
+				add(rulemonth, position198)
 			}
 			return true
-		l123:
-			position, tokenIndex = position123, tokenIndex123
+		l197:
+			position, tokenIndex = position197, tokenIndex197
 			return false
 		},
-		/* 10 day <- <(((&('3') '3') | (&('2') '2') | (&('1') '1') | (&('0') '0')) digit)> */
+		/* 15 day <- <(((&('3') '3') | (&('2') '2') | (&('1') '1') | (&('0') '0')) digit)> */
 		func() bool {
-			position127, tokenIndex127 := position, tokenIndex
+			position201, tokenIndex201 := position, tokenIndex
 			{
-				position128 := position
+				position202 := position
 				{
 					switch buffer[position] {
 					case '3':
 						if buffer[position] != rune('3') {
-							goto l127
+							goto l201
 						}
 						position++
-						break
 					case '2':
 						if buffer[position] != rune('2') {
-							goto l127
+							goto l201
 						}
 						position++
-						break
 					case '1':
 						if buffer[position] != rune('1') {
-							goto l127
+							goto l201
 						}
 						position++
-						break
 					default:
 						if buffer[position] != rune('0') {
-							goto l127
+							goto l201
 						}
 						position++
-						break
 					}
 				}
 
 				if !_rules[ruledigit]() {
-					goto l127
+					goto l201
 				}
-				add(ruleday, position128)
+				add(ruleday, position202)
 			}
 			return true
-		l127:
-			position, tokenIndex = position127, tokenIndex127
+		l201:
+			position, tokenIndex = position201, tokenIndex201
 			return false
 		},
-		/* 11 and <- <(('a' / 'A') ('n' / 'N') ('d' / 'D'))> */
+		/* 16 and <- <(('a' / 'A') ('n' / 'N') ('d' / 'D'))> */
+		nil,
+		/* 17 or <- <(('o' / 'O') ('r' / 'R'))> */
+		nil,
+		/* 18 not <- <(('n' / 'N') ('o' / 'O') ('t' / 'T'))> */
 		nil,
-		/* 12 equal <- <'='> */
+		/* 19 equal <- <'='> */
 		nil,
-		/* 13 contains <- <(('c' / 'C') ('o' / 'O') ('n' / 'N') ('t' / 'T') ('a' / 'A') ('i' / 'I') ('n' / 'N') ('s' / 'S'))> */
+		/* 20 contains <- <(('c' / 'C') ('o' / 'O') ('n' / 'N') ('t' / 'T') ('a' / 'A') ('i' / 'I') ('n' / 'N') ('s' / 'S'))> */
 		nil,
-		/* 14 le <- <('<' '=')> */
+		/* 21 in <- <(('i' / 'I') ('n' / 'N'))> */
 		nil,
-		/* 15 ge <- <('>' '=')> */
+		/* 22 exists <- <(('e' / 'E') ('x' / 'X') ('i' / 'I') ('s' / 'S') ('t' / 'T') ('s' / 'S'))> */
 		nil,
-		/* 16 l <- <'<'> */
+		/* 23 le <- <('<' '=')> */
 		nil,
-		/* 17 g <- <'>'> */
+		/* 24 ge <- <('>' '=')> */
+		nil,
+		/* 25 l <- <'<'> */
+		nil,
+		/* 26 g <- <'>'> */
 		nil,
 		nil,
 	}
 	p.rules = _rules
+	return nil
 }
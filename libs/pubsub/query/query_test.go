@@ -8,6 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/pubsub"
 	"github.com/tendermint/tendermint/libs/pubsub/query"
 )
@@ -44,6 +45,22 @@ func TestMatches(t *testing.T) {
 
 		{"abci.owner.name CONTAINS 'Igor'", map[string]string{"abci.owner.name": "Igor,Ivan"}, false, true},
 		{"abci.owner.name CONTAINS 'Igor'", map[string]string{"abci.owner.name": "Pavel,Ivan"}, false, false},
+
+		{"transfer.sender='A' OR transfer.recipient='A'", map[string]string{"transfer.sender": "B", "transfer.recipient": "A"}, false, true},
+		{"transfer.sender='A' OR transfer.recipient='A'", map[string]string{"transfer.sender": "B", "transfer.recipient": "C"}, false, false},
+		{"tx.gas > 7 AND tx.gas < 9 OR tx.gas = 20", map[string]string{"tx.gas": "20"}, false, true},
+		{"tx.gas > 7 AND (tx.gas < 9 OR tx.gas = 20)", map[string]string{"tx.gas": "10"}, false, false},
+		{"NOT transfer.sender='A'", map[string]string{"transfer.sender": "B"}, false, true},
+		{"NOT transfer.sender='A'", map[string]string{"transfer.sender": "A"}, false, false},
+		{"NOT (transfer.sender='A' OR transfer.recipient='A')", map[string]string{"transfer.sender": "B", "transfer.recipient": "C"}, false, true},
+		{"NOT transfer.fee EXISTS", map[string]string{}, false, true},
+
+		{"transfer.currency IN ('BTC', 'ETH')", map[string]string{"transfer.currency": "ETH"}, false, true},
+		{"transfer.currency IN ('BTC', 'ETH')", map[string]string{"transfer.currency": "XRP"}, false, false},
+		{"tx.gas IN (7, 8.5)", map[string]string{"tx.gas": "8.5"}, false, true},
+
+		{"transfer.fee EXISTS", map[string]string{"transfer.fee": "1"}, false, true},
+		{"transfer.fee EXISTS", map[string]string{"transfer.sender": "A"}, false, false},
 	}
 
 	for _, tc := range testCases {
@@ -60,6 +77,54 @@ func TestMatches(t *testing.T) {
 	}
 }
 
+func TestMatchesTyped(t *testing.T) {
+	testCases := []struct {
+		s       string
+		value   string
+		typ     cmn.KVPair_Type
+		matches bool
+	}{
+		// untyped values which can't be converted don't match (and don't panic)
+		{"tag > 7", "seven", cmn.KVPair_UNTYPED, false},
+		{"tag > 7.5", "seven", cmn.KVPair_UNTYPED, false},
+		{"tag > DATE 2017-01-01", "seven", cmn.KVPair_UNTYPED, false},
+
+		{"tag = '7'", "7", cmn.KVPair_STRING, true},
+		{"tag = 7", "7", cmn.KVPair_STRING, false},
+		{"tag > 6", "7", cmn.KVPair_STRING, false},
+		{"tag CONTAINS '20'", "2018-05-03", cmn.KVPair_STRING, true},
+		{"tag < DATE 2019-01-01", "2018-05-03", cmn.KVPair_STRING, false},
+
+		{"tag = 7", "7", cmn.KVPair_INT, true},
+		{"tag > 6.5", "7", cmn.KVPair_INT, true},
+		{"tag = '7'", "7", cmn.KVPair_INT, false},
+		{"tag = 9007199254740993", "9007199254740993", cmn.KVPair_INT, true},
+		{"tag > 9007199254740992", "9007199254740993", cmn.KVPair_INT, true},
+
+		{"tag > 7", "7.5", cmn.KVPair_DECIMAL, true},
+		{"tag = 7.5", "7.5", cmn.KVPair_DECIMAL, true},
+		{"tag < 1", "-1e-6", cmn.KVPair_DECIMAL, true},
+		{"tag CONTAINS '.'", "7.5", cmn.KVPair_DECIMAL, false},
+
+		{"tag = TIME 2013-05-03T14:45:00Z", "2013-05-03T16:45:00+02:00", cmn.KVPair_TIME, true},
+		{"tag > DATE 2013-05-03", "2013-05-03T14:45:00.5Z", cmn.KVPair_TIME, true},
+		{"tag = 2013", "2013-05-03T14:45:00Z", cmn.KVPair_TIME, false},
+
+		{"tag = 'true'", "true", cmn.KVPair_BOOL, true},
+		{"tag = 1", "true", cmn.KVPair_BOOL, false},
+
+		{"tag EXISTS", "true", cmn.KVPair_BOOL, true},
+		{"tag IN (6, 7)", "7", cmn.KVPair_INT, true},
+		{"tag IN ('6', '7')", "7", cmn.KVPair_INT, false},
+	}
+
+	for _, tc := range testCases {
+		q := query.MustParse(tc.s)
+		tags := pubsub.NewTypedTagMap(map[string]string{"tag": tc.value}, map[string]cmn.KVPair_Type{"tag": tc.typ})
+		assert.Equal(t, tc.matches, q.Matches(tags), "Query '%s' on %v %q", tc.s, tc.typ, tc.value)
+	}
+}
+
 func TestMustParse(t *testing.T) {
 	assert.Panics(t, func() { query.MustParse("=") })
 	assert.NotPanics(t, func() { query.MustParse("tm.events.type='NewBlock'") })
@@ -76,6 +141,7 @@ func TestConditions(t *testing.T) {
 		{s: "tm.events.type='NewBlock'", conditions: []query.Condition{query.Condition{Tag: "tm.events.type", Op: query.OpEqual, Operand: "NewBlock"}}},
 		{s: "tx.gas > 7 AND tx.gas < 9", conditions: []query.Condition{query.Condition{Tag: "tx.gas", Op: query.OpGreater, Operand: int64(7)}, query.Condition{Tag: "tx.gas", Op: query.OpLess, Operand: int64(9)}}},
 		{s: "tx.time >= TIME 2013-05-03T14:45:00Z", conditions: []query.Condition{query.Condition{Tag: "tx.time", Op: query.OpGreaterEqual, Operand: txTime}}},
+		{s: "tx.gas IN (7, 'eight') OR tx.fee EXISTS", conditions: []query.Condition{query.Condition{Tag: "tx.gas", Op: query.OpIn, Operand: []interface{}{int64(7), "eight"}}, query.Condition{Tag: "tx.fee", Op: query.OpExists}}},
 	}
 
 	for _, tc := range testCases {
@@ -85,3 +151,31 @@ func TestConditions(t *testing.T) {
 		assert.Equal(t, tc.conditions, q.Conditions())
 	}
 }
+
+func TestExpr(t *testing.T) {
+	a := &query.Expr{Type: query.ExprCondition, Condition: query.Condition{Tag: "a", Op: query.OpEqual, Operand: int64(1)}}
+	b := &query.Expr{Type: query.ExprCondition, Condition: query.Condition{Tag: "b", Op: query.OpEqual, Operand: int64(2)}}
+	c := &query.Expr{Type: query.ExprCondition, Condition: query.Condition{Tag: "c", Op: query.OpEqual, Operand: int64(3)}}
+
+	testCases := []struct {
+		s             string
+		expr          *query.Expr
+		isConjunction bool
+	}{
+		{"a = 1", a, true},
+		{"a = 1 AND b = 2 AND c = 3", &query.Expr{Type: query.ExprAnd, Args: []*query.Expr{a, b, c}}, true},
+		{"(a = 1 AND b = 2) AND c = 3", &query.Expr{Type: query.ExprAnd, Args: []*query.Expr{{Type: query.ExprAnd, Args: []*query.Expr{a, b}}, c}}, false},
+		{"a = 1 OR b = 2 AND c = 3", &query.Expr{Type: query.ExprOr, Args: []*query.Expr{a, {Type: query.ExprAnd, Args: []*query.Expr{b, c}}}}, false},
+		{"(a = 1 OR b = 2) AND c = 3", &query.Expr{Type: query.ExprAnd, Args: []*query.Expr{{Type: query.ExprOr, Args: []*query.Expr{a, b}}, c}}, false},
+		{"NOT a = 1 AND b = 2", &query.Expr{Type: query.ExprAnd, Args: []*query.Expr{{Type: query.ExprNot, Args: []*query.Expr{a}}, b}}, false},
+		{"NOT (a = 1 AND b = 2)", &query.Expr{Type: query.ExprNot, Args: []*query.Expr{{Type: query.ExprAnd, Args: []*query.Expr{a, b}}}}, false},
+	}
+
+	for _, tc := range testCases {
+		q, err := query.New(tc.s)
+		require.Nil(t, err)
+
+		assert.Equal(t, tc.expr, q.Expr(), tc.s)
+		assert.Equal(t, tc.isConjunction, q.IsConjunction(), tc.s)
+	}
+}
@@ -76,13 +76,18 @@ func (memR *MempoolReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
 	// broadcast routine checks if peer is gone and returns
 }
 
+// DecodeMessage implements p2p.MessageDecoder.
+func (memR *MempoolReactor) DecodeMessage(chID byte, msgBytes []byte) (interface{}, error) {
+	return decodeMsg(msgBytes)
+}
+
 // Receive implements Reactor.
 // It adds any received transactions to the mempool.
 func (memR *MempoolReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 	msg, err := decodeMsg(msgBytes)
 	if err != nil {
 		memR.Logger.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
-		memR.Switch.StopPeerForError(src, err)
+		memR.Switch.StopPeerForMisbehavior(src, err)
 		return
 	}
 	memR.Logger.Debug("Receive", "src", src, "chId", chID, "msg", msg)
@@ -3,10 +3,14 @@ package node
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -20,6 +24,7 @@ import (
 	bc "github.com/tendermint/tendermint/blockchain"
 	cfg "github.com/tendermint/tendermint/config"
 	cs "github.com/tendermint/tendermint/consensus"
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	"github.com/tendermint/tendermint/evidence"
 	cmn "github.com/tendermint/tendermint/libs/common"
@@ -28,6 +33,7 @@ import (
 	mempl "github.com/tendermint/tendermint/mempool"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/p2p/pex"
+	"github.com/tendermint/tendermint/p2p/trust"
 	"github.com/tendermint/tendermint/privval"
 	"github.com/tendermint/tendermint/proxy"
 	rpccore "github.com/tendermint/tendermint/rpc/core"
@@ -35,9 +41,13 @@ import (
 	grpccore "github.com/tendermint/tendermint/rpc/grpc"
 	"github.com/tendermint/tendermint/rpc/lib/server"
 	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/blockindex"
+	blockkv "github.com/tendermint/tendermint/state/blockindex/kv"
+	blocknull "github.com/tendermint/tendermint/state/blockindex/null"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/state/txindex/kv"
 	"github.com/tendermint/tendermint/state/txindex/null"
+	sqlidx "github.com/tendermint/tendermint/state/txindex/sql"
 	"github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
 	"github.com/tendermint/tendermint/version"
@@ -98,18 +108,19 @@ func DefaultNewNode(config *cfg.Config, logger log.Logger) (*Node, error) {
 	)
 }
 
-// MetricsProvider returns a consensus, p2p and mempool Metrics.
-type MetricsProvider func() (*cs.Metrics, *p2p.Metrics, *mempl.Metrics, *sm.Metrics)
+// MetricsProvider returns a consensus, p2p, mempool, state and privval Metrics.
+type MetricsProvider func() (*cs.Metrics, *p2p.Metrics, *mempl.Metrics, *sm.Metrics, *privval.Metrics)
 
 // DefaultMetricsProvider returns Metrics build using Prometheus client library
 // if Prometheus is enabled. Otherwise, it returns no-op Metrics.
 func DefaultMetricsProvider(config *cfg.InstrumentationConfig) MetricsProvider {
-	return func() (*cs.Metrics, *p2p.Metrics, *mempl.Metrics, *sm.Metrics) {
+	return func() (*cs.Metrics, *p2p.Metrics, *mempl.Metrics, *sm.Metrics, *privval.Metrics) {
 		if config.Prometheus {
 			return cs.PrometheusMetrics(config.Namespace), p2p.PrometheusMetrics(config.Namespace),
-				mempl.PrometheusMetrics(config.Namespace), sm.PrometheusMetrics(config.Namespace)
+				mempl.PrometheusMetrics(config.Namespace), sm.PrometheusMetrics(config.Namespace),
+				privval.PrometheusMetrics(config.Namespace)
 		}
-		return cs.NopMetrics(), p2p.NopMetrics(), mempl.NopMetrics(), sm.NopMetrics()
+		return cs.NopMetrics(), p2p.NopMetrics(), mempl.NopMetrics(), sm.NopMetrics(), privval.NopMetrics()
 	}
 }
 
@@ -134,6 +145,8 @@ type Node struct {
 	transport   *p2p.MultiplexTransport
 	sw          *p2p.Switch  // p2p connections
 	addrBook    pex.AddrBook // known peers
+	trustStore  *trust.TrustMetricStore
+	capture     *p2p.Capture // nil if the messages aren't captured
 	nodeInfo    p2p.NodeInfo
 	nodeKey     *p2p.NodeKey // our node privkey
 	isListening bool
@@ -141,7 +154,7 @@ type Node struct {
 	// services
 	eventBus         *types.EventBus // pub/sub for services
 	stateDB          dbm.DB
-	blockStore       *bc.BlockStore         // store the blockchain to disk
+	blockStore       sm.BlockStore          // store the blockchain to disk
 	bcReactor        *bc.BlockchainReactor  // for fast-syncing
 	mempoolReactor   *mempl.MempoolReactor  // for gossipping transactions
 	consensusState   *cs.ConsensusState     // latest consensus state
@@ -151,9 +164,80 @@ type Node struct {
 	rpcListeners     []net.Listener         // rpc servers
 	txIndexer        txindex.TxIndexer
 	indexerService   *txindex.IndexerService
+	blockIndexer     blockindex.BlockIndexer
+	blockIndexerSvc  *blockindex.IndexerService
 	prometheusSrv    *http.Server
 }
 
+// CreateAddrBook returns the address book saved with the backend selected in
+// the config, using dbProvider to open its database.
+func CreateAddrBook(config *cfg.Config, dbProvider DBProvider) (pex.AddrBook, error) {
+	if config.P2P.AddrBookBackend == cfg.AddrBookBackendFile {
+		return pex.NewAddrBook(config.P2P.AddrBookFile(), config.P2P.AddrBookStrict), nil
+	}
+	addrBookDB, err := dbProvider(&DBContext{"addrbook", config})
+	if err != nil {
+		return nil, err
+	}
+	return pex.NewDBAddrBook(addrBookDB, config.P2P.AddrBookFile(), config.P2P.AddrBookStrict), nil
+}
+
+// CreateTxIndexer returns the tx indexer selected in the config, using
+// dbProvider to open its database.
+func CreateTxIndexer(config *cfg.Config, dbProvider DBProvider) (txindex.TxIndexer, error) {
+	switch config.TxIndex.Indexer {
+	case "kv":
+		store, err := dbProvider(&DBContext{"tx_index", config})
+		if err != nil {
+			return nil, err
+		}
+		if config.TxIndex.IndexTags != "" {
+			return kv.NewTxIndex(store, kv.IndexTags(splitAndTrimEmpty(config.TxIndex.IndexTags, ",", " "))), nil
+		} else if config.TxIndex.IndexAllTags {
+			return kv.NewTxIndex(store, kv.IndexAllTags()), nil
+		}
+		return kv.NewTxIndex(store), nil
+	case "sql":
+		conn := config.TxIndex.SQLConn
+		if conn == "" && config.TxIndex.SQLDriver == sqlidx.DriverSQLite {
+			conn = filepath.Join(config.DBDir(), "tx_index.sqlite")
+		}
+		db, err := sql.Open(config.TxIndex.SQLDriver, conn)
+		if err != nil {
+			return nil, err
+		}
+		if config.TxIndex.IndexTags != "" {
+			return sqlidx.NewTxIndex(db, config.TxIndex.SQLDriver, sqlidx.IndexTags(splitAndTrimEmpty(config.TxIndex.IndexTags, ",", " ")))
+		} else if config.TxIndex.IndexAllTags {
+			return sqlidx.NewTxIndex(db, config.TxIndex.SQLDriver, sqlidx.IndexAllTags())
+		}
+		return sqlidx.NewTxIndex(db, config.TxIndex.SQLDriver)
+	default:
+		return &null.TxIndex{}, nil
+	}
+}
+
+// CreateBlockIndexer returns the block indexer matching the tx indexer
+// selected in the config, using dbProvider to open its database. Blocks are
+// indexed by the same tags as txs.
+func CreateBlockIndexer(config *cfg.Config, dbProvider DBProvider) (blockindex.BlockIndexer, error) {
+	switch config.TxIndex.Indexer {
+	case "kv":
+		store, err := dbProvider(&DBContext{"block_index", config})
+		if err != nil {
+			return nil, err
+		}
+		if config.TxIndex.IndexTags != "" {
+			return blockkv.NewBlockIndex(store, blockkv.IndexTags(splitAndTrimEmpty(config.TxIndex.IndexTags, ",", " "))), nil
+		} else if config.TxIndex.IndexAllTags {
+			return blockkv.NewBlockIndex(store, blockkv.IndexAllTags()), nil
+		}
+		return blockkv.NewBlockIndex(store), nil
+	default:
+		return &blocknull.BlockIndex{}, nil
+	}
+}
+
 // NewNode returns a new, ready to go, Tendermint Node.
 func NewNode(config *cfg.Config,
 	privValidator types.PrivValidator,
@@ -170,7 +254,10 @@ func NewNode(config *cfg.Config,
 	if err != nil {
 		return nil, err
 	}
-	blockStore := bc.NewBlockStore(blockStoreDB)
+	blockStore, err := bc.CreateBlockStore(config.BaseConfig, blockStoreDB)
+	if err != nil {
+		return nil, err
+	}
 
 	// Get State
 	stateDB, err := dbProvider(&DBContext{"state", config})
@@ -216,25 +303,21 @@ func NewNode(config *cfg.Config,
 	}
 
 	// Transaction indexing
-	var txIndexer txindex.TxIndexer
-	switch config.TxIndex.Indexer {
-	case "kv":
-		store, err := dbProvider(&DBContext{"tx_index", config})
+	txIndexer, err := CreateTxIndexer(config, dbProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexerOptions []txindex.IndexerServiceOption
+	if _, ok := txIndexer.(*null.TxIndex); !ok {
+		// record the last indexed height, to catch up on the blocks missed
+		indexerStateDB, err := dbProvider(&DBContext{"tx_index_state", config})
 		if err != nil {
 			return nil, err
 		}
-		if config.TxIndex.IndexTags != "" {
-			txIndexer = kv.NewTxIndex(store, kv.IndexTags(splitAndTrimEmpty(config.TxIndex.IndexTags, ",", " ")))
-		} else if config.TxIndex.IndexAllTags {
-			txIndexer = kv.NewTxIndex(store, kv.IndexAllTags())
-		} else {
-			txIndexer = kv.NewTxIndex(store)
-		}
-	default:
-		txIndexer = &null.TxIndex{}
+		indexerOptions = append(indexerOptions, txindex.CatchUp(indexerStateDB, &txResultsSource{stateDB, blockStore}))
 	}
-
-	indexerService := txindex.NewIndexerService(txIndexer, eventBus)
+	indexerService := txindex.NewIndexerService(txIndexer, eventBus, indexerOptions...)
 	indexerService.SetLogger(logger.With("module", "txindex"))
 
 	err = indexerService.Start()
@@ -242,6 +325,20 @@ func NewNode(config *cfg.Config,
 		return nil, err
 	}
 
+	// Block indexing
+	blockIndexer, err := CreateBlockIndexer(config, dbProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	blockIndexerSvc := blockindex.NewIndexerService(blockIndexer, eventBus)
+	blockIndexerSvc.SetLogger(logger.With("module", "blockindex"))
+
+	err = blockIndexerSvc.Start()
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the handshaker, which calls RequestInfo, sets the AppVersion on the state,
 	// and replays any blocks as necessary to sync tendermint with the app.
 	consensusLogger := logger.With("module", "consensus")
@@ -272,11 +369,19 @@ func NewNode(config *cfg.Config,
 		)
 	}
 
+	csMetrics, p2pMetrics, memplMetrics, smMetrics, privvalMetrics := metricsProvider()
+
 	if config.PrivValidatorListenAddr != "" {
 		// If an address is provided, listen on the socket for a connection from an
 		// external signing process.
 		// FIXME: we should start services inside OnStart
-		privValidator, err = createAndStartPrivValidatorSocketClient(config.PrivValidatorListenAddr, logger)
+		privValidator, err = createAndStartPrivValidatorSocketClient(
+			config.PrivValidatorListenAddr,
+			nodeKey,
+			splitAndTrimEmpty(config.PrivValidatorAllowedSigners, ",", " "),
+			privvalMetrics,
+			logger,
+		)
 		if err != nil {
 			return nil, errors.Wrap(err, "Error with private validator socket client")
 		}
@@ -299,8 +404,6 @@ func NewNode(config *cfg.Config,
 		consensusLogger.Info("This node is not a validator", "addr", privValidator.GetAddress(), "pubKey", privValidator.GetPubKey())
 	}
 
-	csMetrics, p2pMetrics, memplMetrics, smMetrics := metricsProvider()
-
 	// Make MempoolReactor
 	mempool := mempl.NewMempool(
 		config.Mempool,
@@ -395,11 +498,21 @@ func NewNode(config *cfg.Config,
 	// Setup Transport.
 	var (
 		mConnConfig = p2p.MConnConfig(config.P2P)
-		transport   = p2p.NewMultiplexTransport(nodeInfo, *nodeKey, mConnConfig)
+		transport   *p2p.MultiplexTransport
+		swTransport p2p.Transport
 		connFilters = []p2p.ConnFilterFunc{}
 		peerFilters = []p2p.PeerFilterFunc{}
 	)
 
+	if config.P2P.Transport == "stream" {
+		// Give each channel its own stream with the peers supporting it.
+		streamTransport := p2p.NewStreamTransport(nodeInfo, *nodeKey, mConnConfig)
+		transport, swTransport = streamTransport.MultiplexTransport, streamTransport
+	} else {
+		transport = p2p.NewMultiplexTransport(nodeInfo, *nodeKey, mConnConfig)
+		swTransport = transport
+	}
+
 	if !config.P2P.AllowDuplicateIP {
 		connFilters = append(connFilters, p2p.ConnDuplicateIPFilter())
 	}
@@ -445,13 +558,92 @@ func NewNode(config *cfg.Config,
 	}
 
 	p2p.MultiplexTransportConnFilters(connFilters...)(transport)
+	p2p.MultiplexTransportHandshake(config.P2P.SecretHandshake)(transport)
+
+	// Reject banned peers in the transport, and ban misbehaving ones from the
+	// switch.
+	banList, err := p2p.NewBanList(config.P2P.BanListFile())
+	if err != nil {
+		return nil, err
+	}
+	p2p.MultiplexTransportBanList(banList)(transport)
+
+	// Only accept the connections of the allowed peers, if listed.
+	var allowList *p2p.AllowList
+	if config.P2P.AllowListFile() != "" {
+		allowList, err = p2p.NewAllowList(config.P2P.AllowListFile())
+		if err != nil {
+			return nil, err
+		}
+		p2p.MultiplexTransportAllowList(allowList)(transport)
+	}
+
+	// In sentry mode, never give up reconnecting to the validators.
+	var validatorIDs []p2p.ID
+	if config.P2P.SentryMode {
+		validatorIDs, err = peerIDs(splitAndTrimEmpty(config.P2P.ValidatorPeers, ",", " "))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid validator_peers")
+		}
+	}
+
+	// Never ban the persistent and private peers for misbehavior.
+	var protectedIDs []p2p.ID
+	for _, addr := range splitAndTrimEmpty(config.P2P.PersistentPeers, ",", " ") {
+		if ids, err := peerIDs([]string{addr}); err == nil {
+			protectedIDs = append(protectedIDs, ids...)
+		}
+	}
+	for _, id := range splitAndTrimEmpty(config.P2P.PrivatePeerIDs, ",", " ") {
+		protectedIDs = append(protectedIDs, p2p.ID(id))
+	}
+
+	// Track the behavior of peers, to prefer the trusted ones.
+	trustHistoryDB, err := dbProvider(&DBContext{"trusthistory", config})
+	if err != nil {
+		return nil, err
+	}
+	trustMetricStore := trust.NewTrustMetricStore(trustHistoryDB, trust.DefaultConfig())
+	trustMetricStore.SetLogger(p2pLogger)
+
+	// Optionally, record the messages of the peers.
+	var capture *p2p.Capture
+	if config.P2P.CaptureFile() != "" {
+		capture, err = p2p.NewCapture(config.P2P.CaptureFile())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open the p2p capture")
+		}
+		capture.SetLogger(p2pLogger.With("module", "capture"))
+	}
+
+	// Optionally, allow faults to be injected into the connections to test
+	// the network.
+	var faultInjector *p2p.FaultInjector
+	if config.P2P.TestFaultInjection {
+		faultInjector = p2p.NewFaultInjector(config.P2P.TestFaultInjectionSeed)
+		p2pLogger.Info("Fault injection is enabled. Never enable it in production",
+			"seed", faultInjector.Seed())
+	}
+
+	channelRates, err := config.P2P.ParseChannelRates()
+	if err != nil {
+		return nil, err
+	}
 
 	// Setup Switch.
 	sw := p2p.NewSwitch(
 		config.P2P,
-		transport,
+		swTransport,
 		p2p.WithMetrics(p2pMetrics),
 		p2p.SwitchPeerFilters(peerFilters...),
+		p2p.SwitchBanList(banList),
+		p2p.SwitchAllowList(allowList),
+		p2p.SwitchSentryValidators(validatorIDs),
+		p2p.SwitchProtectedPeers(protectedIDs),
+		p2p.SwitchTrustMetricStore(trustMetricStore),
+		p2p.SwitchCapture(capture),
+		p2p.SwitchFaultInjector(faultInjector),
+		p2p.SwitchChannelRates(channelRates),
 	)
 	sw.SetLogger(p2pLogger)
 
@@ -481,10 +673,15 @@ func NewNode(config *cfg.Config,
 	//
 	// If PEX is on, it should handle dialing the seeds. Otherwise the switch does it.
 	// Note we currently use the addrBook regardless at least for AddOurAddress
-	addrBook := pex.NewAddrBook(config.P2P.AddrBookFile(), config.P2P.AddrBookStrict)
+	addrBook, err := CreateAddrBook(config, dbProvider)
+	if err != nil {
+		return nil, err
+	}
 
 	// Add ourselves to addrbook to prevent dialing ourselves
-	addrBook.AddOurAddress(nodeInfo.NetAddress())
+	for _, addr := range nodeInfo.NetAddresses() {
+		addrBook.AddOurAddress(addr)
+	}
 
 	addrBook.SetLogger(p2pLogger.With("book", config.P2P.AddrBookFile()))
 	if config.P2P.PexReactor {
@@ -492,6 +689,7 @@ func NewNode(config *cfg.Config,
 		pexReactor := pex.NewPEXReactor(addrBook,
 			&pex.PEXReactorConfig{
 				Seeds:    splitAndTrimEmpty(config.P2P.Seeds, ",", " "),
+				DNSSeeds: splitAndTrimEmpty(config.P2P.DNSSeeds, ",", " "),
 				SeedMode: config.P2P.SeedMode,
 			})
 		pexReactor.SetLogger(logger.With("module", "pex"))
@@ -513,11 +711,13 @@ func NewNode(config *cfg.Config,
 		genesisDoc:    genDoc,
 		privValidator: privValidator,
 
-		transport: transport,
-		sw:        sw,
-		addrBook:  addrBook,
-		nodeInfo:  nodeInfo,
-		nodeKey:   nodeKey,
+		transport:  transport,
+		sw:         sw,
+		addrBook:   addrBook,
+		trustStore: trustMetricStore,
+		capture:    capture,
+		nodeInfo:   nodeInfo,
+		nodeKey:    nodeKey,
 
 		stateDB:          stateDB,
 		blockStore:       blockStore,
@@ -529,6 +729,8 @@ func NewNode(config *cfg.Config,
 		proxyApp:         proxyApp,
 		txIndexer:        txIndexer,
 		indexerService:   indexerService,
+		blockIndexer:     blockIndexer,
+		blockIndexerSvc:  blockIndexerSvc,
 		eventBus:         eventBus,
 	}
 	node.BaseService = *cmn.NewBaseService(logger, "Node", node)
@@ -547,6 +749,18 @@ func (n *Node) OnStart() error {
 	// Add private IDs to addrbook to block those peers being added
 	n.addrBook.AddPrivateIDs(splitAndTrimEmpty(n.config.P2P.PrivatePeerIDs, ",", " "))
 
+	// Keep the validators guarded in sentry mode private too
+	validatorPeers := []string{}
+	if n.config.P2P.SentryMode {
+		validatorPeers = splitAndTrimEmpty(n.config.P2P.ValidatorPeers, ",", " ")
+		validatorIDs, _ := peerIDs(validatorPeers) // validated in NewNode
+		privateIDs := make([]string, len(validatorIDs))
+		for i, id := range validatorIDs {
+			privateIDs[i] = string(id)
+		}
+		n.addrBook.AddPrivateIDs(privateIDs)
+	}
+
 	// Start the RPC server before the P2P server
 	// so we can eg. receive txs for the first block
 	if n.config.RPC.ListenAddress != "" {
@@ -562,16 +776,35 @@ func (n *Node) OnStart() error {
 		n.prometheusSrv = n.startPrometheusServer(n.config.Instrumentation.PrometheusListenAddr)
 	}
 
-	// Start the transport.
-	addr, err := p2p.NewNetAddressStringWithOptionalID(n.config.P2P.ListenAddress)
-	if err != nil {
-		return err
+	// Start the transport, listening on each of the addresses (e.g. an IPv4
+	// and an IPv6 one).
+	// On error, closing the transport closes the listeners already opened.
+	for _, lAddr := range splitAndTrimEmpty(n.config.P2P.ListenAddress, ",", " ") {
+		addr, err := p2p.NewNetAddressStringWithOptionalID(lAddr)
+		if err != nil {
+			n.transport.Close() // nolint: errcheck
+			return err
+		}
+		if err := n.transport.Listen(*addr); err != nil {
+			n.transport.Close() // nolint: errcheck
+			return err
+		}
 	}
-	if err := n.transport.Listen(*addr); err != nil {
+
+	n.isListening = true
+
+	// Load the trust metrics of the peers before connecting to them.
+	err := n.trustStore.Start()
+	if err != nil {
 		return err
 	}
 
-	n.isListening = true
+	// Capture the messages from the first peer.
+	if n.capture != nil {
+		if err := n.capture.Start(); err != nil {
+			return err
+		}
+	}
 
 	// Start the switch (the P2P server).
 	err = n.sw.Start()
@@ -587,6 +820,14 @@ func (n *Node) OnStart() error {
 		}
 	}
 
+	// Always connect to the validators guarded in sentry mode
+	if len(validatorPeers) > 0 {
+		err = n.sw.DialPeersAsync(n.addrBook, validatorPeers, true)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -599,16 +840,31 @@ func (n *Node) OnStop() {
 	// first stop the non-reactor services
 	n.eventBus.Stop()
 	n.indexerService.Stop()
+	n.blockIndexerSvc.Stop()
 
 	// now stop the reactors
 	// TODO: gracefully disconnect from peers.
 	n.sw.Stop()
 
+	// save the trust metrics of the peers
+	n.trustStore.Stop()
+
+	if n.capture != nil {
+		n.capture.Stop()
+	}
+
 	// stop mempool WAL
 	if n.config.Mempool.WalEnabled() {
 		n.mempoolReactor.Mempool.CloseWAL()
 	}
 
+	// the reactors writing blocks are stopped, so the block store can be closed
+	if closer, ok := n.blockStore.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			n.Logger.Error("Error closing block store", "err", err)
+		}
+	}
+
 	if err := n.transport.Close(); err != nil {
 		n.Logger.Error("Error closing transport", "err", err)
 	}
@@ -635,6 +891,13 @@ func (n *Node) OnStop() {
 	}
 }
 
+// ReloadAllowList reads the allow list file again (see p2p.allow_list_file),
+// and disconnects from the inbound peers which are no longer allowed. It
+// returns an error if the node has no allow list.
+func (n *Node) ReloadAllowList() error {
+	return n.sw.ReloadAllowList()
+}
+
 // ConfigureRPC sets all variables in rpccore so they will serve
 // rpc calls from this node
 func (n *Node) ConfigureRPC() {
@@ -650,6 +913,7 @@ func (n *Node) ConfigureRPC() {
 	rpccore.SetAddrBook(n.addrBook)
 	rpccore.SetProxyAppQuery(n.proxyApp.Query())
 	rpccore.SetTxIndexer(n.txIndexer)
+	rpccore.SetBlockIndexer(n.blockIndexer)
 	rpccore.SetConsensusReactor(n.consensusReactor)
 	rpccore.SetEventBus(n.eventBus)
 	rpccore.SetLogger(n.Logger.With("module", "rpc"))
@@ -743,7 +1007,7 @@ func (n *Node) Switch() *p2p.Switch {
 }
 
 // BlockStore returns the Node's BlockStore.
-func (n *Node) BlockStore() *bc.BlockStore {
+func (n *Node) BlockStore() sm.BlockStore {
 	return n.blockStore
 }
 
@@ -791,9 +1055,15 @@ func (n *Node) ProxyApp() proxy.AppConns {
 //------------------------------------------------------------------------------
 
 func (n *Node) Listeners() []string {
-	return []string{
-		fmt.Sprintf("Listener(@%v)", n.config.P2P.ExternalAddress),
+	addrs := splitAndTrimEmpty(n.config.P2P.ExternalAddress, ",", " ")
+	if len(addrs) == 0 {
+		addrs = splitAndTrimEmpty(n.config.P2P.ListenAddress, ",", " ")
+	}
+	listeners := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		listeners = append(listeners, fmt.Sprintf("Listener(@%v)", addr))
 	}
+	return listeners
 }
 
 func (n *Node) IsListening() bool {
@@ -835,6 +1105,10 @@ func makeNodeInfo(
 		},
 	}
 
+	if config.P2P.Transport == "stream" {
+		nodeInfo.Other.StreamMux = "on"
+	}
+
 	if config.P2P.PexReactor {
 		nodeInfo.Channels = append(nodeInfo.Channels, pex.PexChannel)
 	}
@@ -843,13 +1117,18 @@ func makeNodeInfo(
 		nodeInfo.Channels = append(nodeInfo.Channels, additionalChannel.ID)
 	}
 
-	lAddr := config.P2P.ExternalAddress
+	// Advertise the first external (or listen) address as the ListenAddr, and
+	// the others, e.g. the IPv6 ones of a dual-stack node, as ListenAddrs.
+	lAddrs := splitAndTrimEmpty(config.P2P.ExternalAddress, ",", " ")
 
-	if lAddr == "" {
-		lAddr = config.P2P.ListenAddress
+	if len(lAddrs) == 0 {
+		lAddrs = splitAndTrimEmpty(config.P2P.ListenAddress, ",", " ")
 	}
 
-	nodeInfo.ListenAddr = lAddr
+	if len(lAddrs) > 0 {
+		nodeInfo.ListenAddr = lAddrs[0]
+		nodeInfo.ListenAddrs = lAddrs[1:]
+	}
 
 	err := nodeInfo.Validate()
 	return nodeInfo, err
@@ -884,25 +1163,90 @@ func saveGenesisDoc(db dbm.DB, genDoc *types.GenesisDoc) {
 	db.SetSync(genesisDocKey, bytes)
 }
 
+// txResultsSource provides the txs and results of the committed blocks to
+// the tx IndexerService, from the blockstore and state databases.
+type txResultsSource struct {
+	stateDB    dbm.DB
+	blockStore sm.BlockStore
+}
+
+var _ txindex.TxResultsSource = (*txResultsSource)(nil)
+
+func (s *txResultsSource) LastHeight() int64 {
+	return sm.LoadState(s.stateDB).LastBlockHeight
+}
+
+func (s *txResultsSource) TxResults(height int64) ([]*types.TxResult, error) {
+	return sm.LoadTxResults(s.stateDB, s.blockStore, height)
+}
+
+// createAndStartPrivValidatorSocketClient listens on listenAddr for the
+// connection of an external signing process. Over TCP, the node authenticates
+// itself with its node key, and only accepts the signers with the given IDs
+// (any signer if there are none).
 func createAndStartPrivValidatorSocketClient(
 	listenAddr string,
+	nodeKey *p2p.NodeKey,
+	allowedSigners []string,
+	metrics *privval.Metrics,
 	logger log.Logger,
 ) (types.PrivValidator, error) {
-	var pvsc types.PrivValidator
+	var (
+		addrs       = splitAndTrimEmpty(listenAddr, ",", " ")
+		privKey     ed25519.PrivKeyEd25519
+		signerAddrs []crypto.Address
+		hasTCP      bool
+	)
+	for _, addr := range addrs {
+		protocol, _ := cmn.ProtocolAndAddress(addr)
+		switch protocol {
+		case "unix":
+		case "tcp":
+			hasTCP = true
+		default:
+			return nil, fmt.Errorf(
+				"Wrong listen address: expected either 'tcp' or 'unix' protocols, got %s",
+				protocol,
+			)
+		}
+	}
 
-	protocol, address := cmn.ProtocolAndAddress(listenAddr)
-	switch protocol {
-	case "unix":
-		pvsc = privval.NewIPCVal(logger.With("module", "privval"), address)
-	case "tcp":
-		// TODO: persist this key so external signer
-		// can actually authenticate us
-		pvsc = privval.NewTCPVal(logger.With("module", "privval"), listenAddr, ed25519.GenPrivKey())
-	default:
-		return nil, fmt.Errorf(
-			"Wrong listen address: expected either 'tcp' or 'unix' protocols, got %s",
-			protocol,
-		)
+	if hasTCP {
+		var ok bool
+		privKey, ok = nodeKey.PrivKey.(ed25519.PrivKeyEd25519)
+		if !ok {
+			return nil, fmt.Errorf("node key must be an ed25519 key to authenticate to the remote signer, got %T", nodeKey.PrivKey)
+		}
+		signerAddrs = make([]crypto.Address, len(allowedSigners))
+		for i, id := range allowedSigners {
+			addr, err := hex.DecodeString(id)
+			if err != nil || len(addr) != crypto.AddressSize {
+				return nil, fmt.Errorf("invalid priv_validator_allowed_signers ID %q", id)
+			}
+			signerAddrs[i] = addr
+		}
+		if len(signerAddrs) == 0 {
+			logger.Error("Accepting any remote signer, which could sign with a key of its own! Set priv_validator_allowed_signers to authenticate it")
+		}
+	}
+
+	pvLogger := logger.With("module", "privval")
+	newEndpoint := func(addr string) privval.SignerEndpoint {
+		protocol, address := cmn.ProtocolAndAddress(addr)
+		if protocol == "unix" {
+			return privval.NewIPCVal(pvLogger, address)
+		}
+		tcpVal := privval.NewTCPVal(pvLogger, addr, privKey)
+		privval.TCPValAllowedSigners(signerAddrs...)(tcpVal)
+		return tcpVal
+	}
+
+	var pvsc types.PrivValidator
+	if len(addrs) == 1 {
+		pvsc = newEndpoint(addrs[0])
+	} else {
+		// Fail over between the signers of the validator.
+		pvsc = privval.NewFailoverVal(pvLogger, addrs, newEndpoint, privval.FailoverValMetrics(metrics))
 	}
 
 	if pvsc, ok := pvsc.(cmn.Service); ok {
@@ -914,6 +1258,23 @@ func createAndStartPrivValidatorSocketClient(
 	return pvsc, nil
 }
 
+// peerIDs returns the IDs of the peers with the given addresses
+// (ID@host:port).
+func peerIDs(addrs []string) ([]p2p.ID, error) {
+	ids := make([]p2p.ID, 0, len(addrs))
+	for _, addr := range addrs {
+		if i := strings.Index(addr, "://"); i >= 0 {
+			addr = addr[i+len("://"):]
+		}
+		spl := strings.Split(addr, "@")
+		if len(spl) != 2 {
+			return nil, p2p.ErrNetAddressNoID{Addr: addr}
+		}
+		ids = append(ids, p2p.ID(spl[0]))
+	}
+	return ids, nil
+}
+
 // splitAndTrimEmpty slices s into all subslices separated by sep and returns a
 // slice of the string s with all leading and trailing Unicode code points
 // contained in cutset removed. If sep is empty, SplitAndTrim splits after each
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
@@ -116,6 +117,43 @@ func TestNodeSetAppVersion(t *testing.T) {
 	assert.Equal(t, n.nodeInfo.(p2p.DefaultNodeInfo).ProtocolVersion.App, appVersion)
 }
 
+func TestNodeListenAddresses(t *testing.T) {
+	config := cfg.ResetTestRoot("node_listen_addresses_test")
+	config.P2P.ListenAddress = "tcp://127.0.0.1:0, tcp://127.0.0.1:0"
+	config.P2P.ExternalAddress = "tcp://1.2.3.4:26656, tcp://[2600:1::1]:26656"
+	config.RPC.ListenAddress = "" // the default RPC port would clash with the other node tests
+
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+
+	// the first external address is the ListenAddr, the others ListenAddrs
+	nodeInfo := n.nodeInfo.(p2p.DefaultNodeInfo)
+	assert.Equal(t, "tcp://1.2.3.4:26656", nodeInfo.ListenAddr)
+	assert.Equal(t, []string{"tcp://[2600:1::1]:26656"}, nodeInfo.ListenAddrs)
+	assert.Len(t, n.Listeners(), 2)
+
+	// listening on each of the listen addresses
+	require.NoError(t, n.Start())
+	defer n.Stop()
+	assert.True(t, n.IsListening())
+}
+
+func TestNodeListenAddressesError(t *testing.T) {
+	addr := testFreeAddr(t)
+	config := cfg.ResetTestRoot("node_listen_addresses_error_test")
+	config.P2P.ListenAddress = "tcp://" + addr + ", tcp://" + addr
+	config.RPC.ListenAddress = "" // the default RPC port would clash with the other node tests
+
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+
+	// the second listener fails, and the first one is closed
+	assert.Error(t, n.Start())
+	ln, err := net.Listen("tcp", addr)
+	require.NoError(t, err)
+	ln.Close()
+}
+
 func TestNodeSetPrivValTCP(t *testing.T) {
 	addr := "tcp://" + testFreeAddr(t)
 
@@ -143,6 +181,46 @@ func TestNodeSetPrivValTCP(t *testing.T) {
 	assert.IsType(t, &privval.TCPVal{}, n.PrivValidator())
 }
 
+func TestNodeSetPrivValTCPFailover(t *testing.T) {
+	addrs := []string{"tcp://" + testFreeAddr(t), "tcp://" + testFreeAddr(t)}
+
+	config := cfg.ResetTestRoot("node_priv_val_tcp_test")
+	config.BaseConfig.PrivValidatorListenAddr = strings.Join(addrs, ",")
+
+	privVal := types.NewMockPV()
+	for _, addr := range addrs {
+		rs := privval.NewRemoteSigner(
+			log.TestingLogger(),
+			config.ChainID(),
+			addr,
+			privVal,
+			ed25519.GenPrivKey(),
+		)
+		privval.RemoteSignerConnDeadline(100 * time.Millisecond)(rs)
+		go func() {
+			err := rs.Start()
+			if err != nil {
+				panic(err)
+			}
+		}()
+		defer rs.Stop()
+	}
+
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+	assert.IsType(t, &privval.FailoverVal{}, n.PrivValidator())
+	assert.Equal(t, privVal.GetPubKey(), n.PrivValidator().GetPubKey())
+}
+
+func TestNodeSetPrivValTCPAllowedSigners(t *testing.T) {
+	config := cfg.ResetTestRoot("node_priv_val_tcp_test")
+	config.BaseConfig.PrivValidatorListenAddr = "tcp://" + testFreeAddr(t)
+	config.BaseConfig.PrivValidatorAllowedSigners = "not-an-id"
+
+	_, err := DefaultNewNode(config, log.TestingLogger())
+	assert.Error(t, err)
+}
+
 // address without a protocol must result in error
 func TestPrivValidatorListenAddrNoProtocol(t *testing.T) {
 	addrNoPrefix := testFreeAddr(t)
@@ -0,0 +1,87 @@
+package p2p
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AllowList is the list of the node IDs allowed to connect to the node, read
+// from a file with one ID per line. Blank lines and lines starting with '#'
+// are ignored. It is safe for concurrent use, and a nil *AllowList allows
+// every node.
+type AllowList struct {
+	mtx      sync.RWMutex
+	filePath string
+	ids      map[ID]struct{}
+}
+
+// NewAllowList returns the allow list read from the given file.
+func NewAllowList(filePath string) (*AllowList, error) {
+	al := &AllowList{filePath: filePath}
+	if err := al.Reload(); err != nil {
+		return nil, err
+	}
+	return al, nil
+}
+
+// Reload reads the file again. On error, the list is left unchanged.
+func (al *AllowList) Reload() error {
+	f, err := os.Open(al.filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+
+	ids := make(map[ID]struct{})
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		idBytes, err := hex.DecodeString(line)
+		if err != nil || len(idBytes) != IDByteLength {
+			return fmt.Errorf("error reading allow list from %s: invalid node ID %q on line %d", al.filePath, line, n)
+		}
+		ids[ID(strings.ToLower(line))] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading allow list from %s: %v", al.filePath, err)
+	}
+
+	al.mtx.Lock()
+	al.ids = ids
+	al.mtx.Unlock()
+	return nil
+}
+
+// IsAllowed returns true if the node with the given ID may connect.
+func (al *AllowList) IsAllowed(id ID) bool {
+	if al == nil {
+		return true
+	}
+	al.mtx.RLock()
+	defer al.mtx.RUnlock()
+	_, ok := al.ids[ID(strings.ToLower(string(id)))]
+	return ok
+}
+
+// List returns the allowed node IDs, sorted.
+func (al *AllowList) List() []ID {
+	if al == nil {
+		return nil
+	}
+	al.mtx.RLock()
+	defer al.mtx.RUnlock()
+	ids := make([]ID, 0, len(al.ids))
+	for id := range al.ids {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -0,0 +1,56 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAllowList(t *testing.T, content string) (*AllowList, func()) {
+	dir, err := ioutil.TempDir("", "allow_list_test")
+	require.NoError(t, err)
+	filePath := filepath.Join(dir, "allowlist")
+	require.NoError(t, ioutil.WriteFile(filePath, []byte(content), 0644))
+	al, err := NewAllowList(filePath)
+	require.NoError(t, err)
+	return al, func() { os.RemoveAll(dir) }
+}
+
+func TestAllowList(t *testing.T) {
+	al, cleanup := newTestAllowList(t, `
+# sentries
+75E6CE0E63BDA00AB5AB9A4B0D5CC0EB3BAFAD5B
+  3a8b7e6bd8f6d0c0b8a6cfa8c6e0d7a5d91a8d13
+`)
+	defer cleanup()
+
+	assert.True(t, al.IsAllowed("75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b"))
+	assert.True(t, al.IsAllowed("3A8B7E6BD8F6D0C0B8A6CFA8C6E0D7A5D91A8D13"))
+	assert.False(t, al.IsAllowed("0000000000000000000000000000000000000000"))
+	assert.Equal(t, []ID{
+		"3a8b7e6bd8f6d0c0b8a6cfa8c6e0d7a5d91a8d13",
+		"75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b",
+	}, al.List())
+
+	// reload the file
+	require.NoError(t, ioutil.WriteFile(al.filePath, []byte("0000000000000000000000000000000000000000\n"), 0644))
+	require.NoError(t, al.Reload())
+	assert.True(t, al.IsAllowed("0000000000000000000000000000000000000000"))
+	assert.False(t, al.IsAllowed("75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b"))
+
+	// an invalid file leaves the list unchanged
+	require.NoError(t, ioutil.WriteFile(al.filePath, []byte("foo\n"), 0644))
+	assert.Error(t, al.Reload())
+	assert.True(t, al.IsAllowed("0000000000000000000000000000000000000000"))
+
+	_, err := NewAllowList(filepath.Join(filepath.Dir(al.filePath), "missing"))
+	assert.Error(t, err)
+
+	// a nil list allows every node
+	var nilList *AllowList
+	assert.True(t, nilList.IsAllowed("75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b"))
+}
@@ -0,0 +1,214 @@
+package p2p
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// Ban keeps a node, or all the nodes of an IP range, from connecting until it
+// expires.
+type Ban struct {
+	// Target is a node ID, an IP or a CIDR range (e.g. "10.0.0.0/8").
+	Target  string    `json:"target"`
+	Reason  string    `json:"reason"`
+	Created time.Time `json:"created"`
+	// Expires is the time at which the ban is lifted, or the zero time if the
+	// ban is permanent.
+	Expires time.Time `json:"expires"`
+
+	id    ID
+	ipNet *net.IPNet
+}
+
+// newBan parses the target of the ban, normalizing it.
+func newBan(target, reason string, created, expires time.Time) (Ban, error) {
+	b := Ban{Reason: reason, Created: created, Expires: expires}
+	target = strings.TrimSpace(target)
+
+	if _, ipNet, err := net.ParseCIDR(target); err == nil {
+		b.ipNet = ipNet
+		b.Target = ipNet.String()
+		return b, nil
+	}
+	if ip := net.ParseIP(target); ip != nil {
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 8*net.IPv4len
+		}
+		b.ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		b.Target = ip.String()
+		return b, nil
+	}
+
+	idBytes, err := hex.DecodeString(target)
+	if err != nil || len(idBytes) != IDByteLength {
+		return b, fmt.Errorf("invalid ban target %q: expected a node ID, an IP or a CIDR range", target)
+	}
+	b.id = ID(strings.ToLower(target))
+	b.Target = string(b.id)
+	return b, nil
+}
+
+// Matches returns true if the ban applies to the node with the given ID or
+// IP. Either may be empty.
+func (b Ban) Matches(id ID, ip net.IP) bool {
+	if b.ipNet != nil {
+		return ip != nil && b.ipNet.Contains(ip)
+	}
+	return id != "" && b.id == id
+}
+
+// IsExpired returns true if the ban was lifted at the given time.
+func (b Ban) IsExpired(now time.Time) bool {
+	return !b.Expires.IsZero() && !now.Before(b.Expires)
+}
+
+// BanList is a list of banned node IDs and IP ranges, saved to a file on each
+// change. It is safe for concurrent use, and a nil *BanList bans nothing.
+type BanList struct {
+	mtx      sync.Mutex
+	filePath string
+	bans     map[string]Ban // by target
+}
+
+type banListJSON struct {
+	Bans []Ban `json:"bans"`
+}
+
+// NewBanList returns the ban list saved in the given file, which doesn't have
+// to exist yet.
+func NewBanList(filePath string) (*BanList, error) {
+	bl := &BanList{filePath: filePath, bans: make(map[string]Ban)}
+
+	bz, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return bl, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var blJSON banListJSON
+	if err := json.Unmarshal(bz, &blJSON); err != nil {
+		return nil, fmt.Errorf("error reading ban list from %s: %v", filePath, err)
+	}
+	for _, b := range blJSON.Bans {
+		ban, err := newBan(b.Target, b.Reason, b.Created, b.Expires)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ban list from %s: %v", filePath, err)
+		}
+		bl.bans[ban.Target] = ban
+	}
+	return bl, nil
+}
+
+// Add bans the target (a node ID, an IP or a CIDR range) for the given
+// duration, or permanently if it is zero. It replaces any previous ban of the
+// same target.
+func (bl *BanList) Add(target string, duration time.Duration, reason string) (Ban, error) {
+	if duration < 0 {
+		return Ban{}, fmt.Errorf("ban duration can't be negative, got %v", duration)
+	}
+	now := time.Now().UTC()
+	var expires time.Time
+	if duration > 0 {
+		expires = now.Add(duration)
+	}
+	ban, err := newBan(target, reason, now, expires)
+	if err != nil {
+		return ban, err
+	}
+
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	bl.bans[ban.Target] = ban
+	return ban, bl.save()
+}
+
+// Remove lifts the ban of the target.
+func (bl *BanList) Remove(target string) error {
+	ban, err := newBan(target, "", time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	if _, ok := bl.bans[ban.Target]; !ok {
+		return fmt.Errorf("%s is not banned", ban.Target)
+	}
+	delete(bl.bans, ban.Target)
+	return bl.save()
+}
+
+// List returns the bans which haven't expired, sorted by target.
+func (bl *BanList) List() []Ban {
+	if bl == nil {
+		return nil
+	}
+	now := time.Now()
+
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	bans := make([]Ban, 0, len(bl.bans))
+	for _, ban := range bl.bans {
+		if !ban.IsExpired(now) {
+			bans = append(bans, ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Target < bans[j].Target })
+	return bans
+}
+
+// IsBanned returns true if the node with the given ID or IP is banned. Either
+// may be empty.
+func (bl *BanList) IsBanned(id ID, ip net.IP) bool {
+	if bl == nil {
+		return false
+	}
+	now := time.Now()
+
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	for _, ban := range bl.bans {
+		if !ban.IsExpired(now) && ban.Matches(id, ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAddrBanned returns true if the ID or the IP of the address is banned.
+func (bl *BanList) IsAddrBanned(addr *NetAddress) bool {
+	return bl.IsBanned(addr.ID, addr.IP)
+}
+
+// save writes the bans which haven't expired to the file, dropping the
+// others. bl.mtx must be held.
+func (bl *BanList) save() error {
+	now := time.Now()
+	blJSON := banListJSON{Bans: make([]Ban, 0, len(bl.bans))}
+	for target, ban := range bl.bans {
+		if ban.IsExpired(now) {
+			delete(bl.bans, target)
+			continue
+		}
+		blJSON.Bans = append(blJSON.Bans, ban)
+	}
+	sort.Slice(blJSON.Bans, func(i, j int) bool { return blJSON.Bans[i].Target < blJSON.Bans[j].Target })
+
+	bz, err := json.MarshalIndent(blJSON, "", "\t")
+	if err != nil {
+		return err
+	}
+	return cmn.WriteFileAtomic(bl.filePath, bz, 0644)
+}
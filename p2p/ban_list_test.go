@@ -0,0 +1,118 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBanList(t *testing.T) (*BanList, func()) {
+	dir, err := ioutil.TempDir("", "ban_list_test")
+	require.NoError(t, err)
+	bl, err := NewBanList(filepath.Join(dir, "banlist.json"))
+	require.NoError(t, err)
+	return bl, func() { os.RemoveAll(dir) }
+}
+
+func TestBanListTargets(t *testing.T) {
+	bl, cleanup := newTestBanList(t)
+	defer cleanup()
+
+	id := ID("75E6CE0E63BDA00AB5AB9A4B0D5CC0EB3BAFAD5B")
+	testCases := []struct {
+		target     string
+		normalized string
+	}{
+		{string(id), "75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b"},
+		{"1.2.3.4", "1.2.3.4"},
+		{"10.1.2.3/8", "10.0.0.0/8"},
+		{"::ffff:5.6.7.8", "5.6.7.8"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+	}
+	for _, tc := range testCases {
+		ban, err := bl.Add(tc.target, 0, "test")
+		require.NoError(t, err, tc.target)
+		assert.Equal(t, tc.normalized, ban.Target)
+	}
+
+	for _, target := range []string{"", "foo", "75e6ce0e63bda00ab5ab9a4b", "1.2.3.4/33"} {
+		_, err := bl.Add(target, 0, "test")
+		assert.Error(t, err, target)
+	}
+
+	assert.True(t, bl.IsBanned("75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b", nil))
+	assert.False(t, bl.IsBanned("0000000000000000000000000000000000000000", nil))
+	assert.True(t, bl.IsBanned("", net.ParseIP("1.2.3.4")))
+	assert.True(t, bl.IsBanned("", net.ParseIP("10.200.0.1")))
+	assert.True(t, bl.IsBanned("", net.ParseIP("5.6.7.8")))
+	assert.True(t, bl.IsBanned("", net.ParseIP("2001:db8:1::2")))
+	assert.False(t, bl.IsBanned("", net.ParseIP("1.2.3.5")))
+	assert.False(t, bl.IsBanned("", net.ParseIP("2001:db9::1")))
+
+	addr, err := NewNetAddressString("0000000000000000000000000000000000000000@10.0.0.1:26656")
+	require.NoError(t, err)
+	assert.True(t, bl.IsAddrBanned(addr))
+
+	// a nil list bans nothing
+	var nilList *BanList
+	assert.False(t, nilList.IsAddrBanned(addr))
+	assert.Empty(t, nilList.List())
+}
+
+func TestBanListExpiryAndRemove(t *testing.T) {
+	bl, cleanup := newTestBanList(t)
+	defer cleanup()
+
+	_, err := bl.Add("1.2.3.4", time.Millisecond, "short")
+	require.NoError(t, err)
+	ban, err := bl.Add("5.6.7.8", time.Hour, "long")
+	require.NoError(t, err)
+	assert.Equal(t, "long", ban.Reason)
+	assert.False(t, ban.Expires.IsZero())
+
+	_, err = bl.Add("1.2.3.4", -time.Second, "negative")
+	assert.Error(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, bl.IsBanned("", net.ParseIP("1.2.3.4")))
+	assert.True(t, bl.IsBanned("", net.ParseIP("5.6.7.8")))
+	bans := bl.List()
+	require.Len(t, bans, 1)
+	assert.Equal(t, "5.6.7.8", bans[0].Target)
+
+	require.NoError(t, bl.Remove("5.6.7.8"))
+	assert.False(t, bl.IsBanned("", net.ParseIP("5.6.7.8")))
+	assert.Error(t, bl.Remove("5.6.7.8"))
+	assert.Empty(t, bl.List())
+}
+
+func TestBanListPersistence(t *testing.T) {
+	bl, cleanup := newTestBanList(t)
+	defer cleanup()
+
+	_, err := bl.Add("10.0.0.0/8", 0, "forever")
+	require.NoError(t, err)
+	_, err = bl.Add("75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b", time.Hour, "an hour")
+	require.NoError(t, err)
+
+	loaded, err := NewBanList(bl.filePath)
+	require.NoError(t, err)
+	assert.Equal(t, len(bl.List()), len(loaded.List()))
+	for i, ban := range loaded.List() {
+		assert.Equal(t, bl.List()[i].Target, ban.Target)
+		assert.Equal(t, bl.List()[i].Reason, ban.Reason)
+		assert.True(t, bl.List()[i].Expires.Equal(ban.Expires))
+	}
+	assert.True(t, loaded.IsBanned("", net.ParseIP("10.1.1.1")))
+	assert.True(t, loaded.IsBanned("75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b", nil))
+
+	require.NoError(t, ioutil.WriteFile(bl.filePath, []byte(`{"bans":[{"target":"foo"}]}`), 0644))
+	_, err = NewBanList(bl.filePath)
+	assert.Error(t, err)
+}
@@ -30,6 +30,13 @@ type Reactor interface {
 	Receive(chID byte, peer Peer, msgBytes []byte)
 }
 
+// MessageDecoder is implemented by the reactors which can decode the messages
+// of their channels, so that a Capture records their types.
+type MessageDecoder interface {
+	// DecodeMessage returns the message of msgBytes, sent or received on chID.
+	DecodeMessage(chID byte, msgBytes []byte) (interface{}, error)
+}
+
 //--------------------------------------
 
 type BaseReactor struct {
@@ -0,0 +1,286 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	auto "github.com/tendermint/tendermint/libs/autofile"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+const (
+	// CaptureSend is the direction of the messages sent to a peer.
+	CaptureSend = "send"
+	// CaptureRecv is the direction of the messages received from a peer.
+	CaptureRecv = "recv"
+
+	// the buffered records are written to the file at least this often
+	captureFlushInterval = 1 * time.Second
+)
+
+// CaptureRecord is a message sent to or received from a peer, as recorded by a
+// Capture.
+type CaptureRecord struct {
+	Time      time.Time `json:"time"`
+	Peer      ID        `json:"peer"`
+	Direction string    `json:"direction"` // CaptureSend or CaptureRecv
+	ChID      byte      `json:"ch_id"`
+	Type      string    `json:"type,omitempty"` // Go type of the decoded message, if the reactor is a MessageDecoder
+	Size      int       `json:"size"`
+	Msg       []byte    `json:"msg"`
+}
+
+// Capture records the messages sent to and received from the peers of a
+// Switch, one JSON CaptureRecord per line, in a rotating autofile.Group. It is
+// enabled with SwitchCapture, and read with ReadCapture.
+type Capture struct {
+	cmn.BaseService
+
+	group *auto.Group
+}
+
+// NewCapture returns a Capture writing to the group of files with the given
+// head path.
+func NewCapture(headPath string, groupOptions ...func(*auto.Group)) (*Capture, error) {
+	if err := cmn.EnsureDir(filepath.Dir(headPath), 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to ensure capture directory is in place")
+	}
+	group, err := auto.OpenGroup(headPath, groupOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c := &Capture{group: group}
+	c.BaseService = *cmn.NewBaseService(nil, "Capture", c)
+	return c, nil
+}
+
+// SetLogger implements cmn.Service.
+func (c *Capture) SetLogger(l log.Logger) {
+	c.BaseService.Logger = l
+	c.group.SetLogger(l)
+}
+
+// OnStart implements cmn.Service.
+func (c *Capture) OnStart() error {
+	if err := c.group.Start(); err != nil {
+		return err
+	}
+	go c.flushRoutine()
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (c *Capture) OnStop() {
+	if err := c.group.Flush(); err != nil {
+		c.Logger.Error("Error flushing capture", "err", err)
+	}
+	c.group.Stop()
+	c.group.Close()
+}
+
+func (c *Capture) flushRoutine() {
+	ticker := time.NewTicker(captureFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.group.Flush(); err != nil {
+				c.Logger.Error("Error flushing capture", "err", err)
+			}
+		case <-c.Quit():
+			return
+		}
+	}
+}
+
+// record writes the message sent to or received from the peer on chID. If the
+// reactor of the channel is a MessageDecoder, the type of the message is
+// recorded too. It's a no-op if c is nil or not running.
+func (c *Capture) record(peerID ID, direction string, chID byte, reactor Reactor, msgBytes []byte) {
+	if c == nil || !c.IsRunning() {
+		return
+	}
+
+	rec := CaptureRecord{
+		Time:      time.Now().UTC(),
+		Peer:      peerID,
+		Direction: direction,
+		ChID:      chID,
+		Size:      len(msgBytes),
+		Msg:       msgBytes,
+	}
+	if dec, ok := reactor.(MessageDecoder); ok {
+		if msg, err := dec.DecodeMessage(chID, msgBytes); err == nil {
+			rec.Type = fmt.Sprintf("%T", msg)
+		}
+	}
+
+	bz, err := json.Marshal(rec)
+	if err != nil {
+		c.Logger.Error("Error encoding capture record", "err", err)
+		return
+	}
+	if err := c.group.WriteLine(string(bz)); err != nil {
+		c.Logger.Error("Error writing capture record", "err", err)
+	}
+}
+
+// ReadCapture calls fn with the records of the capture with the given head
+// path, oldest first, until fn returns an error, which is returned.
+func ReadCapture(headPath string, fn func(CaptureRecord) error) error {
+	group, err := auto.OpenGroup(headPath)
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+
+	r, err := group.NewReader(group.MinIndex())
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	for {
+		line, err := r.ReadLine()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var rec CaptureRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return errors.Wrap(err, "invalid capture record")
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// CaptureFilter selects capture records. The empty fields match every
+// record.
+type CaptureFilter struct {
+	Peer      ID
+	Direction string
+	ChIDs     []byte
+	Type      string // substring of the type of the message
+	Since     time.Time
+	Until     time.Time
+}
+
+// Match returns true if the record is selected by the filter.
+func (f CaptureFilter) Match(rec CaptureRecord) bool {
+	if f.Peer != "" && rec.Peer != f.Peer {
+		return false
+	}
+	if f.Direction != "" && rec.Direction != f.Direction {
+		return false
+	}
+	if len(f.ChIDs) > 0 && !containsChID(f.ChIDs, rec.ChID) {
+		return false
+	}
+	if f.Type != "" && !strings.Contains(rec.Type, f.Type) {
+		return false
+	}
+	if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && rec.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+func containsChID(chIDs []byte, chID byte) bool {
+	for _, id := range chIDs {
+		if id == chID {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplayCapture feeds the messages of the capture with the given head path,
+// which were received on the channels of the reactor and are selected by the
+// filter, to the reactor. Each peer of the capture is replaced by a started
+// peer with the same ID, added to the reactor before its first message and
+// never removed; what the reactor sends to it is dropped. It returns the
+// number of messages received by the reactor.
+//
+// It is meant to reproduce the behavior of a reactor in tests.
+func ReplayCapture(headPath string, reactor Reactor, filter CaptureFilter) (int, error) {
+	chIDs := []byte{}
+	for _, chDesc := range reactor.GetChannels() {
+		chIDs = append(chIDs, chDesc.ID)
+	}
+
+	var (
+		peers = make(map[ID]*replayPeer)
+		n     = 0
+	)
+	err := ReadCapture(headPath, func(rec CaptureRecord) error {
+		if rec.Direction != CaptureRecv || !containsChID(chIDs, rec.ChID) || !filter.Match(rec) {
+			return nil
+		}
+		p, ok := peers[rec.Peer]
+		if !ok {
+			p = newReplayPeer(rec.Peer)
+			if err := p.Start(); err != nil {
+				return err
+			}
+			peers[rec.Peer] = p
+			reactor.AddPeer(p)
+		}
+		reactor.Receive(rec.ChID, p, rec.Msg)
+		n++
+		return nil
+	})
+	return n, err
+}
+
+//------------------------------------------------------------------
+
+// replayPeer is the peer of the messages fed to a reactor by ReplayCapture.
+type replayPeer struct {
+	cmn.BaseService
+
+	id   ID
+	data *cmn.CMap
+}
+
+var _ Peer = (*replayPeer)(nil)
+
+func newReplayPeer(id ID) *replayPeer {
+	p := &replayPeer{
+		id:   id,
+		data: cmn.NewCMap(),
+	}
+	p.BaseService = *cmn.NewBaseService(nil, "replayPeer", p)
+	return p
+}
+
+func (p *replayPeer) FlushStop()                       { p.Stop() } // nolint: errcheck
+func (p *replayPeer) ID() ID                           { return p.id }
+func (p *replayPeer) RemoteIP() net.IP                 { return net.IPv4(127, 0, 0, 1) }
+func (p *replayPeer) RemoteAddr() net.Addr             { return &net.TCPAddr{IP: p.RemoteIP(), Port: 26656} }
+func (p *replayPeer) IsOutbound() bool                 { return false }
+func (p *replayPeer) IsPersistent() bool               { return false }
+func (p *replayPeer) CloseConn() error                 { return nil }
+func (p *replayPeer) NodeInfo() NodeInfo               { return DefaultNodeInfo{ID_: p.id} }
+func (p *replayPeer) Status() conn.ConnectionStatus    { return conn.ConnectionStatus{} }
+func (p *replayPeer) OriginalAddr() *NetAddress        { return nil }
+func (p *replayPeer) RTT() time.Duration               { return 0 }
+func (p *replayPeer) Send(byte, []byte) bool           { return true }
+func (p *replayPeer) TrySend(byte, []byte) bool        { return true }
+func (p *replayPeer) Set(key string, data interface{}) { p.data.Set(key, data) }
+func (p *replayPeer) Get(key string) interface{}       { return p.data.Get(key) }
@@ -0,0 +1,154 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+type captureTestMsg string
+
+// decodingTestReactor is a TestReactor which decodes its messages.
+type decodingTestReactor struct {
+	*TestReactor
+}
+
+func (r decodingTestReactor) DecodeMessage(chID byte, msgBytes []byte) (interface{}, error) {
+	return captureTestMsg(msgBytes), nil
+}
+
+func readCaptureRecords(t *testing.T, headPath string) []CaptureRecord {
+	recs := []CaptureRecord{}
+	err := ReadCapture(headPath, func(rec CaptureRecord) error {
+		recs = append(recs, rec)
+		return nil
+	})
+	require.NoError(t, err)
+	return recs
+}
+
+func TestSwitchCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p_capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	capture, err := NewCapture(filepath.Join(dir, "capture"))
+	require.NoError(t, err)
+	capture.SetLogger(log.TestingLogger())
+	require.NoError(t, capture.Start())
+
+	s1, s2 := MakeSwitchPair(t, func(i int, sw *Switch) *Switch {
+		sw = initSwitchFunc(i, sw)
+		sw.AddReactor("baz", decodingTestReactor{NewTestReactor([]*conn.ChannelDescriptor{
+			{ID: byte(0x04), Priority: 10},
+		}, true)})
+		if i == 0 {
+			SwitchCapture(capture)(sw)
+		}
+		return sw
+	})
+	defer s1.Stop()
+	defer s2.Stop()
+
+	s1.Broadcast(byte(0x00), []byte("channel zero"))
+	s1.Broadcast(byte(0x04), []byte("ping"))
+	assertMsgReceivedWithTimeout(t, []byte("ping"), byte(0x04), s2.Reactor("baz").(decodingTestReactor).TestReactor, 10*time.Millisecond, 5*time.Second)
+	s2.Broadcast(byte(0x04), []byte("pong"))
+	assertMsgReceivedWithTimeout(t, []byte("pong"), byte(0x04), s1.Reactor("baz").(decodingTestReactor).TestReactor, 10*time.Millisecond, 5*time.Second)
+
+	require.NoError(t, capture.Stop())
+
+	recs := readCaptureRecords(t, filepath.Join(dir, "capture"))
+	require.Len(t, recs, 3)
+	for _, rec := range recs {
+		assert.Equal(t, s2.NodeInfo().ID(), rec.Peer)
+		assert.Equal(t, len(rec.Msg), rec.Size)
+		assert.False(t, rec.Time.IsZero())
+		rec.Time, rec.Peer, rec.Size = time.Time{}, "", 0
+		switch string(rec.Msg) {
+		case "channel zero":
+			assert.Equal(t, CaptureRecord{Direction: CaptureSend, ChID: 0x00, Msg: rec.Msg}, rec)
+		case "ping":
+			assert.Equal(t, CaptureRecord{Direction: CaptureSend, ChID: 0x04, Type: "p2p.captureTestMsg", Msg: rec.Msg}, rec)
+		case "pong":
+			assert.Equal(t, CaptureRecord{Direction: CaptureRecv, ChID: 0x04, Type: "p2p.captureTestMsg", Msg: rec.Msg}, rec)
+		default:
+			t.Errorf("unexpected record %v", rec)
+		}
+	}
+}
+
+func TestReplayCapture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "p2p_capture")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	headPath := filepath.Join(dir, "capture")
+	capture, err := NewCapture(headPath)
+	require.NoError(t, err)
+	require.NoError(t, capture.Start())
+	capture.record("peer1", CaptureRecv, 0x00, nil, []byte("a"))
+	capture.record("peer2", CaptureRecv, 0x01, nil, []byte("b"))
+	capture.record("peer1", CaptureSend, 0x00, nil, []byte("c"))
+	capture.record("peer1", CaptureRecv, 0x02, nil, []byte("d"))
+	capture.record("peer2", CaptureRecv, 0x00, nil, []byte("e"))
+	require.NoError(t, capture.Stop())
+
+	reactor := NewTestReactor([]*conn.ChannelDescriptor{
+		{ID: byte(0x00), Priority: 10},
+		{ID: byte(0x01), Priority: 10},
+	}, true)
+
+	// the messages received on the channels of the reactor
+	n, err := ReplayCapture(headPath, reactor, CaptureFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, []PeerMessage{{"peer1", []byte("a"), 0}, {"peer2", []byte("e"), 2}}, reactor.getMsgs(0x00))
+	assert.Equal(t, []PeerMessage{{"peer2", []byte("b"), 1}}, reactor.getMsgs(0x01))
+
+	// filtered by peer
+	reactor = NewTestReactor(reactor.GetChannels(), true)
+	n, err = ReplayCapture(headPath, reactor, CaptureFilter{Peer: "peer2", ChIDs: []byte{0x00}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []PeerMessage{{"peer2", []byte("e"), 0}}, reactor.getMsgs(0x00))
+}
+
+func TestCaptureFilter(t *testing.T) {
+	now := time.Now()
+	rec := CaptureRecord{
+		Time:      now,
+		Peer:      "peer1",
+		Direction: CaptureRecv,
+		ChID:      0x20,
+		Type:      "*consensus.VoteMessage",
+	}
+
+	testCases := []struct {
+		filter CaptureFilter
+		match  bool
+	}{
+		{CaptureFilter{}, true},
+		{CaptureFilter{Peer: "peer1"}, true},
+		{CaptureFilter{Peer: "peer2"}, false},
+		{CaptureFilter{Direction: CaptureSend}, false},
+		{CaptureFilter{ChIDs: []byte{0x21, 0x20}}, true},
+		{CaptureFilter{ChIDs: []byte{0x21}}, false},
+		{CaptureFilter{Type: "VoteMessage"}, true},
+		{CaptureFilter{Type: "ProposalMessage"}, false},
+		{CaptureFilter{Since: now.Add(-time.Second), Until: now}, true},
+		{CaptureFilter{Since: now.Add(time.Second)}, false},
+		{CaptureFilter{Until: now.Add(-time.Second)}, false},
+	}
+	for i, tc := range testCases {
+		assert.Equal(t, tc.match, tc.filter.Match(rec), "#%d %+v", i, tc.filter)
+	}
+}
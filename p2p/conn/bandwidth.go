@@ -0,0 +1,125 @@
+package conn
+
+import (
+	"sync"
+	"time"
+
+	flow "github.com/tendermint/tendermint/libs/flowrate"
+)
+
+// Bandwidth is a send and receive budget shared by all the connections of a
+// node, on top of the rates of each connection. A nil *Bandwidth doesn't limit
+// anything.
+type Bandwidth struct {
+	sendRate    int64
+	recvRate    int64
+	sendMonitor *flow.Monitor
+	recvMonitor *flow.Monitor
+}
+
+// NewBandwidth returns a budget of sendRate and recvRate bytes/second. A rate
+// of 0 means no limit.
+func NewBandwidth(sendRate, recvRate int64) *Bandwidth {
+	return &Bandwidth{
+		sendRate:    sendRate,
+		recvRate:    recvRate,
+		sendMonitor: flow.New(0, 0),
+		recvMonitor: flow.New(0, 0),
+	}
+}
+
+// Status returns the send and receive statuses of all the connections.
+func (b *Bandwidth) Status() (send, recv flow.Status) {
+	if b == nil {
+		return
+	}
+	return b.sendMonitor.Status(), b.recvMonitor.Status()
+}
+
+// limitSend blocks until the budget allows to send n bytes.
+func (b *Bandwidth) limitSend(n int) {
+	if b != nil {
+		b.sendMonitor.Limit(n, b.sendRate, true)
+	}
+}
+
+// updateSend records the sending of n bytes.
+func (b *Bandwidth) updateSend(n int) {
+	if b != nil {
+		b.sendMonitor.Update(n)
+	}
+}
+
+// limitRecv blocks until the budget allows to receive n bytes.
+func (b *Bandwidth) limitRecv(n int) {
+	if b != nil {
+		b.recvMonitor.Limit(n, b.recvRate, true)
+	}
+}
+
+// updateRecv records the receipt of n bytes.
+func (b *Bandwidth) updateRecv(n int) {
+	if b != nil {
+		b.recvMonitor.Update(n)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// rateLimiter caps the rate of a channel, in bytes/second, allowing bursts of
+// up to a second worth of bytes. A nil *rateLimiter doesn't limit anything.
+type rateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64
+	tokens float64 // bytes which can be transferred right away, if positive
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter of the given rate, or nil if it is 0.
+func newRateLimiter(rate int64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// isThrottled returns true if nothing can be transferred right now.
+func (rl *rateLimiter) isThrottled() bool {
+	if rl == nil {
+		return false
+	}
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	rl.refill()
+	return rl.tokens < 0
+}
+
+// take records the transfer of n bytes, and returns how long to wait before
+// the transfer fits the rate.
+func (rl *rateLimiter) take(n int) time.Duration {
+	if rl == nil {
+		return 0
+	}
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	rl.refill()
+	rl.tokens -= float64(n)
+	if rl.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+}
+
+// refill adds the tokens earned since the last call. rl.mtx must be held.
+func (rl *rateLimiter) refill() {
+	now := time.Now()
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.rate {
+		rl.tokens = rl.rate
+	}
+	rl.last = now
+}
@@ -0,0 +1,49 @@
+package conn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	// no limit
+	var rl *rateLimiter = newRateLimiter(0)
+	assert.Nil(t, rl)
+	assert.Zero(t, rl.take(1000000))
+	assert.False(t, rl.isThrottled())
+
+	// a burst of up to a second worth of bytes goes through right away
+	rl = newRateLimiter(1000)
+	assert.Zero(t, rl.take(600))
+	assert.Zero(t, rl.take(400))
+	assert.False(t, rl.isThrottled())
+
+	// then the transfers must wait for the rate
+	wait := rl.take(500)
+	assert.InDelta(t, 500*time.Millisecond, wait, float64(50*time.Millisecond))
+	assert.True(t, rl.isThrottled())
+
+	time.Sleep(wait)
+	assert.False(t, rl.isThrottled())
+}
+
+func TestBandwidthStatus(t *testing.T) {
+	var b *Bandwidth
+	b.limitSend(100)
+	b.updateSend(100)
+	send, recv := b.Status()
+	assert.Zero(t, send.Bytes)
+	assert.Zero(t, recv.Bytes)
+
+	b = NewBandwidth(0, 0)
+	b.updateSend(100)
+	b.updateSend(50)
+	b.updateRecv(10)
+	// the monitors account for the bytes once their sample is over
+	time.Sleep(200 * time.Millisecond)
+	send, recv = b.Status()
+	assert.EqualValues(t, 150, send.Bytes)
+	assert.EqualValues(t, 10, recv.Bytes)
+}
@@ -39,6 +39,14 @@ const (
 	defaultSendTimeout         = 10 * time.Second
 	defaultPingInterval        = 60 * time.Second
 	defaultPongTimeout         = 45 * time.Second
+
+	// how long to wait before retrying to send from the channels which are
+	// over their SendRate
+	throttleRetryInterval = 10 * time.Millisecond
+
+	// number of received messages of a channel over its RecvRate which can
+	// wait to be delivered before reading from the connection waits too
+	recvQueueCapacity = 100
 )
 
 type receiveCbFunc func(chID byte, msgBytes []byte)
@@ -90,17 +98,24 @@ type MConnection struct {
 	quitSendRoutine chan struct{}
 	doneSendRoutine chan struct{}
 
+	// Closed when recvRoutine exits, to stop the delivery of the messages of
+	// the throttled channels.
+	quitRecvRoutine chan struct{}
+
 	// used to ensure FlushStop and OnStop
 	// are safe to call concurrently.
 	stopMtx sync.Mutex
 
-	flushTimer *cmn.ThrottleTimer // flush writes as necessary but throttled.
-	pingTimer  *cmn.RepeatTimer   // send pings periodically
+	flushTimer    *cmn.ThrottleTimer // flush writes as necessary but throttled.
+	throttleTimer *cmn.ThrottleTimer // retry sending from throttled channels
+	pingTimer     *cmn.RepeatTimer   // send pings periodically
 
 	// close conn if pong is not received in pongTimeout
 	pongTimer     *time.Timer
 	pongTimeoutCh chan bool // true - timeout, false - peer sent pong
 
+	rtt rttMonitor // round-trip time of the pings
+
 	chStatsTimer *cmn.RepeatTimer // update channel stats periodically
 
 	created time.Time // time of creation
@@ -124,6 +139,9 @@ type MConnConfig struct {
 
 	// Maximum wait time for pongs
 	PongTimeout time.Duration `mapstructure:"pong_timeout"`
+
+	// Budget shared with the other connections of the node, if any
+	Bandwidth *Bandwidth `mapstructure:"-"`
 }
 
 // DefaultMConnConfig returns the default config.
@@ -202,12 +220,19 @@ func (c *MConnection) OnStart() error {
 	}
 	c.quitSendRoutine = make(chan struct{})
 	c.doneSendRoutine = make(chan struct{})
+	c.quitRecvRoutine = make(chan struct{})
 	c.flushTimer = cmn.NewThrottleTimer("flush", c.config.FlushThrottle)
+	c.throttleTimer = cmn.NewThrottleTimer("throttle", throttleRetryInterval)
 	c.pingTimer = cmn.NewRepeatTimer("ping", c.config.PingInterval)
 	c.pongTimeoutCh = make(chan bool, 1)
 	c.chStatsTimer = cmn.NewRepeatTimer("chStats", updateStats)
 	go c.sendRoutine()
 	go c.recvRoutine()
+	for _, ch := range c.channels {
+		if ch.recvQueue != nil {
+			go c.recvThrottledRoutine(ch)
+		}
+	}
 	return nil
 }
 
@@ -228,6 +253,7 @@ func (c *MConnection) FlushStop() {
 
 	c.BaseService.OnStop()
 	c.flushTimer.Stop()
+	c.throttleTimer.Stop()
 	c.pingTimer.Stop()
 	c.chStatsTimer.Stop()
 	if c.quitSendRoutine != nil {
@@ -273,6 +299,7 @@ func (c *MConnection) OnStop() {
 
 	c.BaseService.OnStop()
 	c.flushTimer.Stop()
+	c.throttleTimer.Stop()
 	c.pingTimer.Stop()
 	c.chStatsTimer.Stop()
 	close(c.quitSendRoutine)
@@ -416,6 +443,7 @@ FOR_LOOP:
 				default:
 				}
 			})
+			c.rtt.sentPing()
 			c.flush()
 		case timeout := <-c.pongTimeoutCh:
 			if timeout {
@@ -432,6 +460,12 @@ FOR_LOOP:
 			}
 			c.sendMonitor.Update(int(_n))
 			c.flush()
+		case <-c.throttleTimer.Ch:
+			// Retry sending from the channels which were over their SendRate.
+			select {
+			case c.send <- struct{}{}:
+			default:
+			}
 		case <-c.quitSendRoutine:
 			close(c.doneSendRoutine)
 			break FOR_LOOP
@@ -468,6 +502,7 @@ func (c *MConnection) sendSomePacketMsgs() bool {
 	// Once we're ready we send more than we asked for,
 	// but amortized it should even out.
 	c.sendMonitor.Limit(c._maxPacketMsgSize, atomic.LoadInt64(&c.config.SendRate), true)
+	c.config.Bandwidth.limitSend(c._maxPacketMsgSize)
 
 	// Now send some PacketMsgs.
 	for i := 0; i < numBatchPacketMsgs; i++ {
@@ -484,11 +519,17 @@ func (c *MConnection) sendPacketMsg() bool {
 	// The chosen channel will be the one whose recentlySent/priority is the least.
 	var leastRatio float32 = math.MaxFloat32
 	var leastChannel *Channel
+	var throttled bool
 	for _, channel := range c.channels {
 		// If nothing to send, skip this channel
 		if !channel.isSendPending() {
 			continue
 		}
+		// If over its SendRate, skip this channel for now
+		if channel.isSendThrottled() {
+			throttled = true
+			continue
+		}
 		// Get ratio, and keep track of lowest ratio.
 		ratio := float32(channel.recentlySent) / float32(channel.desc.Priority)
 		if ratio < leastRatio {
@@ -499,6 +540,9 @@ func (c *MConnection) sendPacketMsg() bool {
 
 	// Nothing to send?
 	if leastChannel == nil {
+		if throttled {
+			c.throttleTimer.Set()
+		}
 		return true
 	}
 	// c.Logger.Info("Found a msgPacket to send")
@@ -511,6 +555,7 @@ func (c *MConnection) sendPacketMsg() bool {
 		return true
 	}
 	c.sendMonitor.Update(int(_n))
+	c.config.Bandwidth.updateSend(int(_n))
 	c.flushTimer.Set()
 	return false
 }
@@ -521,11 +566,13 @@ func (c *MConnection) sendPacketMsg() bool {
 // Otherwise, it never blocks.
 func (c *MConnection) recvRoutine() {
 	defer c._recover()
+	defer close(c.quitRecvRoutine)
 
 FOR_LOOP:
 	for {
 		// Block until .recvMonitor says we can read.
 		c.recvMonitor.Limit(c._maxPacketMsgSize, atomic.LoadInt64(&c.config.RecvRate), true)
+		c.config.Bandwidth.limitRecv(c._maxPacketMsgSize)
 
 		// Peek into bufConnReader for debugging
 		/*
@@ -547,6 +594,7 @@ FOR_LOOP:
 		var err error
 		_n, err = cdc.UnmarshalBinaryLengthPrefixedReader(c.bufConnReader, &packet, int64(c._maxPacketMsgSize))
 		c.recvMonitor.Update(int(_n))
+		c.config.Bandwidth.updateRecv(int(_n))
 		if err != nil {
 			if c.IsRunning() {
 				c.Logger.Error("Connection failed @ recvRoutine (reading byte)", "conn", c, "err", err)
@@ -568,6 +616,7 @@ FOR_LOOP:
 			}
 		case PacketPong:
 			c.Logger.Debug("Receive Pong")
+			c.rtt.receivedPong()
 			select {
 			case c.pongTimeoutCh <- false:
 			default:
@@ -592,6 +641,15 @@ FOR_LOOP:
 			}
 			if msgBytes != nil {
 				c.Logger.Debug("Received bytes", "chID", pkt.ChannelID, "msgBytes", fmt.Sprintf("%X", msgBytes))
+				if channel.recvQueue != nil {
+					// copied, as msgBytes is reused for the next message
+					select {
+					case channel.recvQueue <- append([]byte(nil), msgBytes...):
+					case <-c.quitSendRoutine: // closed by OnStop and FlushStop
+						break FOR_LOOP
+					}
+					continue
+				}
 				// NOTE: This means the reactor.Receive runs in the same thread as the p2p recv routine
 				c.onReceive(pkt.ChannelID, msgBytes)
 			}
@@ -610,6 +668,31 @@ FOR_LOOP:
 	}
 }
 
+// recvThrottledRoutine delivers the messages received on a channel with a
+// RecvRate once the rate allows them, so that the throttled channel doesn't
+// hold back the reads of the others.
+func (c *MConnection) recvThrottledRoutine(ch *Channel) {
+	defer c._recover()
+
+	for {
+		select {
+		case msgBytes := <-ch.recvQueue:
+			if wait := ch.recvLimiter.take(len(msgBytes)); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-c.quitRecvRoutine:
+					timer.Stop()
+					return
+				}
+			}
+			c.onReceive(ch.desc.ID, msgBytes)
+		case <-c.quitRecvRoutine:
+			return
+		}
+	}
+}
+
 // not goroutine-safe
 func (c *MConnection) stopPongTimer() {
 	if c.pongTimer != nil {
@@ -633,6 +716,11 @@ type ConnectionStatus struct {
 	SendMonitor flow.Status
 	RecvMonitor flow.Status
 	Channels    []ChannelStatus
+
+	// Round-trip time of the pings: moving average and last one, 0 until
+	// the first pong is received
+	RTT     time.Duration
+	LastRTT time.Duration
 }
 
 type ChannelStatus struct {
@@ -641,6 +729,10 @@ type ChannelStatus struct {
 	SendQueueSize     int
 	Priority          int
 	RecentlySent      int64
+	SendBytes         int64 // total number of message bytes sent
+	RecvBytes         int64 // total number of message bytes received
+	SendRate          int64 // current send rate, in bytes/second
+	RecvRate          int64 // current receive rate, in bytes/second
 }
 
 func (c *MConnection) Status() ConnectionStatus {
@@ -648,14 +740,20 @@ func (c *MConnection) Status() ConnectionStatus {
 	status.Duration = time.Since(c.created)
 	status.SendMonitor = c.sendMonitor.Status()
 	status.RecvMonitor = c.recvMonitor.Status()
+	status.LastRTT, status.RTT = c.rtt.status()
 	status.Channels = make([]ChannelStatus, len(c.channels))
 	for i, channel := range c.channels {
+		sendStatus, recvStatus := channel.sendMonitor.Status(), channel.recvMonitor.Status()
 		status.Channels[i] = ChannelStatus{
 			ID:                channel.desc.ID,
 			SendQueueCapacity: cap(channel.sendQueue),
 			SendQueueSize:     int(atomic.LoadInt32(&channel.sendQueueSize)),
 			Priority:          channel.desc.Priority,
 			RecentlySent:      atomic.LoadInt64(&channel.recentlySent),
+			SendBytes:         atomic.LoadInt64(&channel.sendTotal),
+			RecvBytes:         atomic.LoadInt64(&channel.recvTotal),
+			SendRate:          sendStatus.CurRate,
+			RecvRate:          recvStatus.CurRate,
 		}
 	}
 	return status
@@ -669,6 +767,14 @@ type ChannelDescriptor struct {
 	SendQueueCapacity   int
 	RecvBufferCapacity  int
 	RecvMessageCapacity int
+
+	// Optional caps on the rates at which the channel sends and receives
+	// messages, in bytes/second. 0 means no cap.
+	// NOTE: an MConnection reads all the channels from the same routine: the
+	// messages of a channel over its RecvRate are queued, and once the queue
+	// is full, reading from the connection waits too.
+	SendRate int64
+	RecvRate int64
 }
 
 func (chDesc ChannelDescriptor) FillDefaults() (filled ChannelDescriptor) {
@@ -695,6 +801,13 @@ type Channel struct {
 	recving       []byte
 	sending       []byte
 	recentlySent  int64 // exponential moving average
+	sendTotal     int64 // atomic, bytes sent
+	recvTotal     int64 // atomic, bytes received
+	sendMonitor   *flow.Monitor
+	recvMonitor   *flow.Monitor
+	sendLimiter   *rateLimiter
+	recvLimiter   *rateLimiter
+	recvQueue     chan []byte // messages waiting for the RecvRate, if any
 
 	maxPacketMsgPayloadSize int
 
@@ -706,11 +819,20 @@ func newChannel(conn *MConnection, desc ChannelDescriptor) *Channel {
 	if desc.Priority <= 0 {
 		cmn.PanicSanity("Channel default priority must be a positive integer")
 	}
+	var recvQueue chan []byte
+	if desc.RecvRate > 0 {
+		recvQueue = make(chan []byte, recvQueueCapacity)
+	}
 	return &Channel{
 		conn:                    conn,
 		desc:                    desc,
 		sendQueue:               make(chan []byte, desc.SendQueueCapacity),
 		recving:                 make([]byte, 0, desc.RecvBufferCapacity),
+		sendMonitor:             flow.New(0, 0),
+		recvMonitor:             flow.New(0, 0),
+		sendLimiter:             newRateLimiter(desc.SendRate),
+		recvLimiter:             newRateLimiter(desc.RecvRate),
+		recvQueue:               recvQueue,
 		maxPacketMsgPayloadSize: conn.config.MaxPacketMsgPayloadSize,
 	}
 }
@@ -769,6 +891,12 @@ func (ch *Channel) isSendPending() bool {
 	return true
 }
 
+// Returns true if the channel has sent as much as its SendRate allows for now.
+// Goroutine-safe
+func (ch *Channel) isSendThrottled() bool {
+	return ch.sendLimiter.isThrottled()
+}
+
 // Creates a new PacketMsg to send.
 // Not goroutine-safe
 func (ch *Channel) nextPacketMsg() PacketMsg {
@@ -793,6 +921,9 @@ func (ch *Channel) writePacketMsgTo(w io.Writer) (n int64, err error) {
 	var packet = ch.nextPacketMsg()
 	n, err = cdc.MarshalBinaryLengthPrefixedWriter(w, packet)
 	atomic.AddInt64(&ch.recentlySent, n)
+	atomic.AddInt64(&ch.sendTotal, int64(len(packet.Bytes)))
+	ch.sendMonitor.Update(len(packet.Bytes))
+	ch.sendLimiter.take(len(packet.Bytes))
 	return
 }
 
@@ -805,6 +936,8 @@ func (ch *Channel) recvPacketMsg(packet PacketMsg) ([]byte, error) {
 	if recvCap < recvReceived {
 		return nil, fmt.Errorf("Received message exceeds available capacity: %v < %v", recvCap, recvReceived)
 	}
+	atomic.AddInt64(&ch.recvTotal, int64(len(packet.Bytes)))
+	ch.recvMonitor.Update(len(packet.Bytes))
 	ch.recving = append(ch.recving, packet.Bytes...)
 	if packet.EOF == byte(0x01) {
 		msgBytes := ch.recving
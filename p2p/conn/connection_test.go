@@ -3,6 +3,7 @@ package conn
 import (
 	"bytes"
 	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -155,6 +156,165 @@ func TestMConnectionStatus(t *testing.T) {
 	assert.Zero(t, status.Channels[0].SendQueueSize)
 }
 
+func TestMConnectionRTT(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	mconn1 := createTestMConnection(client)
+	require.Nil(t, mconn1.Start())
+	defer mconn1.Stop()
+	mconn2 := createTestMConnection(server)
+	require.Nil(t, mconn2.Start())
+	defer mconn2.Stop()
+
+	assert.Zero(t, mconn1.Status().RTT)
+
+	// wait for a couple of pings (PingInterval is 90ms)
+	time.Sleep(250 * time.Millisecond)
+
+	for _, mconn := range []*MConnection{mconn1, mconn2} {
+		status := mconn.Status()
+		assert.True(t, status.RTT > 0, "RTT should be measured")
+		assert.True(t, status.LastRTT > 0, "LastRTT should be measured")
+		assert.True(t, status.RTT < 45*time.Millisecond, "RTT should be less than PongTimeout")
+	}
+}
+
+func TestMConnectionChannelBytes(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	receivedCh := make(chan []byte)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- msgBytes
+	}
+	onError := func(r interface{}) {}
+	mconn1 := createMConnectionWithCallbacks(client, onReceive, onError)
+	require.Nil(t, mconn1.Start())
+	defer mconn1.Stop()
+
+	mconn2 := createTestMConnection(server)
+	require.Nil(t, mconn2.Start())
+	defer mconn2.Stop()
+
+	msg := []byte("Cyclops")
+	assert.True(t, mconn2.Send(0x01, msg))
+	select {
+	case <-receivedCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatalf("Did not receive %s message in 500ms", msg)
+	}
+
+	assert.EqualValues(t, len(msg), mconn2.Status().Channels[0].SendBytes)
+	assert.EqualValues(t, len(msg), mconn1.Status().Channels[0].RecvBytes)
+}
+
+func TestMConnectionChannelSendRate(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	var (
+		received   int
+		receivedCh = make(chan struct{})
+		msgSize    = 1000
+		numMsgs    = 6
+	)
+	onReceive := func(chID byte, msgBytes []byte) {
+		if received++; received == numMsgs {
+			close(receivedCh)
+		}
+	}
+	onError := func(r interface{}) {}
+	mconn1 := createMConnectionWithCallbacks(client, onReceive, onError)
+	require.Nil(t, mconn1.Start())
+	defer mconn1.Stop()
+
+	// 2 messages per second at most
+	chDescs := []*ChannelDescriptor{{ID: 0x01, Priority: 1, SendQueueCapacity: numMsgs, SendRate: int64(2 * msgSize)}}
+	mconn2 := NewMConnectionWithConfig(server, chDescs, func(byte, []byte) {}, onError, DefaultMConnConfig())
+	mconn2.SetLogger(log.TestingLogger())
+	require.Nil(t, mconn2.Start())
+	defer mconn2.Stop()
+
+	start := time.Now()
+	for i := 0; i < numMsgs; i++ {
+		assert.True(t, mconn2.Send(0x01, make([]byte, msgSize)))
+	}
+	select {
+	case <-receivedCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Did not receive the messages in 10s")
+	}
+	assert.True(t, time.Since(start) > time.Second, "the channel exceeded its SendRate")
+}
+
+func TestMConnectionChannelRecvRate(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	const (
+		msgSize = 1000
+		numMsgs = 6
+	)
+	var (
+		mtx      sync.Mutex
+		received = make(map[byte]int)
+	)
+	onReceive := func(chID byte, msgBytes []byte) {
+		mtx.Lock()
+		received[chID]++
+		mtx.Unlock()
+	}
+	numReceived := func(chID byte) int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return received[chID]
+	}
+	onError := func(r interface{}) {}
+
+	// 2 messages per second at most on channel 0x01
+	cfg := DefaultMConnConfig()
+	cfg.PingInterval = 90 * time.Millisecond
+	cfg.PongTimeout = 45 * time.Millisecond
+	chDescs := []*ChannelDescriptor{
+		{ID: 0x01, Priority: 1, RecvRate: int64(2 * msgSize)},
+		{ID: 0x02, Priority: 1},
+	}
+	mconn1 := NewMConnectionWithConfig(client, chDescs, onReceive, onError, cfg)
+	mconn1.SetLogger(log.TestingLogger())
+	require.Nil(t, mconn1.Start())
+	defer mconn1.Stop()
+
+	chDescs = []*ChannelDescriptor{
+		{ID: 0x01, Priority: 1, SendQueueCapacity: numMsgs},
+		{ID: 0x02, Priority: 1, SendQueueCapacity: numMsgs},
+	}
+	mconn2 := NewMConnectionWithConfig(server, chDescs, func(byte, []byte) {}, onError, cfg)
+	mconn2.SetLogger(log.TestingLogger())
+	require.Nil(t, mconn2.Start())
+	defer mconn2.Stop()
+
+	for i := 0; i < numMsgs; i++ {
+		assert.True(t, mconn2.Send(0x01, make([]byte, msgSize)))
+	}
+	assert.True(t, mconn2.Send(0x02, make([]byte, msgSize)))
+
+	// the other channel isn't held back, and the pongs keep the connection up
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, 1, numReceived(0x02))
+	assert.True(t, numReceived(0x01) < numMsgs, "the channel exceeded its RecvRate")
+	assert.True(t, mconn1.IsRunning())
+
+	// stopping doesn't wait for the throttled messages
+	start := time.Now()
+	mconn1.Stop()
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+}
+
 func TestMConnectionPongTimeoutResultsInError(t *testing.T) {
 	server, client := net.Pipe()
 	defer server.Close()
@@ -0,0 +1,412 @@
+package conn
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Handshakes establishing a SecretConnection.
+const (
+	// HandshakeSTS is the original handshake, see MakeSecretConnection.
+	HandshakeSTS = "sts"
+	// HandshakeNoise is the Noise_XX_25519_ChaChaPoly_SHA256 handshake, see
+	// MakeNoiseSecretConnection.
+	HandshakeNoise = "noise"
+)
+
+const (
+	// exactly sha256.Size bytes long, so it is used as is as the initial
+	// handshake hash
+	noiseProtocolName = "Noise_XX_25519_ChaChaPoly_SHA256"
+
+	noiseMaxMsgSize = 65535
+	noiseKeySize    = 32
+)
+
+var (
+	// noiseMagic precedes the first handshake message of both peers, so that
+	// the responder tells the Noise handshake from the STS one (whose first
+	// message starts with 0x21), and the initiator tells a responder which
+	// doesn't support it. It is also the prologue of the handshake.
+	noiseMagic = []byte("TMNOISE1")
+
+	// prefix of the message signed with the node key to bind it to the Noise
+	// static key
+	noiseStaticKeySigPrefix = []byte("tendermint-noise-static-key:")
+
+	// ErrNoiseNotSupported is returned by MakeNoiseSecretConnection if the
+	// remote peer answered with the STS handshake.
+	ErrNoiseNotSupported = errors.New("remote peer doesn't support the Noise handshake")
+)
+
+// MakeNoiseSecretConnection performs the Noise handshake as the initiator,
+// and returns a new authenticated SecretConnection. The handshake follows the
+// XX pattern of the Noise Protocol Framework
+// (https://noiseprotocol.org/noise.html), with X25519, ChaCha20-Poly1305 and
+// SHA256:
+//
+//   -> e
+//   <- e, ee, s, es
+//   -> s, se
+//
+// Each message is preceded by its length as a 2-byte big-endian integer, and
+// the first message of each peer by noiseMagic, which is also the prologue.
+// The static keys are generated for each connection, and bound to the node
+// keys by the payloads of the last two messages: an authSigMessage holding the
+// node public key and its signature of noiseStaticKeySigPrefix followed by the
+// static public key. The transport keys are the ones of Split(), and are used
+// with the same framing and nonces as the STS handshake.
+//
+// The remote peer must accept the connection with AcceptSecretConnection. If
+// it only supports the STS handshake, ErrNoiseNotSupported is returned, and
+// the connection must be closed.
+func MakeNoiseSecretConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*SecretConnection, error) {
+	ns := newNoiseHandshakeState(locPrivKey)
+
+	// -> e
+	ns.mixHash(ns.e.pub[:])
+	msg1, err := ns.encryptAndHash(append([]byte{}, ns.e.pub[:]...), nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeNoiseMessage(conn, noiseMagic, msg1); err != nil {
+		return nil, err
+	}
+
+	// <- e, ee, s, es
+	magic := make([]byte, len(noiseMagic))
+	if _, err := io.ReadFull(conn, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic, noiseMagic) {
+		return nil, ErrNoiseNotSupported
+	}
+	msg2, err := readNoiseMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg2) < 2*noiseKeySize+aeadSizeOverhead {
+		return nil, errors.New("noise handshake message is too short")
+	}
+	re, msg2 := msg2[:noiseKeySize], msg2[noiseKeySize:]
+	if err := ns.readEphemeral(re); err != nil {
+		return nil, err
+	}
+	if err := ns.mixDH(&ns.e, ns.re); err != nil {
+		return nil, err
+	}
+	rs, msg2 := msg2[:noiseKeySize+aeadSizeOverhead], msg2[noiseKeySize+aeadSizeOverhead:]
+	if err := ns.readStatic(rs); err != nil {
+		return nil, err
+	}
+	if err := ns.mixDH(&ns.e, ns.rs); err != nil {
+		return nil, err
+	}
+	if err := ns.readPayload(msg2); err != nil {
+		return nil, err
+	}
+
+	// -> s, se
+	msg3, err := ns.encryptAndHash(nil, ns.s.pub[:])
+	if err != nil {
+		return nil, err
+	}
+	if err := ns.mixDH(&ns.s, ns.re); err != nil {
+		return nil, err
+	}
+	if msg3, err = ns.writePayload(msg3); err != nil {
+		return nil, err
+	}
+	if err := writeNoiseMessage(conn, nil, msg3); err != nil {
+		return nil, err
+	}
+
+	sendSecret, recvSecret := ns.split()
+	return ns.secretConnection(conn, recvSecret, sendSecret), nil
+}
+
+// makeNoiseResponderConnection performs the Noise handshake as the responder,
+// once noiseMagic was read from conn. See MakeNoiseSecretConnection.
+func makeNoiseResponderConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*SecretConnection, error) {
+	ns := newNoiseHandshakeState(locPrivKey)
+
+	// -> e
+	msg1, err := readNoiseMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg1) != noiseKeySize {
+		return nil, errors.New("invalid noise handshake message")
+	}
+	if err := ns.readEphemeral(msg1); err != nil {
+		return nil, err
+	}
+	if _, err := ns.decryptAndHash(nil); err != nil {
+		return nil, err
+	}
+
+	// <- e, ee, s, es
+	ns.mixHash(ns.e.pub[:])
+	msg2 := append([]byte{}, ns.e.pub[:]...)
+	if err := ns.mixDH(&ns.e, ns.re); err != nil {
+		return nil, err
+	}
+	if msg2, err = ns.encryptAndHash(msg2, ns.s.pub[:]); err != nil {
+		return nil, err
+	}
+	if err := ns.mixDH(&ns.s, ns.re); err != nil {
+		return nil, err
+	}
+	if msg2, err = ns.writePayload(msg2); err != nil {
+		return nil, err
+	}
+	if err := writeNoiseMessage(conn, noiseMagic, msg2); err != nil {
+		return nil, err
+	}
+
+	// -> s, se
+	msg3, err := readNoiseMessage(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg3) < noiseKeySize+2*aeadSizeOverhead {
+		return nil, errors.New("noise handshake message is too short")
+	}
+	if err := ns.readStatic(msg3[:noiseKeySize+aeadSizeOverhead]); err != nil {
+		return nil, err
+	}
+	if err := ns.mixDH(&ns.e, ns.rs); err != nil {
+		return nil, err
+	}
+	if err := ns.readPayload(msg3[noiseKeySize+aeadSizeOverhead:]); err != nil {
+		return nil, err
+	}
+
+	recvSecret, sendSecret := ns.split()
+	return ns.secretConnection(conn, recvSecret, sendSecret), nil
+}
+
+func writeNoiseMessage(w io.Writer, magic, msg []byte) error {
+	if len(msg) > noiseMaxMsgSize {
+		return errors.New("noise handshake message is too long")
+	}
+	buf := make([]byte, len(magic)+2+len(msg))
+	copy(buf, magic)
+	binary.BigEndian.PutUint16(buf[len(magic):], uint16(len(msg)))
+	copy(buf[len(magic)+2:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readNoiseMessage(r io.Reader) ([]byte, error) {
+	var lenBuf [2]byte
+	if _, err := io.ReadFull(r, lenBuf[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+//--------------------------------------------------------------------------------
+
+type noiseKeyPair struct {
+	pub, priv *[32]byte
+}
+
+func newNoiseKeyPair() noiseKeyPair {
+	pub, priv := genEphKeys()
+	return noiseKeyPair{pub, priv}
+}
+
+// noiseHandshakeState is the HandshakeState of the Noise specification,
+// including its SymmetricState and CipherState.
+type noiseHandshakeState struct {
+	ck, h [sha256.Size]byte
+	k     *[aeadKeySize]byte // nil until the first DH
+	n     uint64
+
+	locPrivKey crypto.PrivKey
+	s, e       noiseKeyPair
+	rs, re     *[32]byte
+	remPubKey  crypto.PubKey
+}
+
+func newNoiseHandshakeState(locPrivKey crypto.PrivKey) *noiseHandshakeState {
+	ns := &noiseHandshakeState{
+		locPrivKey: locPrivKey,
+		s:          newNoiseKeyPair(),
+		e:          newNoiseKeyPair(),
+	}
+	copy(ns.h[:], noiseProtocolName)
+	ns.ck = ns.h
+	ns.mixHash(noiseMagic)
+	return ns
+}
+
+func (ns *noiseHandshakeState) mixHash(data []byte) {
+	h := sha256.New()
+	h.Write(ns.h[:]) // nolint: errcheck
+	h.Write(data)    // nolint: errcheck
+	copy(ns.h[:], h.Sum(nil))
+}
+
+func (ns *noiseHandshakeState) mixKey(ikm []byte) {
+	var k [aeadKeySize]byte
+	ns.ck, k = noiseHKDF(ns.ck[:], ikm)
+	ns.k = &k
+	ns.n = 0
+}
+
+// mixDH mixes the Diffie-Hellman secret of the local key pair and the remote
+// public key into the chaining key.
+func (ns *noiseHandshakeState) mixDH(loc *noiseKeyPair, rem *[32]byte) error {
+	dhSecret, err := computeDHSecret(rem, loc.priv)
+	if err != nil {
+		return err
+	}
+	ns.mixKey(dhSecret[:])
+	return nil
+}
+
+// encryptAndHash appends the encryption of plaintext to out.
+func (ns *noiseHandshakeState) encryptAndHash(out, plaintext []byte) ([]byte, error) {
+	ciphertext := plaintext
+	if ns.k != nil {
+		aead, err := chacha20poly1305.New(ns.k[:])
+		if err != nil {
+			return nil, err
+		}
+		ciphertext = aead.Seal(nil, noiseNonce(ns.n), plaintext, ns.h[:])
+		ns.n++
+	}
+	ns.mixHash(ciphertext)
+	return append(out, ciphertext...), nil
+}
+
+func (ns *noiseHandshakeState) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	plaintext := ciphertext
+	if ns.k != nil {
+		aead, err := chacha20poly1305.New(ns.k[:])
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err = aead.Open(nil, noiseNonce(ns.n), ciphertext, ns.h[:])
+		if err != nil {
+			return nil, errors.New("failed to decrypt noise handshake message")
+		}
+		ns.n++
+	}
+	ns.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+func (ns *noiseHandshakeState) readEphemeral(re []byte) error {
+	ns.re = new([32]byte)
+	copy(ns.re[:], re)
+	if hasSmallOrder(*ns.re) {
+		return ErrSmallOrderRemotePubKey
+	}
+	ns.mixHash(re)
+	return nil
+}
+
+func (ns *noiseHandshakeState) readStatic(ciphertext []byte) error {
+	rs, err := ns.decryptAndHash(ciphertext)
+	if err != nil {
+		return err
+	}
+	ns.rs = new([32]byte)
+	copy(ns.rs[:], rs)
+	if hasSmallOrder(*ns.rs) {
+		return ErrSmallOrderRemotePubKey
+	}
+	return nil
+}
+
+// writePayload appends the encrypted payload binding the node key to the
+// static key to out.
+func (ns *noiseHandshakeState) writePayload(out []byte) ([]byte, error) {
+	sig, err := ns.locPrivKey.Sign(append(append([]byte{}, noiseStaticKeySigPrefix...), ns.s.pub[:]...))
+	if err != nil {
+		return nil, err
+	}
+	payload, err := cdc.MarshalBinaryBare(authSigMessage{ns.locPrivKey.PubKey(), sig})
+	if err != nil {
+		return nil, err
+	}
+	return ns.encryptAndHash(out, payload)
+}
+
+// readPayload decrypts the payload of the remote peer, and checks that its
+// node key signed its static key.
+func (ns *noiseHandshakeState) readPayload(ciphertext []byte) error {
+	payload, err := ns.decryptAndHash(ciphertext)
+	if err != nil {
+		return err
+	}
+	var msg authSigMessage
+	if err := cdc.UnmarshalBinaryBare(payload, &msg); err != nil {
+		return err
+	}
+	if msg.Key == nil {
+		return errors.New("peer sent a nil public key")
+	}
+	if !msg.Key.VerifyBytes(append(append([]byte{}, noiseStaticKeySigPrefix...), ns.rs[:]...), msg.Sig) {
+		return errors.New("Static key verification failed")
+	}
+	ns.remPubKey = msg.Key
+	return nil
+}
+
+// split returns the keys of the initiator and of the responder.
+func (ns *noiseHandshakeState) split() (k1, k2 *[aeadKeySize]byte) {
+	out1, out2 := noiseHKDF(ns.ck[:], nil)
+	return &out1, &out2
+}
+
+func (ns *noiseHandshakeState) secretConnection(conn io.ReadWriteCloser, recvSecret, sendSecret *[aeadKeySize]byte) *SecretConnection {
+	return &SecretConnection{
+		conn:       conn,
+		recvNonce:  new([aeadNonceSize]byte),
+		sendNonce:  new([aeadNonceSize]byte),
+		recvSecret: recvSecret,
+		sendSecret: sendSecret,
+		remPubKey:  ns.remPubKey,
+		handshake:  HandshakeNoise,
+	}
+}
+
+// noiseHKDF is the HKDF function of the Noise specification, returning two
+// outputs.
+func noiseHKDF(chainingKey, ikm []byte) (out1, out2 [32]byte) {
+	tempKey := hmacSHA256(chainingKey, ikm)
+	copy(out1[:], hmacSHA256(tempKey, []byte{0x01}))
+	copy(out2[:], hmacSHA256(tempKey, append(out1[:], 0x02)))
+	return
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data) // nolint: errcheck
+	return mac.Sum(nil)
+}
+
+// noiseNonce encodes n as the ChaChaPoly nonce of the Noise specification: 4
+// zero bytes followed by n, little-endian, like the nonces of the
+// SecretConnection frames.
+func noiseNonce(n uint64) []byte {
+	nonce := make([]byte, aeadNonceSize)
+	binary.LittleEndian.PutUint64(nonce[4:], n)
+	return nonce
+}
@@ -0,0 +1,127 @@
+package conn
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+type secretConnFn func(io.ReadWriteCloser, crypto.PrivKey) (*SecretConnection, error)
+
+type secretConnResult struct {
+	sc  *SecretConnection
+	err error
+}
+
+// makeSecretConnPairWith runs the handshakes of dialFn and acceptFn over a
+// pipe, and returns their results. The pipe is closed if either fails, so the
+// other returns too.
+func makeSecretConnPairWith(dialFn, acceptFn secretConnFn) (dialed, accepted secretConnResult, dialKey, acceptKey crypto.PrivKey) {
+	fooConn, barConn := makeKVStoreConnPair()
+	dialKey, acceptKey = ed25519.GenPrivKey(), ed25519.GenPrivKey()
+
+	acceptCh := make(chan secretConnResult, 1)
+	go func() {
+		sc, err := acceptFn(barConn, acceptKey)
+		if err != nil {
+			barConn.Close() // nolint: errcheck
+		}
+		acceptCh <- secretConnResult{sc, err}
+	}()
+
+	sc, err := dialFn(fooConn, dialKey)
+	if err != nil {
+		fooConn.Close() // nolint: errcheck
+	}
+	return secretConnResult{sc, err}, <-acceptCh, dialKey, acceptKey
+}
+
+func assertSecretConnsTalk(t *testing.T, foo, bar *SecretConnection) {
+	go func() {
+		_, err := foo.Write([]byte("ping"))
+		assert.NoError(t, err)
+	}()
+	buf := make([]byte, 4)
+	_, err := io.ReadFull(bar, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	go func() {
+		_, err := bar.Write([]byte("pong"))
+		assert.NoError(t, err)
+	}()
+	_, err = io.ReadFull(foo, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "pong", string(buf))
+}
+
+func TestNoiseSecretConnectionHandshake(t *testing.T) {
+	dialed, accepted, dialKey, acceptKey := makeSecretConnPairWith(MakeNoiseSecretConnection, AcceptSecretConnection)
+	require.NoError(t, dialed.err)
+	require.NoError(t, accepted.err)
+	defer dialed.sc.Close()   // nolint: errcheck
+	defer accepted.sc.Close() // nolint: errcheck
+
+	assert.Equal(t, HandshakeNoise, dialed.sc.Handshake())
+	assert.Equal(t, HandshakeNoise, accepted.sc.Handshake())
+	assert.True(t, dialed.sc.RemotePubKey().Equals(acceptKey.PubKey()))
+	assert.True(t, accepted.sc.RemotePubKey().Equals(dialKey.PubKey()))
+	assertSecretConnsTalk(t, dialed.sc, accepted.sc)
+}
+
+func TestAcceptSecretConnectionSTS(t *testing.T) {
+	dialed, accepted, dialKey, acceptKey := makeSecretConnPairWith(MakeSecretConnection, AcceptSecretConnection)
+	require.NoError(t, dialed.err)
+	require.NoError(t, accepted.err)
+	defer dialed.sc.Close()   // nolint: errcheck
+	defer accepted.sc.Close() // nolint: errcheck
+
+	assert.Equal(t, HandshakeSTS, dialed.sc.Handshake())
+	assert.Equal(t, HandshakeSTS, accepted.sc.Handshake())
+	assert.True(t, dialed.sc.RemotePubKey().Equals(acceptKey.PubKey()))
+	assert.True(t, accepted.sc.RemotePubKey().Equals(dialKey.PubKey()))
+	assertSecretConnsTalk(t, dialed.sc, accepted.sc)
+}
+
+func TestNoiseSecretConnectionNotSupported(t *testing.T) {
+	// a peer which only supports the STS handshake
+	dialed, accepted, _, _ := makeSecretConnPairWith(MakeNoiseSecretConnection, MakeSecretConnection)
+	assert.Equal(t, ErrNoiseNotSupported, dialed.err)
+	assert.Error(t, accepted.err)
+}
+
+// tamperConn flips the byte at offset off of the data read from the
+// underlying connection.
+type tamperConn struct {
+	io.ReadWriteCloser
+	off, read int
+}
+
+func (c *tamperConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	if c.read <= c.off && c.off < c.read+n {
+		p[c.off-c.read] ^= 0x01
+	}
+	c.read += n
+	return n, err
+}
+
+func TestNoiseSecretConnectionTampered(t *testing.T) {
+	// offsets in the second handshake message: magic, length, ephemeral key,
+	// then the encrypted static key and payload
+	static := len(noiseMagic) + 2 + noiseKeySize
+	payload := static + noiseKeySize + aeadSizeOverhead
+	for _, off := range []int{static + 1, payload + 1} {
+		dialFn := func(conn io.ReadWriteCloser, priv crypto.PrivKey) (*SecretConnection, error) {
+			return MakeNoiseSecretConnection(&tamperConn{ReadWriteCloser: conn, off: off}, priv)
+		}
+		dialed, accepted, _, _ := makeSecretConnPairWith(dialFn, AcceptSecretConnection)
+		assert.Error(t, dialed.err, "offset %d", off)
+		assert.Error(t, accepted.err, "offset %d", off)
+	}
+}
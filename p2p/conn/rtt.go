@@ -0,0 +1,51 @@
+package conn
+
+import (
+	"sync"
+	"time"
+)
+
+// weight of the last round-trip time in the moving average
+const rttSmoothing = 0.2
+
+// rttMonitor measures the round-trip time of the pings sent on a connection,
+// and keeps an exponentially weighted moving average of them. It is safe for
+// concurrent use.
+type rttMonitor struct {
+	mtx      sync.Mutex
+	pingSent time.Time // zero if no ping is waiting for its pong
+	last     time.Duration
+	avg      time.Duration
+}
+
+// sentPing records that a ping was just sent.
+func (m *rttMonitor) sentPing() {
+	m.mtx.Lock()
+	m.pingSent = time.Now()
+	m.mtx.Unlock()
+}
+
+// receivedPong records the round-trip time of the last ping, if any.
+func (m *rttMonitor) receivedPong() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.pingSent.IsZero() {
+		return
+	}
+	m.last = time.Since(m.pingSent)
+	m.pingSent = time.Time{}
+	if m.avg == 0 {
+		m.avg = m.last
+	} else {
+		m.avg = time.Duration(rttSmoothing*float64(m.last) + (1-rttSmoothing)*float64(m.avg))
+	}
+}
+
+// status returns the last round-trip time and the moving average, which are
+// 0 until the first pong is received.
+func (m *rttMonitor) status() (last, avg time.Duration) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.last, m.avg
+}
@@ -0,0 +1,32 @@
+package conn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTTMonitor(t *testing.T) {
+	var m rttMonitor
+
+	// pongs without a ping are ignored
+	m.receivedPong()
+	last, avg := m.status()
+	assert.Zero(t, last)
+	assert.Zero(t, avg)
+
+	m.sentPing()
+	m.pingSent = m.pingSent.Add(-100 * time.Millisecond)
+	m.receivedPong()
+	last, avg = m.status()
+	assert.True(t, last >= 100*time.Millisecond)
+	assert.Equal(t, last, avg, "the first round-trip time is the average")
+
+	m.sentPing()
+	m.pingSent = m.pingSent.Add(-200 * time.Millisecond)
+	m.receivedPong()
+	last2, avg2 := m.status()
+	assert.True(t, last2 >= 200*time.Millisecond)
+	assert.Equal(t, time.Duration(rttSmoothing*float64(last2)+(1-rttSmoothing)*float64(avg)), avg2)
+}
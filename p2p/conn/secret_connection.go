@@ -50,6 +50,7 @@ type SecretConnection struct {
 	sendSecret *[aeadKeySize]byte
 	remPubKey  crypto.PubKey
 	conn       io.ReadWriteCloser
+	handshake  string
 
 	// net.Conn must be thread safe:
 	// https://golang.org/pkg/net/#Conn.
@@ -72,6 +73,33 @@ type SecretConnection struct {
 // Caller should call conn.Close()
 // See docs/sts-final.pdf for more information.
 func MakeSecretConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*SecretConnection, error) {
+	return makeSTSSecretConnection(conn, conn, locPrivKey)
+}
+
+// AcceptSecretConnection performs the handshake started by the remote peer,
+// which dialed the connection, and returns a new authenticated
+// SecretConnection. The remote peer may use either MakeSecretConnection or
+// MakeNoiseSecretConnection.
+// Returns nil if there is an error in handshake.
+// Caller should call conn.Close()
+func AcceptSecretConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*SecretConnection, error) {
+	// The STS handshake is symmetric, so the remote peer sends its first
+	// message without waiting for ours.
+	prefix := make([]byte, len(noiseMagic))
+	if _, err := io.ReadFull(conn, prefix); err != nil {
+		return nil, err
+	}
+	if bytes.Equal(prefix, noiseMagic) {
+		return makeNoiseResponderConnection(conn, locPrivKey)
+	}
+	// Give the bytes read back to the STS handshake. They are part of the
+	// remote ephemeral public key message.
+	return makeSTSSecretConnection(conn, io.MultiReader(bytes.NewReader(prefix), conn), locPrivKey)
+}
+
+// makeSTSSecretConnection performs the STS handshake, reading the remote
+// ephemeral public key from r.
+func makeSTSSecretConnection(conn io.ReadWriteCloser, r io.Reader, locPrivKey crypto.PrivKey) (*SecretConnection, error) {
 	locPubKey := locPrivKey.PubKey()
 
 	// Generate ephemeral keys for perfect forward secrecy.
@@ -80,7 +108,10 @@ func MakeSecretConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*
 	// Write local ephemeral pubkey and receive one too.
 	// NOTE: every 32-byte string is accepted as a Curve25519 public key
 	// (see DJB's Curve25519 paper: http://cr.yp.to/ecdh/curve25519-20060209.pdf)
-	remEphPub, err := shareEphPubKey(conn, locEphPub)
+	remEphPub, err := shareEphPubKey(struct {
+		io.Reader
+		io.Writer
+	}{r, conn}, locEphPub)
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +140,7 @@ func MakeSecretConnection(conn io.ReadWriteCloser, locPrivKey crypto.PrivKey) (*
 		sendNonce:  new([aeadNonceSize]byte),
 		recvSecret: recvSecret,
 		sendSecret: sendSecret,
+		handshake:  HandshakeSTS,
 	}
 
 	// Sign the challenge bytes for authentication.
@@ -140,6 +172,12 @@ func (sc *SecretConnection) RemotePubKey() crypto.PubKey {
 	return sc.remPubKey
 }
 
+// Handshake returns the handshake which established the connection,
+// HandshakeSTS or HandshakeNoise.
+func (sc *SecretConnection) Handshake() string {
+	return sc.handshake
+}
+
 // Writes encrypted frames of `totalFrameSize + aeadSizeOverhead`.
 // CONTRACT: data smaller than dataMaxSize is written atomically.
 func (sc *SecretConnection) Write(data []byte) (n int, err error) {
@@ -251,7 +289,7 @@ func genEphKeys() (ephPub, ephPriv *[32]byte) {
 	return
 }
 
-func shareEphPubKey(conn io.ReadWriteCloser, locEphPub *[32]byte) (remEphPub *[32]byte, err error) {
+func shareEphPubKey(conn io.ReadWriter, locEphPub *[32]byte) (remEphPub *[32]byte, err error) {
 
 	// Send our pubkey and receive theirs in tandem.
 	var trs, _ = cmn.Parallel(
@@ -0,0 +1,751 @@
+package conn
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	flow "github.com/tendermint/tendermint/libs/flowrate"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+const (
+	// frame types
+	frameData         = byte(0x01)
+	frameWindowUpdate = byte(0x02)
+	framePing         = byte(0x03)
+	framePong         = byte(0x04)
+
+	// type (1 byte) | stream ID (1 byte) | length (4 bytes)
+	frameHeaderSize = 6
+
+	// number of bytes a stream may have in flight before the receiver
+	// acknowledges them
+	defaultStreamWindow = 256 * 1024
+)
+
+var errStreamConnClosed = errors.New("stream connection closed")
+
+/*
+StreamConnection is an alternative to MConnection, which gives each channel its
+own stream over the connection, so that a large message on one channel (e.g. a
+block part) doesn't hold back the messages of the others (e.g. votes).
+
+Every channel is a stream with the channel's ID. Its messages are length
+prefixed and cut into data frames, which the channels write concurrently to the
+connection. Each stream has a window of defaultStreamWindow bytes: the sender
+stops once it has that many bytes unacknowledged, and the receiver acknowledges
+the bytes its reactor consumed with window updates. A reactor which is slow to
+process the messages of a channel thus only stalls that channel.
+
+The messages received on a channel are passed to onReceive in order, from a
+routine of their own, so onReceive may be called concurrently for different
+channels.
+*/
+type StreamConnection struct {
+	cmn.BaseService
+
+	conn          net.Conn
+	bufConnReader *bufio.Reader
+	bufConnWriter *bufio.Writer
+	writeMtx      sync.Mutex // guards bufConnWriter
+	sendMonitor   *flow.Monitor
+	recvMonitor   *flow.Monitor
+	pong          chan struct{}
+	streams       []*stream
+	streamsIdx    map[byte]*stream
+	onReceive     receiveCbFunc
+	onError       errorCbFunc
+	errored       uint32
+	config        MConnConfig
+
+	// Closing quit stops all the routines, closing flushing only stops the
+	// send routines once their queues are empty.
+	quit     chan struct{}
+	flushing chan struct{}
+	sendWg   sync.WaitGroup
+
+	// used to ensure FlushStop and OnStop
+	// are safe to call concurrently.
+	stopMtx sync.Mutex
+
+	flushTimer *cmn.ThrottleTimer // flush writes as necessary but throttled.
+	pingTimer  *cmn.RepeatTimer   // send pings periodically
+
+	// close conn if pong is not received in pongTimeout
+	pongTimer     *time.Timer
+	pongTimeoutCh chan bool // true - timeout, false - peer sent pong
+
+	rtt rttMonitor // round-trip time of the pings
+
+	chStatsTimer *cmn.RepeatTimer // update channel stats periodically
+
+	created time.Time // time of creation
+}
+
+// NewStreamConnection wraps net.Conn and creates a stream connection with a
+// config.
+func NewStreamConnection(conn net.Conn, chDescs []*ChannelDescriptor, onReceive receiveCbFunc, onError errorCbFunc, config MConnConfig) *StreamConnection {
+	if config.PongTimeout >= config.PingInterval {
+		panic("pongTimeout must be less than pingInterval (otherwise, next ping will reset pong timer)")
+	}
+
+	sconn := &StreamConnection{
+		conn:          conn,
+		bufConnReader: bufio.NewReaderSize(conn, minReadBufferSize),
+		bufConnWriter: bufio.NewWriterSize(conn, minWriteBufferSize),
+		sendMonitor:   flow.New(0, 0),
+		recvMonitor:   flow.New(0, 0),
+		pong:          make(chan struct{}, 1),
+		streamsIdx:    map[byte]*stream{},
+		onReceive:     onReceive,
+		onError:       onError,
+		config:        config,
+		quit:          make(chan struct{}),
+		flushing:      make(chan struct{}),
+		created:       time.Now(),
+	}
+
+	for _, desc := range chDescs {
+		s := newStream(sconn, *desc)
+		sconn.streamsIdx[s.desc.ID] = s
+		sconn.streams = append(sconn.streams, s)
+	}
+
+	sconn.BaseService = *cmn.NewBaseService(nil, "StreamConnection", sconn)
+
+	return sconn
+}
+
+func (c *StreamConnection) SetLogger(l log.Logger) {
+	c.BaseService.SetLogger(l)
+	for _, s := range c.streams {
+		s.Logger = l
+	}
+}
+
+// OnStart implements BaseService
+func (c *StreamConnection) OnStart() error {
+	if err := c.BaseService.OnStart(); err != nil {
+		return err
+	}
+	c.flushTimer = cmn.NewThrottleTimer("flush", c.config.FlushThrottle)
+	c.pingTimer = cmn.NewRepeatTimer("ping", c.config.PingInterval)
+	c.pongTimeoutCh = make(chan bool, 1)
+	c.chStatsTimer = cmn.NewRepeatTimer("chStats", updateStats)
+	for _, s := range c.streams {
+		c.sendWg.Add(1)
+		go s.sendRoutine()
+		go s.recvRoutine()
+	}
+	go c.controlRoutine()
+	go c.recvRoutine()
+	return nil
+}
+
+// FlushStop replicates the logic of OnStop.
+// It additionally ensures that all successful
+// .Send() calls will get flushed before closing
+// the connection, unless the peer doesn't take them
+// within defaultSendTimeout.
+func (c *StreamConnection) FlushStop() {
+	c.stopMtx.Lock()
+	defer c.stopMtx.Unlock()
+
+	if c.isClosed() {
+		// already quit via OnStop
+		return
+	}
+
+	c.BaseService.OnStop()
+
+	// Let the send routines empty their queues.
+	close(c.flushing)
+	done := make(chan struct{})
+	go func() {
+		c.sendWg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(defaultSendTimeout):
+		c.Logger.Error("Timed out flushing the stream connection", "conn", c)
+	}
+
+	c.writeMtx.Lock()
+	c.flush()
+	c.writeMtx.Unlock()
+
+	c.close()
+}
+
+// OnStop implements BaseService
+func (c *StreamConnection) OnStop() {
+	c.stopMtx.Lock()
+	defer c.stopMtx.Unlock()
+
+	if c.isClosed() {
+		// already quit via FlushStop
+		return
+	}
+
+	c.BaseService.OnStop()
+	c.close()
+}
+
+// close stops the timers and the routines and closes the connection.
+// c.stopMtx must be held.
+func (c *StreamConnection) close() {
+	c.flushTimer.Stop()
+	c.pingTimer.Stop()
+	c.chStatsTimer.Stop()
+	close(c.quit)
+	c.conn.Close() // nolint: errcheck
+}
+
+func (c *StreamConnection) isClosed() bool {
+	select {
+	case <-c.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *StreamConnection) String() string {
+	return fmt.Sprintf("StreamConn{%v}", c.conn.RemoteAddr())
+}
+
+// flush flushes the buffered frames. c.writeMtx must be held.
+func (c *StreamConnection) flush() {
+	c.Logger.Debug("Flush", "conn", c)
+	err := c.bufConnWriter.Flush()
+	if err != nil {
+		c.Logger.Error("StreamConnection flush failed", "err", err)
+	}
+}
+
+// Catch panics, usually caused by remote disconnects.
+func (c *StreamConnection) _recover() {
+	if r := recover(); r != nil {
+		err := cmn.ErrorWrap(r, "recovered panic in StreamConnection")
+		c.stopForError(err)
+	}
+}
+
+func (c *StreamConnection) stopForError(r interface{}) {
+	c.Stop()
+	if atomic.CompareAndSwapUint32(&c.errored, 0, 1) {
+		if c.onError != nil {
+			c.onError(r)
+		}
+	}
+}
+
+// failed stops the connection for the error, unless it is already closing,
+// in which case the error is most likely caused by closing it.
+func (c *StreamConnection) failed(where string, err error) {
+	if c.IsRunning() && !c.isClosed() {
+		c.Logger.Error("Connection failed @ "+where, "conn", c, "err", err)
+		c.stopForError(err)
+	}
+}
+
+// Queues a message to be sent to channel.
+func (c *StreamConnection) Send(chID byte, msgBytes []byte) bool {
+	if !c.IsRunning() {
+		return false
+	}
+
+	c.Logger.Debug("Send", "channel", chID, "conn", c, "msgBytes", fmt.Sprintf("%X", msgBytes))
+
+	s, ok := c.streamsIdx[chID]
+	if !ok {
+		c.Logger.Error(fmt.Sprintf("Cannot send bytes, unknown channel %X", chID))
+		return false
+	}
+
+	select {
+	case s.sendQueue <- msgBytes:
+		atomic.AddInt32(&s.sendQueueSize, 1)
+		return true
+	case <-time.After(defaultSendTimeout):
+		c.Logger.Debug("Send failed", "channel", chID, "conn", c, "msgBytes", fmt.Sprintf("%X", msgBytes))
+		return false
+	}
+}
+
+// Queues a message to be sent to channel.
+// Nonblocking, returns true if successful.
+func (c *StreamConnection) TrySend(chID byte, msgBytes []byte) bool {
+	if !c.IsRunning() {
+		return false
+	}
+
+	c.Logger.Debug("TrySend", "channel", chID, "conn", c, "msgBytes", fmt.Sprintf("%X", msgBytes))
+
+	s, ok := c.streamsIdx[chID]
+	if !ok {
+		c.Logger.Error(fmt.Sprintf("Cannot send bytes, unknown channel %X", chID))
+		return false
+	}
+
+	select {
+	case s.sendQueue <- msgBytes:
+		atomic.AddInt32(&s.sendQueueSize, 1)
+		return true
+	default:
+		return false
+	}
+}
+
+// CanSend returns true if you can send more data onto the chID, false
+// otherwise. Use only as a heuristic.
+func (c *StreamConnection) CanSend(chID byte) bool {
+	if !c.IsRunning() {
+		return false
+	}
+
+	s, ok := c.streamsIdx[chID]
+	if !ok {
+		c.Logger.Error(fmt.Sprintf("Unknown channel %X", chID))
+		return false
+	}
+	return int(atomic.LoadInt32(&s.sendQueueSize)) < defaultSendQueueCapacity
+}
+
+func (c *StreamConnection) Status() ConnectionStatus {
+	var status ConnectionStatus
+	status.Duration = time.Since(c.created)
+	status.SendMonitor = c.sendMonitor.Status()
+	status.RecvMonitor = c.recvMonitor.Status()
+	status.LastRTT, status.RTT = c.rtt.status()
+	status.Channels = make([]ChannelStatus, len(c.streams))
+	for i, s := range c.streams {
+		sendStatus, recvStatus := s.sendMonitor.Status(), s.recvMonitor.Status()
+		status.Channels[i] = ChannelStatus{
+			ID:                s.desc.ID,
+			SendQueueCapacity: cap(s.sendQueue),
+			SendQueueSize:     int(atomic.LoadInt32(&s.sendQueueSize)),
+			Priority:          s.desc.Priority,
+			RecentlySent:      atomic.LoadInt64(&s.recentlySent),
+			SendBytes:         atomic.LoadInt64(&s.sendTotal),
+			RecvBytes:         atomic.LoadInt64(&s.recvTotal),
+			SendRate:          sendStatus.CurRate,
+			RecvRate:          recvStatus.CurRate,
+		}
+	}
+	return status
+}
+
+// writeFrame writes a frame to the connection. It is goroutine-safe, and
+// blocks in accordance to .sendMonitor throttling.
+func (c *StreamConnection) writeFrame(frameType, streamID byte, length uint32, payload []byte) error {
+	n := frameHeaderSize + len(payload)
+	c.sendMonitor.Limit(n, atomic.LoadInt64(&c.config.SendRate), true)
+	c.config.Bandwidth.limitSend(n)
+
+	var header [frameHeaderSize]byte
+	header[0] = frameType
+	header[1] = streamID
+	binary.BigEndian.PutUint32(header[2:], length)
+
+	c.writeMtx.Lock()
+	_, err := c.bufConnWriter.Write(header[:])
+	if err == nil && len(payload) > 0 {
+		_, err = c.bufConnWriter.Write(payload)
+	}
+	c.writeMtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	c.sendMonitor.Update(n)
+	c.config.Bandwidth.updateSend(n)
+	// NOTE: flushTimer.Set() must be called every time
+	// something is written to .bufConnWriter.
+	c.flushTimer.Set()
+	return nil
+}
+
+// controlRoutine flushes the writes, exchanges pings and pongs with the peer
+// and updates the channel stats.
+func (c *StreamConnection) controlRoutine() {
+	defer c._recover()
+
+FOR_LOOP:
+	for {
+		var err error
+		select {
+		case <-c.flushTimer.Ch:
+			c.writeMtx.Lock()
+			c.flush()
+			c.writeMtx.Unlock()
+		case <-c.chStatsTimer.Chan():
+			for _, s := range c.streams {
+				s.updateStats()
+			}
+		case <-c.pingTimer.Chan():
+			c.Logger.Debug("Send Ping")
+			if err = c.writeFrame(framePing, 0, 0, nil); err != nil {
+				break
+			}
+			c.Logger.Debug("Starting pong timer", "dur", c.config.PongTimeout)
+			c.pongTimer = time.AfterFunc(c.config.PongTimeout, func() {
+				select {
+				case c.pongTimeoutCh <- true:
+				default:
+				}
+			})
+			// don't wait for the flush timer, so that it isn't counted in
+			// the round-trip time
+			c.rtt.sentPing()
+			c.writeMtx.Lock()
+			c.flush()
+			c.writeMtx.Unlock()
+		case timeout := <-c.pongTimeoutCh:
+			if timeout {
+				c.Logger.Debug("Pong timeout")
+				err = errors.New("pong timeout")
+			} else {
+				c.stopPongTimer()
+			}
+		case <-c.pong:
+			c.Logger.Debug("Send Pong")
+			if err = c.writeFrame(framePong, 0, 0, nil); err != nil {
+				break
+			}
+			c.writeMtx.Lock()
+			c.flush()
+			c.writeMtx.Unlock()
+		case <-c.quit:
+			break FOR_LOOP
+		}
+
+		if err != nil {
+			c.failed("controlRoutine", err)
+			break FOR_LOOP
+		}
+	}
+
+	// Cleanup
+	c.stopPongTimer()
+}
+
+// not goroutine-safe
+func (c *StreamConnection) stopPongTimer() {
+	if c.pongTimer != nil {
+		_ = c.pongTimer.Stop()
+		c.pongTimer = nil
+	}
+}
+
+// recvRoutine reads the frames and dispatches them to the streams.
+// Blocks depending on how the connection is throttled.
+// Otherwise, it never blocks: a stream which receives more than its window
+// is a protocol error.
+func (c *StreamConnection) recvRoutine() {
+	defer c._recover()
+
+	maxFrameSize := frameHeaderSize + c.config.MaxPacketMsgPayloadSize
+	var header [frameHeaderSize]byte
+	for {
+		// Block until .recvMonitor says we can read.
+		c.recvMonitor.Limit(maxFrameSize, atomic.LoadInt64(&c.config.RecvRate), true)
+		c.config.Bandwidth.limitRecv(maxFrameSize)
+
+		if _, err := io.ReadFull(c.bufConnReader, header[:]); err != nil {
+			c.failed("recvRoutine (reading frame)", err)
+			return
+		}
+		c.recvMonitor.Update(frameHeaderSize)
+		c.config.Bandwidth.updateRecv(frameHeaderSize)
+		frameType, streamID, length := header[0], header[1], binary.BigEndian.Uint32(header[2:])
+
+		var err error
+		switch frameType {
+		case framePing:
+			// TODO: prevent abuse, as they cause flush()'s.
+			c.Logger.Debug("Receive Ping")
+			select {
+			case c.pong <- struct{}{}:
+			default:
+				// never block
+			}
+		case framePong:
+			c.Logger.Debug("Receive Pong")
+			c.rtt.receivedPong()
+			select {
+			case c.pongTimeoutCh <- false:
+			default:
+				// never block
+			}
+		case frameData, frameWindowUpdate:
+			s, ok := c.streamsIdx[streamID]
+			if !ok {
+				err = fmt.Errorf("Unknown channel %X", streamID)
+				break
+			}
+			if frameType == frameWindowUpdate {
+				err = s.addSendWindow(length)
+				break
+			}
+			if length > uint32(c.config.MaxPacketMsgPayloadSize) {
+				err = fmt.Errorf("Frame exceeds the maximum payload size: %v > %v", length, c.config.MaxPacketMsgPayloadSize)
+				break
+			}
+			payload := make([]byte, length)
+			if _, err = io.ReadFull(c.bufConnReader, payload); err != nil {
+				break
+			}
+			c.recvMonitor.Update(int(length))
+			c.config.Bandwidth.updateRecv(int(length))
+			err = s.push(payload)
+		default:
+			err = fmt.Errorf("Unknown frame type %X", frameType)
+		}
+		if err != nil {
+			c.failed("recvRoutine", err)
+			return
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// stream is a channel of a StreamConnection.
+type stream struct {
+	conn          *StreamConnection
+	desc          ChannelDescriptor
+	sendQueue     chan []byte
+	sendQueueSize int32 // atomic.
+	recentlySent  int64 // exponential moving average
+	sendTotal     int64 // atomic, bytes sent
+	recvTotal     int64 // atomic, bytes received
+	sendMonitor   *flow.Monitor
+	recvMonitor   *flow.Monitor
+	sendLimiter   *rateLimiter
+	recvLimiter   *rateLimiter
+
+	mtx        sync.Mutex
+	sendWindow uint32        // bytes we may send before the peer acknowledges them
+	windowc    chan struct{} // signaled when sendWindow grows
+	recving    []byte        // received bytes the reactor hasn't consumed yet
+	unacked    uint32        // consumed bytes we haven't acknowledged yet
+	recvc      chan struct{} // signaled when bytes are received
+
+	Logger log.Logger
+}
+
+func newStream(conn *StreamConnection, desc ChannelDescriptor) *stream {
+	desc = desc.FillDefaults()
+	if desc.Priority <= 0 {
+		cmn.PanicSanity("Channel default priority must be a positive integer")
+	}
+	return &stream{
+		conn:        conn,
+		desc:        desc,
+		sendQueue:   make(chan []byte, desc.SendQueueCapacity),
+		sendWindow:  defaultStreamWindow,
+		sendMonitor: flow.New(0, 0),
+		recvMonitor: flow.New(0, 0),
+		sendLimiter: newRateLimiter(desc.SendRate),
+		recvLimiter: newRateLimiter(desc.RecvRate),
+		windowc:     make(chan struct{}, 1),
+		recvc:       make(chan struct{}, 1),
+		Logger:      log.NewNopLogger(),
+	}
+}
+
+// sendRoutine writes the queued messages of the stream, each prefixed with
+// its length.
+func (s *stream) sendRoutine() {
+	defer s.conn.sendWg.Done()
+	defer s.conn._recover()
+
+	for {
+		var msgBytes []byte
+		select {
+		case msgBytes = <-s.sendQueue:
+		case <-s.conn.quit:
+			return
+		case <-s.conn.flushing:
+			select {
+			case msgBytes = <-s.sendQueue:
+			default:
+				return
+			}
+		}
+
+		// Wait until the SendRate of the stream allows the message.
+		if !s.wait(s.sendLimiter.take(len(msgBytes))) {
+			return
+		}
+
+		buf := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(msgBytes))
+		buf = append(buf[:binary.PutUvarint(buf, uint64(len(msgBytes)))], msgBytes...)
+		err := s.write(buf)
+		atomic.AddInt32(&s.sendQueueSize, -1)
+		atomic.AddInt64(&s.sendTotal, int64(len(msgBytes)))
+		s.sendMonitor.Update(len(msgBytes))
+		if err != nil {
+			if err != errStreamConnClosed {
+				s.conn.failed("sendRoutine", err)
+			}
+			return
+		}
+	}
+}
+
+// write cuts the bytes into data frames, waiting for the peer to acknowledge
+// the previous ones when the window is full.
+func (s *stream) write(bz []byte) error {
+	for len(bz) > 0 {
+		s.mtx.Lock()
+		n := cmn.MinInt(cmn.MinInt(len(bz), int(s.sendWindow)), s.conn.config.MaxPacketMsgPayloadSize)
+		s.sendWindow -= uint32(n)
+		s.mtx.Unlock()
+
+		if n == 0 {
+			select {
+			case <-s.windowc:
+				continue
+			case <-s.conn.quit:
+				return errStreamConnClosed
+			}
+		}
+
+		if err := s.conn.writeFrame(frameData, s.desc.ID, uint32(n), bz[:n]); err != nil {
+			return err
+		}
+		atomic.AddInt64(&s.recentlySent, int64(n))
+		bz = bz[n:]
+	}
+	return nil
+}
+
+// addSendWindow handles a window update of the peer.
+func (s *stream) addSendWindow(n uint32) error {
+	s.mtx.Lock()
+	if uint64(s.sendWindow)+uint64(n) > defaultStreamWindow {
+		s.mtx.Unlock()
+		return fmt.Errorf("Window update overflows the window of channel %X", s.desc.ID)
+	}
+	s.sendWindow += n
+	s.mtx.Unlock()
+
+	select {
+	case s.windowc <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// push buffers received bytes until the reactor consumes them.
+func (s *stream) push(bz []byte) error {
+	s.mtx.Lock()
+	if len(s.recving)+int(s.unacked)+len(bz) > defaultStreamWindow {
+		s.mtx.Unlock()
+		return fmt.Errorf("Received data exceeds the window of channel %X", s.desc.ID)
+	}
+	s.recving = append(s.recving, bz...)
+	s.mtx.Unlock()
+
+	select {
+	case s.recvc <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Read implements io.Reader, returning the received bytes and acknowledging
+// them once half of the window has been consumed.
+func (s *stream) Read(p []byte) (int, error) {
+	for {
+		s.mtx.Lock()
+		if len(s.recving) > 0 {
+			n := copy(p, s.recving)
+			s.recving = s.recving[n:]
+			s.unacked += uint32(n)
+			var ack uint32
+			if s.unacked >= defaultStreamWindow/2 {
+				ack, s.unacked = s.unacked, 0
+			}
+			s.mtx.Unlock()
+
+			if ack > 0 {
+				if err := s.conn.writeFrame(frameWindowUpdate, s.desc.ID, ack, nil); err != nil {
+					return n, err
+				}
+			}
+			return n, nil
+		}
+		s.mtx.Unlock()
+
+		select {
+		case <-s.recvc:
+		case <-s.conn.quit:
+			return 0, io.EOF
+		}
+	}
+}
+
+// recvRoutine reassembles the messages of the stream and passes them to
+// onReceive.
+func (s *stream) recvRoutine() {
+	defer s.conn._recover()
+
+	r := bufio.NewReaderSize(s, minReadBufferSize)
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == nil && size > uint64(s.desc.RecvMessageCapacity) {
+			err = fmt.Errorf("Received message exceeds available capacity: %v < %v", s.desc.RecvMessageCapacity, size)
+		}
+		var msgBytes []byte
+		if err == nil {
+			msgBytes = make([]byte, size)
+			_, err = io.ReadFull(r, msgBytes)
+		}
+		if err != nil {
+			s.conn.failed("stream recvRoutine", err)
+			return
+		}
+
+		// Wait until the RecvRate of the stream allows the message. As the
+		// stream isn't read meanwhile, the peer eventually runs out of window.
+		if !s.wait(s.recvLimiter.take(len(msgBytes))) {
+			return
+		}
+		atomic.AddInt64(&s.recvTotal, int64(len(msgBytes)))
+		s.recvMonitor.Update(len(msgBytes))
+
+		s.Logger.Debug("Received bytes", "chID", s.desc.ID, "msgBytes", fmt.Sprintf("%X", msgBytes))
+		s.conn.onReceive(s.desc.ID, msgBytes)
+	}
+}
+
+// wait sleeps for d, and returns false if the connection quits meanwhile.
+func (s *stream) wait(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-s.conn.quit:
+		return false
+	}
+}
+
+// Call this periodically to update stats for throttling purposes.
+func (s *stream) updateStats() {
+	// Exponential decay of stats.
+	atomic.StoreInt64(&s.recentlySent, int64(float64(atomic.LoadInt64(&s.recentlySent))*0.8))
+}
@@ -0,0 +1,228 @@
+package conn
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func createStreamConnectionWithCallbacks(conn net.Conn, chDescs []*ChannelDescriptor, onReceive func(chID byte, msgBytes []byte), onError func(r interface{})) *StreamConnection {
+	cfg := DefaultMConnConfig()
+	cfg.SendRate = 100 * 1024 * 1024
+	cfg.RecvRate = 100 * 1024 * 1024
+	c := NewStreamConnection(conn, chDescs, onReceive, onError, cfg)
+	c.SetLogger(log.TestingLogger())
+	return c
+}
+
+func testStreamChDescs() []*ChannelDescriptor {
+	return []*ChannelDescriptor{
+		{ID: 0x01, Priority: 1, SendQueueCapacity: 1},
+		{ID: 0x02, Priority: 1, SendQueueCapacity: 1},
+	}
+}
+
+type streamMsg struct {
+	chID     byte
+	msgBytes []byte
+}
+
+func TestStreamConnectionSendReceive(t *testing.T) {
+	server, client := NetPipe()
+
+	receivedCh := make(chan streamMsg, 10)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- streamMsg{chID, msgBytes}
+	}
+	onError := func(r interface{}) {
+		t.Errorf("unexpected error: %v", r)
+	}
+	sconn1 := createStreamConnectionWithCallbacks(client, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn1.Start())
+	defer sconn1.Stop()
+	sconn2 := createStreamConnectionWithCallbacks(server, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn2.Start())
+	defer sconn2.Stop()
+
+	// a message many times larger than the window
+	bigMsg := cmn.RandBytes(4 * defaultStreamWindow)
+	msgs := []streamMsg{{0x01, []byte("Cyclops")}, {0x02, bigMsg}, {0x01, []byte("Wolverine")}}
+	for _, msg := range msgs {
+		assert.True(t, sconn1.Send(msg.chID, msg.msgBytes))
+	}
+	assert.False(t, sconn1.Send(0x05, []byte("Absorbing Man")), "Send should return false because channel is unknown")
+
+	received := make(map[byte][][]byte)
+	for range msgs {
+		select {
+		case msg := <-receivedCh:
+			received[msg.chID] = append(received[msg.chID], msg.msgBytes)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Did not receive the messages in 5s")
+		}
+	}
+	assert.Equal(t, [][]byte{[]byte("Cyclops"), []byte("Wolverine")}, received[0x01])
+	require.Len(t, received[0x02], 1)
+	assert.True(t, bytes.Equal(bigMsg, received[0x02][0]))
+
+	// both ends account for the message bytes of each channel
+	for _, status := range sconn1.Status().Channels {
+		assert.EqualValues(t, len(bytes.Join(received[status.ID], nil)), status.SendBytes)
+	}
+	for _, status := range sconn2.Status().Channels {
+		assert.EqualValues(t, len(bytes.Join(received[status.ID], nil)), status.RecvBytes)
+	}
+}
+
+func TestStreamConnectionSlowChannelDoesNotBlockOthers(t *testing.T) {
+	server, client := NetPipe()
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+	receivedCh := make(chan streamMsg, 10)
+	onReceive := func(chID byte, msgBytes []byte) {
+		if chID == 0x01 {
+			<-unblock
+		}
+		receivedCh <- streamMsg{chID, msgBytes}
+	}
+	onError := func(r interface{}) {}
+	sconn1 := createStreamConnectionWithCallbacks(client, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn1.Start())
+	defer sconn1.Stop()
+	sconn2 := createStreamConnectionWithCallbacks(server, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn2.Start())
+	defer sconn2.Stop()
+
+	// the reactor of channel 0x01 is stuck on the first message, and the
+	// second one fills the window of the channel
+	assert.True(t, sconn1.Send(0x01, []byte("Juggernaut")))
+	assert.True(t, sconn1.Send(0x01, cmn.RandBytes(2*defaultStreamWindow)))
+
+	assert.True(t, sconn1.Send(0x02, []byte("Nightcrawler")))
+	select {
+	case msg := <-receivedCh:
+		assert.Equal(t, streamMsg{0x02, []byte("Nightcrawler")}, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Channel 0x02 was blocked by channel 0x01")
+	}
+}
+
+func TestStreamConnectionRTT(t *testing.T) {
+	server, client := NetPipe()
+
+	conns := make([]*StreamConnection, 2)
+	for i, conn := range []net.Conn{client, server} {
+		conns[i] = createStreamConnectionWithCallbacks(conn, testStreamChDescs(), nil, nil)
+		conns[i].config.PingInterval = 90 * time.Millisecond
+		conns[i].config.PongTimeout = 45 * time.Millisecond
+		require.Nil(t, conns[i].Start())
+		defer conns[i].Stop()
+	}
+
+	// wait for a couple of pings
+	time.Sleep(250 * time.Millisecond)
+
+	for _, sconn := range conns {
+		status := sconn.Status()
+		assert.True(t, status.RTT > 0, "RTT should be measured")
+		assert.True(t, status.RTT < 45*time.Millisecond, "RTT should be less than PongTimeout")
+	}
+}
+
+func TestStreamConnectionSendFlushStop(t *testing.T) {
+	server, client := NetPipe()
+
+	receivedCh := make(chan streamMsg, 10)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- streamMsg{chID, msgBytes}
+	}
+	onError := func(r interface{}) {}
+	sconn1 := createStreamConnectionWithCallbacks(client, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn1.Start())
+	defer sconn1.Stop()
+	sconn2 := createStreamConnectionWithCallbacks(server, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn2.Start())
+	defer sconn2.Stop()
+
+	assert.True(t, sconn1.Send(0x01, []byte("abc")))
+	assert.True(t, sconn1.Send(0x02, []byte("def")))
+
+	// stop the conn - it should flush all the streams
+	sconn1.FlushStop()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-receivedCh:
+		case <-time.After(3 * time.Second):
+			t.Fatal("timed out waiting for msgs to be read")
+		}
+	}
+}
+
+func TestStreamConnectionStopsAndReturnsError(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+
+	errorsCh := make(chan interface{}, 1)
+	onReceive := func(chID byte, msgBytes []byte) {}
+	onError := func(r interface{}) {
+		errorsCh <- r
+	}
+	sconn := createStreamConnectionWithCallbacks(client, testStreamChDescs(), onReceive, onError)
+	require.Nil(t, sconn.Start())
+	defer sconn.Stop()
+
+	if err := client.Close(); err != nil {
+		t.Error(err)
+	}
+
+	select {
+	case err := <-errorsCh:
+		assert.NotNil(t, err)
+		assert.False(t, sconn.IsRunning())
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Did not receive error in 500ms")
+	}
+}
+
+func TestStreamConnectionReadErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		frame []byte
+	}{
+		{"unknown channel", []byte{frameData, 0x03, 0, 0, 0, 1, 0xFF}},
+		{"unknown frame type", []byte{0xFF, 0x01, 0, 0, 0, 0}},
+		{"frame too long", []byte{frameData, 0x01, 0, 0, 0xFF, 0xFF}},
+		{"window overflow", []byte{frameWindowUpdate, 0x01, 0, 0, 0, 1}},
+	}
+
+	for _, tc := range testCases {
+		server, client := NetPipe()
+
+		errorsCh := make(chan interface{}, 1)
+		onReceive := func(chID byte, msgBytes []byte) {}
+		onError := func(r interface{}) {
+			errorsCh <- r
+		}
+		sconn := createStreamConnectionWithCallbacks(server, testStreamChDescs(), onReceive, onError)
+		require.Nil(t, sconn.Start())
+
+		go client.Write(tc.frame) // nolint: errcheck
+
+		select {
+		case <-errorsCh:
+		case <-time.After(time.Second):
+			t.Errorf("%s: expected an error", tc.name)
+		}
+		sconn.Stop()
+		client.Close() // nolint: errcheck
+	}
+}
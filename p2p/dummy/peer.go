@@ -2,6 +2,7 @@ package dummy
 
 import (
 	"net"
+	"time"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
 	p2p "github.com/tendermint/tendermint/p2p"
@@ -98,3 +99,8 @@ func (p *peer) Get(key string) interface{} {
 func (p *peer) OriginalAddr() *p2p.NetAddress {
 	return nil
 }
+
+// RTT always returns 0.
+func (p *peer) RTT() time.Duration {
+	return 0
+}
@@ -20,10 +20,12 @@ type ErrRejected struct {
 	err               error
 	id                ID
 	isAuthFailure     bool
+	isBanned          bool
 	isDuplicate       bool
 	isFiltered        bool
 	isIncompatible    bool
 	isNodeInfoInvalid bool
+	isNotAllowed      bool
 	isSelf            bool
 }
 
@@ -37,6 +39,19 @@ func (e ErrRejected) Error() string {
 		return fmt.Sprintf("auth failure: %s", e.err)
 	}
 
+	if e.isBanned {
+		if e.id != "" {
+			return fmt.Sprintf("banned ID<%v>", e.id)
+		}
+		if e.conn != nil {
+			return fmt.Sprintf(
+				"banned CONN<%s>",
+				e.conn.RemoteAddr().String(),
+			)
+		}
+		return fmt.Sprintf("banned ADDR<%v>", e.addr)
+	}
+
 	if e.isDuplicate {
 		if e.conn != nil {
 			return fmt.Sprintf(
@@ -71,6 +86,10 @@ func (e ErrRejected) Error() string {
 		return fmt.Sprintf("invalid NodeInfo: %s", e.err)
 	}
 
+	if e.isNotAllowed {
+		return fmt.Sprintf("not allowed ID<%v>", e.id)
+	}
+
 	if e.isSelf {
 		return fmt.Sprintf("self ID<%v>", e.id)
 	}
@@ -81,6 +100,9 @@ func (e ErrRejected) Error() string {
 // IsAuthFailure when Peer authentication was unsuccessful.
 func (e ErrRejected) IsAuthFailure() bool { return e.isAuthFailure }
 
+// IsBanned when Peer ID or IP is in the ban list.
+func (e ErrRejected) IsBanned() bool { return e.isBanned }
+
 // IsDuplicate when Peer ID or IP are present already.
 func (e ErrRejected) IsDuplicate() bool { return e.isDuplicate }
 
@@ -93,6 +115,9 @@ func (e ErrRejected) IsIncompatible() bool { return e.isIncompatible }
 // IsNodeInfoInvalid when the sent NodeInfo is not valid.
 func (e ErrRejected) IsNodeInfoInvalid() bool { return e.isNodeInfoInvalid }
 
+// IsNotAllowed when Peer ID is not in the allow list.
+func (e ErrRejected) IsNotAllowed() bool { return e.isNotAllowed }
+
 // IsSelf when Peer is our own node.
 func (e ErrRejected) IsSelf() bool { return e.isSelf }
 
@@ -0,0 +1,287 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// Fault drops or delays the messages sent to and received from some peers on
+// some channels. It is injected with FaultInjector.AddFault.
+type Fault struct {
+	ID          int           `json:"id"`
+	Peers       []ID          `json:"peers"`        // all the peers if empty
+	ChIDs       []int         `json:"ch_ids"`       // all the channels if empty
+	DropPercent int           `json:"drop_percent"` // percentage of the messages dropped
+	Delay       time.Duration `json:"delay"`        // delay of the messages which aren't dropped
+}
+
+func (f Fault) matches(peerID ID, chID byte) bool {
+	if len(f.Peers) > 0 {
+		found := false
+		for _, id := range f.Peers {
+			if id == peerID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(f.ChIDs) > 0 {
+		found := false
+		for _, id := range f.ChIDs {
+			if id == int(chID) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FaultInjector injects faults into the p2p layer of a node, to test how the
+// network recovers from them: it partitions the node from sets of peers, and
+// drops or delays the messages of some peers on some channels. Faults can be
+// injected and healed at runtime, e.g. with the unsafe RPC endpoints.
+//
+// It is enabled with SwitchFaultInjector, and must never be used in
+// production.
+type FaultInjector struct {
+	mtx         sync.Mutex
+	seed        int64
+	rng         *cmn.Rand
+	lastID      int
+	faults      []Fault
+	partitioned map[ID]struct{}
+}
+
+// NewFaultInjector returns a FaultInjector without faults. The messages are
+// dropped at random from the seed, so that a run can be reproduced, or from a
+// random seed if it is 0.
+func NewFaultInjector(seed int64) *FaultInjector {
+	if seed == 0 {
+		seed = cmn.RandInt63()
+	}
+	rng := cmn.NewRand()
+	rng.Seed(seed)
+	return &FaultInjector{
+		seed:        seed,
+		rng:         rng,
+		partitioned: make(map[ID]struct{}),
+	}
+}
+
+// Seed returns the seed of the messages dropped at random.
+func (fi *FaultInjector) Seed() int64 {
+	return fi.seed
+}
+
+// AddFault injects the fault, and returns it with its ID.
+func (fi *FaultInjector) AddFault(f Fault) (Fault, error) {
+	if f.DropPercent < 0 || f.DropPercent > 100 {
+		return f, fmt.Errorf("drop percentage must be between 0 and 100, got %d", f.DropPercent)
+	}
+	if f.Delay < 0 {
+		return f, errors.New("delay can't be negative")
+	}
+	for _, chID := range f.ChIDs {
+		if chID < 0 || chID > 0xff {
+			return f, fmt.Errorf("invalid channel ID %d", chID)
+		}
+	}
+	if f.DropPercent == 0 && f.Delay == 0 {
+		return f, errors.New("fault neither drops nor delays messages")
+	}
+
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	fi.lastID++
+	f.ID = fi.lastID
+	fi.faults = append(fi.faults, f)
+	return f, nil
+}
+
+// RemoveFault removes the fault with the given ID.
+func (fi *FaultInjector) RemoveFault(id int) error {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	for i, f := range fi.faults {
+		if f.ID == id {
+			fi.faults = append(fi.faults[:i], fi.faults[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("no fault with ID %d", id)
+}
+
+// Faults returns the injected faults.
+func (fi *FaultInjector) Faults() []Fault {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	return append([]Fault{}, fi.faults...)
+}
+
+// Partition partitions the node from the peers: they are rejected until
+// healed. The Switch disconnects from them with Switch.Partition.
+func (fi *FaultInjector) Partition(ids []ID) {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	for _, id := range ids {
+		fi.partitioned[id] = struct{}{}
+	}
+}
+
+// Heal heals the partitions from the peers, or all of them if ids is empty.
+func (fi *FaultInjector) Heal(ids []ID) {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	if len(ids) == 0 {
+		fi.partitioned = make(map[ID]struct{})
+		return
+	}
+	for _, id := range ids {
+		delete(fi.partitioned, id)
+	}
+}
+
+// Partitioned returns the IDs of the peers the node is partitioned from,
+// sorted.
+func (fi *FaultInjector) Partitioned() []ID {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	ids := make([]ID, 0, len(fi.partitioned))
+	for id := range fi.partitioned {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// IsPartitioned returns true if the node is partitioned from the peer. It's
+// false if fi is nil.
+func (fi *FaultInjector) IsPartitioned(id ID) bool {
+	if fi == nil {
+		return false
+	}
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	_, ok := fi.partitioned[id]
+	return ok
+}
+
+// Clear heals all the partitions and removes all the faults.
+func (fi *FaultInjector) Clear() {
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	fi.faults = nil
+	fi.partitioned = make(map[ID]struct{})
+}
+
+// fault returns whether the message sent to or received from the peer on chID
+// must be dropped and, if not, how long it must be delayed, according to the
+// matching faults. It's a no-op if fi is nil.
+func (fi *FaultInjector) fault(peerID ID, chID byte) (drop bool, delay time.Duration) {
+	if fi == nil {
+		return false, 0
+	}
+	fi.mtx.Lock()
+	defer fi.mtx.Unlock()
+	for _, f := range fi.faults {
+		if !f.matches(peerID, chID) {
+			continue
+		}
+		if f.DropPercent > 0 && fi.rng.Intn(100) < f.DropPercent {
+			return true, 0
+		}
+		if f.Delay > delay {
+			delay = f.Delay
+		}
+	}
+	return false, delay
+}
+
+//-----------------------------------------------------------------------------
+
+// delayedMsg is a message delayed until due.
+type delayedMsg struct {
+	due      time.Time
+	msgBytes []byte
+}
+
+// delayQueue delays the messages sent to or received from a peer on a
+// channel. They are delivered in order by a single routine, even if the
+// faults delaying them change in between.
+type delayQueue struct {
+	mtx     sync.Mutex
+	msgs    []delayedMsg
+	pushed  chan struct{}
+	deliver func(msgBytes []byte)
+}
+
+func newDelayQueue(deliver func(msgBytes []byte)) *delayQueue {
+	return &delayQueue{
+		pushed:  make(chan struct{}, 1),
+		deliver: deliver,
+	}
+}
+
+// push delays the message, which is delivered after the messages already
+// pushed.
+func (q *delayQueue) push(delay time.Duration, msgBytes []byte) {
+	q.mtx.Lock()
+	q.msgs = append(q.msgs, delayedMsg{time.Now().Add(delay), msgBytes})
+	q.mtx.Unlock()
+	select {
+	case q.pushed <- struct{}{}:
+	default:
+	}
+}
+
+// pending returns true if messages are waiting to be delivered.
+func (q *delayQueue) pending() bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return len(q.msgs) > 0
+}
+
+// run delivers the messages once due, until quit is closed.
+func (q *delayQueue) run(quit <-chan struct{}) {
+	for {
+		q.mtx.Lock()
+		if len(q.msgs) == 0 {
+			q.mtx.Unlock()
+			select {
+			case <-q.pushed:
+				continue
+			case <-quit:
+				return
+			}
+		}
+		msg := q.msgs[0]
+		q.mtx.Unlock()
+
+		if wait := time.Until(msg.due); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-quit:
+				timer.Stop()
+				return
+			}
+		}
+
+		q.mtx.Lock()
+		q.msgs = q.msgs[1:]
+		q.mtx.Unlock()
+		q.deliver(msg.msgBytes)
+	}
+}
@@ -0,0 +1,208 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+func TestFaultInjectorFaults(t *testing.T) {
+	fi := NewFaultInjector(0)
+
+	for _, f := range []Fault{
+		{},
+		{DropPercent: 101},
+		{DropPercent: -1},
+		{Delay: -time.Second},
+		{ChIDs: []int{0x100}, DropPercent: 10},
+	} {
+		_, err := fi.AddFault(f)
+		assert.Error(t, err, "%+v", f)
+	}
+
+	drop, err := fi.AddFault(Fault{Peers: []ID{"peer1"}, ChIDs: []int{0x20}, DropPercent: 100})
+	require.NoError(t, err)
+	_, err = fi.AddFault(Fault{ChIDs: []int{0x20, 0x21}, Delay: time.Second})
+	require.NoError(t, err)
+	_, err = fi.AddFault(Fault{Delay: 2 * time.Second})
+	require.NoError(t, err)
+	assert.Len(t, fi.Faults(), 3)
+
+	testCases := []struct {
+		peerID ID
+		chID   byte
+		drop   bool
+		delay  time.Duration
+	}{
+		{"peer1", 0x20, true, 0},
+		{"peer2", 0x20, false, 2 * time.Second},
+		{"peer1", 0x22, false, 2 * time.Second},
+	}
+	for i, tc := range testCases {
+		drop, delay := fi.fault(tc.peerID, tc.chID)
+		assert.Equal(t, tc.drop, drop, "#%d", i)
+		assert.Equal(t, tc.delay, delay, "#%d", i)
+	}
+
+	require.NoError(t, fi.RemoveFault(drop.ID))
+	assert.Error(t, fi.RemoveFault(drop.ID))
+	d, delay := fi.fault("peer1", 0x20)
+	assert.False(t, d)
+	assert.Equal(t, 2*time.Second, delay)
+
+	fi.Clear()
+	assert.Empty(t, fi.Faults())
+	d, delay = fi.fault("peer1", 0x20)
+	assert.False(t, d)
+	assert.Zero(t, delay)
+
+	var nilFI *FaultInjector
+	d, delay = nilFI.fault("peer1", 0x20)
+	assert.False(t, d)
+	assert.Zero(t, delay)
+	assert.False(t, nilFI.IsPartitioned("peer1"))
+}
+
+func TestFaultInjectorPartitions(t *testing.T) {
+	fi := NewFaultInjector(0)
+
+	fi.Partition([]ID{"peer2", "peer1", "peer3"})
+	assert.Equal(t, []ID{"peer1", "peer2", "peer3"}, fi.Partitioned())
+	assert.True(t, fi.IsPartitioned("peer1"))
+
+	fi.Heal([]ID{"peer1"})
+	assert.False(t, fi.IsPartitioned("peer1"))
+	assert.Equal(t, []ID{"peer2", "peer3"}, fi.Partitioned())
+
+	fi.Heal(nil)
+	assert.Empty(t, fi.Partitioned())
+}
+
+func TestSwitchPartition(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	sw := MakeSwitch(cfg, 1, "testing", "123.123.123", initSwitchFunc)
+	require.Nil(sw.Start())
+	defer sw.Stop()
+	require.Error(sw.Partition([]ID{"peer1"}), "fault injection is disabled")
+
+	sw = MakeSwitch(cfg, 2, "testing", "123.123.123", initSwitchFunc, SwitchFaultInjector(NewFaultInjector(0)))
+	require.Nil(sw.Start())
+	defer sw.Stop()
+
+	// simulate remote peer
+	rp := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	rp.Start()
+	defer rp.Stop()
+
+	require.Nil(sw.DialPeerWithAddress(rp.Addr(), false))
+	p := sw.Peers().Get(rp.ID())
+	require.NotNil(p)
+
+	// partitioned peers are disconnected and rejected
+	require.Nil(sw.Partition([]ID{rp.ID()}))
+	assert.False(p.IsRunning())
+	assert.Nil(sw.Peers().Get(rp.ID()))
+
+	err := sw.DialPeerWithAddress(rp.Addr(), false)
+	require.NotNil(err)
+	e, ok := err.(ErrRejected)
+	require.True(ok, "%v", err)
+	assert.True(e.IsFiltered())
+
+	// until healed
+	require.Nil(sw.Heal(nil))
+	require.Nil(sw.DialPeerWithAddress(rp.Addr(), false))
+	assert.NotNil(sw.Peers().Get(rp.ID()))
+}
+
+func TestSwitchFaultsDropAndDelayMessages(t *testing.T) {
+	faults := NewFaultInjector(0)
+	s1, s2 := MakeSwitchPair(t, func(i int, sw *Switch) *Switch {
+		sw = initSwitchFunc(i, sw)
+		if i == 0 {
+			SwitchFaultInjector(faults)(sw)
+		}
+		return sw
+	})
+	defer s1.Stop()
+	defer s2.Stop()
+
+	_, err := faults.AddFault(Fault{ChIDs: []int{0x00}, DropPercent: 100})
+	require.NoError(t, err)
+	_, err = faults.AddFault(Fault{ChIDs: []int{0x02}, Delay: 300 * time.Millisecond})
+	require.NoError(t, err)
+
+	// dropped when sent on 0x00, not on 0x01
+	s1.Broadcast(byte(0x00), []byte("dropped"))
+	s1.Broadcast(byte(0x01), []byte("sent"))
+	assertMsgReceivedWithTimeout(t, []byte("sent"), byte(0x01), s2.Reactor("foo").(*TestReactor), 10*time.Millisecond, 5*time.Second)
+	assert.Empty(t, s2.Reactor("foo").(*TestReactor).getMsgs(0x00))
+
+	// dropped when received on 0x00
+	s2.Broadcast(byte(0x00), []byte("dropped"))
+	s2.Broadcast(byte(0x01), []byte("received"))
+	assertMsgReceivedWithTimeout(t, []byte("received"), byte(0x01), s1.Reactor("foo").(*TestReactor), 10*time.Millisecond, 5*time.Second)
+	assert.Empty(t, s1.Reactor("foo").(*TestReactor).getMsgs(0x00))
+
+	// delayed when received on 0x02
+	start := time.Now()
+	s2.Broadcast(byte(0x02), []byte("delayed"))
+	assertMsgReceivedWithTimeout(t, []byte("delayed"), byte(0x02), s1.Reactor("bar").(*TestReactor), 10*time.Millisecond, 5*time.Second)
+	assert.True(t, time.Since(start) >= 300*time.Millisecond, "received after %v", time.Since(start))
+}
+
+func TestFaultInjectorSeed(t *testing.T) {
+	fi1, fi2 := NewFaultInjector(42), NewFaultInjector(42)
+	assert.EqualValues(t, 42, fi1.Seed())
+	assert.NotZero(t, NewFaultInjector(0).Seed())
+
+	for _, fi := range []*FaultInjector{fi1, fi2} {
+		_, err := fi.AddFault(Fault{DropPercent: 50})
+		require.NoError(t, err)
+	}
+	// the same messages are dropped with the same seed
+	for i := 0; i < 100; i++ {
+		drop1, _ := fi1.fault("peer1", 0x20)
+		drop2, _ := fi2.fault("peer1", 0x20)
+		require.Equal(t, drop1, drop2, "#%d", i)
+	}
+}
+
+func TestSwitchFaultsKeepMessagesInOrder(t *testing.T) {
+	faults := NewFaultInjector(0)
+	s1, s2 := MakeSwitchPair(t, func(i int, sw *Switch) *Switch {
+		sw = initSwitchFunc(i, sw)
+		if i == 0 {
+			SwitchFaultInjector(faults)(sw)
+		}
+		return sw
+	})
+	defer s1.Stop()
+	defer s2.Stop()
+
+	// the messages sent after the fault is removed wait for the delayed ones
+	peer := s1.Peers().List()[0]
+	fault, err := faults.AddFault(Fault{ChIDs: []int{0x02}, Delay: 300 * time.Millisecond})
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		require.True(t, peer.Send(0x02, []byte{byte(i)}))
+	}
+	require.NoError(t, faults.RemoveFault(fault.ID))
+	for i := 5; i < 10; i++ {
+		require.True(t, peer.Send(0x02, []byte{byte(i)}))
+	}
+
+	reactor := s2.Reactor("bar").(*TestReactor)
+	for start := time.Now(); len(reactor.getMsgs(0x02)) < 10; {
+		require.True(t, time.Since(start) < 5*time.Second, "received %d messages", len(reactor.getMsgs(0x02)))
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i, msg := range reactor.getMsgs(0x02) {
+		assert.Equal(t, []byte{byte(i)}, msg.Bytes)
+	}
+}
@@ -13,12 +13,14 @@ const MetricsSubsystem = "p2p"
 type Metrics struct {
 	// Number of peers.
 	Peers metrics.Gauge
-	// Number of bytes received from a given peer.
+	// Number of bytes received from a given peer, per channel.
 	PeerReceiveBytesTotal metrics.Counter
-	// Number of bytes sent to a given peer.
+	// Number of bytes sent to a given peer, per channel.
 	PeerSendBytesTotal metrics.Counter
 	// Pending bytes to be sent to a given peer.
 	PeerPendingSendBytes metrics.Gauge
+	// Moving average of the ping round-trip time to a given peer, in seconds.
+	PeerRTT metrics.Gauge
 	// Number of transactions submitted by each peer.
 	NumTxs metrics.Gauge
 }
@@ -36,20 +38,26 @@ func PrometheusMetrics(namespace string) *Metrics {
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "peer_receive_bytes_total",
-			Help:      "Number of bytes received from a given peer.",
-		}, []string{"peer_id"}),
+			Help:      "Number of bytes received from a given peer, per channel.",
+		}, []string{"peer_id", "chID"}),
 		PeerSendBytesTotal: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "peer_send_bytes_total",
-			Help:      "Number of bytes sent to a given peer.",
-		}, []string{"peer_id"}),
+			Help:      "Number of bytes sent to a given peer, per channel.",
+		}, []string{"peer_id", "chID"}),
 		PeerPendingSendBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "peer_pending_send_bytes",
 			Help:      "Number of pending bytes to be sent to a given peer.",
 		}, []string{"peer_id"}),
+		PeerRTT: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_rtt_seconds",
+			Help:      "Moving average of the ping round-trip time to a given peer, in seconds.",
+		}, []string{"peer_id"}),
 		NumTxs: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
@@ -66,6 +74,7 @@ func NopMetrics() *Metrics {
 		PeerReceiveBytesTotal: discard.NewCounter(),
 		PeerSendBytesTotal:    discard.NewCounter(),
 		PeerPendingSendBytes:  discard.NewGauge(),
+		PeerRTT:               discard.NewGauge(),
 		NumTxs:                discard.NewGauge(),
 	}
 }
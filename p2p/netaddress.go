@@ -220,7 +220,8 @@ func (na *NetAddress) DialTimeout(timeout time.Duration) (net.Conn, error) {
 func (na *NetAddress) Routable() bool {
 	// TODO(oga) bitcoind doesn't include RFC3849 here, but should we?
 	return na.Valid() && !(na.RFC1918() || na.RFC3927() || na.RFC4862() ||
-		na.RFC4193() || na.RFC4843() || na.Local())
+		na.RFC4193() || na.RFC4843() || na.RFC7343() || na.Local() ||
+		na.IP.IsMulticast())
 }
 
 // For IPv4 these are either a 0 or all bits set address. For IPv6 a zero
@@ -277,7 +278,7 @@ func (na *NetAddress) ReachabilityTo(o *NetAddress) int {
 	} else /* ipv6 */ {
 		var tunnelled bool
 		// Is our v6 is tunnelled?
-		if o.RFC3964() || o.RFC6052() || o.RFC6145() {
+		if na.RFC3964() || na.RFC6052() || na.RFC6145() {
 			tunnelled = true
 		}
 		if !o.Routable() {
@@ -301,9 +302,10 @@ func (na *NetAddress) ReachabilityTo(o *NetAddress) int {
 // RFC4193: IPv6 unique local (FC00::/7)
 // RFC4380: IPv6 Teredo tunneling (2001::/32)
 // RFC4843: IPv6 ORCHID: (2001:10::/28)
-// RFC4862: IPv6 Autoconfig (FE80::/64)
+// RFC4862: IPv6 Autoconfig, link-local (FE80::/10)
 // RFC6052: IPv6 well known prefix (64:FF9B::/96)
 // RFC6145: IPv6 IPv4 translated address ::FFFF:0:0:0/96
+// RFC7343: IPv6 ORCHIDv2 (2001:20::/28)
 var rfc1918_10 = net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(8, 32)}
 var rfc1918_192 = net.IPNet{IP: net.ParseIP("192.168.0.0"), Mask: net.CIDRMask(16, 32)}
 var rfc1918_172 = net.IPNet{IP: net.ParseIP("172.16.0.0"), Mask: net.CIDRMask(12, 32)}
@@ -313,9 +315,10 @@ var rfc3964 = net.IPNet{IP: net.ParseIP("2002::"), Mask: net.CIDRMask(16, 128)}
 var rfc4193 = net.IPNet{IP: net.ParseIP("FC00::"), Mask: net.CIDRMask(7, 128)}
 var rfc4380 = net.IPNet{IP: net.ParseIP("2001::"), Mask: net.CIDRMask(32, 128)}
 var rfc4843 = net.IPNet{IP: net.ParseIP("2001:10::"), Mask: net.CIDRMask(28, 128)}
-var rfc4862 = net.IPNet{IP: net.ParseIP("FE80::"), Mask: net.CIDRMask(64, 128)}
+var rfc4862 = net.IPNet{IP: net.ParseIP("FE80::"), Mask: net.CIDRMask(10, 128)}
 var rfc6052 = net.IPNet{IP: net.ParseIP("64:FF9B::"), Mask: net.CIDRMask(96, 128)}
 var rfc6145 = net.IPNet{IP: net.ParseIP("::FFFF:0:0:0"), Mask: net.CIDRMask(96, 128)}
+var rfc7343 = net.IPNet{IP: net.ParseIP("2001:20::"), Mask: net.CIDRMask(28, 128)}
 var zero4 = net.IPNet{IP: net.ParseIP("0.0.0.0"), Mask: net.CIDRMask(8, 32)}
 
 func (na *NetAddress) RFC1918() bool {
@@ -332,6 +335,7 @@ func (na *NetAddress) RFC4843() bool { return rfc4843.Contains(na.IP) }
 func (na *NetAddress) RFC4862() bool { return rfc4862.Contains(na.IP) }
 func (na *NetAddress) RFC6052() bool { return rfc6052.Contains(na.IP) }
 func (na *NetAddress) RFC6145() bool { return rfc6145.Contains(na.IP) }
+func (na *NetAddress) RFC7343() bool { return rfc7343.Contains(na.IP) }
 
 func removeProtocolIfDefined(addr string) string {
 	if strings.Contains(addr, "://") {
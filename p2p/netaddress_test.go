@@ -150,3 +150,45 @@ func TestNetAddressReachabilityTo(t *testing.T) {
 		assert.Equal(t, tc.reachability, addr.ReachabilityTo(other))
 	}
 }
+
+func TestNetAddressIPv6(t *testing.T) {
+	addr, err := NewNetAddressStringWithOptionalID("tcp://deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@[2600:1::1]:26656")
+	require.Nil(t, err)
+	assert.Equal(t, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@[2600:1::1]:26656", addr.String())
+	assert.Equal(t, "[2600:1::1]:26656", addr.DialString())
+
+	testCases := []struct {
+		addr     string
+		valid    bool
+		local    bool
+		routable bool
+	}{
+		{"[::1]:26656", true, true, false},
+		{"[::]:26656", false, false, false},
+		{"[2600:1::1]:26656", true, false, true},
+		{"[2001:db8::1]:26656", false, false, false}, // RFC3849
+		{"[fd00::1]:26656", true, false, false},      // RFC4193
+		{"[fe80::1]:26656", true, false, false},      // RFC4862
+		{"[fe80:1::1]:26656", true, false, false},    // RFC4862
+		{"[2001:10::1]:26656", true, false, false},   // RFC4843
+		{"[2001:20::1]:26656", true, false, false},   // RFC7343
+		{"[ff02::1]:26656", true, false, false},      // multicast
+	}
+	for _, tc := range testCases {
+		addr, err := NewNetAddressStringWithOptionalID(tc.addr)
+		require.Nil(t, err)
+
+		assert.Equal(t, tc.valid, addr.Valid(), tc.addr)
+		assert.Equal(t, tc.local, addr.Local(), tc.addr)
+		assert.Equal(t, tc.routable, addr.Routable(), tc.addr)
+	}
+
+	ipv6 := addr
+	ipv4, err := NewNetAddressStringWithOptionalID("1.2.3.4:26656")
+	require.Nil(t, err)
+	sixToFour, err := NewNetAddressStringWithOptionalID("[2002:102:304::1]:26656")
+	require.Nil(t, err)
+	// a native IPv6 address reaches IPv6 better than a tunnelled one
+	assert.True(t, ipv6.ReachabilityTo(ipv6) > sixToFour.ReachabilityTo(ipv6))
+	assert.True(t, ipv6.ReachabilityTo(ipv6) > ipv6.ReachabilityTo(ipv4))
+}
@@ -11,6 +11,8 @@ import (
 const (
 	maxNodeInfoSize = 10240 // 10Kb
 	maxNumChannels  = 16    // plenty of room for upgrades, for now
+
+	maxNumListenAddrs = 8 // more than enough for a few interfaces, IPv4 and IPv6
 )
 
 // Max size of the NodeInfo struct
@@ -31,6 +33,7 @@ type NodeInfo interface {
 type nodeInfoAddress interface {
 	ID() ID
 	NetAddress() *NetAddress
+	NetAddresses() []*NetAddress
 }
 
 // nodeInfoTransport validates a nodeInfo and checks
@@ -90,12 +93,20 @@ type DefaultNodeInfo struct {
 	// ASCIIText fields
 	Moniker string               `json:"moniker"` // arbitrary moniker
 	Other   DefaultNodeInfoOther `json:"other"`   // other application specific data
+
+	// Other addresses accepting incoming, e.g. the IPv6 ones of a dual-stack
+	// node advertising an IPv4 ListenAddr. Last, so that the nodes which
+	// don't know about it can still decode the NodeInfo.
+	ListenAddrs []string `json:"listen_addrs"`
 }
 
 // DefaultNodeInfoOther is the misc. applcation specific data
 type DefaultNodeInfoOther struct {
 	TxIndex    string `json:"tx_index"`
 	RPCAddress string `json:"rpc_address"`
+	// StreamMux is "on" if the node runs a StreamTransport, which gives each
+	// channel its own stream when the peer's StreamMux is "on" too.
+	StreamMux string `json:"stream_mux"`
 }
 
 // ID returns the node's peer ID.
@@ -106,8 +117,8 @@ func (info DefaultNodeInfo) ID() ID {
 // Validate checks the self-reported DefaultNodeInfo is safe.
 // It returns an error if there
 // are too many Channels, if there are any duplicate Channels,
-// if the ListenAddr is malformed, or if the ListenAddr is a host name
-// that can not be resolved to some IP.
+// if the ListenAddr or one of the ListenAddrs is malformed, or if it's a host
+// name that can not be resolved to some IP.
 // TODO: constraints for Moniker/Other? Or is that for the UI ?
 // JAE: It needs to be done on the client, but to prevent ambiguous
 // unicode characters, maybe it's worth sanitizing it here.
@@ -126,6 +137,17 @@ func (info DefaultNodeInfo) Validate() error {
 		return err
 	}
 
+	// Validate ListenAddrs.
+	if len(info.ListenAddrs) > maxNumListenAddrs {
+		return fmt.Errorf("info.ListenAddrs is too long (%v). Max is %v", len(info.ListenAddrs), maxNumListenAddrs)
+	}
+	for _, addr := range info.ListenAddrs {
+		_, err := NewNetAddressString(IDAddressString(info.ID(), addr))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Network is validated in CompatibleWith.
 
 	// Validate Version
@@ -166,6 +188,11 @@ func (info DefaultNodeInfo) Validate() error {
 	if len(rpcAddr) > 0 && (!cmn.IsASCIIText(rpcAddr) || cmn.ASCIITrim(rpcAddr) == "") {
 		return fmt.Errorf("info.Other.RPCAddress=%v must be valid ASCII text without tabs", rpcAddr)
 	}
+	switch other.StreamMux {
+	case "", "on", "off":
+	default:
+		return fmt.Errorf("info.Other.StreamMux should be either 'on', 'off', or empty string, got '%v'", other.StreamMux)
+	}
 
 	return nil
 }
@@ -231,6 +258,26 @@ func (info DefaultNodeInfo) NetAddress() *NetAddress {
 	return netAddr
 }
 
+// NetAddresses returns the NetAddresses derived from the ListenAddr and the
+// ListenAddrs, in this order. Like NetAddress, the addresses are
+// self-reported, and the host names which can't be resolved are skipped.
+func (info DefaultNodeInfo) NetAddresses() []*NetAddress {
+	netAddrs := make([]*NetAddress, 0, 1+len(info.ListenAddrs))
+	for _, addr := range append([]string{info.ListenAddr}, info.ListenAddrs...) {
+		netAddr, err := NewNetAddressString(IDAddressString(info.ID(), addr))
+		if err != nil {
+			switch err.(type) {
+			case ErrNetAddressLookup:
+				continue
+			default:
+				panic(err) // everything should be well formed by now
+			}
+		}
+		netAddrs = append(netAddrs, netAddr)
+	}
+	return netAddrs
+}
+
 //-----------------------------------------------------------
 // These methods are for Protobuf Compatibility
 
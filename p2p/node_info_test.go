@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 )
 
@@ -37,6 +38,11 @@ func TestNodeInfoValidate(t *testing.T) {
 
 		{"Invalid NetAddress", func(ni *DefaultNodeInfo) { ni.ListenAddr = "not-an-address" }, true},
 		{"Good NetAddress", func(ni *DefaultNodeInfo) { ni.ListenAddr = "0.0.0.0:26656" }, false},
+		{"Good IPv6 NetAddress", func(ni *DefaultNodeInfo) { ni.ListenAddr = "[::]:26656" }, false},
+
+		{"Invalid ListenAddrs", func(ni *DefaultNodeInfo) { ni.ListenAddrs = []string{"[::1]:26656", "not-an-address"} }, true},
+		{"Too Many ListenAddrs", func(ni *DefaultNodeInfo) { ni.ListenAddrs = make([]string, maxNumListenAddrs+1) }, true},
+		{"Good ListenAddrs", func(ni *DefaultNodeInfo) { ni.ListenAddrs = []string{"[::1]:26656", "tcp://127.0.0.1:26657"} }, false},
 
 		{"Non-ASCII Version", func(ni *DefaultNodeInfo) { ni.Version = nonAscii }, true},
 		{"Empty tab Version", func(ni *DefaultNodeInfo) { ni.Version = emptyTab }, true},
@@ -84,6 +90,26 @@ func TestNodeInfoValidate(t *testing.T) {
 
 }
 
+func TestNodeInfoNetAddresses(t *testing.T) {
+	nodeKey := NodeKey{PrivKey: ed25519.GenPrivKey()}
+	ni := testNodeInfo(nodeKey.ID(), "testing").(DefaultNodeInfo)
+	ni.ListenAddr = "127.0.0.1:26656"
+	ni.ListenAddrs = []string{"tcp://[::1]:26656"}
+	require.NoError(t, ni.Validate())
+
+	netAddrs := ni.NetAddresses()
+	require.Len(t, netAddrs, 2)
+	assert.Equal(t, ni.NetAddress(), netAddrs[0])
+	assert.Equal(t, IDAddressString(nodeKey.ID(), "[::1]:26656"), netAddrs[1].String())
+
+	// the ListenAddrs survive an amino round-trip
+	bz, err := cdc.MarshalBinaryBare(ni)
+	require.NoError(t, err)
+	var decoded DefaultNodeInfo
+	require.NoError(t, cdc.UnmarshalBinaryBare(bz, &decoded))
+	assert.Equal(t, ni.ListenAddrs, decoded.ListenAddrs)
+}
+
 func TestNodeInfoCompatible(t *testing.T) {
 
 	nodeKey1 := NodeKey{PrivKey: ed25519.GenPrivKey()}
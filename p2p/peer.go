@@ -3,6 +3,7 @@ package p2p
 import (
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
@@ -30,6 +31,7 @@ type Peer interface {
 	NodeInfo() NodeInfo // peer's info
 	Status() tmconn.ConnectionStatus
 	OriginalAddr() *NetAddress // original address for outbound peers
+	RTT() time.Duration        // moving average of the ping round-trip time, 0 until measured
 
 	Send(byte, []byte) bool
 	TrySend(byte, []byte) bool
@@ -44,6 +46,7 @@ type Peer interface {
 type peerConn struct {
 	outbound   bool
 	persistent bool
+	streams    bool     // use a StreamConnection rather than an MConnection
 	conn       net.Conn // source connection
 
 	originalAddr *NetAddress // nil for inbound connections
@@ -53,7 +56,7 @@ type peerConn struct {
 }
 
 func newPeerConn(
-	outbound, persistent bool,
+	outbound, persistent, streams bool,
 	conn net.Conn,
 	originalAddr *NetAddress,
 ) peerConn {
@@ -61,6 +64,7 @@ func newPeerConn(
 	return peerConn{
 		outbound:     outbound,
 		persistent:   persistent,
+		streams:      streams,
 		conn:         conn,
 		originalAddr: originalAddr,
 	}
@@ -93,6 +97,20 @@ func (pc peerConn) RemoteIP() net.IP {
 	return pc.ip
 }
 
+// multiplexConn multiplexes the channels of the reactors over the connection
+// to a peer. It is implemented by tmconn.MConnection and
+// tmconn.StreamConnection.
+type multiplexConn interface {
+	cmn.Service
+	FlushStop()
+
+	Send(byte, []byte) bool
+	TrySend(byte, []byte) bool
+	CanSend(byte) bool
+
+	Status() tmconn.ConnectionStatus
+}
+
 // peer implements Peer.
 //
 // Before using a peer, you will need to perform a handshake on connection.
@@ -101,7 +119,7 @@ type peer struct {
 
 	// raw peerConn and the multiplex connection
 	peerConn
-	mconn *tmconn.MConnection
+	mconn multiplexConn
 
 	// peer's node info and the channel it knows about
 	// channels = nodeInfo.Channels
@@ -114,6 +132,22 @@ type peer struct {
 
 	metrics       *Metrics
 	metricsTicker *time.Ticker
+
+	// records the messages, if not nil
+	capture      *Capture
+	reactorsByCh map[byte]Reactor
+
+	// drops or delays the messages, if not nil
+	faults      *FaultInjector
+	delayMtx    sync.Mutex
+	delayQueues map[delayQueueKey]*delayQueue
+}
+
+// delayQueueKey identifies the messages sent to or received from the peer on
+// a channel.
+type delayQueueKey struct {
+	chID byte
+	recv bool
 }
 
 type PeerOption func(*peer)
@@ -134,6 +168,7 @@ func newPeer(
 		Data:          cmn.NewCMap(),
 		metricsTicker: time.NewTicker(metricsTickerDuration),
 		metrics:       NopMetrics(),
+		reactorsByCh:  reactorsByCh,
 	}
 
 	p.mconn = createMConnection(
@@ -237,9 +272,28 @@ func (p *peer) Status() tmconn.ConnectionStatus {
 	return p.mconn.Status()
 }
 
+// RTT returns the moving average of the round-trip time of the pings sent to
+// the peer, or 0 if it hasn't been measured yet.
+func (p *peer) RTT() time.Duration {
+	return p.mconn.Status().RTT
+}
+
 // Send msg bytes to the channel identified by chID byte. Returns false if the
 // send queue is full after timeout, specified by MConnection.
 func (p *peer) Send(chID byte, msgBytes []byte) bool {
+	return p.send(chID, msgBytes, p.mconn.Send)
+}
+
+// TrySend msg bytes to the channel identified by chID byte. Immediately returns
+// false if the send queue is full.
+func (p *peer) TrySend(chID byte, msgBytes []byte) bool {
+	return p.send(chID, msgBytes, p.mconn.TrySend)
+}
+
+// send sends msg bytes with sendFn, unless an injected fault drops or delays
+// them. The dropped messages are reported as sent, and so are the delayed ones
+// which are sent later.
+func (p *peer) send(chID byte, msgBytes []byte, sendFn func(byte, []byte) bool) bool {
 	if !p.IsRunning() {
 		// see Switch#Broadcast, where we fetch the list of peers and loop over
 		// them - while we're looping, one peer may be removed and stopped.
@@ -247,24 +301,49 @@ func (p *peer) Send(chID byte, msgBytes []byte) bool {
 	} else if !p.hasChannel(chID) {
 		return false
 	}
-	res := p.mconn.Send(chID, msgBytes)
-	if res {
-		p.metrics.PeerSendBytesTotal.With("peer_id", string(p.ID())).Add(float64(len(msgBytes)))
+	if drop, delay := p.faults.fault(p.ID(), chID); drop {
+		return true
+	} else if q := p.delayQueue(chID, false, delay, func(msgBytes []byte) {
+		p.sendNow(chID, msgBytes, p.mconn.Send)
+	}); q != nil {
+		q.push(delay, msgBytes)
+		return true
 	}
-	return res
+	return p.sendNow(chID, msgBytes, sendFn)
 }
 
-// TrySend msg bytes to the channel identified by chID byte. Immediately returns
-// false if the send queue is full.
-func (p *peer) TrySend(chID byte, msgBytes []byte) bool {
-	if !p.IsRunning() {
-		return false
-	} else if !p.hasChannel(chID) {
-		return false
+// delayQueue returns the queue delaying the messages sent to or received
+// from the peer on chID, if they must be delayed, or if previous messages are
+// still delayed: they can't be overtaken. It's nil otherwise. A new queue
+// delivers the messages with deliver until the peer stops.
+func (p *peer) delayQueue(chID byte, recv bool, delay time.Duration, deliver func([]byte)) *delayQueue {
+	if p.faults == nil {
+		return nil
+	}
+	p.delayMtx.Lock()
+	defer p.delayMtx.Unlock()
+	key := delayQueueKey{chID, recv}
+	q, ok := p.delayQueues[key]
+	if ok && (delay > 0 || q.pending()) {
+		return q
+	} else if delay == 0 {
+		return nil
+	}
+
+	if p.delayQueues == nil {
+		p.delayQueues = make(map[delayQueueKey]*delayQueue)
 	}
-	res := p.mconn.TrySend(chID, msgBytes)
+	q = newDelayQueue(deliver)
+	p.delayQueues[key] = q
+	go q.run(p.Quit())
+	return q
+}
+
+func (p *peer) sendNow(chID byte, msgBytes []byte, sendFn func(byte, []byte) bool) bool {
+	res := sendFn(chID, msgBytes)
 	if res {
-		p.metrics.PeerSendBytesTotal.With("peer_id", string(p.ID())).Add(float64(len(msgBytes)))
+		p.metrics.PeerSendBytesTotal.With("peer_id", string(p.ID()), "chID", chIDLabel(chID)).Add(float64(len(msgBytes)))
+		p.capture.record(p.ID(), CaptureSend, chID, p.reactorsByCh[chID], msgBytes)
 	}
 	return res
 }
@@ -334,6 +413,22 @@ func PeerMetrics(metrics *Metrics) PeerOption {
 	}
 }
 
+// PeerCapture records the messages sent to and received from the peer with
+// the capture, if not nil.
+func PeerCapture(capture *Capture) PeerOption {
+	return func(p *peer) {
+		p.capture = capture
+	}
+}
+
+// PeerFaultInjector drops or delays the messages sent to and received from
+// the peer according to the faults of the injector, if not nil.
+func PeerFaultInjector(faults *FaultInjector) PeerOption {
+	return func(p *peer) {
+		p.faults = faults
+	}
+}
+
 func (p *peer) metricsReporter() {
 	for {
 		select {
@@ -345,6 +440,9 @@ func (p *peer) metricsReporter() {
 			}
 
 			p.metrics.PeerPendingSendBytes.With("peer_id", string(p.ID())).Set(sendQueueSize)
+			if status.RTT > 0 {
+				p.metrics.PeerRTT.With("peer_id", string(p.ID())).Set(status.RTT.Seconds())
+			}
 		case <-p.Quit():
 			return
 		}
@@ -354,6 +452,11 @@ func (p *peer) metricsReporter() {
 //------------------------------------------------------------------
 // helper funcs
 
+// chIDLabel returns the metrics label of the channel.
+func chIDLabel(chID byte) string {
+	return fmt.Sprintf("%#x", chID)
+}
+
 func createMConnection(
 	conn net.Conn,
 	p *peer,
@@ -361,7 +464,7 @@ func createMConnection(
 	chDescs []*tmconn.ChannelDescriptor,
 	onPeerError func(Peer, interface{}),
 	config tmconn.MConnConfig,
-) *tmconn.MConnection {
+) multiplexConn {
 
 	onReceive := func(chID byte, msgBytes []byte) {
 		reactor := reactorsByCh[chID]
@@ -370,7 +473,23 @@ func createMConnection(
 			// which does onPeerError.
 			panic(fmt.Sprintf("Unknown channel %X", chID))
 		}
-		p.metrics.PeerReceiveBytesTotal.With("peer_id", string(p.ID())).Add(float64(len(msgBytes)))
+		p.metrics.PeerReceiveBytesTotal.With("peer_id", string(p.ID()), "chID", chIDLabel(chID)).Add(float64(len(msgBytes)))
+		p.capture.record(p.ID(), CaptureRecv, chID, reactor, msgBytes)
+
+		if drop, delay := p.faults.fault(p.ID(), chID); drop {
+			return
+		} else if q := p.delayQueue(chID, true, delay, func(msgBytes []byte) {
+			defer func() {
+				if r := recover(); r != nil {
+					onPeerError(p, r)
+				}
+			}()
+			reactor.Receive(chID, p, msgBytes)
+		}); q != nil {
+			// the connection reuses msgBytes once we return
+			q.push(delay, append([]byte{}, msgBytes...))
+			return
+		}
 		reactor.Receive(chID, p, msgBytes)
 	}
 
@@ -378,6 +497,16 @@ func createMConnection(
 		onPeerError(p, r)
 	}
 
+	if p.streams {
+		return tmconn.NewStreamConnection(
+			conn,
+			chDescs,
+			onReceive,
+			onError,
+			config,
+		)
+	}
+
 	return tmconn.NewMConnectionWithConfig(
 		conn,
 		chDescs,
@@ -7,7 +7,9 @@ package pex
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"net"
 	"sync"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/tendermint/tendermint/crypto"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
 	"github.com/tendermint/tendermint/p2p"
 )
 
@@ -58,6 +61,10 @@ type AddrBook interface {
 	MarkAttempt(*p2p.NetAddress)
 	MarkBad(*p2p.NetAddress)
 
+	// Record what we learned about the peer of the address
+	SetNodeInfo(*p2p.NetAddress, p2p.NodeInfo)
+	SetLatency(*p2p.NetAddress, time.Duration)
+
 	IsGood(*p2p.NetAddress) bool
 
 	// Send a selection of addresses to peers
@@ -68,8 +75,14 @@ type AddrBook interface {
 	// TODO: remove
 	ListOfKnownAddresses() []*knownAddress
 
+	// Maintenance
+	Prune(PruneOptions) []*p2p.NetAddress
+	ImportFile(filePath string) (int, error)
+
 	// Persist to disk
 	Save()
+	// Wait for the book to be saved once stopped
+	Wait()
 }
 
 var _ AddrBook = (*addrBook)(nil)
@@ -81,6 +94,7 @@ type addrBook struct {
 
 	// immutable after creation
 	filePath          string
+	db                dbm.DB // if set, the book is saved to it instead of filePath
 	routabilityStrict bool
 	key               string // random prefix for bucket placement
 
@@ -94,6 +108,7 @@ type addrBook struct {
 	bucketsNew []map[string]*knownAddress
 	nOld       int
 	nNew       int
+	dirty      map[p2p.ID]struct{} // changed since saved to the db
 
 	wg sync.WaitGroup
 }
@@ -114,6 +129,17 @@ func NewAddrBook(filePath string, routabilityStrict bool) *addrBook {
 	return am
 }
 
+// NewDBAddrBook creates a new address book saved to db, which only writes the
+// addresses changed since it last saved. If the db is new, the addresses of the
+// JSON address book at filePath, if any, are imported.
+// Use Start to begin processing asynchronous address updates.
+func NewDBAddrBook(db dbm.DB, filePath string, routabilityStrict bool) *addrBook {
+	am := NewAddrBook(filePath, routabilityStrict)
+	am.db = db
+	am.dirty = make(map[p2p.ID]struct{})
+	return am
+}
+
 // Initialize the buckets.
 // When modifying this, don't forget to update loadFromFile()
 func (a *addrBook) init() {
@@ -135,7 +161,11 @@ func (a *addrBook) OnStart() error {
 	if err := a.BaseService.OnStart(); err != nil {
 		return err
 	}
-	a.loadFromFile(a.filePath)
+	if a.db != nil {
+		a.loadFromDB()
+	} else {
+		a.loadFromFile(a.filePath)
+	}
 
 	// wg.Add to ensure that any invocation of .Wait()
 	// later on will wait for saveRoutine to terminate.
@@ -150,6 +180,7 @@ func (a *addrBook) OnStop() {
 	a.BaseService.OnStop()
 }
 
+// Wait implements AddrBook.
 func (a *addrBook) Wait() {
 	a.wg.Wait()
 }
@@ -178,12 +209,17 @@ func (a *addrBook) OurAddress(addr *p2p.NetAddress) bool {
 	return ok
 }
 
+// AddPrivateIDs implements AddrBook. The addresses of the private peers
+// which are already in the book are removed, so that they are never gossiped.
 func (a *addrBook) AddPrivateIDs(IDs []string) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
 	for _, id := range IDs {
 		a.privateIDs[p2p.ID(id)] = struct{}{}
+		if ka, ok := a.addrLookup[p2p.ID(id)]; ok {
+			a.removeFromAllBuckets(ka)
+		}
 	}
 }
 
@@ -308,6 +344,7 @@ func (a *addrBook) MarkGood(addr *p2p.NetAddress) {
 	if ka.isNew() {
 		a.moveToOld(ka)
 	}
+	a.markDirty(ka)
 }
 
 // MarkAttempt implements AddrBook - it marks that an attempt was made to connect to the address.
@@ -320,6 +357,40 @@ func (a *addrBook) MarkAttempt(addr *p2p.NetAddress) {
 		return
 	}
 	ka.markAttempt()
+	a.markDirty(ka)
+}
+
+// SetNodeInfo implements AddrBook - it records the protocol version, network
+// and software version of the peer, which we just connected to.
+func (a *addrBook) SetNodeInfo(addr *p2p.NetAddress, nodeInfo p2p.NodeInfo) {
+	ni, ok := nodeInfo.(p2p.DefaultNodeInfo)
+	if !ok {
+		return
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	ka := a.addrLookup[addr.ID]
+	if ka == nil {
+		return
+	}
+	ka.setNodeInfo(ni)
+	a.markDirty(ka)
+}
+
+// SetLatency implements AddrBook - it records how long it took to dial the
+// address.
+func (a *addrBook) SetLatency(addr *p2p.NetAddress, latency time.Duration) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	ka := a.addrLookup[addr.ID]
+	if ka == nil {
+		return
+	}
+	ka.Latency = latency
+	a.markDirty(ka)
 }
 
 // MarkBad implements AddrBook. Currently it just ejects the address.
@@ -480,6 +551,73 @@ func (a *addrBook) ListOfKnownAddresses() []*knownAddress {
 	return addrs
 }
 
+// PruneOptions selects the addresses removed by Prune.
+type PruneOptions struct {
+	// Remove the addresses which are considered bad, see knownAddress.isBad.
+	Bad bool
+	// If not 0, remove the addresses we haven't connected to for longer than
+	// this, including the ones we never connected to.
+	NotSeenFor time.Duration
+	// If not empty, remove the addresses of the peers of another network
+	// (chain ID). The peers we never connected to are kept.
+	Network string
+}
+
+// Prune removes the addresses selected by opts, and returns them.
+func (a *addrBook) Prune(opts PruneOptions) []*p2p.NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	pruned := []*p2p.NetAddress{}
+	for _, ka := range a.addrLookup {
+		lastSeen := ka.LastSuccess
+		if ka.LastConnected.After(lastSeen) {
+			lastSeen = ka.LastConnected
+		}
+		if (opts.Bad && ka.isBad()) ||
+			(opts.NotSeenFor > 0 && now.Sub(lastSeen) > opts.NotSeenFor) ||
+			(opts.Network != "" && ka.Network != "" && ka.Network != opts.Network) {
+			a.removeFromAllBuckets(ka)
+			pruned = append(pruned, ka.Addr)
+		}
+	}
+	return pruned
+}
+
+// ImportFile adds the addresses of the JSON address book at filePath to the
+// new buckets, and returns how many were added.
+func (a *addrBook) ImportFile(filePath string) (int, error) {
+	bz, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	aJSON := &addrBookJSON{}
+	if err := json.Unmarshal(bz, aJSON); err != nil {
+		return 0, fmt.Errorf("error reading file %s: %v", filePath, err)
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	n := 0
+	for _, ka := range aJSON.Addrs {
+		if ka.Addr == nil || a.addrLookup[ka.ID()] != nil {
+			continue
+		}
+		src := ka.Src
+		if src == nil {
+			src = ka.Addr
+		}
+		if err := a.addAddress(ka.Addr, src); err != nil {
+			a.Logger.Debug("Failed to import address", "addr", ka.Addr, "err", err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
 //------------------------------------------------
 
 // Size returns the number of addresses in the book.
@@ -498,7 +636,15 @@ func (a *addrBook) size() int {
 
 // Save persists the address book to disk.
 func (a *addrBook) Save() {
-	a.saveToFile(a.filePath) // thread safe
+	a.save() // thread safe
+}
+
+func (a *addrBook) save() {
+	if a.db != nil {
+		a.saveToDB()
+	} else {
+		a.saveToFile(a.filePath)
+	}
 }
 
 func (a *addrBook) saveRoutine() {
@@ -509,13 +655,13 @@ out:
 	for {
 		select {
 		case <-saveFileTicker.C:
-			a.saveToFile(a.filePath)
+			a.save()
 		case <-a.Quit():
 			break out
 		}
 	}
 	saveFileTicker.Stop()
-	a.saveToFile(a.filePath)
+	a.save()
 }
 
 //----------------------------------------------------------
@@ -532,6 +678,13 @@ func (a *addrBook) getBucket(bucketType byte, bucketIdx int) map[string]*knownAd
 	}
 }
 
+func (a *addrBook) bucketsOf(bucketType byte) []map[string]*knownAddress {
+	if bucketType == bucketTypeOld {
+		return a.bucketsOld
+	}
+	return a.bucketsNew
+}
+
 // Adds ka to new bucket. Returns false if it couldn't do it cuz buckets full.
 // NOTE: currently it always returns true.
 func (a *addrBook) addToNewBucket(ka *knownAddress, bucketIdx int) {
@@ -564,6 +717,7 @@ func (a *addrBook) addToNewBucket(ka *knownAddress, bucketIdx int) {
 
 	// Add it to addrLookup
 	a.addrLookup[ka.ID()] = ka
+	a.markDirty(ka)
 }
 
 // Adds ka to old bucket. Returns false if it couldn't do it cuz buckets full.
@@ -599,6 +753,7 @@ func (a *addrBook) addToOldBucket(ka *knownAddress, bucketIdx int) bool {
 
 	// Ensure in addrLookup
 	a.addrLookup[ka.ID()] = ka
+	a.markDirty(ka)
 
 	return true
 }
@@ -618,6 +773,7 @@ func (a *addrBook) removeFromBucket(ka *knownAddress, bucketType byte, bucketIdx
 		}
 		delete(a.addrLookup, ka.ID())
 	}
+	a.markDirty(ka)
 }
 
 func (a *addrBook) removeFromAllBuckets(ka *knownAddress) {
@@ -632,6 +788,15 @@ func (a *addrBook) removeFromAllBuckets(ka *knownAddress) {
 		a.nOld--
 	}
 	delete(a.addrLookup, ka.ID())
+	a.markDirty(ka)
+}
+
+// markDirty records that ka changed, to save it to the db. a.mtx must be
+// held.
+func (a *addrBook) markDirty(ka *knownAddress) {
+	if a.dirty != nil {
+		a.dirty[ka.ID()] = struct{}{}
+	}
 }
 
 //----------------------------------------------------------
@@ -814,18 +979,16 @@ func (a *addrBook) groupKey(na *p2p.NetAddress) string {
 	}
 
 	if ipv4 := na.IP.To4(); ipv4 != nil {
-		return (&net.IPNet{IP: na.IP, Mask: net.CIDRMask(16, 32)}).String()
+		return ipNetString(ipv4, 16, 32)
 	}
 	if na.RFC6145() || na.RFC6052() {
 		// last four bytes are the ip address
-		ip := net.IP(na.IP[12:16])
-		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(16, 32)}).String()
+		return ipNetString(na.IP[12:16], 16, 32)
 	}
 
 	if na.RFC3964() {
-		ip := net.IP(na.IP[2:7])
-		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(16, 32)}).String()
-
+		// 6to4 tunnels have the v4 address after the 2002::/16 prefix.
+		return ipNetString(na.IP[2:6], 16, 32)
 	}
 	if na.RFC4380() {
 		// teredo tunnels have the last 4 bytes as the v4 address XOR
@@ -834,7 +997,7 @@ func (a *addrBook) groupKey(na *p2p.NetAddress) string {
 		for i, byte := range na.IP[12:16] {
 			ip[i] = byte ^ 0xff
 		}
-		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(16, 32)}).String()
+		return ipNetString(ip, 16, 32)
 	}
 
 	// OK, so now we know ourselves to be a IPv6 address.
@@ -847,7 +1010,14 @@ func (a *addrBook) groupKey(na *p2p.NetAddress) string {
 		bits = 36
 	}
 
-	return (&net.IPNet{IP: na.IP, Mask: net.CIDRMask(bits, 128)}).String()
+	return ipNetString(na.IP, bits, 128)
+}
+
+// ipNetString returns the network of the ip with the given prefix length, in
+// CIDR notation, e.g. 1.2.0.0/16 for 1.2.3.4.
+func ipNetString(ip net.IP, ones, bits int) string {
+	mask := net.CIDRMask(ones, bits)
+	return (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
 }
 
 // doubleSha256 calculates sha256(sha256(b)) and returns the resulting bytes.
@@ -4,14 +4,17 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/stretchr/testify/assert"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
 )
@@ -417,3 +420,197 @@ func TestPrivatePeers(t *testing.T) {
 		assert.True(t, ok)
 	}
 }
+
+func TestPrivatePeersRemovedFromBook(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+
+	addrs, private := testCreatePrivateAddrs(t, 10)
+	for _, addr := range addrs {
+		require.NoError(t, book.AddAddress(addr, addr))
+	}
+	book.saveToFile(fname)
+
+	// the addresses saved before the peers were made private are dropped
+	book = NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+	book.AddPrivateIDs(private[:5])
+	book.loadFromFile(fname)
+	assert.Equal(t, 5, book.Size())
+
+	// and so are the ones already in the book
+	book.AddPrivateIDs(private[5:])
+	assert.Equal(t, 0, book.Size())
+	assert.Empty(t, book.GetSelection())
+}
+
+func TestAddrBookDBSaveLoad(t *testing.T) {
+	db := dbm.NewMemDB()
+
+	book := NewDBAddrBook(db, "", true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromDB()
+	assert.Zero(t, book.Size())
+
+	randAddrs := randNetAddressPairs(t, 100)
+	for _, addrSrc := range randAddrs {
+		require.NoError(t, book.AddAddress(addrSrc.addr, addrSrc.src))
+	}
+	addr := randAddrs[0].addr
+	book.MarkGood(addr)
+	book.SetLatency(addr, 25*time.Millisecond)
+	book.SetNodeInfo(addr, p2p.DefaultNodeInfo{
+		ProtocolVersion: p2p.NewProtocolVersion(1, 2, 3),
+		Network:         "test-chain",
+		Version:         "0.1.0",
+	})
+	book.saveToDB()
+	assert.Empty(t, book.dirty)
+
+	book = NewDBAddrBook(db, "", true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromDB()
+	assert.Equal(t, 100, book.Size())
+	assert.Empty(t, book.dirty)
+
+	ka := book.addrLookup[addr.ID]
+	require.NotNil(t, ka)
+	assert.True(t, ka.isOld())
+	assert.Equal(t, 25*time.Millisecond, ka.Latency)
+	assert.Equal(t, p2p.NewProtocolVersion(1, 2, 3), ka.ProtocolVersion)
+	assert.Equal(t, "test-chain", ka.Network)
+	assert.Equal(t, "0.1.0", ka.Version)
+	assert.False(t, ka.LastConnected.IsZero())
+
+	// removed addresses are deleted from the DB
+	book.RemoveAddress(addr)
+	assert.Len(t, book.dirty, 1)
+	book.saveToDB()
+	assert.Nil(t, db.Get(addrKey(addr.ID)))
+}
+
+func TestAddrBookDBDropsCorruptedAddresses(t *testing.T) {
+	db := dbm.NewMemDB()
+
+	book := NewDBAddrBook(db, "", true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromDB()
+	randAddrs := randNetAddressPairs(t, 10)
+	for _, addrSrc := range randAddrs {
+		require.NoError(t, book.AddAddress(addrSrc.addr, addrSrc.src))
+	}
+	book.saveToDB()
+
+	corrupted := addrKey(randAddrs[0].addr.ID)
+	db.Set(corrupted, []byte("{"))
+
+	book = NewDBAddrBook(db, "", true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromDB()
+	assert.Equal(t, 9, book.Size())
+
+	book.saveToDB()
+	assert.Nil(t, db.Get(corrupted))
+}
+
+func TestAddrBookDBImportsFile(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	fileBook := NewAddrBook(fname, true)
+	fileBook.SetLogger(log.TestingLogger())
+	for _, addrSrc := range randNetAddressPairs(t, 20) {
+		require.NoError(t, fileBook.AddAddress(addrSrc.addr, addrSrc.src))
+	}
+	fileBook.saveToFile(fname)
+
+	db := dbm.NewMemDB()
+	book := NewDBAddrBook(db, fname, true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromDB()
+	assert.Equal(t, 20, book.Size())
+	assert.Equal(t, fileBook.key, book.key)
+
+	// the file is only imported once
+	fileBook.AddAddress(randIPv4Address(t), randIPv4Address(t))
+	fileBook.saveToFile(fname)
+
+	book = NewDBAddrBook(db, fname, true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromDB()
+	assert.Equal(t, 20, book.Size())
+
+	// unless asked to
+	n, err := book.ImportFile(fname)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 21, book.Size())
+}
+
+func TestAddrBookPrune(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+	randAddrs := randNetAddressPairs(t, 10)
+	for _, addrSrc := range randAddrs {
+		require.NoError(t, book.AddAddress(addrSrc.addr, addrSrc.src))
+	}
+	for i, addrSrc := range randAddrs[:5] {
+		network := "test-chain"
+		if i == 0 {
+			network = "other-chain"
+		}
+		book.SetNodeInfo(addrSrc.addr, p2p.DefaultNodeInfo{Network: network})
+	}
+
+	pruned := book.Prune(PruneOptions{Network: "test-chain"})
+	assert.Equal(t, []*p2p.NetAddress{randAddrs[0].addr}, pruned)
+	assert.Equal(t, 9, book.Size())
+
+	pruned = book.Prune(PruneOptions{NotSeenFor: time.Hour})
+	assert.Len(t, pruned, 5)
+	assert.Equal(t, 4, book.Size())
+}
+
+func TestAddrBookGroupKey(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	book := NewAddrBook(fname, true)
+
+	testCases := []struct {
+		ip       string
+		groupKey string
+	}{
+		{"127.0.0.1", "local"},
+		{"192.168.1.1", "unroutable"},
+		{"1.2.3.4", "1.2.0.0/16"},
+		{"1.2.200.4", "1.2.0.0/16"},
+		{"::ffff:1.2.3.4", "1.2.0.0/16"},                       // IPv4-mapped
+		{"64:ff9b::102:304", "1.2.0.0/16"},                     // RFC6052
+		{"2002:102:304::1", "1.2.0.0/16"},                      // RFC3964 (6to4)
+		{"2001:0:4136:e378:8000:63bf:fefd:fcfb", "1.2.0.0/16"}, // RFC4380 (teredo)
+		{"2600:1:2:3::1", "2600:1::/32"},
+		{"2600:1:ffff::1", "2600:1::/32"},
+		{"2001:470:abcd::1", "2001:470:a000::/36"}, // he.net
+		{"::1", "local"},
+		{"fd00::1", "unroutable"},   // RFC4193
+		{"fe80::1:1", "unroutable"}, // RFC4862
+		{"ff02::1", "unroutable"},   // multicast
+	}
+	for _, tc := range testCases {
+		addr := p2p.NewNetAddressIPPort(net.ParseIP(tc.ip), 26656)
+		assert.Equal(t, tc.groupKey, book.groupKey(addr), tc.ip)
+	}
+
+	// addresses in the same group share the buckets
+	src := p2p.NewNetAddressIPPort(net.ParseIP("2600:1::1"), 26656)
+	assert.Equal(t,
+		book.calcNewBucket(p2p.NewNetAddressIPPort(net.ParseIP("2600:1:2::1"), 26656), src),
+		book.calcNewBucket(p2p.NewNetAddressIPPort(net.ParseIP("2600:1:3::1"), 26656), src))
+}
@@ -0,0 +1,83 @@
+package pex
+
+import (
+	"encoding/json"
+
+	dbm "github.com/tendermint/tendermint/libs/db"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+/* Loading & Saving to a DB */
+
+var (
+	addrBookKeyKey = []byte("key")
+	addrKeyPrefix  = []byte("addr:")
+)
+
+func addrKey(id p2p.ID) []byte {
+	return append(append([]byte{}, addrKeyPrefix...), id...)
+}
+
+// saveToDB writes the addresses changed since the last save, in a single
+// batch.
+func (a *addrBook) saveToDB() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.Logger.Info("Saving AddrBook to DB", "size", a.size(), "changed", len(a.dirty))
+
+	batch := a.db.NewBatch()
+	defer batch.Close()
+	batch.Set(addrBookKeyKey, []byte(a.key))
+	for id := range a.dirty {
+		ka, ok := a.addrLookup[id]
+		if !ok {
+			batch.Delete(addrKey(id))
+			continue
+		}
+		bz, err := json.Marshal(ka)
+		if err != nil {
+			a.Logger.Error("Failed to save address to DB", "addr", ka.Addr, "err", err)
+			continue
+		}
+		batch.Set(addrKey(id), bz)
+	}
+	batch.WriteSync()
+	a.dirty = make(map[p2p.ID]struct{})
+}
+
+// loadFromDB restores the addresses saved to the DB. The corrupted ones are
+// dropped, instead of the whole book. If the DB is new, it imports the JSON
+// address book at a.filePath, if any.
+func (a *addrBook) loadFromDB() {
+	key := a.db.Get(addrBookKeyKey)
+	if key == nil {
+		if a.filePath != "" && a.loadFromFile(a.filePath) {
+			a.Logger.Info("Imported AddrBook file to DB", "file", a.filePath, "size", a.Size())
+			for id := range a.addrLookup {
+				a.dirty[id] = struct{}{}
+			}
+		}
+		a.saveToDB()
+		return
+	}
+	a.key = string(key)
+
+	itr := dbm.IteratePrefix(a.db, addrKeyPrefix)
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		ka := new(knownAddress)
+		err := json.Unmarshal(itr.Value(), ka)
+		restored := false
+		if err == nil {
+			restored, err = a.restoreAddress(ka)
+		}
+		if err != nil {
+			a.Logger.Error("Dropping corrupted address from AddrBook", "key", string(itr.Key()), "err", err)
+		}
+		if !restored {
+			// deleted on the next save
+			a.dirty[p2p.ID(itr.Key()[len(addrKeyPrefix):])] = struct{}{}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package pex
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+const (
+	// timeout of the lookups of a DNS seed name
+	dnsSeedLookupTimeout = 10 * time.Second
+
+	// the DNS seed names are resolved again when dialing the seeds, at most
+	// this often
+	dnsSeedsRefreshInterval = 5 * time.Minute
+)
+
+// Resolver looks up the DNS records of the DNS seed names. *net.Resolver
+// implements it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (cname string, addrs []*net.SRV, err error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolveDNSSeed returns the addresses of the seed nodes published under the
+// DNS name, in two kinds of records:
+//  - TXT records, each holding ID@host:port addresses separated by commas or
+//    spaces
+//  - SRV records, the first label of their target being the node ID, e.g.
+//    "seeds.example.com. SRV 0 0 26656 <ID>.seed1.example.com."
+// It returns an error if the name has no valid record.
+func resolveDNSSeed(resolver Resolver, name string) ([]*p2p.NetAddress, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dnsSeedLookupTimeout)
+	defer cancel()
+
+	var (
+		addrs   []*p2p.NetAddress
+		lastErr error
+	)
+	add := func(id, host, port string) {
+		addr, err := dnsSeedAddress(ctx, resolver, id, host, port)
+		if err != nil {
+			lastErr = err
+			return
+		}
+		addrs = append(addrs, addr)
+	}
+
+	txts, txtErr := resolver.LookupTXT(ctx, name)
+	for _, txt := range txts {
+		for _, s := range strings.FieldsFunc(txt, func(r rune) bool { return r == ',' || r == ' ' }) {
+			spl := strings.Split(s, "@")
+			if len(spl) != 2 {
+				lastErr = p2p.ErrNetAddressNoID{Addr: s}
+				continue
+			}
+			host, port, err := net.SplitHostPort(spl[1])
+			if err != nil {
+				lastErr = p2p.ErrNetAddressInvalid{Addr: s, Err: err}
+				continue
+			}
+			add(spl[0], host, port)
+		}
+	}
+
+	_, srvs, srvErr := resolver.LookupSRV(ctx, "", "", name)
+	for _, srv := range srvs {
+		target := strings.TrimSuffix(srv.Target, ".")
+		id := strings.SplitN(target, ".", 2)[0]
+		add(id, target, strconv.Itoa(int(srv.Port)))
+	}
+
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	if lastErr == nil {
+		// neither kind of record was found
+		lastErr = txtErr
+		if lastErr == nil {
+			lastErr = srvErr
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no TXT or SRV records")
+		}
+	}
+	return nil, fmt.Errorf("failed to resolve DNS seed %s: %v", name, lastErr)
+}
+
+// dnsSeedAddress returns the address of the node with the given ID, resolving
+// the host with resolver if it isn't an IP.
+func dnsSeedAddress(ctx context.Context, resolver Resolver, id, host, port string) (*p2p.NetAddress, error) {
+	if net.ParseIP(host) == nil {
+		ips, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, p2p.ErrNetAddressLookup{Addr: host, Err: err}
+		}
+		if len(ips) == 0 {
+			return nil, p2p.ErrNetAddressLookup{Addr: host, Err: fmt.Errorf("no IP address")}
+		}
+		host = ips[0].IP.String()
+	}
+	return p2p.NewNetAddressString(p2p.IDAddressString(p2p.ID(id), net.JoinHostPort(host, port)))
+}
@@ -0,0 +1,132 @@
+package pex
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// stubResolver resolves the names from its maps, and fails to resolve the
+// others.
+type stubResolver struct {
+	txt map[string][]string
+	srv map[string][]*net.SRV
+	ip  map[string][]net.IPAddr
+}
+
+func (r stubResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txts, ok := r.txt[name]; ok {
+		return txts, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name}
+}
+
+func (r stubResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if srvs, ok := r.srv[name]; ok {
+		return name, srvs, nil
+	}
+	return "", nil, &net.DNSError{Err: "no such host", Name: name}
+}
+
+func (r stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if ips, ok := r.ip[host]; ok {
+		return ips, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host}
+}
+
+const (
+	testSeedID1 = "ed3dfd27bfc4af18f67a49862f04cc100696e84d"
+	testSeedID2 = "d824b13cb5d40fa1d8a614e089357c7eff31b670"
+)
+
+func TestResolveDNSSeed(t *testing.T) {
+	resolver := stubResolver{
+		txt: map[string][]string{
+			"txt.seeds.example.com": {
+				testSeedID1 + "@1.2.3.4:26656, " + testSeedID2 + "@seed.example.com:26656",
+				"not-an-address",
+			},
+		},
+		srv: map[string][]*net.SRV{
+			"srv.seeds.example.com": {
+				{Target: testSeedID1 + ".seed.example.com.", Port: 26657},
+				{Target: "invalid.seed.example.com.", Port: 26657},
+			},
+		},
+		ip: map[string][]net.IPAddr{
+			"seed.example.com":                {{IP: net.ParseIP("5.6.7.8")}},
+			testSeedID1 + ".seed.example.com": {{IP: net.ParseIP("::1")}},
+			"invalid.seed.example.com":        {{IP: net.ParseIP("5.6.7.9")}},
+		},
+	}
+
+	addrs, err := resolveDNSSeed(resolver, "txt.seeds.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		testSeedID1 + "@1.2.3.4:26656",
+		testSeedID2 + "@5.6.7.8:26656",
+	}, addrStrings(addrs))
+
+	addrs, err = resolveDNSSeed(resolver, "srv.seeds.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{testSeedID1 + "@[::1]:26657"}, addrStrings(addrs))
+
+	_, err = resolveDNSSeed(resolver, "unknown.example.com")
+	assert.Error(t, err)
+}
+
+func TestPEXReactorUsesDNSSeeds(t *testing.T) {
+	// directory to store address books
+	dir, err := ioutil.TempDir("", "pex_reactor")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	// 1. create seed
+	seed := testCreateSeed(dir, 0, []*p2p.NetAddress{}, []*p2p.NetAddress{})
+	require.Nil(t, seed.Start())
+	defer seed.Stop()
+
+	// 2. a peer fails to start if its DNS seed doesn't resolve and its book
+	// is empty
+	badConf := &PEXReactorConfig{
+		DNSSeeds: []string{"seeds.example.com"},
+		Resolver: stubResolver{},
+	}
+	peer := testCreatePeerWithConfig(dir, 1, badConf)
+	require.Error(t, peer.Start())
+	peer.Stop()
+
+	// 3. create usual peer with only the DNS seed configured
+	conf := &PEXReactorConfig{
+		DNSSeeds: []string{"seeds.example.com"},
+		Resolver: stubResolver{
+			txt: map[string][]string{
+				"seeds.example.com": {seed.NodeInfo().NetAddress().String()},
+			},
+		},
+	}
+	peer = testCreatePeerWithConfig(dir, 2, conf)
+	require.Nil(t, peer.Start())
+	defer peer.Stop()
+
+	// 4. check that the peer connects to seed immediately
+	assertPeersWithTimeout(t, []*p2p.Switch{peer}, 10*time.Millisecond, 3*time.Second, 1)
+}
+
+func addrStrings(addrs []*p2p.NetAddress) []string {
+	strs := make([]string, len(addrs))
+	for i, addr := range addrs {
+		strs[i] = fmt.Sprint(addr)
+	}
+	return strs
+}
@@ -2,6 +2,7 @@ package pex
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 
@@ -69,16 +70,41 @@ func (a *addrBook) loadFromFile(filePath string) bool {
 	a.key = aJSON.Key
 	// Restore .bucketsNew & .bucketsOld
 	for _, ka := range aJSON.Addrs {
-		for _, bucketIndex := range ka.Buckets {
-			bucket := a.getBucket(ka.BucketType, bucketIndex)
-			bucket[ka.Addr.String()] = ka
-		}
-		a.addrLookup[ka.ID()] = ka
-		if ka.BucketType == bucketTypeNew {
-			a.nNew++
-		} else {
-			a.nOld++
+		if _, err := a.restoreAddress(ka); err != nil {
+			cmn.PanicCrisis(fmt.Sprintf("Error reading file %s: %v", filePath, err))
 		}
 	}
 	return true
 }
+
+// restoreAddress puts a saved address back in its buckets. It returns false
+// if the address is dropped, and an error if it is invalid.
+func (a *addrBook) restoreAddress(ka *knownAddress) (bool, error) {
+	if ka.Addr == nil {
+		return false, errors.New("nil address")
+	}
+	if ka.BucketType != bucketTypeNew && ka.BucketType != bucketTypeOld {
+		return false, fmt.Errorf("invalid bucket type %d of %v", ka.BucketType, ka.Addr)
+	}
+	for _, bucketIndex := range ka.Buckets {
+		if bucketIndex < 0 || bucketIndex >= len(a.bucketsOf(ka.BucketType)) {
+			return false, fmt.Errorf("invalid bucket %d of %v", bucketIndex, ka.Addr)
+		}
+	}
+
+	// Drop the private peers, saved before they were made private
+	if _, ok := a.privateIDs[ka.ID()]; ok {
+		return false, nil
+	}
+	for _, bucketIndex := range ka.Buckets {
+		bucket := a.getBucket(ka.BucketType, bucketIndex)
+		bucket[ka.Addr.String()] = ka
+	}
+	a.addrLookup[ka.ID()] = ka
+	if ka.BucketType == bucketTypeNew {
+		a.nNew++
+	} else {
+		a.nOld++
+	}
+	return true, nil
+}
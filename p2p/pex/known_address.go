@@ -16,6 +16,13 @@ type knownAddress struct {
 	LastSuccess time.Time       `json:"last_success"`
 	BucketType  byte            `json:"bucket_type"`
 	Buckets     []int           `json:"buckets"`
+
+	// What we learned about the peer when we last connected to it.
+	LastConnected   time.Time           `json:"last_connected,omitempty"`
+	Latency         time.Duration       `json:"latency,omitempty"` // of the last dial
+	ProtocolVersion p2p.ProtocolVersion `json:"protocol_version"`
+	Network         string              `json:"network,omitempty"` // chain ID
+	Version         string              `json:"version,omitempty"`
 }
 
 func newKnownAddress(addr *p2p.NetAddress, src *p2p.NetAddress) *knownAddress {
@@ -42,6 +49,12 @@ func (ka *knownAddress) copy() *knownAddress {
 		LastSuccess: ka.LastSuccess,
 		BucketType:  ka.BucketType,
 		Buckets:     ka.Buckets,
+
+		LastConnected:   ka.LastConnected,
+		Latency:         ka.Latency,
+		ProtocolVersion: ka.ProtocolVersion,
+		Network:         ka.Network,
+		Version:         ka.Version,
 	}
 }
 
@@ -66,6 +79,13 @@ func (ka *knownAddress) markGood() {
 	ka.LastSuccess = now
 }
 
+func (ka *knownAddress) setNodeInfo(ni p2p.DefaultNodeInfo) {
+	ka.LastConnected = time.Now()
+	ka.ProtocolVersion = ni.ProtocolVersion
+	ka.Network = ni.Network
+	ka.Version = ni.Version
+}
+
 func (ka *knownAddress) addBucketRef(bucketIdx int) int {
 	for _, bucket := range ka.Buckets {
 		if bucket == bucketIdx {
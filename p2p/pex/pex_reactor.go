@@ -2,6 +2,7 @@ package pex
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"sort"
 	"sync"
@@ -52,6 +53,14 @@ const (
 	// Especially in the beginning, node should have more trusted peers than
 	// untrusted.
 	biasToSelectNewPeers = 30 // 70 to select good peers
+
+	// pick this many times more addresses than we need to dial, and dial the
+	// most trusted ones
+	trustSelectionFactor = 2
+
+	// when its peer slots are full, a node disconnects from its least trusted
+	// peer if its trust score is below this, to make room for a better one
+	minTrustScoreToKeepPeer = 50
 )
 
 // PEXReactor handles PEX (peer exchange) and ensures that an
@@ -76,6 +85,11 @@ type PEXReactor struct {
 
 	seedAddrs []*p2p.NetAddress
 
+	// addresses the DNS seed names resolved to
+	dnsSeedsMtx      sync.Mutex
+	dnsSeedAddrs     []*p2p.NetAddress
+	dnsSeedsResolved time.Time
+
 	attemptsToDial sync.Map // address (string) -> {number of attempts (int), last time dialed (time.Time)}
 }
 
@@ -93,6 +107,13 @@ type PEXReactorConfig struct {
 	// Seeds is a list of addresses reactor may use
 	// if it can't connect to peers in the addrbook.
 	Seeds []string
+
+	// DNSSeeds is a list of DNS names whose TXT and SRV records list seed
+	// nodes, resolved on start and again when dialing the seeds.
+	DNSSeeds []string
+
+	// Resolver used to look up DNSSeeds. If nil, net.DefaultResolver is used.
+	Resolver Resolver
 }
 
 type _attemptsToDial struct {
@@ -123,7 +144,11 @@ func (r *PEXReactor) OnStart() error {
 	numOnline, seedAddrs, err := r.checkSeeds()
 	if err != nil {
 		return err
-	} else if numOnline == 0 && r.book.Empty() {
+	}
+	if len(r.config.DNSSeeds) > 0 {
+		numOnline = len(seedAddrs) + len(r.resolveDNSSeeds())
+	}
+	if numOnline == 0 && r.book.Empty() {
 		return errors.New("Address book is empty, and could not connect to any seed nodes")
 	}
 
@@ -167,7 +192,7 @@ func (r *PEXReactor) AddPeer(p Peer) {
 		}
 	} else {
 		// inbound peer is its own source
-		addr := p.NodeInfo().NetAddress()
+		addr := inboundNetAddress(p)
 		src := addr
 
 		// add to book. dont RequestAddrs right away because
@@ -175,6 +200,25 @@ func (r *PEXReactor) AddPeer(p Peer) {
 		err := r.book.AddAddress(addr, src)
 		r.logErrAddrBook(err)
 	}
+
+	r.book.SetNodeInfo(p.NodeInfo().NetAddress(), p.NodeInfo())
+}
+
+// inboundNetAddress returns the address advertised by the inbound peer in the
+// family (IPv4 or IPv6) it's connected from, which is known to be reachable,
+// or its NetAddress if it advertises none in this family.
+func inboundNetAddress(p Peer) *p2p.NetAddress {
+	addrs := p.NodeInfo().NetAddresses()
+	if len(addrs) <= 1 {
+		return p.NodeInfo().NetAddress()
+	}
+	isIPv4 := p.RemoteIP().To4() != nil
+	for _, addr := range addrs {
+		if (addr.IP.To4() != nil) == isIPv4 {
+			return addr
+		}
+	}
+	return addrs[0]
 }
 
 func (r *PEXReactor) logErrAddrBook(err error) {
@@ -196,12 +240,17 @@ func (r *PEXReactor) RemovePeer(p Peer, reason interface{}) {
 	r.lastReceivedRequests.Delete(id)
 }
 
+// DecodeMessage implements p2p.MessageDecoder.
+func (r *PEXReactor) DecodeMessage(chID byte, msgBytes []byte) (interface{}, error) {
+	return decodeMsg(msgBytes)
+}
+
 // Receive implements Reactor by handling incoming PEX messages.
 func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
 	msg, err := decodeMsg(msgBytes)
 	if err != nil {
 		r.Logger.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
-		r.Switch.StopPeerForError(src, err)
+		r.Switch.StopPeerForMisbehavior(src, err)
 		return
 	}
 	r.Logger.Debug("Received message", "src", src, "chId", chID, "msg", msg)
@@ -237,7 +286,7 @@ func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
 		} else {
 			// Check we're not receiving requests too frequently.
 			if err := r.receiveRequest(src); err != nil {
-				r.Switch.StopPeerForError(src, err)
+				r.Switch.StopPeerForMisbehavior(src, err)
 				return
 			}
 			r.SendAddrs(src, r.book.GetSelection())
@@ -246,7 +295,7 @@ func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
 	case *pexAddrsMessage:
 		// If we asked for addresses, add them to the book
 		if err := r.ReceiveAddrs(msg.Addrs, src); err != nil {
-			r.Switch.StopPeerForError(src, err)
+			r.Switch.StopPeerForMisbehavior(src, err)
 			return
 		}
 	default:
@@ -325,6 +374,11 @@ func (r *PEXReactor) ReceiveAddrs(addrs []*p2p.NetAddress, src Peer) error {
 			)
 		}
 
+		// Don't learn the addresses of banned peers.
+		if r.isBanned(na) {
+			continue
+		}
+
 		// NOTE: we check netAddr validity and routability in book#AddAddress.
 		err = r.book.AddAddress(na, srcAddr)
 		if err != nil {
@@ -336,7 +390,7 @@ func (r *PEXReactor) ReceiveAddrs(addrs []*p2p.NetAddress, src Peer) error {
 
 		// If this address came from a seed node, try to connect to it without
 		// waiting.
-		for _, seedAddr := range r.seedAddrs {
+		for _, seedAddr := range r.seeds() {
 			if seedAddr.Equals(srcAddr) {
 				r.ensurePeers()
 			}
@@ -345,6 +399,11 @@ func (r *PEXReactor) ReceiveAddrs(addrs []*p2p.NetAddress, src Peer) error {
 	return nil
 }
 
+// isBanned returns true if the address is in the ban list of the switch.
+func (r *PEXReactor) isBanned(addr *p2p.NetAddress) bool {
+	return r.Switch != nil && r.Switch.BanList().IsAddrBanned(addr)
+}
+
 // SendAddrs sends addrs to the peer.
 func (r *PEXReactor) SendAddrs(p Peer, netAddrs []*p2p.NetAddress) {
 	p.Send(PexChannel, cdc.MustMarshalBinaryBare(&pexAddrsMessage{Addrs: netAddrs}))
@@ -378,6 +437,7 @@ func (r *PEXReactor) ensurePeersRoutine() {
 	for {
 		select {
 		case <-ticker.C:
+			r.attemptDisconnects()
 			r.ensurePeers()
 		case <-r.Quit():
 			ticker.Stop()
@@ -413,30 +473,53 @@ func (r *PEXReactor) ensurePeers() {
 	// NOTE: range here is [10, 90]. Too high ?
 	newBias := cmn.MinInt(out, 8)*10 + 10
 
-	toDial := make(map[p2p.ID]*p2p.NetAddress)
-	// Try maxAttempts times to pick numToDial addresses to dial
-	maxAttempts := numToDial * 3
+	// Pick more addresses than we need, so that we can prefer the most
+	// trusted ones.
+	var (
+		candidates    = make([]*p2p.NetAddress, 0, numToDial*trustSelectionFactor)
+		selected      = make(map[p2p.ID]struct{})
+		maxCandidates = numToDial * trustSelectionFactor
+		// Try maxAttempts times to pick maxCandidates addresses
+		maxAttempts = maxCandidates * 3
+	)
 
-	for i := 0; i < maxAttempts && len(toDial) < numToDial; i++ {
+	for i := 0; i < maxAttempts && len(candidates) < maxCandidates; i++ {
 		try := r.book.PickAddress(newBias)
 		if try == nil {
 			continue
 		}
-		if _, selected := toDial[try.ID]; selected {
+		if _, ok := selected[try.ID]; ok {
 			continue
 		}
 		if r.Switch.IsDialingOrExistingAddress(try) {
 			continue
 		}
+		if r.isBanned(try) {
+			r.book.MarkBad(try)
+			continue
+		}
 		// TODO: consider moving some checks from toDial into here
 		// so we don't even consider dialing peers that we want to wait
 		// before dialling again, or have dialed too many times already
-		r.Logger.Info("Will dial address", "addr", try)
-		toDial[try.ID] = try
+		selected[try.ID] = struct{}{}
+		candidates = append(candidates, try)
 	}
 
-	// Dial picked addresses
+	// Dial the most trusted of the picked addresses. The picking order is kept
+	// between equally trusted ones.
+	scores := make(map[p2p.ID]int, len(candidates))
+	for _, addr := range candidates {
+		scores[addr.ID] = r.Switch.PeerTrustScore(addr.ID)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return scores[candidates[i].ID] > scores[candidates[j].ID]
+	})
+	toDial := candidates
+	if len(toDial) > numToDial {
+		toDial = toDial[:numToDial]
+	}
 	for _, addr := range toDial {
+		r.Logger.Info("Will dial address", "addr", addr, "trust", scores[addr.ID])
 		go r.dialPeer(addr)
 	}
 
@@ -491,6 +574,7 @@ func (r *PEXReactor) dialPeer(addr *p2p.NetAddress) {
 		}
 	}
 
+	start := time.Now()
 	err := r.Switch.DialPeerWithAddress(addr, false)
 	if err != nil {
 		r.Logger.Error("Dialing failed", "addr", addr, "err", err, "attempts", attempts)
@@ -507,6 +591,7 @@ func (r *PEXReactor) dialPeer(addr *p2p.NetAddress) {
 			r.attemptsToDial.Store(addr.DialString(), _attemptsToDial{attempts + 1, time.Now()})
 		}
 	} else {
+		r.book.SetLatency(addr, time.Since(start))
 		// cleanup any history
 		r.attemptsToDial.Delete(addr.DialString())
 	}
@@ -535,13 +620,70 @@ func (r *PEXReactor) checkSeeds() (numOnline int, netAddrs []*p2p.NetAddress, er
 	return
 }
 
-// randomly dial seeds until we connect to one or exhaust them
+// resolveDNSSeeds resolves the DNS seed names, and returns the addresses they
+// resolved to. The names which fail to resolve are skipped.
+func (r *PEXReactor) resolveDNSSeeds() []*p2p.NetAddress {
+	var resolver Resolver = net.DefaultResolver
+	if r.config.Resolver != nil {
+		resolver = r.config.Resolver
+	}
+
+	addrs := []*p2p.NetAddress{}
+	seen := make(map[string]bool)
+	for _, name := range r.config.DNSSeeds {
+		nameAddrs, err := resolveDNSSeed(resolver, name)
+		if err != nil {
+			r.Logger.Error("Resolving DNS seed failed", "name", name, "err", err)
+			continue
+		}
+		for _, addr := range nameAddrs {
+			if !seen[addr.String()] {
+				seen[addr.String()] = true
+				addrs = append(addrs, addr)
+			}
+		}
+		r.Logger.Info("Resolved DNS seed", "name", name, "addrs", nameAddrs)
+	}
+
+	r.dnsSeedsMtx.Lock()
+	defer r.dnsSeedsMtx.Unlock()
+	// keep the previous addresses if the names failed to resolve this time
+	if len(addrs) > 0 {
+		r.dnsSeedAddrs = addrs
+	}
+	r.dnsSeedsResolved = time.Now()
+	return addrs
+}
+
+// seeds returns the addresses of the configured seeds, followed by the ones
+// the DNS seed names last resolved to.
+func (r *PEXReactor) seeds() []*p2p.NetAddress {
+	r.dnsSeedsMtx.Lock()
+	defer r.dnsSeedsMtx.Unlock()
+	seeds := make([]*p2p.NetAddress, 0, len(r.seedAddrs)+len(r.dnsSeedAddrs))
+	seeds = append(seeds, r.seedAddrs...)
+	return append(seeds, r.dnsSeedAddrs...)
+}
+
+// randomly dial seeds until we connect to one or exhaust them. The DNS seed
+// names are resolved again first, if they were last resolved more than
+// dnsSeedsRefreshInterval ago.
 func (r *PEXReactor) dialSeeds() {
-	perm := cmn.RandPerm(len(r.seedAddrs))
+	if len(r.config.DNSSeeds) > 0 {
+		r.dnsSeedsMtx.Lock()
+		refresh := time.Since(r.dnsSeedsResolved) > dnsSeedsRefreshInterval
+		r.dnsSeedsMtx.Unlock()
+		if refresh {
+			r.resolveDNSSeeds()
+		}
+	}
+
+	seedAddrs := r.seeds()
+	perm := cmn.RandPerm(len(seedAddrs))
 	// perm := r.Switch.rng.Perm(lSeeds)
 	for _, i := range perm {
 		// dial a random seed
-		seedAddr := r.seedAddrs[i]
+		seedAddr := seedAddrs[i]
 		err := r.Switch.DialPeerWithAddress(seedAddr, false)
 		if err == nil {
 			return
@@ -660,16 +802,52 @@ func (r *PEXReactor) crawlPeers() {
 	}
 }
 
-// attemptDisconnects checks if we've been with each peer long enough to disconnect
+// attemptDisconnects disconnects from peers to make room for others. In seed
+// mode, it checks if we've been with each peer long enough to disconnect.
+// Otherwise, when the outbound or inbound peer slots are full, it disconnects
+// from the least trusted peer of those if its trust score is too low.
 func (r *PEXReactor) attemptDisconnects() {
+	if r.config.SeedMode {
+		for _, peer := range r.Switch.Peers().List() {
+			if peer.Status().Duration < defaultSeedDisconnectWaitPeriod {
+				continue
+			}
+			if peer.IsPersistent() {
+				continue
+			}
+			r.Switch.StopPeerGracefully(peer)
+		}
+		return
+	}
+
+	out, in, _ := r.Switch.NumPeers()
+	if out >= r.Switch.MaxNumOutboundPeers() {
+		r.evictLeastTrustedPeer(true)
+	}
+	if in >= r.Switch.MaxNumInboundPeers() {
+		r.evictLeastTrustedPeer(false)
+	}
+}
+
+// evictLeastTrustedPeer disconnects from the outbound or inbound peer with
+// the lowest trust score, if it's below minTrustScoreToKeepPeer. Persistent
+// peers are kept.
+func (r *PEXReactor) evictLeastTrustedPeer(outbound bool) {
+	var (
+		worst      Peer
+		worstScore = minTrustScoreToKeepPeer
+	)
 	for _, peer := range r.Switch.Peers().List() {
-		if peer.Status().Duration < defaultSeedDisconnectWaitPeriod {
+		if peer.IsOutbound() != outbound || peer.IsPersistent() {
 			continue
 		}
-		if peer.IsPersistent() {
-			continue
+		if score := r.Switch.PeerTrustScore(peer.ID()); score < worstScore {
+			worst, worstScore = peer, score
 		}
-		r.Switch.StopPeerGracefully(peer)
+	}
+	if worst != nil {
+		r.Logger.Info("Disconnecting from least trusted peer", "peer", worst, "trust", worstScore)
+		r.Switch.StopPeerGracefully(worst)
 	}
 }
 
@@ -15,11 +15,13 @@ import (
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
 	"github.com/tendermint/tendermint/libs/log"
 
 	"github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/p2p/conn"
+	"github.com/tendermint/tendermint/p2p/trust"
 )
 
 var (
@@ -444,6 +446,80 @@ func TestPEXReactorDialPeer(t *testing.T) {
 	}
 }
 
+func TestPEXReactorEvictsLeastTrustedPeer(t *testing.T) {
+	pexR, book := createReactor(&PEXReactorConfig{})
+	defer teardownReactor(book)
+
+	trustStore := trust.NewTrustMetricStore(dbm.NewMemDB(), trust.DefaultConfig())
+	trustStore.SetLogger(log.TestingLogger())
+	require.Nil(t, trustStore.Start())
+	defer trustStore.Stop()
+
+	conf := config.DefaultP2PConfig()
+	conf.MaxNumInboundPeers = 2
+	switches := p2p.MakeConnectedSwitches(conf, 3, func(i int, sw *p2p.Switch) *p2p.Switch {
+		if i == 0 {
+			p2p.SwitchTrustMetricStore(trustStore)(sw)
+		}
+		return sw
+	}, p2p.Connect2Switches)
+	for _, sw := range switches {
+		defer sw.Stop()
+	}
+	sw := switches[0]
+	pexR.SetSwitch(sw)
+
+	// the peers are trusted so far
+	pexR.attemptDisconnects()
+	require.Equal(t, 2, sw.Peers().Size())
+
+	// the peer misbehaves
+	badID := switches[1].NodeInfo().ID()
+	trustStore.GetPeerTrustMetric(string(badID)).BadEvents(10)
+	require.True(t, sw.PeerTrustScore(badID) < minTrustScoreToKeepPeer)
+
+	pexR.attemptDisconnects()
+	assert.Equal(t, 1, sw.Peers().Size())
+	assert.False(t, sw.Peers().Has(badID))
+
+	// there is room for a new peer, so the remaining one is kept
+	goodID := switches[2].NodeInfo().ID()
+	trustStore.GetPeerTrustMetric(string(goodID)).BadEvents(10)
+	pexR.attemptDisconnects()
+	assert.True(t, sw.Peers().Has(goodID))
+}
+
+func TestPEXReactorAddsDualStackPeerByFamily(t *testing.T) {
+	r, book := createReactor(&PEXReactorConfig{})
+	defer teardownReactor(book)
+
+	// a dual-stack peer connected over IPv6 is added with its IPv6 address
+	peer := dualStackPeer{mockPeer: newMockPeer(), remoteIP: net.ParseIP("2600:1::1")}
+	r.AddPeer(peer)
+	addr := book.GetSelection()
+	require.Len(t, addr, 1)
+	assert.Equal(t, p2p.IDAddressString(peer.ID(), "[2600:1::1]:26656"), addr[0].String())
+
+	// and one connected over IPv4 with its IPv4 address
+	peer = dualStackPeer{mockPeer: newMockPeer(), remoteIP: net.ParseIP("1.2.3.4")}
+	assert.Equal(t, p2p.IDAddressString(peer.ID(), "1.2.3.4:26656"), inboundNetAddress(peer).String())
+}
+
+// dualStackPeer is an inbound peer advertising an IPv4 and an IPv6 address.
+type dualStackPeer struct {
+	mockPeer
+	remoteIP net.IP
+}
+
+func (p dualStackPeer) RemoteIP() net.IP { return p.remoteIP }
+func (p dualStackPeer) NodeInfo() p2p.NodeInfo {
+	return p2p.DefaultNodeInfo{
+		ID_:         p.ID(),
+		ListenAddr:  "1.2.3.4:26656",
+		ListenAddrs: []string{"[2600:1::1]:26656"},
+	}
+}
+
 type mockPeer struct {
 	*cmn.BaseService
 	pubKey               crypto.PubKey
@@ -479,6 +555,7 @@ func (mockPeer) TrySend(byte, []byte) bool     { return false }
 func (mockPeer) Set(string, interface{})       {}
 func (mockPeer) Get(string) interface{}        { return nil }
 func (mockPeer) OriginalAddr() *p2p.NetAddress { return nil }
+func (mockPeer) RTT() time.Duration            { return 0 }
 func (mockPeer) RemoteAddr() net.Addr          { return &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8800} }
 func (mockPeer) CloseConn() error              { return nil }
 
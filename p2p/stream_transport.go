@@ -0,0 +1,56 @@
+package p2p
+
+import (
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// StreamTransport is a MultiplexTransport which gives each channel its own
+// stream over the secret connection to the peer, using a
+// conn.StreamConnection, so that a large message on one channel doesn't
+// head-of-line block the others.
+//
+// The transports advertise their support with NodeInfo.Other.StreamMux set to
+// "on", and streams are used only if both the node and the peer support them.
+// Otherwise the peer falls back to a conn.MConnection, as with a
+// MultiplexTransport.
+//
+// NOTE: as each stream reads its own messages, Reactor.Receive may be called
+// concurrently for the different channels of a peer.
+type StreamTransport struct {
+	*MultiplexTransport
+}
+
+// Test StreamTransport for interface completeness.
+var _ Transport = (*StreamTransport)(nil)
+var _ transportLifecycle = (*StreamTransport)(nil)
+
+// NewStreamTransport returns a tcp connected transport which multiplexes the
+// channels with streams. nodeInfo should have Other.StreamMux set to "on".
+func NewStreamTransport(
+	nodeInfo NodeInfo,
+	nodeKey NodeKey,
+	mConfig conn.MConnConfig,
+) *StreamTransport {
+	return &StreamTransport{
+		MultiplexTransport: NewMultiplexTransport(nodeInfo, nodeKey, mConfig),
+	}
+}
+
+// Accept implements Transport.
+func (st *StreamTransport) Accept(cfg peerConfig) (Peer, error) {
+	cfg.streams = true
+	return st.MultiplexTransport.Accept(cfg)
+}
+
+// Dial implements Transport.
+func (st *StreamTransport) Dial(addr NetAddress, cfg peerConfig) (Peer, error) {
+	cfg.streams = true
+	return st.MultiplexTransport.Dial(addr, cfg)
+}
+
+// streamMuxEnabled returns true if the node advertises its support for
+// streams.
+func streamMuxEnabled(ni NodeInfo) bool {
+	dni, ok := ni.(DefaultNodeInfo)
+	return ok && dni.Other.StreamMux == "on"
+}
@@ -0,0 +1,116 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+func testStreamNodeInfo(id ID, name string) NodeInfo {
+	ni := testNodeInfo(id, name).(DefaultNodeInfo)
+	ni.Other.StreamMux = "on"
+	return ni
+}
+
+func TestStreamTransport(t *testing.T) {
+	testCases := []struct {
+		name          string
+		dialerStreams bool
+	}{
+		{"both peers support streams", true},
+		{"falls back to an MConnection", false},
+	}
+
+	for _, tc := range testCases {
+		var (
+			pv = ed25519.GenPrivKey()
+			id = PubKeyToID(pv.PubKey())
+			st = NewStreamTransport(
+				testStreamNodeInfo(id, "transport"),
+				NodeKey{PrivKey: pv},
+				conn.DefaultMConnConfig(),
+			)
+		)
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(id, "127.0.0.1:0"))
+		require.NoError(t, err)
+		require.NoError(t, st.Listen(*addr))
+
+		dialerPV := ed25519.GenPrivKey()
+		dialerID := PubKeyToID(dialerPV.PubKey())
+		var dialer interface {
+			Transport
+			transportLifecycle
+		}
+		if tc.dialerStreams {
+			dialer = NewStreamTransport(
+				testStreamNodeInfo(dialerID, defaultNodeName),
+				NodeKey{PrivKey: dialerPV},
+				conn.DefaultMConnConfig(),
+			)
+		} else {
+			dialer = newMultiplexTransport(
+				testNodeInfo(dialerID, defaultNodeName),
+				NodeKey{PrivKey: dialerPV},
+			)
+		}
+
+		reactor := NewTestReactor([]*conn.ChannelDescriptor{{ID: testCh, Priority: 1}}, true)
+		cfg := peerConfig{
+			chDescs:      reactor.GetChannels(),
+			onPeerError:  func(Peer, interface{}) {},
+			reactorsByCh: map[byte]Reactor{testCh: reactor},
+			metrics:      NopMetrics(),
+		}
+
+		var (
+			dialedc = make(chan Peer, 1)
+			errc    = make(chan error, 1)
+		)
+		go func() {
+			addr, err := NewNetAddressStringWithOptionalID(IDAddressString(id, st.listeners[0].Addr().String()))
+			if err != nil {
+				errc <- err
+				return
+			}
+			p, err := dialer.Dial(*addr, cfg)
+			if err != nil {
+				errc <- err
+				return
+			}
+			dialedc <- p
+		}()
+
+		accepted, err := st.Accept(cfg)
+		require.NoError(t, err, tc.name)
+		var dialed Peer
+		select {
+		case dialed = <-dialedc:
+		case err := <-errc:
+			t.Fatalf("%s: dial failed: %v", tc.name, err)
+		case <-time.After(3 * time.Second):
+			t.Fatalf("%s: timed out dialing", tc.name)
+		}
+
+		for _, p := range []Peer{accepted, dialed} {
+			_, isStreamConn := p.(*peer).mconn.(*conn.StreamConnection)
+			assert.Equal(t, tc.dialerStreams, isStreamConn, tc.name)
+			p.SetLogger(log.TestingLogger())
+			require.NoError(t, p.Start())
+		}
+
+		assert.True(t, dialed.Send(testCh, []byte("Storm")), tc.name)
+		assertMsgReceivedWithTimeout(t, []byte("Storm"), testCh, reactor, 10*time.Millisecond, 3*time.Second)
+
+		for _, p := range []Peer{accepted, dialed} {
+			require.NoError(t, p.Stop())
+		}
+		require.NoError(t, dialer.Close())
+		require.NoError(t, st.Close())
+	}
+}
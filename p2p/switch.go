@@ -1,6 +1,7 @@
 package p2p
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"github.com/tendermint/tendermint/config"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/p2p/conn"
+	"github.com/tendermint/tendermint/p2p/trust"
 )
 
 const (
@@ -25,16 +27,28 @@ const (
 	// ie. 3**10 = 16hrs
 	reconnectBackOffAttempts    = 10
 	reconnectBackOffBaseSeconds = 3
+
+	// in sentry mode, keep reconnecting to the validators with an
+	// exponentially increasing interval, up to a minute
+	validatorReconnectBaseInterval = 1 * time.Second
+	validatorReconnectMaxInterval  = 1 * time.Minute
+
+	// trust score of the peers without a trust metric, same as a new metric's
+	defaultTrustScore = 100
 )
 
 // MConnConfig returns an MConnConfig with fields updated
-// from the P2PConfig.
+// from the P2PConfig. Its Bandwidth budget, if any, is shared
+// by all the connections using the returned config.
 func MConnConfig(cfg *config.P2PConfig) conn.MConnConfig {
 	mConfig := conn.DefaultMConnConfig()
 	mConfig.FlushThrottle = cfg.FlushThrottleTimeout
 	mConfig.SendRate = cfg.SendRate
 	mConfig.RecvRate = cfg.RecvRate
 	mConfig.MaxPacketMsgPayloadSize = cfg.MaxPacketMsgPayloadSize
+	if cfg.TotalSendRate > 0 || cfg.TotalRecvRate > 0 {
+		mConfig.Bandwidth = conn.NewBandwidth(cfg.TotalSendRate, cfg.TotalRecvRate)
+	}
 	return mConfig
 }
 
@@ -75,6 +89,12 @@ type Switch struct {
 	nodeInfo     NodeInfo // our node info
 	nodeKey      *NodeKey // our node privkey
 	addrBook     AddrBook
+	banList      *BanList
+	allowList    *AllowList
+	validators   map[ID]struct{} // guarded in sentry mode
+	protected    map[ID]struct{} // never banned for misbehavior
+	channelRates map[byte]config.ChannelRate
+	trustStore   *trust.TrustMetricStore
 
 	transport Transport
 
@@ -84,6 +104,8 @@ type Switch struct {
 	rng *cmn.Rand // seed for randomizing dial times and orders
 
 	metrics *Metrics
+	capture *Capture
+	faults  *FaultInjector
 }
 
 // SwitchOption sets an optional parameter on the Switch.
@@ -130,6 +152,65 @@ func SwitchPeerFilters(filters ...PeerFilterFunc) SwitchOption {
 	return func(sw *Switch) { sw.peerFilters = filters }
 }
 
+// SwitchBanList sets the BanList of the peers banned by the Switch. It should
+// be the one used by the Transport.
+func SwitchBanList(banList *BanList) SwitchOption {
+	return func(sw *Switch) { sw.banList = banList }
+}
+
+// SwitchAllowList sets the AllowList of the peers allowed to connect. It
+// should be the one used by the Transport.
+func SwitchAllowList(allowList *AllowList) SwitchOption {
+	return func(sw *Switch) { sw.allowList = allowList }
+}
+
+// SwitchSentryValidators sets the IDs of the validators guarded by the node in
+// sentry mode. The Switch never gives up reconnecting to them.
+func SwitchSentryValidators(ids []ID) SwitchOption {
+	return func(sw *Switch) {
+		sw.validators = make(map[ID]struct{}, len(ids))
+		for _, id := range ids {
+			sw.validators[id] = struct{}{}
+		}
+	}
+}
+
+// SwitchProtectedPeers sets the IDs of the peers which are never banned for
+// misbehavior, e.g. the persistent and private peers. The persistent peers
+// the Switch dialed and the sentry validators are always protected.
+func SwitchProtectedPeers(ids []ID) SwitchOption {
+	return func(sw *Switch) {
+		sw.protected = make(map[ID]struct{}, len(ids))
+		for _, id := range ids {
+			sw.protected[id] = struct{}{}
+		}
+	}
+}
+
+// SwitchTrustMetricStore sets the store of the trust metrics of the peers,
+// which records their good and bad behavior.
+func SwitchTrustMetricStore(trustStore *trust.TrustMetricStore) SwitchOption {
+	return func(sw *Switch) { sw.trustStore = trustStore }
+}
+
+// SwitchCapture records the messages sent to and received from the peers
+// with the capture. It must be started and stopped by the caller.
+func SwitchCapture(capture *Capture) SwitchOption {
+	return func(sw *Switch) { sw.capture = capture }
+}
+
+// SwitchFaultInjector injects the faults of the injector into the
+// connections to the peers. For testing only.
+func SwitchFaultInjector(faults *FaultInjector) SwitchOption {
+	return func(sw *Switch) { sw.faults = faults }
+}
+
+// SwitchChannelRates caps the rates at which the given channels send and
+// receive, overriding the caps set by their reactors.
+func SwitchChannelRates(rates map[byte]config.ChannelRate) SwitchOption {
+	return func(sw *Switch) { sw.channelRates = rates }
+}
+
 // WithMetrics sets the metrics.
 func WithMetrics(metrics *Metrics) SwitchOption {
 	return func(sw *Switch) { sw.metrics = metrics }
@@ -149,6 +230,11 @@ func (sw *Switch) AddReactor(name string, reactor Reactor) Reactor {
 		if sw.reactorsByCh[chID] != nil {
 			cmn.PanicSanity(fmt.Sprintf("Channel %X has multiple reactors %v & %v", chID, sw.reactorsByCh[chID], reactor))
 		}
+		if rate, ok := sw.channelRates[chID]; ok {
+			capped := *chDesc
+			capped.SendRate, capped.RecvRate = rate.SendRate, rate.RecvRate
+			chDesc = &capped
+		}
 		sw.chDescs = append(sw.chDescs, chDesc)
 		sw.reactorsByCh[chID] = reactor
 	}
@@ -271,6 +357,11 @@ func (sw *Switch) MaxNumOutboundPeers() int {
 	return sw.config.MaxNumOutboundPeers
 }
 
+// MaxNumInboundPeers returns a maximum number of inbound peers.
+func (sw *Switch) MaxNumInboundPeers() int {
+	return sw.config.MaxNumInboundPeers
+}
+
 // Peers returns the set of peers that are connected to the switch.
 func (sw *Switch) Peers() IPeerSet {
 	return sw.peers
@@ -281,6 +372,9 @@ func (sw *Switch) Peers() IPeerSet {
 // TODO: make record depending on reason.
 func (sw *Switch) StopPeerForError(peer Peer, reason interface{}) {
 	sw.Logger.Error("Stopping peer for error", "peer", peer, "err", reason)
+	if sw.trustStore != nil {
+		sw.trustStore.GetPeerTrustMetric(string(peer.ID())).BadEvents(1)
+	}
 	sw.stopAndRemovePeer(peer, reason)
 
 	if peer.IsPersistent() {
@@ -294,6 +388,35 @@ func (sw *Switch) StopPeerForError(peer Peer, reason interface{}) {
 	}
 }
 
+// StopPeerForMisbehavior bans a peer which misbehaved, e.g. by sending an
+// invalid message, for the configured BanDuration, and disconnects from it.
+// If the Switch has no BanList, BanDuration is zero (the default) or the peer
+// is protected (persistent, private or a sentry validator), the peer is only
+// disconnected.
+func (sw *Switch) StopPeerForMisbehavior(peer Peer, reason interface{}) {
+	if sw.banList != nil && sw.config.BanDuration > 0 && !sw.isProtected(peer) {
+		_, err := sw.banList.Add(string(peer.ID()), sw.config.BanDuration, fmt.Sprintf("%v", reason))
+		if err != nil {
+			sw.Logger.Error("Error banning peer", "peer", peer, "err", err)
+		} else {
+			sw.Logger.Info("Banned peer", "peer", peer, "duration", sw.config.BanDuration, "reason", reason)
+		}
+	}
+	sw.StopPeerForError(peer, reason)
+}
+
+// isProtected returns true if the peer must never be banned for misbehavior.
+func (sw *Switch) isProtected(peer Peer) bool {
+	if peer.IsPersistent() {
+		return true
+	}
+	if _, ok := sw.protected[peer.ID()]; ok {
+		return true
+	}
+	_, ok := sw.validators[peer.ID()]
+	return ok
+}
+
 // StopPeerGracefully disconnects from a peer gracefully.
 // TODO: handle graceful disconnects.
 func (sw *Switch) StopPeerGracefully(peer Peer) {
@@ -310,6 +433,9 @@ func (sw *Switch) stopAndRemovePeer(peer Peer, reason interface{}) {
 	for _, reactor := range sw.reactors {
 		reactor.RemovePeer(peer, reason)
 	}
+	if sw.trustStore != nil {
+		sw.trustStore.PeerDisconnected(string(peer.ID()))
+	}
 }
 
 // reconnectToPeer tries to reconnect to the addr, first repeatedly
@@ -327,6 +453,11 @@ func (sw *Switch) reconnectToPeer(addr *NetAddress) {
 	sw.reconnecting.Set(string(addr.ID), addr)
 	defer sw.reconnecting.Delete(string(addr.ID))
 
+	if _, ok := sw.validators[addr.ID]; ok {
+		sw.reconnectToValidator(addr)
+		return
+	}
+
 	start := time.Now()
 	sw.Logger.Info("Reconnecting to peer", "addr", addr)
 	for i := 0; i < reconnectAttempts; i++ {
@@ -334,6 +465,11 @@ func (sw *Switch) reconnectToPeer(addr *NetAddress) {
 			return
 		}
 
+		if sw.banList.IsAddrBanned(addr) {
+			sw.Logger.Info("Peer is banned. Not reconnecting", "addr", addr)
+			return
+		}
+
 		if sw.IsDialingOrExistingAddress(addr) {
 			sw.Logger.Debug("Peer connection has been established or dialed while we waiting next try", "addr", addr)
 			return
@@ -357,6 +493,11 @@ func (sw *Switch) reconnectToPeer(addr *NetAddress) {
 			return
 		}
 
+		if sw.banList.IsAddrBanned(addr) {
+			sw.Logger.Info("Peer is banned. Not reconnecting", "addr", addr)
+			return
+		}
+
 		// sleep an exponentially increasing amount
 		sleepIntervalSeconds := math.Pow(reconnectBackOffBaseSeconds, float64(i))
 		sw.randomSleep(time.Duration(sleepIntervalSeconds) * time.Second)
@@ -369,6 +510,41 @@ func (sw *Switch) reconnectToPeer(addr *NetAddress) {
 	sw.Logger.Error("Failed to reconnect to peer. Giving up", "addr", addr, "elapsed", time.Since(start))
 }
 
+// reconnectToValidator keeps trying to reconnect to a validator guarded by
+// the node in sentry mode, with exponential backoff, until the switch stops.
+func (sw *Switch) reconnectToValidator(addr *NetAddress) {
+	start := time.Now()
+	sw.Logger.Info("Reconnecting to validator", "addr", addr)
+	interval := validatorReconnectBaseInterval
+	for i := 0; ; i++ {
+		if !sw.IsRunning() {
+			return
+		}
+
+		if sw.banList.IsAddrBanned(addr) {
+			sw.Logger.Info("Validator is banned. Not reconnecting", "addr", addr)
+			return
+		}
+
+		if sw.IsDialingOrExistingAddress(addr) {
+			sw.Logger.Debug("Validator connection has been established or dialed while waiting for the next try", "addr", addr)
+			return
+		}
+
+		err := sw.DialPeerWithAddress(addr, true)
+		if err == nil {
+			return // success
+		}
+
+		sw.Logger.Info("Error reconnecting to validator. Trying again", "tries", i, "err", err,
+			"addr", addr, "elapsed", time.Since(start))
+		sw.randomSleep(interval)
+		if interval *= 2; interval > validatorReconnectMaxInterval {
+			interval = validatorReconnectMaxInterval
+		}
+	}
+}
+
 // SetAddrBook allows to set address book on Switch.
 func (sw *Switch) SetAddrBook(addrBook AddrBook) {
 	sw.addrBook = addrBook
@@ -380,11 +556,135 @@ func (sw *Switch) MarkPeerAsGood(peer Peer) {
 	if sw.addrBook != nil {
 		sw.addrBook.MarkGood(peer.NodeInfo().NetAddress())
 	}
+	if sw.trustStore != nil {
+		sw.trustStore.GetPeerTrustMetric(string(peer.ID())).GoodEvents(1)
+	}
+}
+
+// PeerTrustScore returns the trust score, between 0 and 100, of the peer with
+// the given ID. Peers without a trust metric, including all of them if the
+// Switch has no trust metric store, get the score of a new metric.
+func (sw *Switch) PeerTrustScore(id ID) int {
+	if sw.trustStore == nil {
+		return defaultTrustScore
+	}
+	score, ok := sw.trustStore.PeerTrustScore(string(id))
+	if !ok {
+		return defaultTrustScore
+	}
+	return score
+}
+
+//---------------------------------------------------------------------
+// Bans
+
+// BanList returns the list of banned peers, which may be nil.
+func (sw *Switch) BanList() *BanList {
+	return sw.banList
+}
+
+// Ban bans the target (a node ID, an IP or a CIDR range) for the given
+// duration, or permanently if it is zero, and disconnects from the matching
+// peers, without reconnecting to them.
+func (sw *Switch) Ban(target string, duration time.Duration, reason string) (Ban, error) {
+	if sw.banList == nil {
+		return Ban{}, errors.New("no ban list")
+	}
+	ban, err := sw.banList.Add(target, duration, reason)
+	if err != nil {
+		return ban, err
+	}
+	sw.Logger.Info("Banned peers", "target", ban.Target, "duration", duration, "reason", reason)
+
+	for _, peer := range sw.peers.List() {
+		if ban.Matches(peer.ID(), peer.RemoteIP()) {
+			sw.Logger.Info("Stopping banned peer", "peer", peer)
+			sw.stopAndRemovePeer(peer, fmt.Errorf("banned: %s", reason))
+		}
+	}
+	return ban, nil
+}
+
+// Unban lifts the ban of the target.
+func (sw *Switch) Unban(target string) error {
+	if sw.banList == nil {
+		return errors.New("no ban list")
+	}
+	return sw.banList.Remove(target)
+}
+
+// AllowList returns the list of the peers allowed to connect, which is nil
+// if every peer is.
+func (sw *Switch) AllowList() *AllowList {
+	return sw.allowList
+}
+
+// ReloadAllowList reads the allow list file again, and disconnects from the
+// inbound peers which are no longer allowed.
+func (sw *Switch) ReloadAllowList() error {
+	if sw.allowList == nil {
+		return errors.New("no allow list")
+	}
+	if err := sw.allowList.Reload(); err != nil {
+		return err
+	}
+	sw.Logger.Info("Reloaded allow list", "numIDs", len(sw.allowList.List()))
+
+	for _, peer := range sw.peers.List() {
+		if !peer.IsOutbound() && !sw.allowList.IsAllowed(peer.ID()) {
+			sw.Logger.Info("Stopping peer no longer allowed", "peer", peer)
+			sw.stopAndRemovePeer(peer, errors.New("no longer allowed"))
+		}
+	}
+	return nil
 }
 
 //---------------------------------------------------------------------
 // Dialing
 
+// FaultInjector returns the injector of the faults into the connections to
+// the peers, or nil if faults can't be injected.
+func (sw *Switch) FaultInjector() *FaultInjector {
+	return sw.faults
+}
+
+// Partition partitions the node from the peers: it disconnects from them, and
+// rejects them until the partition is healed with Heal. The persistent peers
+// are redialed as usual, and reconnect once healed.
+func (sw *Switch) Partition(ids []ID) error {
+	if sw.faults == nil {
+		return errors.New("fault injection is disabled")
+	}
+	sw.faults.Partition(ids)
+	sw.Logger.Info("Partitioned from peers", "ids", ids)
+
+	for _, id := range ids {
+		peer := sw.peers.Get(id)
+		if peer == nil {
+			continue
+		}
+		sw.stopAndRemovePeer(peer, errors.New("partitioned"))
+		if peer.IsPersistent() {
+			addr := peer.OriginalAddr()
+			if addr == nil {
+				addr = peer.NodeInfo().NetAddress()
+			}
+			go sw.reconnectToPeer(addr)
+		}
+	}
+	return nil
+}
+
+// Heal heals the partitions from the peers, or all of them if ids is empty.
+func (sw *Switch) Heal(ids []ID) error {
+	if sw.faults == nil {
+		return errors.New("fault injection is disabled")
+	}
+	sw.faults.Heal(ids)
+	sw.Logger.Info("Healed partitions from peers", "ids", ids)
+	return nil
+}
+
 // DialPeersAsync dials a list of peers asynchronously in random order (optionally, making them persistent).
 // Used to dial peers from config on startup or from unsafe-RPC (trusted sources).
 // TODO: remove addrBook arg since it's now set on the switch
@@ -478,6 +778,8 @@ func (sw *Switch) acceptRoutine() {
 			onPeerError:  sw.StopPeerForError,
 			reactorsByCh: sw.reactorsByCh,
 			metrics:      sw.metrics,
+			capture:      sw.capture,
+			faults:       sw.faults,
 		})
 		if err != nil {
 			switch err.(type) {
@@ -574,6 +876,8 @@ func (sw *Switch) addOutboundPeerWithConfig(
 		persistent:   persistent,
 		reactorsByCh: sw.reactorsByCh,
 		metrics:      sw.metrics,
+		capture:      sw.capture,
+		faults:       sw.faults,
 	})
 	if err != nil {
 		switch e := err.(type) {
@@ -586,10 +890,13 @@ func (sw *Switch) addOutboundPeerWithConfig(
 
 				return err
 			}
+			if e.IsBanned() {
+				return err
+			}
 		}
 
 		// retry persistent peers after
-		// any dial error besides IsSelf()
+		// any dial error besides IsSelf() and IsBanned()
 		if persistent {
 			go sw.reconnectToPeer(addr)
 		}
@@ -614,6 +921,11 @@ func (sw *Switch) filterPeer(p Peer) error {
 		return ErrRejected{id: p.ID(), isDuplicate: true}
 	}
 
+	// Injected partition
+	if sw.faults.IsPartitioned(p.ID()) {
+		return ErrRejected{id: p.ID(), err: errors.New("partitioned"), isFiltered: true}
+	}
+
 	errc := make(chan error, len(sw.peerFilters))
 
 	for _, f := range sw.peerFilters {
@@ -666,6 +978,11 @@ func (sw *Switch) addPeer(p Peer) error {
 	sw.Logger.Info("Added peer", "peer", p)
 	sw.metrics.Peers.Add(float64(1))
 
+	// Start tracking the peer's behavior.
+	if sw.trustStore != nil {
+		sw.trustStore.GetPeerTrustMetric(string(p.ID()))
+	}
+
 	return nil
 }
 
@@ -20,8 +20,10 @@ import (
 
 	"github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	dbm "github.com/tendermint/tendermint/libs/db"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p/conn"
+	"github.com/tendermint/tendermint/p2p/trust"
 )
 
 var (
@@ -74,6 +76,8 @@ func (tr *TestReactor) Receive(chID byte, peer Peer, msgBytes []byte) {
 		tr.mtx.Lock()
 		defer tr.mtx.Unlock()
 		//fmt.Printf("Received: %X, %X\n", chID, msgBytes)
+		// the connection reuses msgBytes once we return
+		msgBytes = append([]byte{}, msgBytes...)
 		tr.msgsReceived[chID] = append(tr.msgsReceived[chID], PeerMessage{peer.ID(), msgBytes, tr.msgsCounter})
 		tr.msgsCounter++
 	}
@@ -139,6 +143,42 @@ func TestSwitches(t *testing.T) {
 	assertMsgReceivedWithTimeout(t, ch2Msg, byte(0x02), s2.Reactor("bar").(*TestReactor), 10*time.Millisecond, 5*time.Second)
 }
 
+func TestSwitchChannelRates(t *testing.T) {
+	const (
+		msgSize = 1000
+		numMsgs = 6
+	)
+	s1, s2 := MakeSwitchPair(t, func(i int, sw *Switch) *Switch {
+		// 2 messages per second at most on channel 0x00
+		SwitchChannelRates(map[byte]config.ChannelRate{0x00: {SendRate: 2 * msgSize}})(sw)
+		sw.AddReactor("foo", NewTestReactor([]*conn.ChannelDescriptor{
+			{ID: byte(0x00), Priority: 10, SendQueueCapacity: numMsgs},
+			{ID: byte(0x01), Priority: 10, SendQueueCapacity: numMsgs},
+		}, true))
+		return sw
+	})
+	defer s1.Stop()
+	defer s2.Stop()
+	reactor := s2.Reactor("foo").(*TestReactor)
+
+	start := time.Now()
+	for i := 0; i < numMsgs; i++ {
+		s1.Broadcast(byte(0x00), make([]byte, msgSize))
+		s1.Broadcast(byte(0x01), make([]byte, msgSize))
+	}
+	waitMsgs := func(chID byte) time.Duration {
+		for len(reactor.getMsgs(chID)) < numMsgs {
+			if time.Since(start) > 10*time.Second {
+				t.Fatalf("Did not receive the messages of channel %X in 10s", chID)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return time.Since(start)
+	}
+	assert.True(t, waitMsgs(0x01) < time.Second, "the uncapped channel was throttled")
+	assert.True(t, waitMsgs(0x00) > time.Second, "the capped channel exceeded its SendRate")
+}
+
 func assertMsgReceivedWithTimeout(t *testing.T, msgBytes []byte, channel byte, reactor *TestReactor, checkPeriod, timeout time.Duration) {
 	ticker := time.NewTicker(checkPeriod)
 	for {
@@ -391,6 +431,207 @@ func TestSwitchStopPeerForError(t *testing.T) {
 	assert.EqualValues(t, 0, peersMetricValue())
 }
 
+func TestSwitchStopPeerForMisbehaviorProtected(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	bl, cleanup := newTestBanList(t)
+	defer cleanup()
+
+	// simulate remote peers: a persistent one, and a private one
+	persistent := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	persistent.Start()
+	defer persistent.Stop()
+	private := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	private.Start()
+	defer private.Stop()
+
+	banCfg := *cfg
+	banCfg.BanDuration = time.Hour
+	sw := MakeSwitch(&banCfg, 1, "testing", "123.123.123", initSwitchFunc,
+		SwitchBanList(bl), SwitchProtectedPeers([]ID{private.ID()}))
+	sw.transport.(*MultiplexTransport).banList = bl
+	err := sw.Start()
+	require.Nil(err)
+	defer sw.Stop()
+
+	require.Nil(sw.DialPeerWithAddress(persistent.Addr(), true))
+	require.Nil(sw.DialPeerWithAddress(private.Addr(), false))
+
+	for _, rp := range []*remotePeer{persistent, private} {
+		p := sw.Peers().Get(rp.ID())
+		require.NotNil(p)
+		sw.StopPeerForMisbehavior(p, fmt.Errorf("some err"))
+		assert.False(p.IsRunning())
+		assert.False(bl.IsBanned(rp.ID(), nil), "expected peer %v not to be banned", rp.ID())
+	}
+
+	// the persistent peer is reconnected
+	for i := 0; sw.Peers().Get(persistent.ID()) == nil; i++ {
+		if i == 100 {
+			t.Fatal("expected the persistent peer to be reconnected")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSwitchStopPeerForMisbehavior(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	bl, cleanup := newTestBanList(t)
+	defer cleanup()
+
+	banCfg := *cfg
+	banCfg.BanDuration = time.Hour
+	sw := MakeSwitch(&banCfg, 1, "testing", "123.123.123", initSwitchFunc, SwitchBanList(bl))
+	sw.transport.(*MultiplexTransport).banList = bl
+	err := sw.Start()
+	require.Nil(err)
+	defer sw.Stop()
+
+	// simulate remote peer
+	rp := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	rp.Start()
+	defer rp.Stop()
+
+	err = sw.DialPeerWithAddress(rp.Addr(), false)
+	require.Nil(err)
+	p := sw.Peers().Get(rp.ID())
+	require.NotNil(p)
+
+	sw.StopPeerForMisbehavior(p, fmt.Errorf("some err"))
+	assert.False(p.IsRunning())
+	assert.Nil(sw.Peers().Get(rp.ID()))
+	assert.True(bl.IsBanned(rp.ID(), nil))
+
+	// banned peers can't be dialed
+	err = sw.DialPeerWithAddress(rp.Addr(), false)
+	require.NotNil(err)
+	e, ok := err.(ErrRejected)
+	require.True(ok, "%v", err)
+	assert.True(e.IsBanned())
+
+	// banning an IP range disconnects the peers in it
+	require.Nil(sw.Unban(string(rp.ID())))
+	require.Nil(sw.DialPeerWithAddress(rp.Addr(), false))
+	require.NotNil(sw.Peers().Get(rp.ID()))
+	_, err = sw.Ban("127.0.0.0/8", time.Hour, "local")
+	require.Nil(err)
+	assertNoPeersAfterTimeout(t, sw, 100*time.Millisecond)
+	err = sw.DialPeerWithAddress(rp.Addr(), false)
+	require.NotNil(err)
+}
+
+func TestSwitchTrustMetrics(t *testing.T) {
+	trustStore := trust.NewTrustMetricStore(dbm.NewMemDB(), trust.DefaultConfig())
+	trustStore.SetLogger(log.TestingLogger())
+	require.Nil(t, trustStore.Start())
+	defer trustStore.Stop()
+
+	sw1, sw2 := MakeSwitchPair(t, func(i int, sw *Switch) *Switch {
+		if i == 0 {
+			SwitchTrustMetricStore(trustStore)(sw)
+		}
+		return initSwitchFunc(i, sw)
+	})
+	defer sw1.Stop()
+	defer sw2.Stop()
+
+	// connected peers get a trust metric
+	p := sw1.Peers().List()[0]
+	_, ok := trustStore.PeerTrustScore(string(p.ID()))
+	assert.True(t, ok)
+	assert.Equal(t, 100, sw1.PeerTrustScore(p.ID()))
+	assert.Equal(t, 100, sw1.PeerTrustScore("unknown"))
+
+	sw1.MarkPeerAsGood(p)
+	assert.Equal(t, 100, sw1.PeerTrustScore(p.ID()))
+
+	// errors lower the trust score, which is kept once disconnected
+	sw1.StopPeerForError(p, fmt.Errorf("some err"))
+	sw1.StopPeerForError(p, fmt.Errorf("some err"))
+	assert.True(t, sw1.PeerTrustScore(p.ID()) < 100)
+}
+
+func TestSwitchAllowList(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	al, cleanup := newTestAllowList(t, "")
+	defer cleanup()
+
+	sw := MakeSwitch(cfg, 1, "testing", "123.123.123", initSwitchFunc, SwitchAllowList(al))
+	sw.transport.(*MultiplexTransport).allowList = al
+	err := sw.Start()
+	require.Nil(err)
+	defer sw.Stop()
+
+	rp := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	rp.Start()
+	defer rp.Stop()
+
+	// peers which aren't allowed can't connect
+	_, err = rp.Dial(sw.NodeInfo().NetAddress())
+	require.NotNil(err)
+	assert.Equal(0, sw.Peers().Size())
+
+	// but can still be dialed
+	require.Nil(sw.DialPeerWithAddress(rp.Addr(), false))
+	p := sw.Peers().Get(rp.ID())
+	require.NotNil(p)
+	sw.StopPeerGracefully(p)
+
+	// allowed peers can connect
+	require.Nil(ioutil.WriteFile(al.filePath, []byte(rp.ID()), 0644))
+	require.Nil(sw.ReloadAllowList())
+	c, err := rp.Dial(sw.NodeInfo().NetAddress())
+	require.Nil(err)
+	defer c.Close()
+	go func() {
+		// keep reading to prevent the connection from closing
+		one := make([]byte, 1)
+		for {
+			if _, err := c.Read(one); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20 && sw.Peers().Size() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NotNil(sw.Peers().Get(rp.ID()))
+
+	// and are disconnected from once they're no longer allowed
+	require.Nil(ioutil.WriteFile(al.filePath, nil, 0644))
+	require.Nil(sw.ReloadAllowList())
+	assertNoPeersAfterTimeout(t, sw, 100*time.Millisecond)
+}
+
+func TestSwitchReconnectsToValidator(t *testing.T) {
+	sw := MakeSwitch(cfg, 1, "testing", "123.123.123", initSwitchFunc)
+	err := sw.Start()
+	require.NoError(t, err)
+	defer sw.Stop()
+
+	rp := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	rp.Start()
+	defer rp.Stop()
+	SwitchSentryValidators([]ID{rp.ID()})(sw)
+
+	// the validator is down for longer than its first reconnection interval
+	addr := *rp.Addr()
+	rp.Stop()
+	go sw.reconnectToPeer(&addr)
+	time.Sleep(validatorReconnectBaseInterval + 500*time.Millisecond)
+	assert.True(t, sw.reconnecting.Has(string(addr.ID)))
+
+	rp = &remotePeer{PrivKey: rp.PrivKey, Config: cfg, listenAddr: addr.DialString()}
+	rp.Start()
+	defer rp.Stop()
+	for i := 0; i < 100 && sw.Peers().Size() == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.NotNil(t, sw.Peers().Get(rp.ID()))
+}
+
 func TestSwitchReconnectsToPersistentPeer(t *testing.T) {
 	assert, require := assert.New(t), require.New(t)
 
@@ -24,6 +24,7 @@ type mockNodeInfo struct {
 
 func (ni mockNodeInfo) ID() ID                              { return ni.addr.ID }
 func (ni mockNodeInfo) NetAddress() *NetAddress             { return ni.addr }
+func (ni mockNodeInfo) NetAddresses() []*NetAddress         { return []*NetAddress{ni.addr} }
 func (ni mockNodeInfo) Validate() error                     { return nil }
 func (ni mockNodeInfo) CompatibleWith(other NodeInfo) error { return nil }
 
@@ -140,6 +141,8 @@ func (sw *Switch) addPeerWithConnection(conn net.Conn) error {
 		sw.reactorsByCh,
 		sw.chDescs,
 		sw.StopPeerForError,
+		PeerCapture(sw.capture),
+		PeerFaultInjector(sw.faults),
 	)
 
 	if err = sw.addPeer(p); err != nil {
@@ -224,8 +227,8 @@ func testPeerConn(
 		conn = FuzzConnAfterFromConfig(conn, 10*time.Second, cfg.TestFuzzConfig)
 	}
 
-	// Encrypt connection
-	conn, err = upgradeSecretConn(conn, cfg.HandshakeTimeout, ourNodePrivKey)
+	// Encrypt connection, with the STS handshake which is symmetric
+	conn, err = upgradeSecretConn(conn, cfg.HandshakeTimeout, ourNodePrivKey, true, "sts")
 	if err != nil {
 		return pc, cmn.ErrorWrap(err, "Error creating peer")
 	}
@@ -41,6 +41,12 @@ type peerConfig struct {
 	outbound, persistent bool
 	reactorsByCh         map[byte]Reactor
 	metrics              *Metrics
+	capture              *Capture
+	faults               *FaultInjector
+
+	// streams is set by transports which use a StreamConnection with the
+	// peers supporting it.
+	streams bool
 }
 
 // Transport emits and connects to Peers. The implementation of Peer is left to
@@ -106,6 +112,26 @@ func MultiplexTransportFilterTimeout(
 	return func(mt *MultiplexTransport) { mt.filterTimeout = timeout }
 }
 
+// MultiplexTransportBanList sets the BanList used to reject the connections
+// from and to banned peers.
+func MultiplexTransportBanList(banList *BanList) MultiplexTransportOption {
+	return func(mt *MultiplexTransport) { mt.banList = banList }
+}
+
+// MultiplexTransportAllowList sets the AllowList of the peers allowed to
+// connect to the node. The transport still dials any peer.
+func MultiplexTransportAllowList(allowList *AllowList) MultiplexTransportOption {
+	return func(mt *MultiplexTransport) { mt.allowList = allowList }
+}
+
+// MultiplexTransportHandshake sets the handshake used to secure the dialed
+// connections, conn.HandshakeSTS (the default) or conn.HandshakeNoise. With
+// conn.HandshakeNoise, the peers which don't support it are dialed again with
+// conn.HandshakeSTS. Both are accepted from the peers which dial.
+func MultiplexTransportHandshake(handshake string) MultiplexTransportOption {
+	return func(mt *MultiplexTransport) { mt.secretHandshake = handshake }
+}
+
 // MultiplexTransportResolver sets the Resolver used for ip lokkups, defaults to
 // net.DefaultResolver.
 func MultiplexTransportResolver(resolver IPResolver) MultiplexTransportOption {
@@ -113,9 +139,10 @@ func MultiplexTransportResolver(resolver IPResolver) MultiplexTransportOption {
 }
 
 // MultiplexTransport accepts and dials tcp connections and upgrades them to
-// multiplexed peers.
+// multiplexed peers. It accepts the connections of all the addresses it
+// listens on, e.g. an IPv4 and an IPv6 one.
 type MultiplexTransport struct {
-	listener net.Listener
+	listeners []net.Listener
 
 	acceptc chan accept
 	closec  chan struct{}
@@ -123,9 +150,12 @@ type MultiplexTransport struct {
 	// Lookup table for duplicate ip and id checks.
 	conns       ConnSet
 	connFilters []ConnFilterFunc
+	banList     *BanList
+	allowList   *AllowList
 
 	dialTimeout      time.Duration
 	filterTimeout    time.Duration
+	secretHandshake  string
 	handshakeTimeout time.Duration
 	nodeInfo         NodeInfo
 	nodeKey          NodeKey
@@ -152,6 +182,7 @@ func NewMultiplexTransport(
 		closec:           make(chan struct{}),
 		dialTimeout:      defaultDialTimeout,
 		filterTimeout:    defaultFilterTimeout,
+		secretHandshake:  conn.HandshakeSTS,
 		handshakeTimeout: defaultHandshakeTimeout,
 		mConfig:          mConfig,
 		nodeInfo:         nodeInfo,
@@ -184,17 +215,15 @@ func (mt *MultiplexTransport) Dial(
 	addr NetAddress,
 	cfg peerConfig,
 ) (Peer, error) {
-	c, err := addr.DialTimeout(mt.dialTimeout)
-	if err != nil {
-		return nil, err
+	if mt.banList.IsAddrBanned(&addr) {
+		return nil, ErrRejected{addr: addr, id: addr.ID, isBanned: true}
 	}
 
-	// TODO(xla): Evaluate if we should apply filters if we explicitly dial.
-	if err := mt.filterConn(c); err != nil {
-		return nil, err
+	secretConn, nodeInfo, err := mt.dial(addr, mt.secretHandshake)
+	if err == conn.ErrNoiseNotSupported {
+		// the peer only supports the STS handshake
+		secretConn, nodeInfo, err = mt.dial(addr, conn.HandshakeSTS)
 	}
-
-	secretConn, nodeInfo, err := mt.upgrade(c, &addr)
 	if err != nil {
 		return nil, err
 	}
@@ -206,34 +235,57 @@ func (mt *MultiplexTransport) Dial(
 	return p, nil
 }
 
+// dial connects to the address, and upgrades the connection with the
+// secretHandshake.
+func (mt *MultiplexTransport) dial(
+	addr NetAddress,
+	secretHandshake string,
+) (*conn.SecretConnection, NodeInfo, error) {
+	c, err := addr.DialTimeout(mt.dialTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// TODO(xla): Evaluate if we should apply filters if we explicitly dial.
+	if err := mt.filterConn(c); err != nil {
+		return nil, nil, err
+	}
+
+	return mt.upgrade(c, &addr, secretHandshake)
+}
+
 // Close implements transportLifecycle.
 func (mt *MultiplexTransport) Close() error {
 	close(mt.closec)
 
-	if mt.listener != nil {
-		return mt.listener.Close()
+	var err error
+	for _, ln := range mt.listeners {
+		if lnErr := ln.Close(); lnErr != nil && err == nil {
+			err = lnErr
+		}
 	}
 
-	return nil
+	return err
 }
 
-// Listen implements transportLifecycle.
+// Listen implements transportLifecycle. It can be called once per address to
+// listen on, before the transport is closed.
 func (mt *MultiplexTransport) Listen(addr NetAddress) error {
 	ln, err := net.Listen("tcp", addr.DialString())
 	if err != nil {
 		return err
 	}
 
-	mt.listener = ln
+	mt.listeners = append(mt.listeners, ln)
 
-	go mt.acceptPeers()
+	go mt.acceptPeers(ln)
 
 	return nil
 }
 
-func (mt *MultiplexTransport) acceptPeers() {
+func (mt *MultiplexTransport) acceptPeers(ln net.Listener) {
 	for {
-		c, err := mt.listener.Accept()
+		c, err := ln.Accept()
 		if err != nil {
 			// If Close() has been called, silently exit.
 			select {
@@ -262,7 +314,7 @@ func (mt *MultiplexTransport) acceptPeers() {
 
 			err := mt.filterConn(c)
 			if err == nil {
-				secretConn, nodeInfo, err = mt.upgrade(c, nil)
+				secretConn, nodeInfo, err = mt.upgrade(c, nil, mt.secretHandshake)
 			}
 
 			select {
@@ -308,6 +360,12 @@ func (mt *MultiplexTransport) filterConn(c net.Conn) (err error) {
 		return err
 	}
 
+	for _, ip := range ips {
+		if mt.banList.IsBanned("", ip) {
+			return ErrRejected{conn: c, isBanned: true}
+		}
+	}
+
 	errc := make(chan error, len(mt.connFilters))
 
 	for _, f := range mt.connFilters {
@@ -333,9 +391,12 @@ func (mt *MultiplexTransport) filterConn(c net.Conn) (err error) {
 	return nil
 }
 
+// upgrade secures the connection, with the secretHandshake if we dialed it, and
+// exchanges the NodeInfo with the peer.
 func (mt *MultiplexTransport) upgrade(
 	c net.Conn,
 	dialedAddr *NetAddress,
+	secretHandshake string,
 ) (secretConn *conn.SecretConnection, nodeInfo NodeInfo, err error) {
 	defer func() {
 		if err != nil {
@@ -343,8 +404,10 @@ func (mt *MultiplexTransport) upgrade(
 		}
 	}()
 
-	secretConn, err = upgradeSecretConn(c, mt.handshakeTimeout, mt.nodeKey.PrivKey)
-	if err != nil {
+	secretConn, err = upgradeSecretConn(c, mt.handshakeTimeout, mt.nodeKey.PrivKey, dialedAddr != nil, secretHandshake)
+	if err == conn.ErrNoiseNotSupported {
+		return nil, nil, err
+	} else if err != nil {
 		return nil, nil, ErrRejected{
 			conn:          c,
 			err:           fmt.Errorf("secrect conn failed: %v", err),
@@ -352,8 +415,26 @@ func (mt *MultiplexTransport) upgrade(
 		}
 	}
 
-	// For outgoing conns, ensure connection key matches dialed key.
+	// Reject banned peers, which may be known by ID only.
 	connID := PubKeyToID(secretConn.RemotePubKey())
+	if mt.banList.IsBanned(connID, nil) {
+		return nil, nil, ErrRejected{
+			conn:     c,
+			id:       connID,
+			isBanned: true,
+		}
+	}
+
+	// For incoming conns, ensure the peer is allowed to connect.
+	if dialedAddr == nil && !mt.allowList.IsAllowed(connID) {
+		return nil, nil, ErrRejected{
+			conn:         c,
+			id:           connID,
+			isNotAllowed: true,
+		}
+	}
+
+	// For outgoing conns, ensure connection key matches dialed key.
 	if dialedAddr != nil {
 		if dialedID := dialedAddr.ID; connID != dialedID {
 			return nil, nil, ErrRejected{
@@ -432,6 +513,7 @@ func (mt *MultiplexTransport) wrapPeer(
 	peerConn := newPeerConn(
 		cfg.outbound,
 		cfg.persistent,
+		cfg.streams && streamMuxEnabled(mt.nodeInfo) && streamMuxEnabled(ni),
 		c,
 		dialedAddr,
 	)
@@ -444,6 +526,8 @@ func (mt *MultiplexTransport) wrapPeer(
 		cfg.chDescs,
 		cfg.onPeerError,
 		PeerMetrics(cfg.metrics),
+		PeerCapture(cfg.capture),
+		PeerFaultInjector(cfg.faults),
 	)
 
 	return p
@@ -492,12 +576,25 @@ func upgradeSecretConn(
 	c net.Conn,
 	timeout time.Duration,
 	privKey crypto.PrivKey,
+	dialed bool,
+	secretHandshake string,
 ) (*conn.SecretConnection, error) {
 	if err := c.SetDeadline(time.Now().Add(timeout)); err != nil {
 		return nil, err
 	}
 
-	sc, err := conn.MakeSecretConnection(c, privKey)
+	var (
+		sc  *conn.SecretConnection
+		err error
+	)
+	switch {
+	case !dialed:
+		sc, err = conn.AcceptSecretConnection(c, privKey)
+	case secretHandshake == conn.HandshakeNoise:
+		sc, err = conn.MakeNoiseSecretConnection(c, privKey)
+	default:
+		sc, err = conn.MakeSecretConnection(c, privKey)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +58,7 @@ func TestTransportMultiplexConnFilter(t *testing.T) {
 	errc := make(chan error)
 
 	go func() {
-		addr, err := NewNetAddressStringWithOptionalID(mt.listener.Addr().String())
+		addr, err := NewNetAddressStringWithOptionalID(mt.listeners[0].Addr().String())
 		if err != nil {
 			errc <- err
 			return
@@ -115,7 +115,7 @@ func TestTransportMultiplexConnFilterTimeout(t *testing.T) {
 	errc := make(chan error)
 
 	go func() {
-		addr, err := NewNetAddressStringWithOptionalID(mt.listener.Addr().String())
+		addr, err := NewNetAddressStringWithOptionalID(mt.listeners[0].Addr().String())
 		if err != nil {
 			errc <- err
 			return
@@ -160,7 +160,7 @@ func TestTransportMultiplexAcceptMultiple(t *testing.T) {
 					},
 				)
 			)
-			addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+			addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 			if err != nil {
 				errc <- err
 				return
@@ -229,7 +229,7 @@ func TestTransportMultiplexAcceptNonBlocking(t *testing.T) {
 
 	// Simulate slow Peer.
 	go func() {
-		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 		if err != nil {
 			errc <- err
 			return
@@ -251,7 +251,7 @@ func TestTransportMultiplexAcceptNonBlocking(t *testing.T) {
 			errc <- fmt.Errorf("Fast peer timed out")
 		}
 
-		sc, err := upgradeSecretConn(c, 20*time.Millisecond, ed25519.GenPrivKey())
+		sc, err := upgradeSecretConn(c, 20*time.Millisecond, ed25519.GenPrivKey(), true, conn.HandshakeSTS)
 		if err != nil {
 			errc <- err
 			return
@@ -280,7 +280,7 @@ func TestTransportMultiplexAcceptNonBlocking(t *testing.T) {
 				},
 			)
 		)
-		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 		if err != nil {
 			errc <- err
 			return
@@ -326,7 +326,7 @@ func TestTransportMultiplexValidateNodeInfo(t *testing.T) {
 			)
 		)
 
-		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 		if err != nil {
 			errc <- err
 			return
@@ -369,7 +369,7 @@ func TestTransportMultiplexRejectMissmatchID(t *testing.T) {
 				PrivKey: ed25519.GenPrivKey(),
 			},
 		)
-		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 		if err != nil {
 			errc <- err
 			return
@@ -412,7 +412,7 @@ func TestTransportMultiplexDialRejectWrongID(t *testing.T) {
 	)
 
 	wrongID := PubKeyToID(ed25519.GenPrivKey().PubKey())
-	addr, err := NewNetAddressStringWithOptionalID(IDAddressString(wrongID, mt.listener.Addr().String()))
+	addr, err := NewNetAddressStringWithOptionalID(IDAddressString(wrongID, mt.listeners[0].Addr().String()))
 	if err != nil {
 		t.Fatalf("invalid address with ID: %v", err)
 	}
@@ -445,7 +445,7 @@ func TestTransportMultiplexRejectIncompatible(t *testing.T) {
 				},
 			)
 		)
-		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 		if err != nil {
 			errc <- err
 			return
@@ -476,7 +476,7 @@ func TestTransportMultiplexRejectSelf(t *testing.T) {
 	errc := make(chan error)
 
 	go func() {
-		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listener.Addr().String()))
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
 		if err != nil {
 			errc <- err
 			return
@@ -591,6 +591,175 @@ func TestTransportHandshake(t *testing.T) {
 	}
 }
 
+func TestTransportMultiplexListenMultiple(t *testing.T) {
+	mt := testSetupMultiplexTransport(t)
+
+	// listen on another IPv4 address, and on IPv6 if available
+	for _, lAddr := range []string{"127.0.0.1:0", "[::1]:0"} {
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), lAddr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mt.Listen(*addr); err != nil {
+			if addr.IP.To4() == nil {
+				t.Logf("IPv6 not available: %v", err)
+				continue
+			}
+			t.Fatal(err)
+		}
+	}
+
+	addrs := []*NetAddress{}
+	for _, ln := range mt.listeners {
+		addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), ln.Addr().String()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, addr)
+	}
+
+	// peers are accepted on each of the addresses
+	for _, addr := range addrs {
+		errc := make(chan error)
+		go func(addr NetAddress) {
+			pv := ed25519.GenPrivKey()
+			dialer := newMultiplexTransport(
+				testNodeInfo(PubKeyToID(pv.PubKey()), defaultNodeName),
+				NodeKey{
+					PrivKey: pv,
+				},
+			)
+			_, err := dialer.Dial(addr, peerConfig{})
+			errc <- err
+		}(*addr)
+
+		p, err := mt.Accept(peerConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.RemoteIP().To4() == nil, addr.IP.To4() == nil; have != want {
+			t.Errorf("peer accepted on %v has remote IP %v", addr, p.RemoteIP())
+		}
+	}
+
+	// all the listeners are closed
+	if err := mt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range addrs {
+		if c, err := addr.Dial(); err == nil {
+			_ = c.Close()
+			t.Errorf("dialed %v after close", addr)
+		}
+	}
+}
+
+func TestTransportMultiplexNoiseHandshake(t *testing.T) {
+	mt := testSetupMultiplexTransport(t)
+	defer mt.Close() // nolint: errcheck
+
+	var (
+		pv     = ed25519.GenPrivKey()
+		dialer = newMultiplexTransport(
+			testNodeInfo(PubKeyToID(pv.PubKey()), defaultNodeName),
+			NodeKey{
+				PrivKey: pv,
+			},
+		)
+	)
+	MultiplexTransportHandshake(conn.HandshakeNoise)(dialer)
+
+	addr, err := NewNetAddressStringWithOptionalID(IDAddressString(mt.nodeKey.ID(), mt.listeners[0].Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error)
+	go func() {
+		p, err := mt.Accept(peerConfig{})
+		if err == nil {
+			if have, want := secretHandshakeOf(p), conn.HandshakeNoise; have != want {
+				err = fmt.Errorf("accepted peer handshake: have %v, want %v", have, want)
+			}
+		}
+		errc <- err
+	}()
+
+	p, err := dialer.Dial(*addr, peerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if have, want := secretHandshakeOf(p), conn.HandshakeNoise; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestTransportMultiplexNoiseFallback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close() // nolint: errcheck
+
+	var (
+		peerPV       = ed25519.GenPrivKey()
+		peerNodeInfo = testNodeInfo(PubKeyToID(peerPV.PubKey()), defaultNodeName)
+	)
+
+	// A peer which only supports the STS handshake.
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			sc, err := conn.MakeSecretConnection(c, peerPV)
+			if err != nil {
+				c.Close() // nolint: errcheck
+				continue
+			}
+			if _, err := handshake(sc, time.Second, peerNodeInfo); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+
+	var (
+		pv     = ed25519.GenPrivKey()
+		dialer = newMultiplexTransport(
+			testNodeInfo(PubKeyToID(pv.PubKey()), defaultNodeName),
+			NodeKey{
+				PrivKey: pv,
+			},
+		)
+	)
+	MultiplexTransportHandshake(conn.HandshakeNoise)(dialer)
+
+	addr, err := NewNetAddressStringWithOptionalID(IDAddressString(PubKeyToID(peerPV.PubKey()), ln.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := dialer.Dial(*addr, peerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, want := secretHandshakeOf(p), conn.HandshakeSTS; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+// secretHandshakeOf returns the handshake used to secure the connection of p.
+func secretHandshakeOf(p Peer) string {
+	return p.(*peer).conn.(*conn.SecretConnection).Handshake()
+}
+
 func testSetupMultiplexTransport(t *testing.T) *MultiplexTransport {
 	var (
 		pv = ed25519.GenPrivKey()
@@ -114,6 +114,20 @@ func (tms *TrustMetricStore) GetPeerTrustMetric(key string) *TrustMetric {
 	return tm
 }
 
+// PeerTrustScore returns the trust score of the peer identified by the key, and
+// false if the peer has no trust metric yet. Unlike GetPeerTrustMetric, it
+// doesn't create a metric for unknown peers
+func (tms *TrustMetricStore) PeerTrustScore(key string) (int, bool) {
+	tms.mtx.Lock()
+	defer tms.mtx.Unlock()
+
+	tm, ok := tms.peerMetrics[key]
+	if !ok {
+		return 0, false
+	}
+	return tm.TrustScore(), true
+}
+
 // PeerDisconnected pauses the trust metric associated with the peer identified by the key
 func (tms *TrustMetricStore) PeerDisconnected(key string) {
 	tms.mtx.Lock()
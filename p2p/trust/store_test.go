@@ -127,6 +127,8 @@ func TestTrustMetricStorePeerScore(t *testing.T) {
 	store.Start()
 
 	key := "TestKey"
+	_, ok := store.PeerTrustScore(key)
+	assert.False(t, ok, "PeerTrustScore shouldn't create a metric")
 	tm := store.GetPeerTrustMetric(key)
 
 	// This peer is innocent so far
@@ -148,5 +150,8 @@ func TestTrustMetricStorePeerScore(t *testing.T) {
 	// We will remember our experiences with this peer
 	tm = store.GetPeerTrustMetric(key)
 	assert.NotEqual(t, 100, tm.TrustScore())
+	score, ok := store.PeerTrustScore(key)
+	assert.True(t, ok)
+	assert.Equal(t, tm.TrustScore(), score)
 	store.Stop()
 }
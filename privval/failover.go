@@ -0,0 +1,537 @@
+package privval
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Failover errors.
+var (
+	ErrNoSignerEndpoint   = errors.New("no signer endpoint is available")
+	ErrSignerKeyMismatch  = errors.New("signer endpoints have different keys")
+	ErrSignStateUnknown   = errors.New("can't fail over: the sign state of neither the previous signer nor a majority of the signers is known")
+	ErrSignRequestInDoubt = errors.New("conflicts with a sign request which the previous signer may have signed")
+)
+
+// SignerEndpoint is a connection to an external signing process which keeps
+// a SignState, like TCPVal and IPCVal.
+type SignerEndpoint interface {
+	cmn.Service
+	types.PrivValidator
+
+	Ping() error
+	SignState() (SignState, error)
+	SyncSignState(SignState) error
+
+	getPubKey() (crypto.PubKey, error)
+}
+
+// Check that TCPVal and IPCVal implement SignerEndpoint.
+var (
+	_ SignerEndpoint = (*TCPVal)(nil)
+	_ SignerEndpoint = (*IPCVal)(nil)
+)
+
+// FailoverValOption sets an optional parameter on the FailoverVal.
+type FailoverValOption func(*FailoverVal)
+
+// FailoverValHealthCheckPeriod sets the period on which to ping the signer
+// endpoints and to restart the failed ones.
+func FailoverValHealthCheckPeriod(period time.Duration) FailoverValOption {
+	return func(fv *FailoverVal) { fv.healthCheckPeriod = period }
+}
+
+// FailoverValMetrics sets the metrics.
+func FailoverValMetrics(metrics *Metrics) FailoverValOption {
+	return func(fv *FailoverVal) { fv.metrics = metrics }
+}
+
+type failoverEndpoint struct {
+	addr       string
+	endpoint   SignerEndpoint // nil while down
+	restarting bool
+}
+
+// FailoverVal implements PrivValidator with several external signing
+// processes of the same validator, one endpoint each. It signs with one of
+// them and fails over to the next healthy one when it disconnects.
+//
+// The signers share their SignState: before signing with a new endpoint, it
+// reads the state of the reachable signers and syncs the latest one (or the
+// one of its own last signature) to it, so that it refuses to sign for an
+// earlier height/round/step. It only fails over when it has read the state of
+// the previous signer, or of a majority of the signers (the state of each
+// signature is synced to all the reachable standby signers before it is
+// returned); otherwise it refuses to sign with ErrSignStateUnknown until
+// enough signers are back. Run at least three signers to fail over when one
+// of them is down. Until the state of the previous signer is read again, it
+// also refuses to sign for the height/round/step of a request which failed
+// while the previous signer may have signed it (ErrSignRequestInDoubt).
+type FailoverVal struct {
+	cmn.BaseService
+
+	newEndpoint       func(addr string) SignerEndpoint
+	healthCheckPeriod time.Duration
+	metrics           *Metrics
+
+	mtx           sync.Mutex
+	endpoints     []*failoverEndpoint
+	active        *failoverEndpoint
+	lastActive    *failoverEndpoint
+	lastSignState SignState
+	pubKey        crypto.PubKey
+
+	// The endpoint which failed while it was asked to sign, and the request.
+	inDoubt        *failoverEndpoint
+	inDoubtRequest SignState
+
+	// The health and restart routines, stopped by closing stopc and waited
+	// for on stop.
+	stopc    chan struct{}
+	routines sync.WaitGroup
+}
+
+// Check that FailoverVal implements PrivValidator.
+var _ types.PrivValidator = (*FailoverVal)(nil)
+
+// NewFailoverVal returns an instance of FailoverVal, which signs with the
+// endpoints created by newEndpoint for the given addresses, preferably in
+// their order.
+func NewFailoverVal(
+	logger log.Logger,
+	addrs []string,
+	newEndpoint func(addr string) SignerEndpoint,
+	options ...FailoverValOption,
+) *FailoverVal {
+	fv := &FailoverVal{
+		newEndpoint:       newEndpoint,
+		healthCheckPeriod: connHeartbeat,
+		metrics:           NopMetrics(),
+	}
+	for _, addr := range addrs {
+		fv.endpoints = append(fv.endpoints, &failoverEndpoint{addr: addr})
+	}
+	for _, option := range options {
+		option(fv)
+	}
+
+	fv.BaseService = *cmn.NewBaseService(logger, "FailoverVal", fv)
+
+	return fv
+}
+
+// OnStart implements cmn.Service. It waits for the signers to connect to all
+// the endpoints, and fails if none of them does.
+func (fv *FailoverVal) OnStart() error {
+	var (
+		pubKeys = make([]crypto.PubKey, len(fv.endpoints))
+		wg      sync.WaitGroup
+	)
+	for i, fe := range fv.endpoints {
+		wg.Add(1)
+		go func(i int, fe *failoverEndpoint) {
+			defer wg.Done()
+			ep, err := fv.startEndpoint(fe.addr, nil)
+			if err == nil {
+				if pubKeys[i], err = ep.getPubKey(); err != nil {
+					ep.Stop() // nolint: errcheck
+				}
+			}
+			if err != nil {
+				fv.Logger.Error("Signer endpoint failed to start", "addr", fe.addr, "err", err)
+				return
+			}
+			fe.endpoint = ep
+		}(i, fe)
+	}
+	wg.Wait()
+
+	for i, fe := range fv.endpoints {
+		if fe.endpoint == nil {
+			fv.metrics.SignerUp.With("endpoint", fe.addr).Set(0)
+			continue
+		}
+		fv.metrics.SignerUp.With("endpoint", fe.addr).Set(1)
+
+		if fv.pubKey != nil && !fv.pubKey.Equals(pubKeys[i]) {
+			fv.stopEndpoints()
+			return ErrSignerKeyMismatch
+		}
+		fv.pubKey = pubKeys[i]
+	}
+	if fv.pubKey == nil {
+		return ErrNoSignerEndpoint
+	}
+
+	fv.stopc = make(chan struct{})
+	fv.routines.Add(1)
+	go fv.healthRoutine()
+
+	return nil
+}
+
+// OnStop implements cmn.Service. It waits for the endpoints being restarted,
+// which are stopped if they start.
+func (fv *FailoverVal) OnStop() {
+	if fv.stopc != nil {
+		close(fv.stopc)
+	}
+	fv.routines.Wait()
+
+	fv.mtx.Lock()
+	defer fv.mtx.Unlock()
+
+	fv.stopEndpoints()
+}
+
+// GetAddress implements PrivValidator.
+func (fv *FailoverVal) GetAddress() types.Address {
+	return fv.pubKey.Address()
+}
+
+// GetPubKey implements PrivValidator.
+func (fv *FailoverVal) GetPubKey() crypto.PubKey {
+	return fv.pubKey
+}
+
+// SignVote implements PrivValidator.
+func (fv *FailoverVal) SignVote(chainID string, vote *types.Vote) error {
+	request := SignState{
+		Height:    vote.Height,
+		Round:     vote.Round,
+		Step:      voteToStep(vote),
+		SignBytes: vote.SignBytes(chainID),
+	}
+	return fv.sign(request, func(ep SignerEndpoint) (SignState, error) {
+		if err := ep.SignVote(chainID, vote); err != nil {
+			return SignState{}, err
+		}
+		return SignState{
+			Height:    vote.Height,
+			Round:     vote.Round,
+			Step:      voteToStep(vote),
+			Signature: vote.Signature,
+			SignBytes: vote.SignBytes(chainID),
+		}, nil
+	})
+}
+
+// SignProposal implements PrivValidator.
+func (fv *FailoverVal) SignProposal(chainID string, proposal *types.Proposal) error {
+	request := SignState{
+		Height:    proposal.Height,
+		Round:     proposal.Round,
+		Step:      stepPropose,
+		SignBytes: proposal.SignBytes(chainID),
+	}
+	return fv.sign(request, func(ep SignerEndpoint) (SignState, error) {
+		if err := ep.SignProposal(chainID, proposal); err != nil {
+			return SignState{}, err
+		}
+		return SignState{
+			Height:    proposal.Height,
+			Round:     proposal.Round,
+			Step:      stepPropose,
+			Signature: proposal.Signature,
+			SignBytes: proposal.SignBytes(chainID),
+		}, nil
+	})
+}
+
+// Sign implements PrivValidator.
+func (fv *FailoverVal) Sign(msg []byte) ([]byte, error) {
+	var sig []byte
+	err := fv.sign(SignState{}, func(ep SignerEndpoint) (SignState, error) {
+		var err error
+		sig, err = ep.Sign(msg)
+		return SignState{}, err
+	})
+	return sig, err
+}
+
+// sign signs the request with the active endpoint, failing over to the next
+// one as long as the endpoints can't be reached. The signers refusing to sign
+// are not retried: the others would refuse as well.
+func (fv *FailoverVal) sign(request SignState, signFn func(SignerEndpoint) (SignState, error)) error {
+	fv.mtx.Lock()
+	defer fv.mtx.Unlock()
+
+	for {
+		fe, err := fv.activeEndpoint()
+		if err != nil {
+			return err
+		}
+
+		if fv.inDoubt != nil && fv.inDoubt != fe && !fv.inDoubtRequest.Before(request) &&
+			!bytes.Equal(request.SignBytes, fv.inDoubtRequest.SignBytes) {
+			return ErrSignRequestInDoubt
+		}
+
+		state, err := signFn(fe.endpoint)
+		if err == nil {
+			if fv.lastSignState.Before(state) {
+				fv.lastSignState = state
+				fv.syncStandbys(fe)
+			}
+			if fv.inDoubt != nil && fv.inDoubtRequest.Before(fv.lastSignState) {
+				fv.inDoubt = nil
+			}
+			return nil
+		}
+		if _, ok := err.(*RemoteSignerError); ok {
+			return err
+		}
+
+		// The signer may have signed before it failed.
+		if request.Height > 0 {
+			fv.inDoubt, fv.inDoubtRequest = fe, request
+		}
+		fv.endpointFailed(fe, err)
+	}
+}
+
+// syncStandbys syncs the last SignState to the reachable endpoints other than
+// the active one.
+// CONTRACT: fv.mtx is held.
+func (fv *FailoverVal) syncStandbys(active *failoverEndpoint) {
+	var (
+		errs = make([]error, len(fv.endpoints))
+		wg   sync.WaitGroup
+	)
+	for i, fe := range fv.endpoints {
+		if fe == active || fe.endpoint == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, ep SignerEndpoint) {
+			defer wg.Done()
+			errs[i] = ep.SyncSignState(fv.lastSignState)
+		}(i, fe.endpoint)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		fe := fv.endpoints[i]
+		if _, ok := err.(*RemoteSignerError); ok {
+			fv.Logger.Error("Signer endpoint refused the sign state", "addr", fe.addr, "state", fv.lastSignState, "err", err)
+			continue
+		}
+		fv.endpointFailed(fe, err)
+	}
+}
+
+// activeEndpoint returns the endpoint to sign with. When there is none, it
+// reads the SignState of the reachable signers, and syncs the latest one to
+// the previous signer if it is back or else to the first healthy endpoint
+// agreeing on it, which becomes the active one. It returns
+// ErrSignStateUnknown if it could read the state of neither the previous
+// signer nor a majority of the signers.
+// CONTRACT: fv.mtx is held.
+func (fv *FailoverVal) activeEndpoint() (*failoverEndpoint, error) {
+	if fv.active != nil {
+		return fv.active, nil
+	}
+
+	var (
+		state    = fv.lastSignState
+		read     int
+		prevRead bool
+	)
+	for _, fe := range fv.endpoints {
+		if fe.endpoint == nil {
+			continue
+		}
+		s, err := fe.endpoint.SignState()
+		if err != nil {
+			if _, ok := err.(*RemoteSignerError); !ok {
+				fv.endpointFailed(fe, err)
+			}
+			continue
+		}
+		read++
+		if fe == fv.lastActive {
+			prevRead = true
+		}
+		if fe == fv.inDoubt {
+			// Whatever it signed is in its state.
+			fv.inDoubt = nil
+		}
+		if state.Before(s) {
+			state = s
+		}
+	}
+	if !prevRead && read <= len(fv.endpoints)/2 {
+		return nil, ErrSignStateUnknown
+	}
+
+	candidates := fv.endpoints
+	if fv.lastActive != nil {
+		candidates = append([]*failoverEndpoint{fv.lastActive}, fv.endpoints...)
+	}
+	for _, fe := range candidates {
+		if fe.endpoint == nil {
+			continue
+		}
+		if err := fe.endpoint.SyncSignState(state); err != nil {
+			if _, ok := err.(*RemoteSignerError); ok {
+				fv.Logger.Error("Signer endpoint refused the sign state", "addr", fe.addr, "state", state, "err", err)
+			} else {
+				fv.endpointFailed(fe, err)
+			}
+			continue
+		}
+
+		if fv.lastActive != nil && fv.lastActive != fe {
+			fv.Logger.Info("Failing over to signer endpoint", "addr", fe.addr, "state", state)
+			fv.metrics.SignerFailovers.Add(1)
+		}
+		fv.active = fe
+		fv.lastActive = fe
+		fv.lastSignState = state
+		return fe, nil
+	}
+
+	return nil, ErrNoSignerEndpoint
+}
+
+// endpointFailed stops the failed endpoint, which is restarted by the health
+// routine.
+// CONTRACT: fv.mtx is held.
+func (fv *FailoverVal) endpointFailed(fe *failoverEndpoint, err error) {
+	fv.Logger.Error("Signer endpoint failed", "addr", fe.addr, "err", err)
+	fv.metrics.SignerUp.With("endpoint", fe.addr).Set(0)
+
+	if err := fe.endpoint.Stop(); err != nil {
+		fv.Logger.Error("Stopping signer endpoint", "addr", fe.addr, "err", err)
+	}
+	fe.endpoint = nil
+	if fv.active == fe {
+		fv.active = nil
+	}
+}
+
+func (fv *FailoverVal) healthRoutine() {
+	defer fv.routines.Done()
+	ticker := time.NewTicker(fv.healthCheckPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, fe := range fv.endpoints {
+				fv.checkEndpoint(fe)
+			}
+		case <-fv.stopc:
+			return
+		}
+	}
+}
+
+// checkEndpoint pings the endpoint and syncs the latest SignState to it, or
+// restarts it in the background if it is down.
+func (fv *FailoverVal) checkEndpoint(fe *failoverEndpoint) {
+	fv.mtx.Lock()
+	ep, state := fe.endpoint, fv.lastSignState
+	if ep == nil && !fe.restarting && fv.IsRunning() {
+		fe.restarting = true
+		fv.routines.Add(1)
+		go fv.restartEndpoint(fe)
+	}
+	fv.mtx.Unlock()
+
+	if ep == nil {
+		return
+	}
+
+	start := time.Now()
+	err := ep.Ping()
+	if err == nil {
+		fv.metrics.SignerPingRTT.With("endpoint", fe.addr).Set(time.Since(start).Seconds())
+		err = ep.SyncSignState(state)
+		if _, ok := err.(*RemoteSignerError); ok {
+			fv.Logger.Error("Signer endpoint refused the sign state", "addr", fe.addr, "state", state, "err", err)
+			return
+		}
+	}
+	if err != nil {
+		fv.mtx.Lock()
+		if fe.endpoint == ep {
+			fv.endpointFailed(fe, err)
+		}
+		fv.mtx.Unlock()
+	}
+}
+
+func (fv *FailoverVal) restartEndpoint(fe *failoverEndpoint) {
+	defer fv.routines.Done()
+
+	fv.mtx.Lock()
+	pubKey := fv.pubKey
+	fv.mtx.Unlock()
+
+	ep, err := fv.startEndpoint(fe.addr, pubKey)
+
+	fv.mtx.Lock()
+	defer fv.mtx.Unlock()
+
+	fe.restarting = false
+	if err != nil {
+		fv.Logger.Debug("Signer endpoint failed to restart", "addr", fe.addr, "err", err)
+		return
+	}
+	if !fv.IsRunning() {
+		if err := ep.Stop(); err != nil {
+			fv.Logger.Error("Stopping signer endpoint", "addr", fe.addr, "err", err)
+		}
+		return
+	}
+
+	fv.Logger.Info("Signer endpoint is up", "addr", fe.addr)
+	fv.metrics.SignerUp.With("endpoint", fe.addr).Set(1)
+	fe.endpoint = ep
+}
+
+// startEndpoint starts a new endpoint for addr, checking it signs with
+// pubKey if given.
+func (fv *FailoverVal) startEndpoint(addr string, pubKey crypto.PubKey) (SignerEndpoint, error) {
+	ep := fv.newEndpoint(addr)
+	if err := ep.Start(); err != nil {
+		return nil, err
+	}
+	if pubKey == nil {
+		return ep, nil
+	}
+
+	epPubKey, err := ep.getPubKey()
+	if err == nil && !pubKey.Equals(epPubKey) {
+		err = ErrSignerKeyMismatch
+	}
+	if err != nil {
+		ep.Stop() // nolint: errcheck
+		return nil, err
+	}
+	return ep, nil
+}
+
+// stopEndpoints stops the running endpoints.
+func (fv *FailoverVal) stopEndpoints() {
+	for _, fe := range fv.endpoints {
+		if fe.endpoint == nil {
+			continue
+		}
+		if err := fe.endpoint.Stop(); err != nil {
+			fv.Logger.Error("Stopping signer endpoint", "addr", fe.addr, "err", err)
+		}
+		fe.endpoint = nil
+	}
+}
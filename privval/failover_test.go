@@ -0,0 +1,374 @@
+package privval
+
+import (
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestFailoverValFailover(t *testing.T) {
+	var (
+		chainID  = cmn.RandStr(12)
+		logger   = log.TestingLogger()
+		addrs    = []string{testFreeAddr(t), testFreeAddr(t), testFreeAddr(t)}
+		privVals = testSharedKeyFilePVs(t, 3)
+		startc   = make(chan error, 1)
+		block1   = types.BlockID{Hash: []byte{1, 2, 3}}
+		block2   = types.BlockID{Hash: []byte{3, 2, 1}}
+
+		fv = NewFailoverVal(logger, addrs, func(addr string) SignerEndpoint {
+			sc := NewTCPVal(logger, addr, ed25519.GenPrivKey())
+			TCPValAcceptDeadline(500 * time.Millisecond)(sc)
+			TCPValHeartbeat(10 * time.Millisecond)(sc)
+			return sc
+		}, FailoverValHealthCheckPeriod(10*time.Millisecond))
+	)
+
+	go func() { startc <- fv.Start() }()
+	rs0 := testStartRemoteSigner(t, chainID, addrs[0], privVals[0])
+	defer func() { rs0.Stop() }() // the restarted one
+	rs1 := testStartRemoteSigner(t, chainID, addrs[1], privVals[1])
+	defer rs1.Stop()
+	rs2 := testStartRemoteSigner(t, chainID, addrs[2], privVals[2])
+	defer rs2.Stop()
+	require.NoError(t, <-startc)
+	// stopped before the signers, not to restart their endpoints
+	defer fv.Stop()
+	assert.Equal(t, privVals[0].GetPubKey(), fv.GetPubKey())
+
+	// the first signer signs, and the standby ones get its state
+	vote := newVote(fv.GetAddress(), 0, 1, 0, byte(types.PrevoteType), block1)
+	require.NoError(t, fv.SignVote(chainID, vote))
+	for _, privVal := range privVals {
+		assert.Equal(t, int64(1), privVal.LastSignState().Height)
+	}
+
+	// it goes down: the second one takes over
+	require.NoError(t, rs0.Stop())
+	conflicting := newVote(fv.GetAddress(), 0, 1, 0, byte(types.PrevoteType), block2)
+	err := fv.SignVote(chainID, conflicting)
+	assert.IsType(t, &RemoteSignerError{}, err, "expected the second signer to refuse to double sign")
+	precommit := newVote(fv.GetAddress(), 0, 1, 0, byte(types.PrecommitType), block1)
+	require.NoError(t, fv.SignVote(chainID, precommit))
+	assert.Equal(t, stepPrecommit, privVals[1].LastSignState().Step)
+
+	// the first signer comes back, and catches up while on standby
+	rs0 = testStartRemoteSigner(t, chainID, addrs[0], privVals[0])
+	for i := 0; privVals[0].LastSignState().Step != stepPrecommit; i++ {
+		if i == 1000 {
+			t.Fatal("expected the first signer to catch up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFailoverValKeyMismatch(t *testing.T) {
+	var (
+		chainID = cmn.RandStr(12)
+		logger  = log.TestingLogger()
+		addrs   = []string{testFreeAddr(t), testFreeAddr(t)}
+		startc  = make(chan error, 1)
+
+		fv = NewFailoverVal(logger, addrs, func(addr string) SignerEndpoint {
+			return NewTCPVal(logger, addr, ed25519.GenPrivKey())
+		})
+	)
+
+	go func() { startc <- fv.Start() }()
+	rs0 := testStartRemoteSigner(t, chainID, addrs[0], testSharedKeyFilePVs(t, 1)[0])
+	defer rs0.Stop()
+	rs1 := testStartRemoteSigner(t, chainID, addrs[1], testSharedKeyFilePVs(t, 1)[0])
+	defer rs1.Stop()
+	assert.Equal(t, ErrSignerKeyMismatch, <-startc)
+}
+
+func TestFailoverValSignStateUnknown(t *testing.T) {
+	var (
+		chainID   = cmn.RandStr(12)
+		privVals  = testSharedKeyFilePVs(t, 2)
+		endpoints = newTestSignerEndpoints(privVals)
+		block1    = types.BlockID{Hash: []byte{1, 2, 3}}
+		block2    = types.BlockID{Hash: []byte{3, 2, 1}}
+	)
+
+	// the first signer signed before the node restarted, and is down
+	vote := newVote(privVals[0].Address, 0, 1, 0, byte(types.PrevoteType), block1)
+	require.NoError(t, privVals[0].SignVote(chainID, vote))
+	endpoints.setUp(0, false)
+
+	fv := endpoints.newFailoverVal()
+	require.NoError(t, fv.Start())
+	defer fv.Stop()
+
+	// the second one alone isn't a majority: it doesn't sign
+	conflicting := newVote(privVals[0].Address, 0, 1, 0, byte(types.PrevoteType), block2)
+	assert.Equal(t, ErrSignStateUnknown, fv.SignVote(chainID, conflicting))
+	assert.Equal(t, int64(0), privVals[1].LastSignState().Height)
+
+	// the first one is back: its state is synced, and the conflicting vote
+	// refused
+	endpoints.setUp(0, true)
+	endpoints.waitRestarted(t, fv, 0)
+	err := fv.SignVote(chainID, conflicting)
+	assert.IsType(t, &RemoteSignerError{}, err, "expected the signers to refuse to double sign")
+	assert.Equal(t, vote.Signature, privVals[0].LastSignState().Signature)
+	assert.NotEqual(t, stepPrevote, privVals[1].LastSignState().Step)
+}
+
+func TestFailoverValSignRequestInDoubt(t *testing.T) {
+	var (
+		chainID   = cmn.RandStr(12)
+		privVals  = testSharedKeyFilePVs(t, 3)
+		endpoints = newTestSignerEndpoints(privVals)
+		block1    = types.BlockID{Hash: []byte{1, 2, 3}}
+		block2    = types.BlockID{Hash: []byte{3, 2, 1}}
+	)
+
+	fv := endpoints.newFailoverVal()
+	require.NoError(t, fv.Start())
+	defer fv.Stop()
+
+	require.NoError(t, fv.SignVote(chainID, newVote(privVals[0].Address, 0, 1, 0, byte(types.PrevoteType), block1)))
+
+	// the active signer dies right after signing, and the standby ones are
+	// down as well
+	endpoints.setUp(1, false)
+	endpoints.setUp(2, false)
+	endpoints.waitDown(t, fv, 1)
+	endpoints.waitDown(t, fv, 2)
+	endpoints.endpoints[0].dieAfterSigning = true
+	vote := newVote(privVals[0].Address, 0, 2, 0, byte(types.PrevoteType), block1)
+	assert.Equal(t, ErrSignStateUnknown, fv.SignVote(chainID, vote))
+	assert.Equal(t, int64(2), privVals[0].LastSignState().Height)
+
+	// the standby ones are back, but a different vote for the same
+	// height/round/step isn't signed until the first one is back
+	endpoints.setUp(1, true)
+	endpoints.setUp(2, true)
+	endpoints.waitRestarted(t, fv, 1)
+	endpoints.waitRestarted(t, fv, 2)
+	conflicting := newVote(privVals[0].Address, 0, 2, 0, byte(types.PrevoteType), block2)
+	assert.Equal(t, ErrSignRequestInDoubt, fv.SignVote(chainID, conflicting))
+	assert.Equal(t, int64(1), privVals[1].LastSignState().Height)
+	assert.Equal(t, int64(1), privVals[2].LastSignState().Height)
+
+	// the same vote gets the same signature
+	same := *vote
+	same.Signature = nil
+	require.NoError(t, fv.SignVote(chainID, &same))
+	assert.Equal(t, vote.Signature, privVals[1].LastSignState().Signature)
+}
+
+func TestFailoverValStopWaitsForRestarts(t *testing.T) {
+	var (
+		privVals  = testSharedKeyFilePVs(t, 2)
+		endpoints = newTestSignerEndpoints(privVals)
+	)
+
+	// the second signer is down: its endpoint is restarted until stopped
+	endpoints.setUp(1, false)
+	fv := endpoints.newFailoverVal()
+	require.NoError(t, fv.Start())
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, fv.Stop())
+
+	endpoints.mtx.Lock()
+	created := endpoints.created
+	endpoints.mtx.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	endpoints.mtx.Lock()
+	defer endpoints.mtx.Unlock()
+	assert.Equal(t, created, endpoints.created, "expected no restart once stopped")
+	assert.False(t, endpoints.endpoints[0].IsRunning())
+}
+
+// testSignerEndpoint is a SignerEndpoint signing with a FilePV in process.
+type testSignerEndpoint struct {
+	cmn.BaseService
+
+	pv              *FilePV
+	eps             *testSignerEndpoints
+	i               int
+	dieAfterSigning bool
+}
+
+func (ep *testSignerEndpoint) OnStart() error {
+	return ep.err()
+}
+
+func (ep *testSignerEndpoint) err() error {
+	ep.eps.mtx.Lock()
+	defer ep.eps.mtx.Unlock()
+	if !ep.eps.up[ep.i] {
+		return io.EOF
+	}
+	return nil
+}
+
+func (ep *testSignerEndpoint) GetAddress() types.Address {
+	return ep.pv.GetAddress()
+}
+
+func (ep *testSignerEndpoint) GetPubKey() crypto.PubKey {
+	return ep.pv.GetPubKey()
+}
+
+func (ep *testSignerEndpoint) Sign(msg []byte) ([]byte, error) {
+	if err := ep.err(); err != nil {
+		return nil, err
+	}
+	return ep.pv.Sign(msg)
+}
+
+func (ep *testSignerEndpoint) SignVote(chainID string, vote *types.Vote) error {
+	if err := ep.err(); err != nil {
+		return err
+	}
+	if err := ep.pv.SignVote(chainID, vote); err != nil {
+		return &RemoteSignerError{0, err.Error()}
+	}
+	if ep.dieAfterSigning {
+		ep.eps.setUp(ep.i, false)
+		return io.EOF
+	}
+	return nil
+}
+
+func (ep *testSignerEndpoint) SignProposal(chainID string, proposal *types.Proposal) error {
+	if err := ep.err(); err != nil {
+		return err
+	}
+	if err := ep.pv.SignProposal(chainID, proposal); err != nil {
+		return &RemoteSignerError{0, err.Error()}
+	}
+	return nil
+}
+
+func (ep *testSignerEndpoint) Ping() error {
+	return ep.err()
+}
+
+func (ep *testSignerEndpoint) SignState() (SignState, error) {
+	if err := ep.err(); err != nil {
+		return SignState{}, err
+	}
+	return ep.pv.LastSignState(), nil
+}
+
+func (ep *testSignerEndpoint) SyncSignState(state SignState) error {
+	if err := ep.err(); err != nil {
+		return err
+	}
+	if err := ep.pv.SyncSignState(state); err != nil {
+		return &RemoteSignerError{0, err.Error()}
+	}
+	return nil
+}
+
+func (ep *testSignerEndpoint) getPubKey() (crypto.PubKey, error) {
+	return ep.pv.PubKey, ep.err()
+}
+
+// testSignerEndpoints creates the testSignerEndpoints of a FailoverVal, the
+// i-th one signing with the i-th FilePV.
+type testSignerEndpoints struct {
+	mtx       sync.Mutex
+	privVals  []*FilePV
+	up        []bool
+	endpoints []*testSignerEndpoint
+	created   int
+}
+
+func newTestSignerEndpoints(privVals []*FilePV) *testSignerEndpoints {
+	endpoints := &testSignerEndpoints{
+		privVals:  privVals,
+		up:        make([]bool, len(privVals)),
+		endpoints: make([]*testSignerEndpoint, len(privVals)),
+	}
+	for i := range endpoints.up {
+		endpoints.up[i] = true
+	}
+	return endpoints
+}
+
+func (eps *testSignerEndpoints) newFailoverVal() *FailoverVal {
+	addrs := make([]string, len(eps.privVals))
+	for i := range addrs {
+		addrs[i] = strconv.Itoa(i)
+	}
+	return NewFailoverVal(log.TestingLogger(), addrs, func(addr string) SignerEndpoint {
+		i, _ := strconv.Atoi(addr)
+		ep := &testSignerEndpoint{pv: eps.privVals[i], eps: eps, i: i}
+		ep.BaseService = *cmn.NewBaseService(log.TestingLogger(), "testSignerEndpoint", ep)
+		eps.mtx.Lock()
+		eps.endpoints[i] = ep
+		eps.created++
+		eps.mtx.Unlock()
+		return ep
+	}, FailoverValHealthCheckPeriod(time.Millisecond))
+}
+
+func (eps *testSignerEndpoints) setUp(i int, up bool) {
+	eps.mtx.Lock()
+	defer eps.mtx.Unlock()
+	eps.up[i] = up
+}
+
+// waitRestarted waits for fv to restart the i-th endpoint.
+func (eps *testSignerEndpoints) waitRestarted(t *testing.T, fv *FailoverVal, i int) {
+	eps.wait(t, fv, i, true)
+}
+
+// waitDown waits for fv to find the i-th endpoint down.
+func (eps *testSignerEndpoints) waitDown(t *testing.T, fv *FailoverVal, i int) {
+	eps.wait(t, fv, i, false)
+}
+
+func (eps *testSignerEndpoints) wait(t *testing.T, fv *FailoverVal, i int, up bool) {
+	for j := 0; ; j++ {
+		fv.mtx.Lock()
+		running := fv.endpoints[i].endpoint != nil
+		fv.mtx.Unlock()
+		if running == up {
+			return
+		}
+		if j == 1000 {
+			t.Fatalf("expected the endpoint to be up: %v", up)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// testSharedKeyFilePVs returns n FilePVs with the same key.
+func testSharedKeyFilePVs(t *testing.T, n int) []*FilePV {
+	privVals := make([]*FilePV, n)
+	for i := range privVals {
+		tempFile, err := ioutil.TempFile("", "priv_validator_")
+		require.NoError(t, err)
+		privVals[i] = GenFilePV(tempFile.Name())
+		privVals[i].Address = privVals[0].Address
+		privVals[i].PubKey = privVals[0].PubKey
+		privVals[i].PrivKey = privVals[0].PrivKey
+	}
+	return privVals
+}
+
+func testStartRemoteSigner(t *testing.T, chainID, addr string, privVal types.PrivValidator) *RemoteSigner {
+	rs := NewRemoteSigner(log.TestingLogger(), chainID, addr, privVal, ed25519.GenPrivKey())
+	RemoteSignerConnDeadline(100 * time.Millisecond)(rs)
+	RemoteSignerConnRetries(50)(rs)
+	require.NoError(t, rs.Start())
+	return rs
+}
@@ -45,14 +45,14 @@ func TestIPCPVVoteResetDeadline(t *testing.T) {
 	defer sc.Stop()
 	defer rs.Stop()
 
-	time.Sleep(3 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
 
 	require.NoError(t, rs.privVal.SignVote(chainID, want))
 	require.NoError(t, sc.SignVote(chainID, have))
 	assert.Equal(t, want.Signature, have.Signature)
 
 	// This would exceed the deadline if it was not extended by the previous message
-	time.Sleep(3 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
 
 	require.NoError(t, rs.privVal.SignVote(chainID, want))
 	require.NoError(t, sc.SignVote(chainID, have))
@@ -72,7 +72,7 @@ func TestIPCPVVoteKeepalive(t *testing.T) {
 	defer sc.Stop()
 	defer rs.Stop()
 
-	time.Sleep(10 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
 
 	require.NoError(t, rs.privVal.SignVote(chainID, want))
 	require.NoError(t, sc.SignVote(chainID, have))
@@ -103,10 +103,10 @@ func testSetupIPCSocketPair(
 		)
 	)
 
-	IPCValConnTimeout(5 * time.Millisecond)(sc)
-	IPCValHeartbeat(time.Millisecond)(sc)
+	IPCValConnTimeout(50 * time.Millisecond)(sc)
+	IPCValHeartbeat(10 * time.Millisecond)(sc)
 
-	IPCRemoteSignerConnDeadline(time.Millisecond * 5)(rs)
+	IPCRemoteSignerConnDeadline(time.Millisecond * 50)(rs)
 
 	testStartIPCRemoteSigner(t, readyc, rs)
 
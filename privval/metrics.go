@@ -0,0 +1,53 @@
+package privval
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const MetricsSubsystem = "privval"
+
+// Metrics contains metrics exposed by this package.
+type Metrics struct {
+	// Whether a given signer endpoint is healthy (1) or not (0).
+	SignerUp metrics.Gauge
+	// Ping round-trip time to a given signer endpoint, in seconds.
+	SignerPingRTT metrics.Gauge
+	// Number of times the signing failed over to another endpoint.
+	SignerFailovers metrics.Counter
+}
+
+// PrometheusMetrics returns Metrics build using Prometheus client library.
+func PrometheusMetrics(namespace string) *Metrics {
+	return &Metrics{
+		SignerUp: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signer_up",
+			Help:      "Whether a given signer endpoint is healthy (1) or not (0).",
+		}, []string{"endpoint"}),
+		SignerPingRTT: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signer_ping_rtt_seconds",
+			Help:      "Ping round-trip time to a given signer endpoint, in seconds.",
+		}, []string{"endpoint"}),
+		SignerFailovers: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signer_failovers",
+			Help:      "Number of times the signing failed over to another endpoint.",
+		}, []string{}),
+	}
+}
+
+// NopMetrics returns no-op Metrics.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		SignerUp:        discard.NewGauge(),
+		SignerPingRTT:   discard.NewGauge(),
+		SignerFailovers: discard.NewCounter(),
+	}
+}
@@ -297,6 +297,48 @@ func (pv *FilePV) saveSigned(height int64, round int, step int8,
 	pv.save()
 }
 
+// LastSignState returns the state of the last signature.
+// Implements SignStateKeeper.
+func (pv *FilePV) LastSignState() SignState {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+	return SignState{
+		Height:    pv.LastHeight,
+		Round:     pv.LastRound,
+		Step:      pv.LastStep,
+		Signature: pv.LastSignature,
+		SignBytes: pv.LastSignBytes,
+	}
+}
+
+// SyncSignState adopts the state of a signature made by another signer of
+// this validator if it is ahead of the last one, so that pv never signs for
+// an earlier height/round/step. Implements SignStateKeeper.
+func (pv *FilePV) SyncSignState(state SignState) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	last := SignState{Height: pv.LastHeight, Round: pv.LastRound, Step: pv.LastStep}
+	if !last.Before(state) {
+		// We are already there, or ahead.
+		return nil
+	}
+
+	height, round, step, err := signBytesHRS(state.SignBytes)
+	if err != nil {
+		return err
+	}
+	if height != state.Height || round != state.Round || step != state.Step {
+		return fmt.Errorf("Sign bytes are for %v/%v/%v, not %v", height, round, step, state)
+	}
+	if !pv.PubKey.VerifyBytes(state.SignBytes, state.Signature) {
+		return errors.New("Invalid signature")
+	}
+
+	pv.saveSigned(state.Height, state.Round, state.Step, state.SignBytes, state.Signature)
+	return nil
+}
+
 // String returns a string representation of the FilePV.
 func (pv *FilePV) String() string {
 	return fmt.Sprintf("PrivValidator{%v LH:%v, LR:%v, LS:%v}", pv.GetAddress(), pv.LastHeight, pv.LastRound, pv.LastStep)
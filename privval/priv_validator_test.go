@@ -174,12 +174,59 @@ func TestSignProposal(t *testing.T) {
 	assert.Equal(sig, proposal.Signature)
 }
 
+func TestSyncSignState(t *testing.T) {
+	assert := assert.New(t)
+
+	tempFile, err := ioutil.TempFile("", "priv_validator_")
+	require.Nil(t, err)
+	privVal := GenFilePV(tempFile.Name())
+	tempFile, err = ioutil.TempFile("", "priv_validator_")
+	require.Nil(t, err)
+	standby := GenFilePV(tempFile.Name())
+	standby.Address, standby.PubKey, standby.PrivKey = privVal.Address, privVal.PubKey, privVal.PrivKey
+
+	block1 := types.BlockID{Hash: []byte{1, 2, 3}}
+	block2 := types.BlockID{Hash: []byte{3, 2, 1}}
+	height, round := int64(10), 1
+	voteType := byte(types.PrevoteType)
+
+	vote := newVote(privVal.Address, 0, height, round, voteType, block1)
+	require.NoError(t, privVal.SignVote("mychainid", vote))
+	state := privVal.LastSignState()
+
+	// a forged signature or mismatching height/round/step are rejected
+	forged := state
+	forged.Signature = make([]byte, len(state.Signature))
+	assert.Error(standby.SyncSignState(forged))
+	wrongHRS := state
+	wrongHRS.Round++
+	assert.Error(standby.SyncSignState(wrongHRS))
+	assert.Equal(int64(0), standby.LastSignState().Height)
+
+	require.NoError(t, standby.SyncSignState(state))
+	assert.Equal(state, standby.LastSignState())
+
+	// the standby refuses to double sign, and re-signs the same vote
+	err = standby.SignVote("mychainid", newVote(privVal.Address, 0, height, round, voteType, block2))
+	assert.Error(err, "expected error on signing conflicting vote")
+	same := newVote(privVal.Address, 0, height, round, voteType, block1)
+	same.Timestamp = vote.Timestamp
+	require.NoError(t, standby.SignVote("mychainid", same))
+	assert.Equal(vote.Signature, same.Signature)
+
+	// an earlier state is ignored
+	require.NoError(t, privVal.SignProposal("mychainid", newProposal(height+1, 0, block1)))
+	require.NoError(t, standby.SyncSignState(privVal.LastSignState()))
+	require.NoError(t, standby.SyncSignState(state))
+	assert.Equal(privVal.LastSignState(), standby.LastSignState())
+}
+
 func TestDifferByTimestamp(t *testing.T) {
 	tempFile, err := ioutil.TempFile("", "priv_validator_")
 	require.Nil(t, err)
 	privVal := GenFilePV(tempFile.Name())
 
-	block1 := types.BlockID{[]byte{1, 2, 3}, types.PartSetHeader{5, []byte{1, 2, 3}}}
+	block1 := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{Total: 5, Hash: []byte{1, 2, 3}}}
 	height, round := int64(10), 1
 	chainID := "mychainid"
 
@@ -207,7 +254,7 @@ func TestDifferByTimestamp(t *testing.T) {
 	// test vote
 	{
 		voteType := byte(types.PrevoteType)
-		blockID := types.BlockID{[]byte{1, 2, 3}, types.PartSetHeader{}}
+		blockID := types.BlockID{Hash: []byte{1, 2, 3}}
 		vote := newVote(privVal.Address, 0, height, round, voteType, blockID)
 		err := privVal.SignVote("mychainid", vote)
 		assert.NoError(t, err, "expected no error signing vote")
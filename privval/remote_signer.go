@@ -168,6 +168,60 @@ func (sc *RemoteSignerClient) Ping() error {
 	return nil
 }
 
+// SignState returns the state of the last signature of the remote signer.
+func (sc *RemoteSignerClient) SignState() (SignState, error) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	err := writeMsg(sc.conn, &SignStateRequest{})
+	if err != nil {
+		return SignState{}, err
+	}
+
+	res, err := readMsg(sc.conn)
+	if err != nil {
+		return SignState{}, err
+	}
+	resp, ok := res.(*SignStateResponse)
+	if !ok {
+		return SignState{}, ErrUnexpectedResponse
+	}
+	if resp.Error != nil {
+		return SignState{}, resp.Error
+	}
+	if resp.State == nil {
+		return SignState{}, nil
+	}
+
+	return *resp.State, nil
+}
+
+// SyncSignState makes the remote signer adopt the given state if it is ahead
+// of its own, so that it refuses to sign for any earlier height/round/step.
+func (sc *RemoteSignerClient) SyncSignState(state SignState) error {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	err := writeMsg(sc.conn, &SyncSignStateRequest{State: &state})
+	if err != nil {
+		return err
+	}
+
+	res, err := readMsg(sc.conn)
+	if err != nil {
+		return err
+	}
+	resp, ok := res.(*SyncSignStateResponse)
+	if !ok {
+		return ErrUnexpectedResponse
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	return nil
+}
+
 // RemoteSignerMsg is sent between RemoteSigner and the RemoteSigner client.
 type RemoteSignerMsg interface{}
 
@@ -180,6 +234,10 @@ func RegisterRemoteSignerMsg(cdc *amino.Codec) {
 	cdc.RegisterConcrete(&SignedProposalResponse{}, "tendermint/remotesigner/SignedProposalResponse", nil)
 	cdc.RegisterConcrete(&PingRequest{}, "tendermint/remotesigner/PingRequest", nil)
 	cdc.RegisterConcrete(&PingResponse{}, "tendermint/remotesigner/PingResponse", nil)
+	cdc.RegisterConcrete(&SignStateRequest{}, "tendermint/remotesigner/SignStateRequest", nil)
+	cdc.RegisterConcrete(&SignStateResponse{}, "tendermint/remotesigner/SignStateResponse", nil)
+	cdc.RegisterConcrete(&SyncSignStateRequest{}, "tendermint/remotesigner/SyncSignStateRequest", nil)
+	cdc.RegisterConcrete(&SyncSignStateResponse{}, "tendermint/remotesigner/SyncSignStateResponse", nil)
 }
 
 // PubKeyMsg is a PrivValidatorSocket message containing the public key.
@@ -215,6 +273,26 @@ type PingRequest struct {
 type PingResponse struct {
 }
 
+// SignStateRequest is a PrivValidatorSocket message requesting the state of
+// the last signature.
+type SignStateRequest struct {
+}
+
+type SignStateResponse struct {
+	State *SignState
+	Error *RemoteSignerError
+}
+
+// SyncSignStateRequest is a PrivValidatorSocket message containing the state
+// of the last signature of another signer of the same validator.
+type SyncSignStateRequest struct {
+	State *SignState
+}
+
+type SyncSignStateResponse struct {
+	Error *RemoteSignerError
+}
+
 // RemoteSignerError allows (remote) validators to include meaningful error descriptions in their reply.
 type RemoteSignerError struct {
 	// TODO(ismail): create an enum of known errors
@@ -268,6 +346,25 @@ func handleRequest(req RemoteSignerMsg, chainID string, privVal types.PrivValida
 		}
 	case *PingRequest:
 		res = &PingResponse{}
+	case *SignStateRequest:
+		keeper, ok := privVal.(SignStateKeeper)
+		if !ok {
+			res = &SignStateResponse{nil, &RemoteSignerError{0, errSignStateNotSupported.Error()}}
+		} else {
+			state := keeper.LastSignState()
+			res = &SignStateResponse{&state, nil}
+		}
+	case *SyncSignStateRequest:
+		keeper, ok := privVal.(SignStateKeeper)
+		if !ok {
+			res = &SyncSignStateResponse{&RemoteSignerError{0, errSignStateNotSupported.Error()}}
+		} else if r.State == nil {
+			res = &SyncSignStateResponse{&RemoteSignerError{0, "missing sign state"}}
+		} else if err = keeper.SyncSignState(*r.State); err != nil {
+			res = &SyncSignStateResponse{&RemoteSignerError{0, err.Error()}}
+		} else {
+			res = &SyncSignStateResponse{nil}
+		}
 	default:
 		err = fmt.Errorf("unknown msg: %v", r)
 	}
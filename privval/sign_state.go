@@ -0,0 +1,73 @@
+package privval
+
+import (
+	"errors"
+	"fmt"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/types"
+)
+
+var errSignStateNotSupported = errors.New("the private validator doesn't keep a sign state")
+
+// SignState is the height/round/step of the last signature of a private
+// validator, along with the signature and the bytes it signed.
+type SignState struct {
+	Height    int64
+	Round     int
+	Step      int8
+	Signature []byte
+	SignBytes cmn.HexBytes
+}
+
+// Before returns true if the height/round/step of ss is before the one of
+// other.
+func (ss SignState) Before(other SignState) bool {
+	if ss.Height != other.Height {
+		return ss.Height < other.Height
+	}
+	if ss.Round != other.Round {
+		return ss.Round < other.Round
+	}
+	return ss.Step < other.Step
+}
+
+// String returns a string representation of the SignState.
+func (ss SignState) String() string {
+	return fmt.Sprintf("SignState{%v/%v/%v}", ss.Height, ss.Round, ss.Step)
+}
+
+// SignStateKeeper is a private validator which keeps the state of its last
+// signature to refuse signing twice for the same height/round/step. The
+// signers of a validator share this state to never double sign, whichever of
+// them signs.
+type SignStateKeeper interface {
+	// LastSignState returns the state of the last signature.
+	LastSignState() SignState
+	// SyncSignState adopts the given state if it is ahead of the last
+	// signature. It returns an error if the state is not a valid signature
+	// of the validator for its height/round/step.
+	SyncSignState(SignState) error
+}
+
+// signBytesHRS returns the height/round/step of the canonical vote or
+// proposal encoded in signBytes.
+func signBytesHRS(signBytes []byte) (height int64, round int, step int8, err error) {
+	var vote types.CanonicalVote
+	if err := cdc.UnmarshalBinaryLengthPrefixed(signBytes, &vote); err == nil {
+		switch vote.Type {
+		case types.PrevoteType:
+			return vote.Height, int(vote.Round), stepPrevote, nil
+		case types.PrecommitType:
+			return vote.Height, int(vote.Round), stepPrecommit, nil
+		}
+	}
+
+	var proposal types.CanonicalProposal
+	if err := cdc.UnmarshalBinaryLengthPrefixed(signBytes, &proposal); err == nil &&
+		proposal.Type == types.ProposalType {
+		return proposal.Height, int(proposal.Round), stepPropose, nil
+	}
+
+	return 0, 0, 0, errors.New("sign bytes are neither a vote nor a proposal")
+}
@@ -1,10 +1,12 @@
 package privval
 
 import (
+	"bytes"
 	"errors"
 	"net"
 	"time"
 
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
@@ -21,9 +23,11 @@ const (
 
 // Socket errors.
 var (
-	ErrDialRetryMax       = errors.New("dialed maximum retries")
-	ErrConnTimeout        = errors.New("remote signer timed out")
-	ErrUnexpectedResponse = errors.New("received unexpected response")
+	ErrDialRetryMax        = errors.New("dialed maximum retries")
+	ErrConnTimeout         = errors.New("remote signer timed out")
+	ErrUnexpectedResponse  = errors.New("received unexpected response")
+	ErrUnexpectedSignerKey = errors.New("remote signer key is not allowed")
+	ErrUnexpectedNodeKey   = errors.New("node key doesn't match the pinned key")
 )
 
 var (
@@ -53,6 +57,13 @@ func TCPValHeartbeat(period time.Duration) TCPValOption {
 	return func(sc *TCPVal) { sc.connHeartbeat = period }
 }
 
+// TCPValAllowedSigners only accepts the connections of the external signing
+// processes whose SecretConnection keys have the given addresses; the others
+// are rejected during the handshake. Any signer is accepted if none is given.
+func TCPValAllowedSigners(addrs ...crypto.Address) TCPValOption {
+	return func(sc *TCPVal) { sc.allowedSigners = addrs }
+}
+
 // TCPVal implements PrivValidator, it uses a socket to request signatures
 // from an external process.
 type TCPVal struct {
@@ -64,6 +75,7 @@ type TCPVal struct {
 	connTimeout    time.Duration
 	connHeartbeat  time.Duration
 	privKey        ed25519.PrivKeyEd25519
+	allowedSigners []crypto.Address
 
 	conn       net.Conn
 	listener   net.Listener
@@ -103,6 +115,8 @@ func (sc *TCPVal) OnStart() error {
 	conn, err := sc.waitConnection()
 	if err != nil {
 		sc.Logger.Error("OnStart", "err", err)
+		// Free the address for the next start.
+		sc.listener.Close() // nolint: errcheck
 		return err
 	}
 
@@ -163,12 +177,32 @@ func (sc *TCPVal) acceptConnection() (net.Conn, error) {
 
 	}
 
-	conn, err = p2pconn.MakeSecretConnection(conn, sc.privKey)
+	secretConn, err := p2pconn.MakeSecretConnection(conn, sc.privKey)
 	if err != nil {
 		return nil, err
 	}
 
-	return conn, nil
+	if remoteKey := secretConn.RemotePubKey(); !sc.isAllowedSigner(remoteKey) {
+		sc.Logger.Error("Rejecting remote signer", "addr", remoteKey.Address(), "err", ErrUnexpectedSignerKey)
+		secretConn.Close() // nolint: errcheck
+		return nil, ErrUnexpectedSignerKey
+	}
+
+	return secretConn, nil
+}
+
+// isAllowedSigner returns true if the remote signer with the given key is
+// allowed to connect.
+func (sc *TCPVal) isAllowedSigner(pubKey crypto.PubKey) bool {
+	if len(sc.allowedSigners) == 0 {
+		return true
+	}
+	for _, addr := range sc.allowedSigners {
+		if bytes.Equal(pubKey.Address(), addr) {
+			return true
+		}
+	}
+	return false
 }
 
 func (sc *TCPVal) listen() error {
@@ -196,13 +230,20 @@ func (sc *TCPVal) waitConnection() (net.Conn, error) {
 	)
 
 	go func(connc chan<- net.Conn, errc chan<- error) {
-		conn, err := sc.acceptConnection()
-		if err != nil {
-			errc <- err
+		for {
+			conn, err := sc.acceptConnection()
+			if err == ErrUnexpectedSignerKey {
+				// Wait for the allowed signer.
+				continue
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			connc <- conn
 			return
 		}
-
-		connc <- conn
 	}(connc, errc)
 
 	select {
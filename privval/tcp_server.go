@@ -1,10 +1,12 @@
 package privval
 
 import (
+	"bytes"
 	"io"
 	"net"
 	"time"
 
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
@@ -26,6 +28,14 @@ func RemoteSignerConnRetries(retries int) RemoteSignerOption {
 	return func(ss *RemoteSigner) { ss.connRetries = retries }
 }
 
+// RemoteSignerPinnedNodeKey pins the key of the node: the RemoteSigner only
+// serves a node whose SecretConnection key has the given address (its ID, as
+// shown by `tendermint show_node_id`), and rejects the others during the
+// handshake.
+func RemoteSignerPinnedNodeKey(addr crypto.Address) RemoteSignerOption {
+	return func(ss *RemoteSigner) { ss.pinnedNodeKey = addr }
+}
+
 // RemoteSigner implements PrivValidator by dialing to a socket.
 type RemoteSigner struct {
 	cmn.BaseService
@@ -37,6 +47,8 @@ type RemoteSigner struct {
 	privKey      ed25519.PrivKeyEd25519
 	privVal      types.PrivValidator
 
+	pinnedNodeKey crypto.Address
+
 	conn net.Conn
 }
 
@@ -111,7 +123,7 @@ func (rs *RemoteSigner) connect() (net.Conn, error) {
 			continue
 		}
 
-		conn, err = p2pconn.MakeSecretConnection(conn, rs.privKey)
+		secretConn, err := p2pconn.MakeSecretConnection(conn, rs.privKey)
 		if err != nil {
 			rs.Logger.Error(
 				"connect",
@@ -121,7 +133,19 @@ func (rs *RemoteSigner) connect() (net.Conn, error) {
 			continue
 		}
 
-		return conn, nil
+		if remoteKey := secretConn.RemotePubKey(); rs.pinnedNodeKey != nil &&
+			!bytes.Equal(remoteKey.Address(), rs.pinnedNodeKey) {
+			rs.Logger.Error(
+				"connect",
+				"addr", remoteKey.Address(),
+				"err", ErrUnexpectedNodeKey,
+			)
+			secretConn.Close() // nolint: errcheck
+
+			continue
+		}
+
+		return secretConn, nil
 	}
 
 	return nil, ErrDialRetryMax
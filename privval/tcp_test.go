@@ -103,14 +103,14 @@ func TestSocketPVVoteResetDeadline(t *testing.T) {
 	defer sc.Stop()
 	defer rs.Stop()
 
-	time.Sleep(3 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
 
 	require.NoError(t, rs.privVal.SignVote(chainID, want))
 	require.NoError(t, sc.SignVote(chainID, have))
 	assert.Equal(t, want.Signature, have.Signature)
 
 	// This would exceed the deadline if it was not extended by the previous message
-	time.Sleep(3 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
 
 	require.NoError(t, rs.privVal.SignVote(chainID, want))
 	require.NoError(t, sc.SignVote(chainID, have))
@@ -130,7 +130,7 @@ func TestSocketPVVoteKeepalive(t *testing.T) {
 	defer sc.Stop()
 	defer rs.Stop()
 
-	time.Sleep(10 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
 
 	require.NoError(t, rs.privVal.SignVote(chainID, want))
 	require.NoError(t, sc.SignVote(chainID, have))
@@ -341,6 +341,140 @@ func TestErrUnexpectedResponse(t *testing.T) {
 	require.Equal(t, err, ErrUnexpectedResponse)
 }
 
+func TestSocketPVAllowedSigners(t *testing.T) {
+	var (
+		addr      = testFreeAddr(t)
+		logger    = log.TestingLogger()
+		chainID   = cmn.RandStr(12)
+		readyc    = make(chan struct{})
+		signerKey = ed25519.GenPrivKey()
+		privVal   = types.NewMockPV()
+
+		rs = NewRemoteSigner(
+			logger,
+			chainID,
+			addr,
+			privVal,
+			signerKey,
+		)
+		sc = NewTCPVal(
+			logger,
+			addr,
+			ed25519.GenPrivKey(),
+		)
+	)
+
+	TCPValAllowedSigners(signerKey.PubKey().Address())(sc)
+	RemoteSignerConnDeadline(time.Second)(rs)
+	RemoteSignerConnRetries(10)(rs)
+
+	testStartSocketPV(t, readyc, sc)
+	defer sc.Stop()
+
+	// an impersonator completes the handshake, but is disconnected
+	var impersonator net.Conn
+	for {
+		conn, err := cmn.Connect(addr)
+		if err != nil {
+			continue
+		}
+
+		impersonator, err = p2pconn.MakeSecretConnection(
+			conn,
+			ed25519.GenPrivKey(),
+		)
+		if err == nil {
+			break
+		}
+	}
+	_, err := readMsg(impersonator)
+	assert.Error(t, err)
+	select {
+	case <-readyc:
+		t.Fatal("expected the impersonator to be rejected")
+	default:
+	}
+
+	// the allowed signer is accepted
+	require.NoError(t, rs.Start())
+	defer rs.Stop()
+	<-readyc
+
+	assert.Equal(t, privVal.GetPubKey(), sc.GetPubKey())
+}
+
+func TestRemoteSignerPinnedNodeKey(t *testing.T) {
+	var (
+		logger  = log.TestingLogger()
+		chainID = cmn.RandStr(12)
+		readyc  = make(chan struct{})
+		errc    = make(chan error, 1)
+		nodeKey = ed25519.GenPrivKey()
+	)
+
+	// an impersonator of the node completes the handshake...
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			errc <- err
+			return
+		}
+		impersonator, err := p2pconn.MakeSecretConnection(conn, ed25519.GenPrivKey())
+		if err != nil {
+			errc <- err
+			return
+		}
+		_, err = readMsg(impersonator)
+		errc <- err
+	}()
+
+	rs := NewRemoteSigner(
+		logger,
+		chainID,
+		ln.Addr().String(),
+		types.NewMockPV(),
+		ed25519.GenPrivKey(),
+	)
+	RemoteSignerPinnedNodeKey(nodeKey.PubKey().Address())(rs)
+	RemoteSignerConnDeadline(5 * time.Millisecond)(rs)
+	RemoteSignerConnRetries(1)(rs)
+
+	// ...but the signer refuses to serve it
+	assert.Equal(t, ErrDialRetryMax, rs.Start())
+	assert.Error(t, <-errc)
+
+	// the pinned node is served
+	var (
+		privVal = types.NewMockPV()
+		sc      = NewTCPVal(
+			logger,
+			testFreeAddr(t),
+			nodeKey,
+		)
+	)
+	rs = NewRemoteSigner(
+		logger,
+		chainID,
+		sc.addr,
+		privVal,
+		ed25519.GenPrivKey(),
+	)
+	RemoteSignerPinnedNodeKey(nodeKey.PubKey().Address())(rs)
+	RemoteSignerConnDeadline(time.Second)(rs)
+	RemoteSignerConnRetries(10)(rs)
+
+	testStartSocketPV(t, readyc, sc)
+	defer sc.Stop()
+	require.NoError(t, rs.Start())
+	defer rs.Stop()
+	<-readyc
+
+	assert.Equal(t, privVal.GetPubKey(), sc.GetPubKey())
+}
+
 func testSetupSocketPair(
 	t *testing.T,
 	chainID string,
@@ -365,10 +499,10 @@ func testSetupSocketPair(
 		)
 	)
 
-	TCPValConnTimeout(5 * time.Millisecond)(sc)
-	TCPValHeartbeat(2 * time.Millisecond)(sc)
-	RemoteSignerConnDeadline(5 * time.Millisecond)(rs)
-	RemoteSignerConnRetries(1e6)(rs)
+	TCPValConnTimeout(50 * time.Millisecond)(sc)
+	TCPValHeartbeat(20 * time.Millisecond)(sc)
+	RemoteSignerConnDeadline(50 * time.Millisecond)(rs)
+	RemoteSignerConnRetries(100)(rs)
 
 	testStartSocketPV(t, readyc, sc)
 
@@ -183,6 +183,15 @@ func (c *HTTP) Genesis() (*ctypes.ResultGenesis, error) {
 	return result, nil
 }
 
+func (c *HTTP) State(height *int64) (*ctypes.ResultState, error) {
+	result := new(ctypes.ResultState)
+	_, err := c.rpc.Call("state", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "State")
+	}
+	return result, nil
+}
+
 func (c *HTTP) Block(height *int64) (*ctypes.ResultBlock, error) {
 	result := new(ctypes.ResultBlock)
 	_, err := c.rpc.Call("block", map[string]interface{}{"height": height}, result)
@@ -223,13 +232,27 @@ func (c *HTTP) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	return result, nil
 }
 
-func (c *HTTP) TxSearch(query string, prove bool, page, perPage int) (*ctypes.ResultTxSearch, error) {
+func (c *HTTP) Txs(hashes [][]byte, prove bool) (*ctypes.ResultTxs, error) {
+	result := new(ctypes.ResultTxs)
+	params := map[string]interface{}{
+		"hashes": hashes,
+		"prove":  prove,
+	}
+	_, err := c.rpc.Call("txs", params, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "Txs")
+	}
+	return result, nil
+}
+
+func (c *HTTP) TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
 	result := new(ctypes.ResultTxSearch)
 	params := map[string]interface{}{
 		"query":    query,
 		"prove":    prove,
 		"page":     page,
 		"per_page": perPage,
+		"order_by": orderBy,
 	}
 	_, err := c.rpc.Call("tx_search", params, result)
 	if err != nil {
@@ -238,6 +261,20 @@ func (c *HTTP) TxSearch(query string, prove bool, page, perPage int) (*ctypes.Re
 	return result, nil
 }
 
+func (c *HTTP) BlockSearch(query string, page, perPage int) (*ctypes.ResultBlockSearch, error) {
+	result := new(ctypes.ResultBlockSearch)
+	params := map[string]interface{}{
+		"query":    query,
+		"page":     page,
+		"per_page": perPage,
+	}
+	_, err := c.rpc.Call("block_search", params, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "BlockSearch")
+	}
+	return result, nil
+}
+
 func (c *HTTP) Validators(height *int64) (*ctypes.ResultValidators, error) {
 	result := new(ctypes.ResultValidators)
 	_, err := c.rpc.Call("validators", map[string]interface{}{"height": height}, result)
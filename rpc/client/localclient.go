@@ -108,6 +108,46 @@ func (Local) DialPeers(peers []string, persistent bool) (*ctypes.ResultDialPeers
 	return core.UnsafeDialPeers(peers, persistent)
 }
 
+func (Local) BanPeer(target, duration, reason string) (*ctypes.ResultBanPeer, error) {
+	return core.UnsafeBanPeer(target, duration, reason)
+}
+
+func (Local) UnbanPeer(target string) (*ctypes.ResultUnbanPeer, error) {
+	return core.UnsafeUnbanPeer(target)
+}
+
+func (Local) ListBans() (*ctypes.ResultListBans, error) {
+	return core.UnsafeListBans()
+}
+
+func (Local) ReloadAllowList() (*ctypes.ResultReloadAllowList, error) {
+	return core.UnsafeReloadAllowList()
+}
+
+func (Local) PartitionPeers(peers []string) (*ctypes.ResultFaults, error) {
+	return core.UnsafePartitionPeers(peers)
+}
+
+func (Local) HealPartition(peers []string) (*ctypes.ResultFaults, error) {
+	return core.UnsafeHealPartition(peers)
+}
+
+func (Local) InjectFault(peers []string, channels string, dropPercent int, delay string) (*ctypes.ResultInjectFault, error) {
+	return core.UnsafeInjectFault(peers, channels, dropPercent, delay)
+}
+
+func (Local) RemoveFault(id int) (*ctypes.ResultFaults, error) {
+	return core.UnsafeRemoveFault(id)
+}
+
+func (Local) ListFaults() (*ctypes.ResultFaults, error) {
+	return core.UnsafeListFaults()
+}
+
+func (Local) ClearFaults() (*ctypes.ResultFaults, error) {
+	return core.UnsafeClearFaults()
+}
+
 func (Local) BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
 	return core.BlockchainInfo(minHeight, maxHeight)
 }
@@ -116,6 +156,10 @@ func (Local) Genesis() (*ctypes.ResultGenesis, error) {
 	return core.Genesis()
 }
 
+func (Local) State(height *int64) (*ctypes.ResultState, error) {
+	return core.State(height)
+}
+
 func (Local) Block(height *int64) (*ctypes.ResultBlock, error) {
 	return core.Block(height)
 }
@@ -136,8 +180,16 @@ func (Local) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	return core.Tx(hash, prove)
 }
 
-func (Local) TxSearch(query string, prove bool, page, perPage int) (*ctypes.ResultTxSearch, error) {
-	return core.TxSearch(query, prove, page, perPage)
+func (Local) Txs(hashes [][]byte, prove bool) (*ctypes.ResultTxs, error) {
+	return core.Txs(hashes, prove)
+}
+
+func (Local) TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
+	return core.TxSearch(query, prove, page, perPage, orderBy)
+}
+
+func (Local) BlockSearch(query string, page, perPage int) (*ctypes.ResultBlockSearch, error) {
+	return core.BlockSearch(query, page, perPage)
 }
 
 func (c *Local) Subscribe(ctx context.Context, subscriber string, query tmpubsub.Query, out chan<- interface{}) error {
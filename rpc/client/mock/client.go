@@ -123,6 +123,10 @@ func (c Client) Genesis() (*ctypes.ResultGenesis, error) {
 	return core.Genesis()
 }
 
+func (c Client) State(height *int64) (*ctypes.ResultState, error) {
+	return core.State(height)
+}
+
 func (c Client) Block(height *int64) (*ctypes.ResultBlock, error) {
 	return core.Block(height)
 }
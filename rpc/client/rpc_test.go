@@ -136,6 +136,39 @@ func TestGenesisAndValidators(t *testing.T) {
 	}
 }
 
+func TestState(t *testing.T) {
+	for i, c := range GetClients() {
+		err := client.WaitForHeight(c, 3, nil)
+		require.Nil(t, err, "%d: %+v", i, err)
+
+		latest, err := c.State(nil)
+		require.Nil(t, err, "%d: %+v", i, err)
+		assert.True(t, latest.BlockHeight >= 3)
+		assert.Equal(t, latest.BlockHeight, latest.State.LastBlockHeight)
+
+		// the state after block h is checked against the header of block h+1
+		h := int64(2)
+		res, err := c.State(&h)
+		require.Nil(t, err, "%d: %+v", i, err)
+		nextHeight := h + 1
+		next, err := c.Block(&nextHeight)
+		require.Nil(t, err, "%d: %+v", i, err)
+
+		state := res.State
+		assert.Equal(t, h, state.LastBlockHeight)
+		assert.Equal(t, next.Block.LastBlockID, state.LastBlockID)
+		assert.EqualValues(t, next.Block.AppHash, state.AppHash)
+		assert.EqualValues(t, next.Block.LastResultsHash, state.LastResultsHash)
+		assert.EqualValues(t, next.Block.ValidatorsHash, state.Validators.Hash())
+		assert.EqualValues(t, next.Block.ConsensusHash, state.ConsensusParams.Hash())
+
+		// heights past the latest state are rejected
+		h = latest.BlockHeight + 100
+		_, err = c.State(&h)
+		assert.NotNil(t, err)
+	}
+}
+
 func TestABCIQuery(t *testing.T) {
 	for i, c := range GetClients() {
 		// write something
@@ -371,6 +404,43 @@ func TestTx(t *testing.T) {
 	}
 }
 
+func TestTxs(t *testing.T) {
+	// first we broadcast two txs, likely in the same block
+	c := getHTTPClient()
+	_, _, tx1 := MakeTxKV()
+	_, _, tx2 := MakeTxKV()
+	_, err := c.BroadcastTxSync(tx1)
+	require.Nil(t, err, "%+v", err)
+	bres, err := c.BroadcastTxCommit(tx2)
+	require.Nil(t, err, "%+v", err)
+	err = client.WaitForHeight(c, bres.Height+1, nil)
+	require.Nil(t, err, "%+v", err)
+
+	anotherTxHash := types.Tx("a different tx").Hash()
+	hashes := [][]byte{tx1.Hash(), anotherTxHash, tx2.Hash()}
+
+	for i, c := range GetClients() {
+		t.Logf("client %d", i)
+
+		result, err := c.Txs(hashes, true)
+		require.Nil(t, err, "%+v", err)
+		// the unknown tx is left out
+		require.Len(t, result.Txs, 2)
+
+		for j, tx := range []types.Tx{tx1, tx2} {
+			ptx := result.Txs[j]
+			assert.EqualValues(t, tx, ptx.Tx)
+			assert.EqualValues(t, tx.Hash(), ptx.Hash)
+			assert.True(t, ptx.TxResult.IsOK())
+
+			proof := ptx.Proof
+			if assert.EqualValues(t, tx, proof.Data) {
+				assert.NoError(t, proof.Proof.Verify(proof.RootHash, tx.Hash()))
+			}
+		}
+	}
+}
+
 func TestTxSearch(t *testing.T) {
 	// first we broadcast a tx
 	c := getHTTPClient()
@@ -388,7 +458,7 @@ func TestTxSearch(t *testing.T) {
 
 		// now we query for the tx.
 		// since there's only one tx, we know index=0.
-		result, err := c.TxSearch(fmt.Sprintf("tx.hash='%v'", txHash), true, 1, 30)
+		result, err := c.TxSearch(fmt.Sprintf("tx.hash='%v'", txHash), true, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 1)
 
@@ -406,27 +476,63 @@ func TestTxSearch(t *testing.T) {
 		}
 
 		// query by height
-		result, err = c.TxSearch(fmt.Sprintf("tx.height=%d", txHeight), true, 1, 30)
+		result, err = c.TxSearch(fmt.Sprintf("tx.height=%d", txHeight), true, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 1)
 
 		// query for non existing tx
-		result, err = c.TxSearch(fmt.Sprintf("tx.hash='%X'", anotherTxHash), false, 1, 30)
+		result, err = c.TxSearch(fmt.Sprintf("tx.hash='%X'", anotherTxHash), false, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 0)
 
 		// query using a tag (see kvstore application)
-		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 30)
+		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		if len(result.Txs) == 0 {
 			t.Fatal("expected a lot of transactions")
 		}
 
 		// query using a tag (see kvstore application) and height
-		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko' AND tx.height<10000", true, 1, 30)
+		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko' AND tx.height<10000", true, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		if len(result.Txs) == 0 {
 			t.Fatal("expected a lot of transactions")
 		}
+
+		// query using a tag (see kvstore application), newest tx first
+		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 1, "desc")
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Txs, 1)
+		assert.True(t, result.TotalCount > 0)
+		assert.EqualValues(t, txHeight, result.Txs[0].Height)
+	}
+}
+
+func TestBlockSearch(t *testing.T) {
+	c := getHTTPClient()
+	err := client.WaitForHeight(c, 3, nil)
+	require.Nil(t, err, "%+v", err)
+
+	for i, c := range GetClients() {
+		t.Logf("client %d", i)
+
+		// query by height
+		result, err := c.BlockSearch("block.height=2", 1, 30)
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 1)
+		assert.EqualValues(t, 2, result.Blocks[0].Block.Height)
+		assert.EqualValues(t, 1, result.TotalCount)
+
+		// query by height range, one block per page
+		result, err = c.BlockSearch("block.height>=1 AND block.height<=2", 2, 1)
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 1)
+		assert.EqualValues(t, 2, result.Blocks[0].Block.Height)
+		assert.EqualValues(t, 2, result.TotalCount)
+
+		// query for a tag no block has
+		result, err = c.BlockSearch("app.unknown='tag'", 1, 30)
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 0)
 	}
 }
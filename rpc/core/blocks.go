@@ -4,8 +4,10 @@ import (
 	"fmt"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
+	tmquery "github.com/tendermint/tendermint/libs/pubsub/query"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	sm "github.com/tendermint/tendermint/state"
+	blocknull "github.com/tendermint/tendermint/state/blockindex/null"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -390,6 +392,92 @@ func BlockResults(heightPtr *int64) (*ctypes.ResultBlockResults, error) {
 	return res, nil
 }
 
+// BlockSearch allows you to query for blocks by the tags returned from
+// BeginBlock and EndBlock, as well as by `block.height`. It returns a list of
+// blocks (maximum ?per_page entries), in ascending order of height, and the
+// total count.
+//
+// ```shell
+// curl "localhost:26657/block_search?query=\"rewards.validator='Ivan'\""
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// res, err := client.BlockSearch("rewards.validator='Ivan' AND block.height > 10", 1, 30)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "jsonrpc": "2.0",
+//   "id": "",
+//   "result": {
+//     "blocks": [
+//       {
+//         "block_meta": {...},
+//         "block": {...}
+//       }
+//     ],
+//     "total_count": "1"
+//   }
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default | Required | Description                           |
+// |-----------+--------+---------+----------+---------------------------------------|
+// | query     | string | ""      | true     | Query                                 |
+// | page      | int    | 1       | false    | Page number (1-based)                 |
+// | per_page  | int    | 30      | false    | Number of entries per page (max: 100) |
+//
+// ### Returns
+//
+// - `blocks`: the matching blocks, each with its `block_meta`
+// - `total_count`: `int` - total number of matching blocks
+func BlockSearch(query string, page, perPage int) (*ctypes.ResultBlockSearch, error) {
+	// if index is disabled, return error
+	if _, ok := blockIndexer.(*blocknull.BlockIndex); ok {
+		return nil, fmt.Errorf("Block indexing is disabled")
+	}
+
+	q, err := tmquery.New(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := blockIndexer.Search(q)
+	if err != nil {
+		return nil, err
+	}
+
+	totalCount := len(results)
+	perPage = validatePerPage(perPage)
+	page = validatePage(page, perPage, totalCount)
+	skipCount := (page - 1) * perPage
+
+	apiResults := make([]*ctypes.ResultBlock, 0, cmn.MinInt(perPage, totalCount-skipCount))
+	for _, height := range results[skipCount : skipCount+cap(apiResults)] {
+		block := blockStore.LoadBlock(height)
+		if block == nil {
+			// the index may be ahead of the store after an unclean shutdown
+			continue
+		}
+		apiResults = append(apiResults, &ctypes.ResultBlock{
+			BlockMeta: blockStore.LoadBlockMeta(height),
+			Block:     block,
+		})
+	}
+
+	return &ctypes.ResultBlockSearch{Blocks: apiResults, TotalCount: totalCount}, nil
+}
+
 func getHeight(currentHeight int64, heightPtr *int64) (int64, error) {
 	if heightPtr != nil {
 		height := *heightPtr
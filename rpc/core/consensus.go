@@ -329,3 +329,76 @@ func ConsensusParams(heightPtr *int64) (*ctypes.ResultConsensusParams, error) {
 	}
 	return &ctypes.ResultConsensusParams{BlockHeight: height, ConsensusParams: consensusparams}, nil
 }
+
+// Get the state as it was right after the block at the given height was
+// committed, as reconstructed from the stored validator sets, consensus params,
+// ABCI responses and block headers.
+// If no height is provided, it will fetch the latest state.
+//
+// ```shell
+// curl 'localhost:26657/state?height=10'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// height := int64(10)
+// state, err := client.State(&height)
+// ```
+//
+// The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "jsonrpc": "2.0",
+//   "id": "",
+//   "result": {
+//     "block_height": "10",
+//     "state": {
+//       "Version": {
+//         "Consensus": {
+//           "block": "9",
+//           "app": "1"
+//         },
+//         "Software": "0.27.4"
+//       },
+//       "ChainID": "test-chain-KnZcJm",
+//       "LastBlockHeight": "10",
+//       "LastBlockTotalTx": "3",
+//       "LastBlockID": {
+//         "hash": "B4D3F1E47E1F2F2D4B0C8D3A1F6C2A9B6E0E5D7C8F9A0B1C2D3E4F5061728394",
+//         "parts": {
+//           "total": "1",
+//           "hash": "4A2C3D4E5F60718293A4B5C6D7E8F90A1B2C3D4E5F60718293A4B5C6D7E8F90A"
+//         }
+//       },
+//       "LastBlockTime": "2019-01-10T12:00:00.000000000Z",
+//       "NextValidators": {...},
+//       "Validators": {...},
+//       "LastValidators": {...},
+//       "LastHeightValidatorsChanged": "1",
+//       "ConsensusParams": {...},
+//       "LastHeightConsensusParamsChanged": "1",
+//       "LastResultsHash": "",
+//       "AppHash": "0600000000000000"
+//     }
+//   }
+// }
+// ```
+func State(heightPtr *int64) (*ctypes.ResultState, error) {
+	height := consensusState.GetState().LastBlockHeight
+	height, err := getHeight(height, heightPtr)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := sm.LoadStateAtHeight(stateDB, blockStore, height)
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultState{BlockHeight: height, State: state}, nil
+}
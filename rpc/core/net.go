@@ -1,10 +1,15 @@
 package core
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
+	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/p2p"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 )
@@ -54,6 +59,7 @@ func NetInfo() (*ctypes.ResultNetInfo, error) {
 			IsOutbound:       peer.IsOutbound(),
 			ConnectionStatus: peer.Status(),
 			RemoteIP:         peer.RemoteIP(),
+			TrustScore:       p2pPeers.PeerTrustScore(peer.ID()),
 		})
 	}
 	// TODO: Should we include PersistentPeers and Seeds in here?
@@ -93,6 +99,309 @@ func UnsafeDialPeers(peers []string, persistent bool) (*ctypes.ResultDialPeers,
 	return &ctypes.ResultDialPeers{"Dialing peers in progress. See /net_info for details"}, nil
 }
 
+// Ban a node ID, an IP or a CIDR range (e.g. "10.0.0.0/8"). The banned peers
+// are disconnected from, and can't connect until the ban expires. An empty
+// duration bans them permanently.
+//
+// ```shell
+// curl 'localhost:26657/ban_peer?target="10.0.0.0/8"&duration="24h"&reason="spam"'
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"jsonrpc": "2.0",
+// 	"id": "",
+// 	"result": {
+// 		"ban": {
+// 			"target": "10.0.0.0/8",
+// 			"reason": "spam",
+// 			"created": "2018-12-03T12:00:00.000000000Z",
+// 			"expires": "2018-12-04T12:00:00.000000000Z"
+// 		}
+// 	}
+// }
+// ```
+func UnsafeBanPeer(target, duration, reason string) (*ctypes.ResultBanPeer, error) {
+	var d time.Duration
+	if duration != "" {
+		var err error
+		d, err = time.ParseDuration(duration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q: %v", duration, err)
+		}
+	}
+	logger.Info("BanPeer", "target", target, "duration", d, "reason", reason)
+	ban, err := p2pPeers.Ban(target, d, reason)
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultBanPeer{Ban: ban}, nil
+}
+
+// Lift the ban of a node ID, an IP or a CIDR range.
+//
+// ```shell
+// curl 'localhost:26657/unban_peer?target="10.0.0.0/8"'
+// ```
+func UnsafeUnbanPeer(target string) (*ctypes.ResultUnbanPeer, error) {
+	logger.Info("UnbanPeer", "target", target)
+	if err := p2pPeers.Unban(target); err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultUnbanPeer{}, nil
+}
+
+// List the bans which haven't expired.
+//
+// ```shell
+// curl 'localhost:26657/list_bans'
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"jsonrpc": "2.0",
+// 	"id": "",
+// 	"result": {
+// 		"bans": [
+// 			{
+// 				"target": "75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b",
+// 				"reason": "error decoding message",
+// 				"created": "2018-12-03T12:00:00.000000000Z",
+// 				"expires": "0001-01-01T00:00:00Z"
+// 			}
+// 		]
+// 	}
+// }
+// ```
+func UnsafeListBans() (*ctypes.ResultListBans, error) {
+	bans := p2pPeers.BanList().List()
+	if bans == nil {
+		bans = []p2p.Ban{}
+	}
+	return &ctypes.ResultListBans{Bans: bans}, nil
+}
+
+// Reload the list of the node IDs allowed to connect (`p2p.allow_list_file`),
+// and disconnect from the inbound peers which are no longer listed.
+//
+// ```shell
+// curl 'localhost:26657/reload_allow_list'
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"jsonrpc": "2.0",
+// 	"id": "",
+// 	"result": {
+// 		"ids": [
+// 			"75e6ce0e63bda00ab5ab9a4b0d5cc0eb3bafad5b"
+// 		]
+// 	}
+// }
+// ```
+func UnsafeReloadAllowList() (*ctypes.ResultReloadAllowList, error) {
+	logger.Info("ReloadAllowList")
+	if err := p2pPeers.ReloadAllowList(); err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultReloadAllowList{IDs: p2pPeers.AllowList().List()}, nil
+}
+
+// Partition the node from the peers with the given IDs: disconnect from them,
+// and reject them until the partition is healed. Requires
+// `p2p.test_fault_injection`.
+//
+// ```shell
+// curl 'localhost:26657/partition_peers?peers=\["f9baeaa15fedf5e1ef7448dd60f46c01f1a9e9c4","0491d373a8e0fcf1023aaf18c51d6a1d0d4f31bd"\]'
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"jsonrpc": "2.0",
+// 	"id": "",
+// 	"result": {
+// 		"partitioned": [
+// 			"0491d373a8e0fcf1023aaf18c51d6a1d0d4f31bd",
+// 			"f9baeaa15fedf5e1ef7448dd60f46c01f1a9e9c4"
+// 		],
+// 		"faults": []
+// 	}
+// }
+// ```
+func UnsafePartitionPeers(peers []string) (*ctypes.ResultFaults, error) {
+	if len(peers) == 0 {
+		return nil, errors.New("No peers provided")
+	}
+	ids, err := faultPeerIDs(peers)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("PartitionPeers", "peers", ids)
+	if err := p2pPeers.Partition(ids); err != nil {
+		return nil, err
+	}
+	return UnsafeListFaults()
+}
+
+// Heal the partitions from the peers with the given IDs, or all of them if no
+// ID is given.
+//
+// ```shell
+// curl 'localhost:26657/heal_partition?peers=\["f9baeaa15fedf5e1ef7448dd60f46c01f1a9e9c4"\]'
+// ```
+func UnsafeHealPartition(peers []string) (*ctypes.ResultFaults, error) {
+	ids, err := faultPeerIDs(peers)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("HealPartition", "peers", ids)
+	if err := p2pPeers.Heal(ids); err != nil {
+		return nil, err
+	}
+	return UnsafeListFaults()
+}
+
+// Drop drop_percent percents of the messages sent to and received from the
+// peers with the given IDs (all the peers if none is given) on the given
+// channels (e.g. "0x20,0x22", all the channels if empty), and delay the
+// others by delay (e.g. "500ms"). Requires `p2p.test_fault_injection`.
+//
+// ```shell
+// curl 'localhost:26657/inject_fault?channels="0x22"&drop_percent=30&delay="200ms"'
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"jsonrpc": "2.0",
+// 	"id": "",
+// 	"result": {
+// 		"fault": {
+// 			"id": "1",
+// 			"peers": null,
+// 			"ch_ids": [
+// 				"34"
+// 			],
+// 			"drop_percent": "30",
+// 			"delay": "200000000"
+// 		}
+// 	}
+// }
+// ```
+func UnsafeInjectFault(peers []string, channels string, dropPercent int, delay string) (*ctypes.ResultInjectFault, error) {
+	faults, err := faultInjector()
+	if err != nil {
+		return nil, err
+	}
+	ids, err := faultPeerIDs(peers)
+	if err != nil {
+		return nil, err
+	}
+	fault := p2p.Fault{Peers: ids, DropPercent: dropPercent}
+	for _, ch := range cmn.SplitAndTrim(channels, ",", " ") {
+		if ch == "" {
+			continue
+		}
+		chID, err := strconv.ParseUint(ch, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid channel %q: %v", ch, err)
+		}
+		fault.ChIDs = append(fault.ChIDs, int(chID))
+	}
+	if delay != "" {
+		if fault.Delay, err = time.ParseDuration(delay); err != nil {
+			return nil, fmt.Errorf("invalid delay %q: %v", delay, err)
+		}
+	}
+	fault, err = faults.AddFault(fault)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("InjectFault", "fault", fault)
+	return &ctypes.ResultInjectFault{Fault: fault}, nil
+}
+
+// Remove the injected fault with the given ID.
+//
+// ```shell
+// curl 'localhost:26657/remove_fault?id=1'
+// ```
+func UnsafeRemoveFault(id int) (*ctypes.ResultFaults, error) {
+	faults, err := faultInjector()
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("RemoveFault", "id", id)
+	if err := faults.RemoveFault(id); err != nil {
+		return nil, err
+	}
+	return UnsafeListFaults()
+}
+
+// List the injected partitions and faults.
+//
+// ```shell
+// curl 'localhost:26657/list_faults'
+// ```
+func UnsafeListFaults() (*ctypes.ResultFaults, error) {
+	faults, err := faultInjector()
+	if err != nil {
+		return nil, err
+	}
+	res := &ctypes.ResultFaults{
+		Partitioned: faults.Partitioned(),
+		Faults:      faults.Faults(),
+	}
+	if res.Faults == nil {
+		res.Faults = []p2p.Fault{}
+	}
+	return res, nil
+}
+
+// Heal all the partitions and remove all the injected faults.
+//
+// ```shell
+// curl 'localhost:26657/clear_faults'
+// ```
+func UnsafeClearFaults() (*ctypes.ResultFaults, error) {
+	faults, err := faultInjector()
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("ClearFaults")
+	faults.Clear()
+	return UnsafeListFaults()
+}
+
+func faultInjector() (*p2p.FaultInjector, error) {
+	faults := p2pPeers.FaultInjector()
+	if faults == nil {
+		return nil, errors.New("fault injection is disabled (p2p.test_fault_injection)")
+	}
+	return faults, nil
+}
+
+func faultPeerIDs(peers []string) ([]p2p.ID, error) {
+	ids := make([]p2p.ID, 0, len(peers))
+	for _, peer := range peers {
+		idBytes, err := hex.DecodeString(peer)
+		if err != nil || len(idBytes) != p2p.IDByteLength {
+			return nil, fmt.Errorf("invalid peer ID %q", peer)
+		}
+		ids = append(ids, p2p.ID(strings.ToLower(peer)))
+	}
+	return ids, nil
+}
+
 // Get genesis file.
 //
 // ```shell
@@ -1,6 +1,8 @@
 package core
 
 import (
+	"time"
+
 	"github.com/tendermint/tendermint/consensus"
 	"github.com/tendermint/tendermint/crypto"
 	dbm "github.com/tendermint/tendermint/libs/db"
@@ -10,6 +12,7 @@ import (
 	"github.com/tendermint/tendermint/proxy"
 	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
 	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/blockindex"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/types"
 )
@@ -43,6 +46,15 @@ type peers interface {
 	DialPeersAsync(p2p.AddrBook, []string, bool) error
 	NumPeers() (outbound, inbound, dialig int)
 	Peers() p2p.IPeerSet
+	PeerTrustScore(p2p.ID) int
+	Ban(string, time.Duration, string) (p2p.Ban, error)
+	Unban(string) error
+	BanList() *p2p.BanList
+	ReloadAllowList() error
+	AllowList() *p2p.AllowList
+	FaultInjector() *p2p.FaultInjector
+	Partition([]p2p.ID) error
+	Heal([]p2p.ID) error
 }
 
 //----------------------------------------------
@@ -66,6 +78,7 @@ var (
 	genDoc           *types.GenesisDoc // cache the genesis structure
 	addrBook         p2p.AddrBook
 	txIndexer        txindex.TxIndexer
+	blockIndexer     blockindex.BlockIndexer
 	consensusReactor *consensus.ConsensusReactor
 	eventBus         *types.EventBus // thread safe
 	mempool          *mempl.Mempool
@@ -121,6 +134,10 @@ func SetTxIndexer(indexer txindex.TxIndexer) {
 	txIndexer = indexer
 }
 
+func SetBlockIndexer(indexer blockindex.BlockIndexer) {
+	blockIndexer = indexer
+}
+
 func SetConsensusReactor(conR *consensus.ConsensusReactor) {
 	consensusReactor = conR
 }
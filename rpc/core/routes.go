@@ -22,11 +22,14 @@ var Routes = map[string]*rpc.RPCFunc{
 	"block_results":        rpc.NewRPCFunc(BlockResults, "height"),
 	"commit":               rpc.NewRPCFunc(Commit, "height"),
 	"tx":                   rpc.NewRPCFunc(Tx, "hash,prove"),
-	"tx_search":            rpc.NewRPCFunc(TxSearch, "query,prove,page,per_page"),
+	"txs":                  rpc.NewRPCFunc(Txs, "hashes,prove"),
+	"tx_search":            rpc.NewRPCFunc(TxSearch, "query,prove,page,per_page,order_by"),
+	"block_search":         rpc.NewRPCFunc(BlockSearch, "query,page,per_page"),
 	"validators":           rpc.NewRPCFunc(Validators, "height"),
 	"dump_consensus_state": rpc.NewRPCFunc(DumpConsensusState, ""),
 	"consensus_state":      rpc.NewRPCFunc(ConsensusState, ""),
 	"consensus_params":     rpc.NewRPCFunc(ConsensusParams, "height"),
+	"state":                rpc.NewRPCFunc(State, "height"),
 	"unconfirmed_txs":      rpc.NewRPCFunc(UnconfirmedTxs, "limit"),
 	"num_unconfirmed_txs":  rpc.NewRPCFunc(NumUnconfirmedTxs, ""),
 	"mempool_txs":          rpc.NewRPCFunc(MempoolTxs, "limit"),
@@ -45,6 +48,16 @@ func AddUnsafeRoutes() {
 	// control API
 	Routes["dial_seeds"] = rpc.NewRPCFunc(UnsafeDialSeeds, "seeds")
 	Routes["dial_peers"] = rpc.NewRPCFunc(UnsafeDialPeers, "peers,persistent")
+	Routes["ban_peer"] = rpc.NewRPCFunc(UnsafeBanPeer, "target,duration,reason")
+	Routes["unban_peer"] = rpc.NewRPCFunc(UnsafeUnbanPeer, "target")
+	Routes["list_bans"] = rpc.NewRPCFunc(UnsafeListBans, "")
+	Routes["reload_allow_list"] = rpc.NewRPCFunc(UnsafeReloadAllowList, "")
+	Routes["partition_peers"] = rpc.NewRPCFunc(UnsafePartitionPeers, "peers")
+	Routes["heal_partition"] = rpc.NewRPCFunc(UnsafeHealPartition, "peers")
+	Routes["inject_fault"] = rpc.NewRPCFunc(UnsafeInjectFault, "peers,channels,drop_percent,delay")
+	Routes["remove_fault"] = rpc.NewRPCFunc(UnsafeRemoveFault, "id")
+	Routes["list_faults"] = rpc.NewRPCFunc(UnsafeListFaults, "")
+	Routes["clear_faults"] = rpc.NewRPCFunc(UnsafeClearFaults, "")
 	Routes["unsafe_flush_mempool"] = rpc.NewRPCFunc(UnsafeFlushMempool, "")
 
 	// profiler API
@@ -7,6 +7,7 @@ import (
 
 	tmquery "github.com/tendermint/tendermint/libs/pubsub/query"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/state/txindex/null"
 	"github.com/tendermint/tendermint/types"
 )
@@ -112,7 +113,8 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 }
 
 // TxSearch allows you to query for multiple transactions results. It returns a
-// list of transactions (maximum ?per_page entries) and the total count.
+// list of transactions (maximum ?per_page entries) and the total count. Only
+// the requested page of transactions is loaded from the index.
 //
 // ```shell
 // curl "localhost:26657/tx_search?query=\"account.owner='Ivan'\"&prove=true"
@@ -126,7 +128,7 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 // }
 // defer client.Stop()
 // q, err := tmquery.New("account.owner='Ivan'")
-// tx, err := client.TxSearch(q, true)
+// tx, err := client.TxSearch(q, true, 1, 30, "asc")
 // ```
 //
 // > The above command returns JSON structured like this:
@@ -173,6 +175,7 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 // | prove     | bool   | false   | false    | Include proofs of the transactions inclusion in the block |
 // | page      | int    | 1       | false    | Page number (1-based)                                     |
 // | per_page  | int    | 30      | false    | Number of entries per page (max: 100)                     |
+// | order_by  | string | "asc"   | false    | Order by height & index: "asc" or "desc"                  |
 //
 // ### Returns
 //
@@ -182,7 +185,7 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 // - `index`: `int` - index of the transaction
 // - `height`: `int` - height of the block where this transaction was in
 // - `hash`: `[]byte` - hash of the transaction
-func TxSearch(query string, prove bool, page, perPage int) (*ctypes.ResultTxSearch, error) {
+func TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
 	// if index is disabled, return error
 	if _, ok := txIndexer.(*null.TxIndex); ok {
 		return nil, fmt.Errorf("Transaction indexing is disabled")
@@ -193,37 +196,167 @@ func TxSearch(query string, prove bool, page, perPage int) (*ctypes.ResultTxSear
 		return nil, err
 	}
 
-	results, err := txIndexer.Search(q)
+	perPage = validatePerPage(perPage)
+	opts := txindex.SearchOptions{
+		OrderBy: orderBy,
+		Offset:  (cmn.MaxInt(page, 1) - 1) * perPage,
+		Limit:   perPage,
+	}
+	it, err := txIndexer.Search(q, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	totalCount := len(results)
-	perPage = validatePerPage(perPage)
-	page = validatePage(page, perPage, totalCount)
-	skipCount := (page - 1) * perPage
+	// pages past the last one are clamped to it, which is only known now
+	totalCount := it.TotalCount()
+	if validPage := validatePage(page, perPage, totalCount); (validPage-1)*perPage != opts.Offset {
+		it.Close() // nolint: errcheck
+		opts.Offset = (validPage - 1) * perPage
+		if it, err = txIndexer.Search(q, opts); err != nil {
+			return nil, err
+		}
+	}
 
-	apiResults := make([]*ctypes.ResultTx, cmn.MinInt(perPage, totalCount-skipCount))
-	var proof types.TxProof
-	for i := 0; i < len(apiResults); i++ {
-		r := results[skipCount+i]
-		height := r.Height
-		index := r.Index
+	results, err := txindex.Collect(it)
+	if err != nil {
+		return nil, err
+	}
 
+	apiResults, err := resultTxs(results, prove)
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultTxSearch{Txs: apiResults, TotalCount: totalCount}, nil
+}
+
+// Txs allows you to query for the results of multiple transactions at once.
+// The proofs of the transactions included in the same block are built from a
+// single load of the block.
+//
+// ```shell
+// curl 'localhost:26657/txs?hashes=["Kz7DK6JXmzuGBuQsBt4vevolVu8=","F1t9gL3iiKuFDKzh6umqPtVBeSU="]&prove=true'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// txs, err := client.Txs([][]byte{hash1, hash2}, true)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "jsonrpc": "2.0",
+//   "id": "",
+//   "result": {
+//     "txs": [
+//       {
+//         "proof": {
+//           "Proof": {
+//             "aunts": [
+//               "iblMO/M1TnNtlAefJyNCeVhjAb0="
+//             ]
+//           },
+//           "Data": "mvZHHa7HhZ4aRT0xMDA=",
+//           "RootHash": "F6541223AA46E428CB1070E9840D2C3DF3B6D776",
+//           "Total": "2",
+//           "Index": "0"
+//         },
+//         "tx": "mvZHHa7HhZ4aRT0xMDA=",
+//         "tx_result": {},
+//         "index": "0",
+//         "height": "12",
+//         "hash": "2B3EC32BA2579B3B8606E42C06DE2F7AFA2556EF"
+//       }
+//     ]
+//   }
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type     | Default | Required | Description                                                |
+// |-----------+----------+---------+----------+------------------------------------------------------------|
+// | hashes    | [][]byte | nil     | true     | The transaction hashes (max: 100)                          |
+// | prove     | bool     | false   | false    | Include proofs of the transactions inclusion in the blocks |
+//
+// ### Returns
+//
+// A list of transactions, in the order of the hashes. Transactions which are
+// not found are left out. For each transaction:
+//
+// - `proof`: the `types.TxProof` object
+// - `tx`: `[]byte` - the transaction
+// - `tx_result`: the `abci.Result` object
+// - `index`: `int` - index of the transaction
+// - `height`: `int` - height of the block where this transaction was in
+// - `hash`: `[]byte` - hash of the transaction
+func Txs(hashes [][]byte, prove bool) (*ctypes.ResultTxs, error) {
+	// if index is disabled, return error
+	if _, ok := txIndexer.(*null.TxIndex); ok {
+		return nil, fmt.Errorf("Transaction indexing is disabled")
+	}
+
+	if len(hashes) > maxPerPage {
+		return nil, fmt.Errorf("Too many hashes: %d (max: %d)", len(hashes), maxPerPage)
+	}
+
+	results := make([]*types.TxResult, 0, len(hashes))
+	for _, hash := range hashes {
+		r, err := txIndexer.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			results = append(results, r)
+		}
+	}
+
+	apiResults, err := resultTxs(results, prove)
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultTxs{Txs: apiResults}, nil
+}
+
+// resultTxs converts the results loaded from the tx index, building their
+// proofs if requested. Each block is loaded once, and the proofs of all its
+// txs are built together.
+func resultTxs(results []*types.TxResult, prove bool) ([]*ctypes.ResultTx, error) {
+	proofs := make(map[int64][]types.TxProof)
+
+	apiResults := make([]*ctypes.ResultTx, len(results))
+	for i, r := range results {
+		var proof types.TxProof
 		if prove {
-			block := blockStore.LoadBlock(height)
-			proof = block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
+			blockProofs, ok := proofs[r.Height]
+			if !ok {
+				block := blockStore.LoadBlock(r.Height)
+				if block == nil {
+					return nil, fmt.Errorf("Block at height %d not found", r.Height)
+				}
+				blockProofs = block.Data.Txs.Proofs()
+				proofs[r.Height] = blockProofs
+			}
+			if int(r.Index) >= len(blockProofs) {
+				return nil, fmt.Errorf("Block at height %d has no tx at index %d", r.Height, r.Index)
+			}
+			proof = blockProofs[r.Index]
 		}
 
 		apiResults[i] = &ctypes.ResultTx{
 			Hash:     r.Tx.Hash(),
-			Height:   height,
-			Index:    index,
+			Height:   r.Height,
+			Index:    r.Index,
 			TxResult: r.Result,
 			Tx:       r.Tx,
 			Proof:    proof,
 		}
 	}
-
-	return &ctypes.ResultTxSearch{Txs: apiResults, TotalCount: totalCount}, nil
+	return apiResults, nil
 }
@@ -107,12 +107,42 @@ type ResultDialPeers struct {
 	Log string `json:"log"`
 }
 
+// A new ban
+type ResultBanPeer struct {
+	Ban p2p.Ban `json:"ban"`
+}
+
+// Empty result of lifting a ban
+type ResultUnbanPeer struct{}
+
+// List of banned peers
+type ResultListBans struct {
+	Bans []p2p.Ban `json:"bans"`
+}
+
+// List of the node IDs allowed to connect
+type ResultReloadAllowList struct {
+	IDs []p2p.ID `json:"ids"`
+}
+
+// An injected fault
+type ResultInjectFault struct {
+	Fault p2p.Fault `json:"fault"`
+}
+
+// The injected partitions and faults
+type ResultFaults struct {
+	Partitioned []p2p.ID    `json:"partitioned"`
+	Faults      []p2p.Fault `json:"faults"`
+}
+
 // A peer
 type Peer struct {
 	NodeInfo         p2p.DefaultNodeInfo  `json:"node_info"`
 	IsOutbound       bool                 `json:"is_outbound"`
 	ConnectionStatus p2p.ConnectionStatus `json:"connection_status"`
 	RemoteIP         net.IP               `json:"remote_ip"`
+	TrustScore       int                  `json:"trust_score"`
 }
 
 // Validators for a height
@@ -127,6 +157,12 @@ type ResultConsensusParams struct {
 	ConsensusParams types.ConsensusParams `json:"consensus_params"`
 }
 
+// State as it was after the block at the given height was committed
+type ResultState struct {
+	BlockHeight int64       `json:"block_height"`
+	State       state.State `json:"state"`
+}
+
 // Info about the consensus state.
 // UNSTABLE
 type ResultDumpConsensusState struct {
@@ -172,12 +208,23 @@ type ResultTx struct {
 	Proof    types.TxProof          `json:"proof,omitempty"`
 }
 
+// List of txs
+type ResultTxs struct {
+	Txs []*ResultTx `json:"txs"`
+}
+
 // Result of searching for txs
 type ResultTxSearch struct {
 	Txs        []*ResultTx `json:"txs"`
 	TotalCount int         `json:"total_count"`
 }
 
+// Result of searching for blocks
+type ResultBlockSearch struct {
+	Blocks     []*ResultBlock `json:"blocks"`
+	TotalCount int            `json:"total_count"`
+}
+
 // List of mempool txs
 type ResultUnconfirmedTxs struct {
 	N   int        `json:"n_txs"`
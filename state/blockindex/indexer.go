@@ -0,0 +1,21 @@
+package blockindex
+
+import (
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/types"
+)
+
+// BlockIndexer interface defines methods to index and search blocks by the
+// tags returned from BeginBlock and EndBlock.
+type BlockIndexer interface {
+
+	// Index analyzes, indexes and stores the BeginBlock and EndBlock tags of
+	// a block.
+	Index(header types.EventDataNewBlockHeader) error
+
+	// Has returns true if the block at the given height has been indexed.
+	Has(height int64) (bool, error)
+
+	// Search allows you to query for the heights of blocks.
+	Search(q *query.Query) ([]int64, error)
+}
@@ -0,0 +1,297 @@
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/blockindex"
+	"github.com/tendermint/tendermint/state/tagvalue"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	tagKeySeparator = "/"
+
+	eventBeginBlock = "begin_block"
+	eventEndBlock   = "end_block"
+	eventHeight     = "height"
+)
+
+var _ blockindex.BlockIndexer = (*BlockIndex)(nil)
+
+// BlockIndex is the simplest possible block indexer, backed by key-value
+// storage (levelDB). Every block is indexed by its height; its BeginBlock and
+// EndBlock tags are indexed according to the options it is created with.
+type BlockIndex struct {
+	store        dbm.DB
+	tagsToIndex  []string
+	indexAllTags bool
+}
+
+// NewBlockIndex creates new KV block indexer.
+func NewBlockIndex(store dbm.DB, options ...func(*BlockIndex)) *BlockIndex {
+	bi := &BlockIndex{store: store, tagsToIndex: make([]string, 0), indexAllTags: false}
+	for _, o := range options {
+		o(bi)
+	}
+	return bi
+}
+
+// IndexTags is an option for setting which tags to index.
+func IndexTags(tags []string) func(*BlockIndex) {
+	return func(bi *BlockIndex) {
+		bi.tagsToIndex = tags
+	}
+}
+
+// IndexAllTags is an option for indexing all tags.
+func IndexAllTags() func(*BlockIndex) {
+	return func(bi *BlockIndex) {
+		bi.indexAllTags = true
+	}
+}
+
+// Has returns true if the block at the given height has been indexed.
+func (bi *BlockIndex) Has(height int64) (bool, error) {
+	if height <= 0 {
+		return false, fmt.Errorf("Height must be greater than 0, got %d", height)
+	}
+	return bi.store.Has(keyForHeight(height)), nil
+}
+
+// Index indexes the BeginBlock and EndBlock tags of a block.
+func (bi *BlockIndex) Index(header types.EventDataNewBlockHeader) error {
+	b := bi.store.NewBatch()
+	defer b.Close()
+
+	height := header.Header.Height
+	value := heightValue(height)
+
+	// index block by tags
+	bi.indexTags(b, header.ResultBeginBlock.Tags, height, eventBeginBlock)
+	bi.indexTags(b, header.ResultEndBlock.Tags, height, eventEndBlock)
+
+	// index block by height, which also marks it as indexed
+	b.Set(keyForHeight(height), value)
+
+	b.Write()
+	return nil
+}
+
+func (bi *BlockIndex) indexTags(b dbm.SetDeleter, tags []cmn.KVPair, height int64, event string) {
+	for _, tag := range tags {
+		if len(tag.Key) == 0 || tag.ValidateValue() != nil {
+			continue
+		}
+		if bi.indexAllTags || cmn.StringInSlice(string(tag.Key), bi.tagsToIndex) {
+			b.Set(keyForTag(tag, height, event), heightValue(height))
+		}
+	}
+}
+
+// Search performs a search using the given query. Each condition of the
+// query (like "reward.recipient='A'") is matched against the index, and the
+// heights of the blocks matching the whole query are returned in ascending
+// order. Conditions on "block.height" match the height of the block itself.
+func (bi *BlockIndex) Search(q *query.Query) ([]int64, error) {
+	heights, err := bi.searchExpr(q.Expr())
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]int64, 0, len(heights))
+	for h := range heights {
+		results = append(results, h)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i] < results[j] })
+	return results, nil
+}
+
+// searchExpr returns the heights of the blocks matching the expression. The
+// results of AND-ed expressions are intersected, those of OR-ed expressions
+// united, and NOT-ed expressions are subtracted from the other results (or
+// from all the indexed heights).
+func (bi *BlockIndex) searchExpr(e *query.Expr) (map[int64]struct{}, error) {
+	switch e.Type {
+	case query.ExprCondition:
+		return bi.match(e.Condition), nil
+	case query.ExprOr:
+		heights := make(map[int64]struct{})
+		for _, arg := range e.Args {
+			matched, err := bi.searchExpr(arg)
+			if err != nil {
+				return nil, err
+			}
+			for h := range matched {
+				heights[h] = struct{}{}
+			}
+		}
+		return heights, nil
+	case query.ExprAnd:
+		var heights map[int64]struct{}
+		var negated []*query.Expr
+		for _, arg := range e.Args {
+			if arg.Type == query.ExprNot {
+				negated = append(negated, arg.Args[0])
+				continue
+			}
+			matched, err := bi.searchExpr(arg)
+			if err != nil {
+				return nil, err
+			}
+			if heights == nil {
+				heights = matched
+			} else {
+				for h := range heights {
+					if _, ok := matched[h]; !ok {
+						delete(heights, h)
+					}
+				}
+			}
+			if len(heights) == 0 {
+				return heights, nil
+			}
+		}
+		if heights == nil {
+			heights = bi.allHeights()
+		}
+		for _, arg := range negated {
+			matched, err := bi.searchExpr(arg)
+			if err != nil {
+				return nil, err
+			}
+			for h := range matched {
+				delete(heights, h)
+			}
+		}
+		return heights, nil
+	case query.ExprNot:
+		matched, err := bi.searchExpr(e.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		heights := bi.allHeights()
+		for h := range matched {
+			delete(heights, h)
+		}
+		return heights, nil
+	default:
+		return nil, fmt.Errorf("unknown expression type %v", e.Type)
+	}
+}
+
+// allHeights returns the heights of all the indexed blocks.
+func (bi *BlockIndex) allHeights() map[int64]struct{} {
+	return bi.match(query.Condition{Tag: types.BlockHeightKey, Op: query.OpExists})
+}
+
+// match returns the heights of the blocks with a tag matching the condition.
+func (bi *BlockIndex) match(c query.Condition) map[int64]struct{} {
+	heights := make(map[int64]struct{})
+
+	if c.Op == query.OpIn {
+		for _, operand := range c.Operand.([]interface{}) {
+			for h := range bi.match(query.Condition{Tag: c.Tag, Op: query.OpEqual, Operand: operand}) {
+				heights[h] = struct{}{}
+			}
+		}
+		return heights
+	}
+
+	// the value is part of the key, so equality conditions can narrow the scan
+	prefixes := [][]byte{startKey(c.Tag)}
+	if c.Op == query.OpEqual {
+		switch operand := c.Operand.(type) {
+		case string:
+			prefixes = [][]byte{
+				startKey(c.Tag, operand),
+				startKey(c.Tag, tagvalue.StringPrefix+operand),
+				startKey(c.Tag, tagvalue.BoolPrefix+operand),
+			}
+		case int64:
+			if c.Tag == types.BlockHeightKey {
+				prefixes = [][]byte{startKey(c.Tag, operand)}
+			}
+		}
+	}
+
+	for _, prefix := range prefixes {
+		bi.matchKeys(prefix, c, heights)
+	}
+	return heights
+}
+
+// matchKeys adds the heights of the tag keys with the prefix whose values
+// match the condition.
+func (bi *BlockIndex) matchKeys(prefix []byte, c query.Condition, heights map[int64]struct{}) {
+	it := dbm.IteratePrefix(bi.store, prefix)
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		if !isTagKey(it.Key()) {
+			continue
+		}
+		if c.Op != query.OpExists && !tagvalue.Matches(c.Tag, valueFromKey(it.Key(), c.Tag), []query.Condition{c}) {
+			continue
+		}
+		height, err := strconv.ParseInt(string(it.Value()), 10, 64)
+		if err != nil {
+			continue
+		}
+		heights[height] = struct{}{}
+	}
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Keys
+
+func isTagKey(key []byte) bool {
+	return strings.Count(string(key), tagKeySeparator) == 3
+}
+
+// valueFromKey returns the (encoded) value of the tag in a tag key.
+func valueFromKey(key []byte, tag string) string {
+	value := strings.TrimPrefix(string(key), tag+tagKeySeparator)
+	for i := 0; i < 2; i++ {
+		if j := strings.LastIndex(value, tagKeySeparator); j >= 0 {
+			value = value[:j]
+		}
+	}
+	return value
+}
+
+func keyForTag(tag cmn.KVPair, height int64, event string) []byte {
+	return []byte(fmt.Sprintf("%s/%s/%d/%s",
+		tag.Key,
+		tagvalue.Encode(tag),
+		height,
+		event,
+	))
+}
+
+func keyForHeight(height int64) []byte {
+	return []byte(fmt.Sprintf("%s/%d/%d/%s",
+		types.BlockHeightKey,
+		height,
+		height,
+		eventHeight,
+	))
+}
+
+func heightValue(height int64) []byte {
+	return []byte(strconv.FormatInt(height, 10))
+}
+
+func startKey(fields ...interface{}) []byte {
+	var b bytes.Buffer
+	for _, f := range fields {
+		b.Write([]byte(fmt.Sprintf("%v", f) + tagKeySeparator))
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,171 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	db "github.com/tendermint/tendermint/libs/db"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBlockIndex(t *testing.T) {
+	indexer := NewBlockIndex(db.NewMemDB())
+
+	ok, err := indexer.Has(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = indexer.Index(blockWithTags(1, nil, nil))
+	require.NoError(t, err)
+
+	ok, err = indexer.Has(1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = indexer.Has(0)
+	assert.Error(t, err)
+}
+
+func TestBlockSearch(t *testing.T) {
+	allowedTags := []string{"reward.recipient", "reward.amount", "slash.validator"}
+	indexer := NewBlockIndex(db.NewMemDB(), IndexTags(allowedTags))
+
+	blocks := []types.EventDataNewBlockHeader{
+		blockWithTags(1,
+			[]cmn.KVPair{{Key: []byte("reward.recipient"), Value: []byte("Ivan")}},
+			[]cmn.KVPair{{Key: []byte("reward.amount"), Value: []byte("10")}},
+		),
+		blockWithTags(2,
+			[]cmn.KVPair{{Key: []byte("reward.recipient"), Value: []byte("Vlad")}},
+			[]cmn.KVPair{
+				{Key: []byte("reward.amount"), Value: []byte("25")},
+				{Key: []byte("not_allowed"), Value: []byte("Vlad")},
+			},
+		),
+		blockWithTags(3,
+			nil,
+			[]cmn.KVPair{{Key: []byte("slash.validator"), Value: []byte("Ivanka")}},
+		),
+	}
+	for _, b := range blocks {
+		require.NoError(t, indexer.Index(b))
+	}
+
+	testCases := []struct {
+		q       string
+		heights []int64
+	}{
+		// search by height
+		{"block.height = 2", []int64{2}},
+		{"block.height >= 2", []int64{2, 3}},
+		// search by a begin block tag
+		{"reward.recipient = 'Ivan'", []int64{1}},
+		// search by an end block tag
+		{"reward.amount > 5", []int64{1, 2}},
+		{"reward.amount > 5 AND reward.amount < 20", []int64{1}},
+		// search by begin and end block tags
+		{"reward.recipient = 'Vlad' AND reward.amount = 25", []int64{2}},
+		{"reward.recipient = 'Vlad' AND reward.amount = 10", []int64{}},
+		// search by tags and height
+		{"reward.amount > 5 AND block.height > 1", []int64{2}},
+		// search using CONTAINS
+		{"slash.validator CONTAINS 'Ivan'", []int64{3}},
+		// search using a prefix of the stored value
+		{"reward.recipient = 'Iv'", []int64{}},
+		// search for a tag which is not indexed
+		{"not_allowed = 'Vlad'", []int64{}},
+		// search using IN
+		{"reward.recipient IN ('Ivan', 'Vlad')", []int64{1, 2}},
+		{"block.height IN (1, 3)", []int64{1, 3}},
+		// search using EXISTS
+		{"slash.validator EXISTS", []int64{3}},
+		// search using OR
+		{"reward.recipient = 'Ivan' OR slash.validator EXISTS", []int64{1, 3}},
+		// search using NOT
+		{"NOT reward.recipient = 'Ivan'", []int64{2, 3}},
+		{"NOT reward.amount EXISTS", []int64{3}},
+		{"reward.amount > 5 AND NOT reward.recipient = 'Vlad'", []int64{1}},
+		// search using parentheses
+		{"(reward.recipient = 'Ivan' OR block.height = 3) AND NOT slash.validator EXISTS", []int64{1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.q, func(t *testing.T) {
+			heights, err := indexer.Search(query.MustParse(tc.q))
+			require.NoError(t, err)
+			assert.Equal(t, tc.heights, heights)
+		})
+	}
+}
+
+func TestBlockSearchTypedTags(t *testing.T) {
+	indexer := NewBlockIndex(db.NewMemDB(), IndexAllTags())
+
+	blocks := []types.EventDataNewBlockHeader{
+		blockWithTags(1,
+			[]cmn.KVPair{{Key: []byte("reward.recipient"), Value: []byte("7"), Type: cmn.KVPair_STRING}},
+			[]cmn.KVPair{
+				{Key: []byte("reward.amount"), Value: []byte("-20"), Type: cmn.KVPair_INT},
+				{Key: []byte("reward.time"), Value: []byte("2013-05-03T14:45:00Z"), Type: cmn.KVPair_TIME},
+			},
+		),
+		blockWithTags(2,
+			[]cmn.KVPair{{Key: []byte("reward.recipient"), Value: []byte("7"), Type: cmn.KVPair_UNTYPED}},
+			[]cmn.KVPair{
+				{Key: []byte("reward.amount"), Value: []byte("7"), Type: cmn.KVPair_INT},
+				{Key: []byte("reward.time"), Value: []byte("2013-05-03T16:45:00.5+02:00"), Type: cmn.KVPair_TIME},
+			},
+		),
+		blockWithTags(3,
+			[]cmn.KVPair{{Key: []byte("reward.recipient"), Value: []byte("Ivan"), Type: cmn.KVPair_STRING}},
+			[]cmn.KVPair{
+				{Key: []byte("reward.amount"), Value: []byte("9007199254740993"), Type: cmn.KVPair_INT},
+				{Key: []byte("reward.time"), Value: []byte("2013"), Type: cmn.KVPair_TIME}, // invalid, not indexed
+			},
+		),
+	}
+	for _, b := range blocks {
+		require.NoError(t, indexer.Index(b))
+	}
+
+	testCases := []struct {
+		q       string
+		heights []int64
+	}{
+		{"reward.recipient = '7'", []int64{1, 2}},
+		{"reward.recipient = 7", []int64{2}},
+		{"reward.recipient > 6", []int64{2}},
+		{"reward.recipient = 'Iv'", []int64{}},
+		{"reward.recipient CONTAINS 'Ivan'", []int64{3}},
+		{"reward.amount < 0", []int64{1}},
+		{"reward.amount = 7.0", []int64{2}},
+		{"reward.amount = '7'", []int64{}},
+		{"reward.amount = 9007199254740993", []int64{3}},
+		{"reward.amount > 9007199254740992", []int64{3}},
+		{"reward.time = TIME 2013-05-03T14:45:00Z", []int64{1}},
+		{"reward.time > TIME 2013-05-03T14:45:00Z", []int64{2}},
+		{"reward.time < DATE 2013-05-04", []int64{1, 2}},
+		{"reward.time EXISTS", []int64{1, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.q, func(t *testing.T) {
+			heights, err := indexer.Search(query.MustParse(tc.q))
+			require.NoError(t, err)
+			assert.Equal(t, tc.heights, heights)
+		})
+	}
+}
+
+func blockWithTags(height int64, beginTags, endTags []cmn.KVPair) types.EventDataNewBlockHeader {
+	return types.EventDataNewBlockHeader{
+		Header:           types.Header{Height: height},
+		ResultBeginBlock: abci.ResponseBeginBlock{Tags: beginTags},
+		ResultEndBlock:   abci.ResponseEndBlock{Tags: endTags},
+	}
+}
@@ -0,0 +1,28 @@
+package null
+
+import (
+	"errors"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/blockindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+var _ blockindex.BlockIndexer = (*BlockIndex)(nil)
+
+// BlockIndex acts as a /dev/null.
+type BlockIndex struct{}
+
+// Index is a noop and always returns nil.
+func (bi *BlockIndex) Index(header types.EventDataNewBlockHeader) error {
+	return nil
+}
+
+// Has on a BlockIndex is disabled and returns an error when invoked.
+func (bi *BlockIndex) Has(height int64) (bool, error) {
+	return false, errors.New(`Indexing is disabled (set 'tx_index = "kv"' in config)`)
+}
+
+func (bi *BlockIndex) Search(q *query.Query) ([]int64, error) {
+	return []int64{}, nil
+}
@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/tendermint/tendermint/abci/example/kvstore"
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	dbm "github.com/tendermint/tendermint/libs/db"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/proxy"
+	tmtime "github.com/tendermint/tendermint/types/time"
 
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/types"
@@ -525,3 +530,107 @@ type paramsChangeTestCase struct {
 	height int64
 	params types.ConsensusParams
 }
+
+// testBlockStore is a BlockStoreRPC holding only block metas.
+type testBlockStore struct {
+	metas map[int64]*types.BlockMeta
+}
+
+func (bs testBlockStore) Height() int64                                     { return int64(len(bs.metas)) }
+func (bs testBlockStore) LoadBlockMeta(height int64) *types.BlockMeta       { return bs.metas[height] }
+func (bs testBlockStore) LoadBlock(height int64) *types.Block               { return nil }
+func (bs testBlockStore) LoadBlockPart(height int64, index int) *types.Part { return nil }
+func (bs testBlockStore) LoadBlockCommit(height int64) *types.Commit        { return nil }
+func (bs testBlockStore) LoadSeenCommit(height int64) *types.Commit         { return nil }
+
+// makeStates executes blocks up to height with the kvstore app, and returns
+// the state after each of them, indexed by height.
+func makeStates(t *testing.T, height int64) (dbm.DB, testBlockStore, map[int64]State) {
+	val, privVal := types.RandValidator(false, 10)
+	genDoc := &types.GenesisDoc{
+		GenesisTime: tmtime.Now(),
+		ChainID:     chainID,
+		Validators:  []types.GenesisValidator{{PubKey: val.PubKey, Power: val.VotingPower}},
+	}
+	stateDB := dbm.NewMemDB()
+	state, err := LoadStateFromDBOrGenesisDoc(stateDB, genDoc)
+	require.NoError(t, err)
+
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication()))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+	blockExec := NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(), MockMempool{}, MockEvidencePool{})
+
+	blockStore := testBlockStore{metas: make(map[int64]*types.BlockMeta)}
+	states := map[int64]State{0: state}
+	lastCommit := &types.Commit{}
+	for h := int64(1); h <= height; h++ {
+		txs := []types.Tx{[]byte(fmt.Sprintf("k%d=%d", h, h))}
+		block, parts := state.MakeBlock(h, txs, lastCommit, nil, state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+		blockStore.metas[h] = types.NewBlockMeta(block, parts)
+		states[h] = state
+
+		vote := &types.Vote{
+			ValidatorAddress: privVal.GetAddress(),
+			Height:           h,
+			Timestamp:        block.Time.Add(time.Second),
+			Type:             types.PrecommitType,
+			BlockID:          blockID,
+		}
+		require.NoError(t, privVal.SignVote(chainID, vote))
+		lastCommit = &types.Commit{BlockID: blockID, Precommits: []*types.Vote{vote}}
+	}
+	return stateDB, blockStore, states
+}
+
+func TestLoadStateAtHeight(t *testing.T) {
+	stateDB, blockStore, states := makeStates(t, 5)
+
+	// every stored height, including the latest one
+	for h := int64(1); h <= 5; h++ {
+		state, err := LoadStateAtHeight(stateDB, blockStore, h)
+		require.NoError(t, err, "height %d", h)
+		assertStatesEqual(t, states[h], state, "height %d", h)
+	}
+
+	// heights without a state
+	for _, h := range []int64{-1, 0, 6} {
+		_, err := LoadStateAtHeight(stateDB, blockStore, h)
+		assert.Equal(t, ErrUnknownBlock{h}, err, "height %d", h)
+	}
+}
+
+// assertStatesEqual asserts that the states are equal, except for the
+// proposer priorities of their validators, which aren't persisted as such.
+func assertStatesEqual(t *testing.T, expected, actual State, msgAndArgs ...interface{}) {
+	for _, s := range []*State{&expected, &actual} {
+		*s = s.Copy()
+		s.NextValidators = types.NewValidatorSet(s.NextValidators.Validators)
+		s.Validators = types.NewValidatorSet(s.Validators.Validators)
+		s.LastValidators = types.NewValidatorSet(s.LastValidators.Validators)
+	}
+	assert.Equal(t, expected, actual, msgAndArgs...)
+}
+
+func TestLoadStateAtHeightMissing(t *testing.T) {
+	stateDB, blockStore, _ := makeStates(t, 5)
+
+	// the header of the next block holds the app hash
+	delete(blockStore.metas, 3)
+	_, err := LoadStateAtHeight(stateDB, blockStore, 2)
+	assert.Equal(t, ErrUnknownBlock{2}, err)
+	_, err = LoadStateAtHeight(stateDB, blockStore, 3)
+	assert.Equal(t, ErrUnknownBlock{3}, err)
+
+	// pruned results
+	stateDB.Delete(calcABCIResponsesKey(1))
+	_, err = LoadStateAtHeight(stateDB, blockStore, 1)
+	assert.Equal(t, ErrNoABCIResponsesForHeight{1}, err)
+
+	// the latest state doesn't depend on the pruned data
+	_, err = LoadStateAtHeight(stateDB, blockStore, 5)
+	assert.NoError(t, err)
+}
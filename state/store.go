@@ -101,6 +101,75 @@ func saveState(db dbm.DB, state State, key []byte) {
 	db.SetSync(key, state.Bytes())
 }
 
+// LoadStateAtHeight reconstructs the State as it was right after the block at
+// the given height was committed, from the validator sets, consensus params
+// and ABCI responses stored in the database and the block headers held by
+// blockStore. The latest state is returned as stored.
+func LoadStateAtHeight(db dbm.DB, blockStore BlockStoreRPC, height int64) (State, error) {
+	latest := LoadState(db)
+	if height == latest.LastBlockHeight {
+		return latest, nil
+	}
+	if height <= 0 || height > latest.LastBlockHeight {
+		return State{}, ErrUnknownBlock{height}
+	}
+
+	// The app hash resulting from executing the block at height is only
+	// recorded in the header of the next block.
+	blockMeta := blockStore.LoadBlockMeta(height)
+	nextBlockMeta := blockStore.LoadBlockMeta(height + 1)
+	if blockMeta == nil || nextBlockMeta == nil {
+		return State{}, ErrUnknownBlock{height}
+	}
+
+	lastValidators, err := LoadValidators(db, height)
+	if err != nil {
+		return State{}, err
+	}
+	validators, err := LoadValidators(db, height+1)
+	if err != nil {
+		return State{}, err
+	}
+	nextValidators, err := LoadValidators(db, height+2)
+	if err != nil {
+		return State{}, err
+	}
+	consensusParams, err := LoadConsensusParams(db, height+1)
+	if err != nil {
+		return State{}, err
+	}
+	abciResponses, err := LoadABCIResponses(db, height)
+	if err != nil {
+		return State{}, err
+	}
+
+	header := blockMeta.Header
+	return State{
+		Version: Version{
+			Consensus: header.Version,
+			Software:  latest.Version.Software,
+		},
+		ChainID: header.ChainID,
+
+		LastBlockHeight:  height,
+		LastBlockTotalTx: header.TotalTxs,
+		LastBlockID:      blockMeta.BlockID,
+		LastBlockTime:    header.Time,
+
+		NextValidators:              nextValidators,
+		Validators:                  validators,
+		LastValidators:              lastValidators,
+		LastHeightValidatorsChanged: loadValidatorsInfo(db, height+2).LastHeightChanged,
+
+		ConsensusParams:                  consensusParams,
+		LastHeightConsensusParamsChanged: loadConsensusParamsInfo(db, height+1).LastHeightChanged,
+
+		LastResultsHash: abciResponses.ResultsHash(),
+
+		AppHash: nextBlockMeta.Header.AppHash,
+	}, nil
+}
+
 //------------------------------------------------------------------------
 
 // ABCIResponses retains the responses
@@ -155,6 +224,37 @@ func LoadABCIResponses(db dbm.DB, height int64) (*ABCIResponses, error) {
 	return abciResponses, nil
 }
 
+// LoadTxResults loads the txs of the block at the given height from the
+// blockStore, and their results from the database.
+func LoadTxResults(db dbm.DB, blockStore BlockStoreRPC, height int64) ([]*types.TxResult, error) {
+	block := blockStore.LoadBlock(height)
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found in the blockstore", height)
+	}
+	if len(block.Data.Txs) == 0 {
+		return []*types.TxResult{}, nil
+	}
+
+	abciResponses, err := LoadABCIResponses(db, height)
+	if err != nil {
+		return nil, err
+	}
+	if len(abciResponses.DeliverTx) != len(block.Data.Txs) {
+		return nil, fmt.Errorf("block %d has %d txs but %d results", height, len(block.Data.Txs), len(abciResponses.DeliverTx))
+	}
+
+	txResults := make([]*types.TxResult, len(block.Data.Txs))
+	for i, tx := range block.Data.Txs {
+		txResults[i] = &types.TxResult{
+			Height: height,
+			Index:  uint32(i),
+			Tx:     tx,
+			Result: *(abciResponses.DeliverTx[i]),
+		}
+	}
+	return txResults, nil
+}
+
 // SaveABCIResponses persists the ABCIResponses to the database.
 // This is useful in case we crash after app.Commit and before s.Save().
 // Responses are indexed by height so they can also be loaded later to produce Merkle proofs.
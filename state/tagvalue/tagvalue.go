@@ -0,0 +1,190 @@
+// Package tagvalue encodes the values of the tags in the keys of the kv
+// indexers, according to their types, and matches them against queries.
+package tagvalue
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/pubsub"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+)
+
+// The keys start with the tag and the separator, followed by the value.
+// Untyped values are stored as they are. Typed values are prefixed with a NUL
+// byte and a letter for their kind:
+//
+//  - strings and bools as they are;
+//  - numbers (INT and DECIMAL) as a hex encoded float64, which sorts in
+//    numerical order, followed by "i" or "d" for the type and the value;
+//  - times as hex encoded seconds and nanoseconds since the epoch, which sort
+//    in chronological order.
+//
+// So range conditions only read the keys between their bounds, and exact
+// comparisons are made on the values decoded from the keys.
+const (
+	// Separator separates the tag from the value in the keys.
+	Separator = "/"
+
+	typedValuePrefix = "\x00"
+	// StringPrefix prefixes the STRING values.
+	StringPrefix = typedValuePrefix + "s"
+	// BoolPrefix prefixes the BOOL values.
+	BoolPrefix  = typedValuePrefix + "b"
+	numberValue = typedValuePrefix + "n"
+	timeValue   = typedValuePrefix + "t"
+
+	encodedFloatLen = 16
+	encodedTimeLen  = 24
+)
+
+// Encode returns the value of the tag as stored in its key. The value must be
+// valid for its type.
+func Encode(tag cmn.KVPair) string {
+	value := string(tag.Value)
+	switch tag.Type {
+	case cmn.KVPair_STRING:
+		return StringPrefix + value
+	case cmn.KVPair_BOOL:
+		return BoolPrefix + value
+	case cmn.KVPair_INT:
+		i, _ := strconv.ParseInt(value, 10, 64)
+		return numberValue + encodeFloat(float64(i)) + "i" + value
+	case cmn.KVPair_DECIMAL:
+		f, _ := strconv.ParseFloat(value, 64)
+		return numberValue + encodeFloat(f) + "d" + value
+	case cmn.KVPair_TIME:
+		t, _ := time.Parse(time.RFC3339Nano, value)
+		return timeValue + encodeTime(t)
+	default:
+		return value
+	}
+}
+
+// decode returns the value and type of a value stored in a key.
+func decode(s string) (string, cmn.KVPair_Type) {
+	if !strings.HasPrefix(s, typedValuePrefix) || len(s) < 2 {
+		return s, cmn.KVPair_UNTYPED
+	}
+	switch s[:2] {
+	case StringPrefix:
+		return s[2:], cmn.KVPair_STRING
+	case BoolPrefix:
+		return s[2:], cmn.KVPair_BOOL
+	case numberValue:
+		s = s[2:]
+		if len(s) <= encodedFloatLen {
+			break
+		}
+		if s[encodedFloatLen] == 'i' {
+			return s[encodedFloatLen+1:], cmn.KVPair_INT
+		}
+		return s[encodedFloatLen+1:], cmn.KVPair_DECIMAL
+	case timeValue:
+		s = s[2:]
+		if len(s) != encodedTimeLen {
+			break
+		}
+		secs, err := strconv.ParseUint(s[:encodedFloatLen], 16, 64)
+		if err != nil {
+			break
+		}
+		nanos, err := strconv.ParseUint(s[encodedFloatLen:], 16, 32)
+		if err != nil {
+			break
+		}
+		t := time.Unix(int64(secs^(1<<63)), int64(nanos)).UTC()
+		return t.Format(time.RFC3339Nano), cmn.KVPair_TIME
+	}
+	return s, cmn.KVPair_UNTYPED
+}
+
+// encodeFloat returns the IEEE 754 bits of f, with the sign bit flipped for
+// positive numbers and all bits flipped for negative ones, so that the hex
+// strings sort like the numbers.
+func encodeFloat(f float64) string {
+	if f == 0 {
+		f = 0 // -0
+	}
+	bits := math.Float64bits(f)
+	if bits&(1<<63) == 0 {
+		bits ^= 1 << 63
+	} else {
+		bits = ^bits
+	}
+	return fmt.Sprintf("%016x", bits)
+}
+
+func encodeTime(t time.Time) string {
+	return fmt.Sprintf("%016x%08x", uint64(t.Unix())^(1<<63), t.Nanosecond())
+}
+
+// encodeOperand returns the encoding of a number or time operand, and the
+// prefix of the values it can be compared with.
+func encodeOperand(operand interface{}) (prefix, encoded string, ok bool) {
+	switch o := operand.(type) {
+	case int64:
+		return numberValue, encodeFloat(float64(o)), true
+	case float64:
+		return numberValue, encodeFloat(o), true
+	case time.Time:
+		return timeValue, encodeTime(o), true
+	default:
+		return "", "", false
+	}
+}
+
+// Range returns the range of keys which may hold the typed values of the tag
+// matching all of the conditions, which are comparisons with the same number
+// or time operand type.
+func Range(tag string, conditions []query.Condition) (start, end []byte, ok bool) {
+	var prefix, lower, upper string
+	for _, c := range conditions {
+		p, encoded, ok := encodeOperand(c.Operand)
+		if !ok || (prefix != "" && p != prefix) {
+			return nil, nil, false
+		}
+		prefix = p
+		switch c.Op {
+		case query.OpEqual:
+			lower, upper = encoded, encoded
+		case query.OpGreater, query.OpGreaterEqual:
+			lower = encoded
+		case query.OpLess, query.OpLessEqual:
+			upper = encoded
+		default:
+			return nil, nil, false
+		}
+	}
+	if prefix == "" {
+		return nil, nil, false
+	}
+
+	start = []byte(tag + Separator)
+	end = append(start[:len(start):len(start)], prefix...)
+	start = append(start, prefix+lower...)
+	if upper != "" {
+		// the keys with the upper bound are followed by the type or separator
+		end = append(end, upper+"\xff"...)
+	} else {
+		end[len(end)-1]++
+	}
+	return start, end, true
+}
+
+// Matches returns true if a value stored in a key matches all of the
+// conditions on the tag.
+func Matches(tag, storedValue string, conditions []query.Condition) bool {
+	value, typ := decode(storedValue)
+	tags := pubsub.NewTypedTagMap(map[string]string{tag: value}, map[string]cmn.KVPair_Type{tag: typ})
+	for _, c := range conditions {
+		if !c.Matches(tags) {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package txindex
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/tendermint/tendermint/libs/pubsub/query"
 	"github.com/tendermint/tendermint/types"
@@ -20,8 +21,79 @@ type TxIndexer interface {
 	// or stored.
 	Get(hash []byte) (*types.TxResult, error)
 
-	// Search allows you to query for transactions.
-	Search(q *query.Query) ([]*types.TxResult, error)
+	// Search allows you to query for transactions. The results are ordered by
+	// height and index, as requested by the options, which also limit the
+	// page of results returned.
+	Search(q *query.Query, opts SearchOptions) (ResultIterator, error)
+}
+
+//----------------------------------------------------
+// Search results
+
+const (
+	// OrderAsc orders search results by ascending height and index.
+	OrderAsc = "asc"
+	// OrderDesc orders search results by descending height and index.
+	OrderDesc = "desc"
+)
+
+// SearchOptions control the order of search results and which of them are
+// returned.
+type SearchOptions struct {
+	// OrderBy is either OrderAsc (the default if empty) or OrderDesc.
+	OrderBy string
+	// Offset is the number of results to skip.
+	Offset int
+	// Limit is the maximum number of results to return. Zero means no limit.
+	Limit int
+}
+
+// ValidateBasic performs basic validation of the options.
+func (opts SearchOptions) ValidateBasic() error {
+	switch opts.OrderBy {
+	case "", OrderAsc, OrderDesc:
+	default:
+		return fmt.Errorf("order_by must be %q or %q, got %q", OrderAsc, OrderDesc, opts.OrderBy)
+	}
+	if opts.Offset < 0 {
+		return errors.New("offset can't be negative")
+	}
+	if opts.Limit < 0 {
+		return errors.New("limit can't be negative")
+	}
+	return nil
+}
+
+// ResultIterator iterates over the results of a search, loading them one at
+// a time. It must be closed once done with.
+type ResultIterator interface {
+	// Next loads the next result. It returns false when there are no more
+	// results or an error occurred (see Err).
+	Next() bool
+
+	// Result returns the result loaded by the last call to Next.
+	Result() *types.TxResult
+
+	// Err returns the error which stopped the iteration, if any.
+	Err() error
+
+	// TotalCount returns the number of transactions matching the query,
+	// ignoring the offset and limit of the search.
+	TotalCount() int
+
+	// Close releases the resources held by the iterator.
+	Close() error
+}
+
+// Collect reads all the remaining results of the iterator and closes it.
+func Collect(it ResultIterator) ([]*types.TxResult, error) {
+	defer it.Close() // nolint: errcheck
+
+	results := make([]*types.TxResult, 0)
+	for it.Next() {
+		results = append(results, it.Result())
+	}
+	return results, it.Err()
 }
 
 //----------------------------------------------------
@@ -2,35 +2,125 @@ package txindex
 
 import (
 	"context"
+	"io"
+	"strconv"
+	"time"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
+	dbm "github.com/tendermint/tendermint/libs/db"
 
 	"github.com/tendermint/tendermint/types"
 )
 
 const (
 	subscriber = "IndexerService"
+
+	// defaultQueueSize is the number of blocks waiting to be indexed after
+	// which new blocks are dropped (and caught up on later) or, without a
+	// TxResultsSource, block the event bus.
+	defaultQueueSize = 100
+
+	// defaultRetryInterval is the time to wait before trying again to
+	// catch up on or index a block which failed.
+	defaultRetryInterval = time.Second
 )
 
+var lastIndexedHeightKey = []byte("IndexerService.lastIndexedHeight")
+
+// TxResultsSource provides the txs and results of the committed blocks, which
+// the IndexerService indexes when it missed them.
+type TxResultsSource interface {
+	// LastHeight returns the height of the last block with results.
+	LastHeight() int64
+
+	// TxResults returns the results of the txs of the block at the given
+	// height, in order.
+	TxResults(height int64) ([]*types.TxResult, error)
+}
+
 // IndexerService connects event bus and transaction indexer together in order
 // to index transactions coming from event bus.
+//
+// Blocks are indexed by a background worker, so that a slow indexer doesn't
+// hold up the event bus. With CatchUp, the service also records the height of
+// the last indexed block, and indexes the blocks it missed (because the node
+// stopped before indexing them, or the queue of blocks to index was full) from
+// the given source. The blocks are then indexed in order: a block which fails
+// to load or to be indexed is retried until it succeeds, so that none is
+// skipped.
 type IndexerService struct {
 	cmn.BaseService
 
 	idr      TxIndexer
 	eventBus *types.EventBus
+
+	// catch-up, if db is not nil
+	db                dbm.DB
+	source            TxResultsSource
+	lastIndexedHeight int64
+
+	queueSize     int
+	queue         chan queuedBlock
+	retryInterval time.Duration
+}
+
+// queuedBlock is a block waiting to be indexed.
+type queuedBlock struct {
+	height int64
+	batch  *Batch
 }
 
+// IndexerServiceOption sets an optional parameter on the IndexerService.
+type IndexerServiceOption func(*IndexerService)
+
 // NewIndexerService returns a new service instance.
-func NewIndexerService(idr TxIndexer, eventBus *types.EventBus) *IndexerService {
-	is := &IndexerService{idr: idr, eventBus: eventBus}
+func NewIndexerService(idr TxIndexer, eventBus *types.EventBus, options ...IndexerServiceOption) *IndexerService {
+	is := &IndexerService{
+		idr:           idr,
+		eventBus:      eventBus,
+		queueSize:     defaultQueueSize,
+		retryInterval: defaultRetryInterval,
+	}
 	is.BaseService = *cmn.NewBaseService(nil, "IndexerService", is)
+	for _, option := range options {
+		option(is)
+	}
 	return is
 }
 
+// CatchUp is an option which makes the service record the last indexed height
+// in db, and index the blocks it missed using source.
+func CatchUp(db dbm.DB, source TxResultsSource) IndexerServiceOption {
+	return func(is *IndexerService) {
+		is.db = db
+		is.source = source
+	}
+}
+
+// QueueSize is an option for setting the number of blocks which can be
+// waiting to be indexed.
+func QueueSize(size int) IndexerServiceOption {
+	return func(is *IndexerService) {
+		is.queueSize = size
+	}
+}
+
+// RetryInterval is an option for setting the time to wait before trying
+// again to catch up on or index a block which failed.
+func RetryInterval(interval time.Duration) IndexerServiceOption {
+	return func(is *IndexerService) {
+		is.retryInterval = interval
+	}
+}
+
 // OnStart implements cmn.Service by subscribing for all transactions
 // and indexing them by tags.
 func (is *IndexerService) OnStart() error {
+	if is.db != nil {
+		is.lastIndexedHeight = loadLastIndexedHeight(is.db)
+	}
+	is.queue = make(chan queuedBlock, is.queueSize)
+
 	blockHeadersCh := make(chan interface{})
 	if err := is.eventBus.Subscribe(context.Background(), subscriber, types.EventQueryNewBlockHeader, blockHeadersCh); err != nil {
 		return err
@@ -41,33 +131,178 @@ func (is *IndexerService) OnStart() error {
 		return err
 	}
 
-	go func() {
-		for {
-			e, ok := <-blockHeadersCh
+	go is.receiveRoutine(blockHeadersCh, txsCh)
+	go is.indexRoutine()
+	return nil
+}
+
+// OnStop implements cmn.Service by unsubscribing from all transactions, and
+// closing the indexer if it holds a connection (like the SQL indexer).
+func (is *IndexerService) OnStop() {
+	if is.eventBus.IsRunning() {
+		_ = is.eventBus.UnsubscribeAll(context.Background(), subscriber)
+	}
+	if closer, ok := is.idr.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			is.Logger.Error("Failed to close the indexer", "err", err)
+		}
+	}
+}
+
+// receiveRoutine groups the txs of each block into a batch and queues it.
+func (is *IndexerService) receiveRoutine(blockHeadersCh, txsCh <-chan interface{}) {
+	for {
+		e, ok := <-blockHeadersCh
+		if !ok {
+			return
+		}
+		header := e.(types.EventDataNewBlockHeader).Header
+		batch := NewBatch(header.NumTxs)
+		for i := int64(0); i < header.NumTxs; i++ {
+			e, ok := <-txsCh
 			if !ok {
+				is.Logger.Error("Failed to index all transactions due to closed transactions channel", "height", header.Height, "numTxs", header.NumTxs, "numProcessed", i)
 				return
 			}
-			header := e.(types.EventDataNewBlockHeader).Header
-			batch := NewBatch(header.NumTxs)
-			for i := int64(0); i < header.NumTxs; i++ {
-				e, ok := <-txsCh
-				if !ok {
-					is.Logger.Error("Failed to index all transactions due to closed transactions channel", "height", header.Height, "numTxs", header.NumTxs, "numProcessed", i)
+			txResult := e.(types.EventDataTx).TxResult
+			batch.Add(&txResult)
+		}
+		is.enqueue(batch, header.Height)
+	}
+}
+
+// enqueue queues the batch of the block at the given height. If the queue is
+// full, the batch is dropped when the block can be indexed later from the
+// source, and waits otherwise.
+func (is *IndexerService) enqueue(batch *Batch, height int64) {
+	block := queuedBlock{height: height, batch: batch}
+	if is.source == nil {
+		select {
+		case is.queue <- block:
+		case <-is.Quit():
+		}
+		return
+	}
+
+	select {
+	case is.queue <- block:
+	default:
+		is.Logger.Info("Indexing queue is full, block will be caught up on later", "height", height)
+	}
+}
+
+// indexRoutine catches up on the missed blocks, then indexes the queued ones.
+func (is *IndexerService) indexRoutine() {
+	if is.source != nil {
+		if !is.catchUp(is.source.LastHeight()) {
+			return
+		}
+	}
+
+	for {
+		select {
+		case block := <-is.queue:
+			if is.source != nil {
+				if block.height <= is.lastIndexedHeight {
+					continue // already caught up on
+				}
+				if !is.catchUp(block.height - 1) {
 					return
 				}
-				txResult := e.(types.EventDataTx).TxResult
-				batch.Add(&txResult)
 			}
-			is.idr.AddBatch(batch)
-			is.Logger.Info("Indexed block", "height", header.Height)
+			if !is.index(block.batch, block.height) {
+				return
+			}
+		case <-is.Quit():
+			return
 		}
-	}()
-	return nil
+	}
 }
 
-// OnStop implements cmn.Service by unsubscribing from all transactions.
-func (is *IndexerService) OnStop() {
-	if is.eventBus.IsRunning() {
-		_ = is.eventBus.UnsubscribeAll(context.Background(), subscriber)
+// catchUp indexes the blocks after the last indexed one up to the given
+// height from the source. It returns false if the service stopped before.
+func (is *IndexerService) catchUp(height int64) bool {
+	if is.lastIndexedHeight >= height {
+		return true
+	}
+	is.Logger.Info("Catching up on missed blocks", "from", is.lastIndexedHeight+1, "to", height)
+
+	for h := is.lastIndexedHeight + 1; h <= height; h++ {
+		select {
+		case <-is.Quit():
+			return false
+		default:
+		}
+		txResults, err := is.source.TxResults(h)
+		for err != nil {
+			is.Logger.Error("Failed to load the transactions to catch up on, retrying", "height", h, "err", err)
+			if !is.waitRetry() {
+				return false
+			}
+			txResults, err = is.source.TxResults(h)
+		}
+		batch := NewBatch(int64(len(txResults)))
+		for _, txResult := range txResults {
+			batch.Add(txResult)
+		}
+		if !is.index(batch, h) {
+			return false
+		}
+	}
+	return true
+}
+
+// index indexes a batch of txs and records its height. With a source, it
+// retries until it succeeds, and returns false if the service stopped
+// before; otherwise a batch which fails to be indexed is dropped.
+func (is *IndexerService) index(batch *Batch, height int64) bool {
+	if batch.Size() > 0 {
+		err := is.idr.AddBatch(batch)
+		for err != nil {
+			if is.source == nil {
+				is.Logger.Error("Failed to index block", "height", height, "err", err)
+				return true
+			}
+			is.Logger.Error("Failed to index block, retrying", "height", height, "err", err)
+			if !is.waitRetry() {
+				return false
+			}
+			err = is.idr.AddBatch(batch)
+		}
 	}
+	is.lastIndexedHeight = height
+	if is.db != nil {
+		saveLastIndexedHeight(is.db, height)
+	}
+	is.Logger.Info("Indexed block", "height", height)
+	return true
+}
+
+// waitRetry waits for the retry interval, and returns false if the service
+// stopped meanwhile.
+func (is *IndexerService) waitRetry() bool {
+	select {
+	case <-time.After(is.retryInterval):
+		return true
+	case <-is.Quit():
+		return false
+	}
+}
+
+func loadLastIndexedHeight(db dbm.DB) int64 {
+	bz := db.Get(lastIndexedHeightKey)
+	if len(bz) == 0 {
+		return 0
+	}
+	height, err := strconv.ParseInt(string(bz), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return height
+}
+
+func saveLastIndexedHeight(db dbm.DB, height int64) {
+	// Synced, so that the recorded height is never ahead of the index after
+	// a crash.
+	db.SetSync(lastIndexedHeightKey, []byte(strconv.FormatInt(height, 10)))
 }
@@ -0,0 +1,166 @@
+package txindex_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	db "github.com/tendermint/tendermint/libs/db"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/txindex/kv"
+	"github.com/tendermint/tendermint/types"
+)
+
+// testSource has one tx per block.
+type testSource struct {
+	lastHeight int64 // atomic
+
+	// the heights at which TxResults fails once
+	mtx      sync.Mutex
+	failures map[int64]bool
+}
+
+func (s *testSource) LastHeight() int64 { return atomic.LoadInt64(&s.lastHeight) }
+
+func (s *testSource) commit(height int64) { atomic.StoreInt64(&s.lastHeight, height) }
+
+func (s *testSource) TxResults(height int64) ([]*types.TxResult, error) {
+	if height > s.LastHeight() {
+		return nil, fmt.Errorf("no block at height %d", height)
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.failures[height] {
+		delete(s.failures, height)
+		return nil, fmt.Errorf("failed to load block at height %d", height)
+	}
+	return []*types.TxResult{txResultAt(height)}, nil
+}
+
+func txResultAt(height int64) *types.TxResult {
+	return &types.TxResult{
+		Height: height,
+		Tx:     types.Tx(fmt.Sprintf("tx at height %d", height)),
+		Result: abci.ResponseDeliverTx{Code: abci.CodeTypeOK},
+	}
+}
+
+func TestIndexerServiceCatchUp(t *testing.T) {
+	eventBus := types.NewEventBus()
+	eventBus.SetLogger(log.TestingLogger())
+	require.NoError(t, eventBus.Start())
+	defer eventBus.Stop()
+
+	indexer := kv.NewTxIndex(db.NewMemDB())
+	stateDB := db.NewMemDB()
+	source := &testSource{lastHeight: 3}
+
+	// the blocks up to height 3 were committed before the service started
+	service := txindex.NewIndexerService(indexer, eventBus, txindex.CatchUp(stateDB, source))
+	service.SetLogger(log.TestingLogger())
+	require.NoError(t, service.Start())
+
+	// block 4 is missed, block 5 comes from the event bus
+	source.commit(5)
+	publishBlock(t, eventBus, txResultAt(5))
+
+	for h := int64(1); h <= 5; h++ {
+		waitForTx(t, indexer, txResultAt(h))
+	}
+	service.Stop()
+
+	// the service starts again from height 6
+	source.commit(6)
+	service = txindex.NewIndexerService(indexer, eventBus, txindex.CatchUp(stateDB, source))
+	service.SetLogger(log.TestingLogger())
+	require.NoError(t, service.Start())
+	defer service.Stop()
+
+	waitForTx(t, indexer, txResultAt(6))
+}
+
+// failingIndexer fails to index the batches of the given heights once.
+type failingIndexer struct {
+	txindex.TxIndexer
+
+	mtx      sync.Mutex
+	failures map[int64]bool
+}
+
+func (idx *failingIndexer) AddBatch(b *txindex.Batch) error {
+	idx.mtx.Lock()
+	defer idx.mtx.Unlock()
+	if height := b.Ops[0].Height; idx.failures[height] {
+		delete(idx.failures, height)
+		return fmt.Errorf("failed to index block at height %d", height)
+	}
+	return idx.TxIndexer.AddBatch(b)
+}
+
+func TestIndexerServiceRetries(t *testing.T) {
+	eventBus := types.NewEventBus()
+	eventBus.SetLogger(log.TestingLogger())
+	require.NoError(t, eventBus.Start())
+	defer eventBus.Stop()
+
+	indexer := &failingIndexer{
+		TxIndexer: kv.NewTxIndex(db.NewMemDB()),
+		failures:  map[int64]bool{4: true},
+	}
+	stateDB := db.NewMemDB()
+	// block 2 fails to load, and block 4 to be indexed
+	source := &testSource{lastHeight: 3, failures: map[int64]bool{2: true}}
+
+	service := txindex.NewIndexerService(indexer, eventBus,
+		txindex.CatchUp(stateDB, source), txindex.RetryInterval(10*time.Millisecond))
+	service.SetLogger(log.TestingLogger())
+	require.NoError(t, service.Start())
+
+	source.commit(5)
+	publishBlock(t, eventBus, txResultAt(4))
+	publishBlock(t, eventBus, txResultAt(5))
+
+	// no block is skipped
+	for h := int64(1); h <= 5; h++ {
+		waitForTx(t, indexer, txResultAt(h))
+	}
+	service.Stop()
+
+	// the service starts again from height 6
+	source.commit(6)
+	service = txindex.NewIndexerService(indexer, eventBus, txindex.CatchUp(stateDB, source))
+	service.SetLogger(log.TestingLogger())
+	require.NoError(t, service.Start())
+	defer service.Stop()
+
+	waitForTx(t, indexer, txResultAt(6))
+}
+
+func publishBlock(t *testing.T, eventBus *types.EventBus, txResult *types.TxResult) {
+	err := eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{
+		Header: types.Header{Height: txResult.Height, NumTxs: 1},
+	})
+	require.NoError(t, err)
+	err = eventBus.PublishEventTx(types.EventDataTx{TxResult: *txResult})
+	require.NoError(t, err)
+}
+
+func waitForTx(t *testing.T, indexer txindex.TxIndexer, txResult *types.TxResult) {
+	for i := 0; i < 100; i++ {
+		res, err := indexer.Get(txResult.Tx.Hash())
+		require.NoError(t, err)
+		if res != nil {
+			assert.Equal(t, txResult, res)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("tx at height %d was not indexed", txResult.Height)
+}
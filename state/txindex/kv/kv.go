@@ -14,12 +14,15 @@ import (
 	dbm "github.com/tendermint/tendermint/libs/db"
 
 	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/tagvalue"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/types"
 )
 
 const (
 	tagKeySeparator = "/"
+	// prefix of the keys of the lists of keys indexing the txs
+	indexKeysPrefix = "tx.keys"
 )
 
 var _ txindex.TxIndexer = (*TxIndex)(nil)
@@ -81,26 +84,9 @@ func (txi *TxIndex) AddBatch(b *txindex.Batch) error {
 	defer storeBatch.Close()
 
 	for _, result := range b.Ops {
-		hash := result.Tx.Hash()
-
-		// index tx by tags
-		for _, tag := range result.Result.Tags {
-			if txi.indexAllTags || cmn.StringInSlice(string(tag.Key), txi.tagsToIndex) {
-				storeBatch.Set(keyForTag(tag, result), hash)
-			}
-		}
-
-		// index tx by height
-		if txi.indexAllTags || cmn.StringInSlice(types.TxHeightKey, txi.tagsToIndex) {
-			storeBatch.Set(keyForHeight(result), hash)
-		}
-
-		// index tx by hash
-		rawBytes, err := cdc.MarshalBinaryBare(result)
-		if err != nil {
+		if err := txi.index(storeBatch, result); err != nil {
 			return err
 		}
-		storeBatch.Set(hash, rawBytes)
 	}
 
 	storeBatch.Write()
@@ -112,19 +98,90 @@ func (txi *TxIndex) Index(result *types.TxResult) error {
 	b := txi.store.NewBatch()
 	defer b.Close()
 
+	if err := txi.index(b, result); err != nil {
+		return err
+	}
+
+	b.Write()
+	return nil
+}
+
+// Reindex indexes a batch of transactions again. Unlike AddBatch, it first
+// deletes all the keys which indexed the txs at the same height and index, so
+// that changing the list of tags to index or the encoding of their values
+// doesn't leave stale entries behind.
+func (txi *TxIndex) Reindex(b *txindex.Batch) error {
+	storeBatch := txi.store.NewBatch()
+	defer storeBatch.Close()
+
+	for _, result := range b.Ops {
+		keys, err := txi.indexKeys(result)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			storeBatch.Delete(key)
+		}
+
+		if err := txi.index(storeBatch, result); err != nil {
+			return err
+		}
+	}
+
+	storeBatch.Write()
+	return nil
+}
+
+// indexKeys returns the tag and height keys which indexed a tx at the height
+// and index of the result. They are read from the list stored with them, or,
+// for the txs indexed before such lists were stored, derived from the tags of
+// the result with both the typed and untyped encodings of their values.
+func (txi *TxIndex) indexKeys(result *types.TxResult) ([][]byte, error) {
+	if rawBytes := txi.store.Get(keyForIndexKeys(result)); rawBytes != nil {
+		var entry txKeys
+		if err := cdc.UnmarshalBinaryBare(rawBytes, &entry); err != nil {
+			return nil, fmt.Errorf("Error reading index keys: %v", err)
+		}
+		return entry.Keys, nil
+	}
+
+	keys := [][]byte{keyForHeight(result)}
+	for _, tag := range result.Result.Tags {
+		keys = append(keys, keyForTag(tag, result))
+		if tag.Type != cmn.KVPair_UNTYPED {
+			untyped := tag
+			untyped.Type = cmn.KVPair_UNTYPED
+			keys = append(keys, keyForTag(untyped, result))
+		}
+	}
+	return keys, nil
+}
+
+func (txi *TxIndex) index(b dbm.SetDeleter, result *types.TxResult) error {
 	hash := result.Tx.Hash()
+	var keys [][]byte
 
 	// index tx by tags
 	for _, tag := range result.Result.Tags {
-		if txi.indexAllTags || cmn.StringInSlice(string(tag.Key), txi.tagsToIndex) {
-			b.Set(keyForTag(tag, result), hash)
+		if txi.isIndexed(string(tag.Key)) && tag.ValidateValue() == nil {
+			keys = append(keys, keyForTag(tag, result))
 		}
 	}
 
 	// index tx by height
-	if txi.indexAllTags || cmn.StringInSlice(types.TxHeightKey, txi.tagsToIndex) {
-		b.Set(keyForHeight(result), hash)
+	if txi.isIndexed(types.TxHeightKey) {
+		keys = append(keys, keyForHeight(result))
+	}
+
+	for _, key := range keys {
+		b.Set(key, hash)
+	}
+	// keep the keys for Reindex, in the order of the txs for Search
+	rawKeys, err := cdc.MarshalBinaryBare(txKeys{Hash: hash, Keys: keys})
+	if err != nil {
+		return err
 	}
+	b.Set(keyForIndexKeys(result), rawKeys)
 
 	// index tx by hash
 	rawBytes, err := cdc.MarshalBinaryBare(result)
@@ -132,23 +189,83 @@ func (txi *TxIndex) Index(result *types.TxResult) error {
 		return err
 	}
 	b.Set(hash, rawBytes)
-
-	b.Write()
 	return nil
 }
 
+func (txi *TxIndex) isIndexed(tag string) bool {
+	return txi.indexAllTags || cmn.StringInSlice(tag, txi.tagsToIndex)
+}
+
 // Search performs a search using the given query. It breaks the query into
 // conditions (like "tx.height > 5"). For each condition, it queries the DB
 // index. One special use cases here: (1) if "tx.hash" is found, it returns tx
 // result for it (2) for range queries it is better for the client to provide
 // both lower and upper bounds, so we are not performing a full scan. Results
 // from querying indexes are then intersected and returned to the caller.
-func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
-	var hashes [][]byte
-	var hashesInitialized bool
+//
+// Queries using OR or NOT are evaluated over the whole expression tree. When
+// the matching txs are bounded by the results of some conditions (e.g. "a OR
+// b", or "a AND NOT b"), only those are checked against the expression.
+// Otherwise (e.g. "NOT a"), the txs are walked in the requested order by the
+// returned iterator, which stops once the requested page is complete.
+//
+// Only the hashes and positions (height & index) of the matching txs, which
+// are part of the index keys, are kept in memory. The txs of the requested
+// page are the only ones loaded, lazily, by the returned iterator.
+func (txi *TxIndex) Search(q *query.Query, opts txindex.SearchOptions) (txindex.ResultIterator, error) {
+	if err := opts.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	var refs []txRef
+	var err error
+
+	if q.IsConjunction() {
+		refs, err = txi.searchConditions(q.Conditions())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		m := &exprMatcher{txi: txi, matched: make(map[*query.Expr]refSet)}
+		if err := m.prepare(q.Expr()); err != nil {
+			return nil, err
+		}
+		set, bounded := m.candidates(q.Expr())
+		if !bounded {
+			match := func(ref txRef) bool { return m.matches(q.Expr(), ref) }
+			return newWalkIterator(txi, match, opts), nil
+		}
+		for _, ref := range set {
+			if m.matches(q.Expr(), ref) {
+				refs = append(refs, ref)
+			}
+		}
+	}
 
-	// get a list of conditions (like "tx.height > 5")
-	conditions := q.Conditions()
+	// sort by height & index
+	desc := opts.OrderBy == txindex.OrderDesc
+	sort.Slice(refs, func(i, j int) bool {
+		if desc {
+			i, j = j, i
+		}
+		if refs[i].height == refs[j].height {
+			return refs[i].index < refs[j].index
+		}
+		return refs[i].height < refs[j].height
+	})
+
+	totalCount := len(refs)
+	page := refs[cmn.MinInt(opts.Offset, totalCount):]
+	if opts.Limit > 0 && opts.Limit < len(page) {
+		page = page[:opts.Limit]
+	}
+	return &resultIterator{txi: txi, refs: page, totalCount: totalCount}, nil
+}
+
+// searchConditions returns the txs matching all of the given conditions.
+func (txi *TxIndex) searchConditions(conditions []query.Condition) ([]txRef, error) {
+	var hashes []txRef
+	var hashesInitialized bool
 
 	// if there is a hash condition, return the result immediately
 	hash, err, ok := lookForHash(conditions)
@@ -156,10 +273,13 @@ func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
 		return nil, errors.Wrap(err, "error during searching for a hash in the query")
 	} else if ok {
 		res, err := txi.Get(hash)
+		if err != nil {
+			return nil, errors.Wrap(err, "error while retrieving the result")
+		}
 		if res == nil {
-			return []*types.TxResult{}, nil
+			return []txRef{}, nil
 		}
-		return []*types.TxResult{res}, errors.Wrap(err, "error while retrieving the result")
+		return []txRef{refForResult(hash, res)}, nil
 	}
 
 	// conditions to skip because they're handled before "everything else"
@@ -191,38 +311,166 @@ func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
 			continue
 		}
 
+		matched, err := txi.matchCondition(c, height)
+		if err != nil {
+			return nil, err
+		}
 		if !hashesInitialized {
-			hashes = txi.match(c, startKeyForCondition(c, height))
+			hashes = matched
 			hashesInitialized = true
 		} else {
-			hashes = intersect(hashes, txi.match(c, startKeyForCondition(c, height)))
+			hashes = intersect(hashes, matched)
 		}
 	}
 
-	results := make([]*types.TxResult, len(hashes))
-	i := 0
-	for _, h := range hashes {
-		results[i], err = txi.Get(h)
+	return hashes, nil
+}
+
+// exprMatcher evaluates an expression for the indexed txs, from the txs
+// matching each of its conditions, which are looked up once.
+type exprMatcher struct {
+	txi     *TxIndex
+	matched map[*query.Expr]refSet
+}
+
+// prepare looks up the txs matching each of the conditions of the expression.
+func (m *exprMatcher) prepare(e *query.Expr) error {
+	switch e.Type {
+	case query.ExprCondition:
+		refs, err := m.txi.matchCondition(e.Condition, 0)
 		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get Tx{%X}", h)
+			return err
 		}
-		i++
+		m.matched[e] = newRefSet(refs)
+		return nil
+	case query.ExprOr, query.ExprAnd, query.ExprNot:
+		for _, arg := range e.Args {
+			if err := m.prepare(arg); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown expression type %v", e.Type)
 	}
+}
 
-	// sort by height & index by default
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Height == results[j].Height {
-			return results[i].Index < results[j].Index
+// matches returns true if the tx matches the expression.
+func (m *exprMatcher) matches(e *query.Expr, ref txRef) bool {
+	switch e.Type {
+	case query.ExprCondition:
+		_, ok := m.matched[e][string(ref.hash)]
+		return ok
+	case query.ExprOr:
+		for _, arg := range e.Args {
+			if m.matches(arg, ref) {
+				return true
+			}
 		}
-		return results[i].Height < results[j].Height
-	})
+		return false
+	case query.ExprAnd:
+		for _, arg := range e.Args {
+			if !m.matches(arg, ref) {
+				return false
+			}
+		}
+		return true
+	case query.ExprNot:
+		return !m.matches(e.Args[0], ref)
+	default:
+		return false
+	}
+}
+
+// candidates returns a set of txs including all the txs matching the
+// expression, or false if any tx may match it (e.g. "NOT a").
+func (m *exprMatcher) candidates(e *query.Expr) (refSet, bool) {
+	switch e.Type {
+	case query.ExprCondition:
+		return m.matched[e], true
+	case query.ExprOr:
+		set := make(refSet)
+		for _, arg := range e.Args {
+			matched, ok := m.candidates(arg)
+			if !ok {
+				return nil, false
+			}
+			set.union(matched)
+		}
+		return set, true
+	case query.ExprAnd:
+		// any bounded argument bounds the conjunction
+		var set refSet
+		for _, arg := range e.Args {
+			matched, ok := m.candidates(arg)
+			if !ok {
+				continue
+			}
+			if set == nil {
+				set = make(refSet, len(matched))
+				set.union(matched)
+			} else {
+				set.intersect(matched)
+			}
+		}
+		return set, set != nil
+	default:
+		return nil, false
+	}
+}
 
-	return results, nil
+// matchCondition returns the txs matching a single condition. If height is
+// not zero, only txs at that height may be returned.
+func (txi *TxIndex) matchCondition(c query.Condition, height int64) ([]txRef, error) {
+	if c.Tag == types.TxHashKey {
+		return txi.matchHash(c)
+	}
+	if isRangeOperation(c.Op) {
+		ranges, _ := lookForRanges([]query.Condition{c})
+		return txi.matchRange(ranges[c.Tag], startKey(c.Tag)), nil
+	}
+	return txi.match(c, startKeyForCondition(c, height)), nil
+}
+
+// matchHash returns the indexed txs matching a "tx.hash" condition.
+func (txi *TxIndex) matchHash(c query.Condition) ([]txRef, error) {
+	var operands []interface{}
+	switch c.Op {
+	case query.OpEqual:
+		operands = []interface{}{c.Operand}
+	case query.OpIn:
+		operands = c.Operand.([]interface{})
+	default:
+		return nil, fmt.Errorf("%s only supports = and IN", types.TxHashKey)
+	}
+
+	refs := make([]txRef, 0, len(operands))
+	for _, operand := range operands {
+		s, ok := operand.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be compared with a string", types.TxHashKey)
+		}
+		hash, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "error during searching for a hash in the query")
+		}
+		if len(hash) == 0 {
+			continue
+		}
+		res, err := txi.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+		if res != nil {
+			refs = append(refs, refForResult(hash, res))
+		}
+	}
+	return refs, nil
 }
 
 func lookForHash(conditions []query.Condition) (hash []byte, err error, ok bool) {
 	for _, c := range conditions {
-		if c.Tag == types.TxHashKey {
+		if c.Tag == types.TxHashKey && c.Op == query.OpEqual {
 			decoded, err := hex.DecodeString(c.Operand.(string))
 			return decoded, err, true
 		}
@@ -298,6 +546,26 @@ func (r queryRange) upperBoundValue() interface{} {
 	}
 }
 
+// conditions returns the conditions the range was made of.
+func (r queryRange) conditions() []query.Condition {
+	var conditions []query.Condition
+	if r.lowerBound != nil {
+		op := query.OpGreater
+		if r.includeLowerBound {
+			op = query.OpGreaterEqual
+		}
+		conditions = append(conditions, query.Condition{Tag: r.key, Op: op, Operand: r.lowerBound})
+	}
+	if r.upperBound != nil {
+		op := query.OpLess
+		if r.includeUpperBound {
+			op = query.OpLessEqual
+		}
+		conditions = append(conditions, query.Condition{Tag: r.key, Op: op, Operand: r.upperBound})
+	}
+	return conditions
+}
+
 func lookForRanges(conditions []query.Condition) (ranges queryRanges, indexes []int) {
 	ranges = make(queryRanges)
 	for i, c := range conditions {
@@ -334,13 +602,37 @@ func isRangeOperation(op query.Operator) bool {
 	}
 }
 
-func (txi *TxIndex) match(c query.Condition, startKeyBz []byte) (hashes [][]byte) {
+func (txi *TxIndex) match(c query.Condition, startKeyBz []byte) (hashes []txRef) {
 	if c.Op == query.OpEqual {
 		it := dbm.IteratePrefix(txi.store, startKeyBz)
 		defer it.Close()
 		for ; it.Valid(); it.Next() {
-			hashes = append(hashes, it.Value())
+			if ref, ok := refForTagKey(it.Key(), it.Value()); ok {
+				hashes = append(hashes, ref)
+			}
+		}
+		hashes = append(hashes, txi.matchTyped(c.Tag, []query.Condition{c})...)
+	} else if c.Op == query.OpIn {
+		// union of the txs matching each of the operands
+		set := make(refSet)
+		for _, operand := range c.Operand.([]interface{}) {
+			eq := query.Condition{Tag: c.Tag, Op: query.OpEqual, Operand: operand}
+			set.union(newRefSet(txi.match(eq, startKey(c.Tag, operand))))
+		}
+		hashes = set.refs()
+	} else if c.Op == query.OpExists {
+		set := make(refSet)
+		it := dbm.IteratePrefix(txi.store, startKey(c.Tag))
+		defer it.Close()
+		for ; it.Valid(); it.Next() {
+			if !isTagKey(it.Key()) {
+				continue
+			}
+			if ref, ok := refForTagKey(it.Key(), it.Value()); ok {
+				set.add(ref)
+			}
 		}
+		hashes = set.refs()
 	} else if c.Op == query.OpContains {
 		// XXX: startKey does not apply here.
 		// For example, if startKey = "account.owner/an/" and search query = "accoutn.owner CONTAINS an"
@@ -351,8 +643,12 @@ func (txi *TxIndex) match(c query.Condition, startKeyBz []byte) (hashes [][]byte
 			if !isTagKey(it.Key()) {
 				continue
 			}
-			if strings.Contains(extractValueFromKey(it.Key()), c.Operand.(string)) {
-				hashes = append(hashes, it.Value())
+			ref, ok := refForTagKey(it.Key(), it.Value())
+			if !ok {
+				continue
+			}
+			if tagvalue.Matches(c.Tag, valueFromKey(it.Key(), c.Tag), []query.Condition{c}) {
+				hashes = append(hashes, ref)
 			}
 		}
 	} else {
@@ -361,9 +657,9 @@ func (txi *TxIndex) match(c query.Condition, startKeyBz []byte) (hashes [][]byte
 	return
 }
 
-func (txi *TxIndex) matchRange(r queryRange, startKey []byte) (hashes [][]byte) {
+func (txi *TxIndex) matchRange(r queryRange, startKey []byte) (hashes []txRef) {
 	// create a map to prevent duplicates
-	hashesMap := make(map[string][]byte)
+	hashesMap := make(refSet)
 
 	lowerBound := r.lowerBoundValue()
 	upperBound := r.upperBoundValue()
@@ -375,6 +671,10 @@ LOOP:
 		if !isTagKey(it.Key()) {
 			continue
 		}
+		ref, ok := refForTagKey(it.Key(), it.Value())
+		if !ok {
+			continue
+		}
 		switch r.AnyBound().(type) {
 		case int64:
 			v, err := strconv.ParseInt(extractValueFromKey(it.Key()), 10, 64)
@@ -389,7 +689,7 @@ LOOP:
 				include = false
 			}
 			if include {
-				hashesMap[fmt.Sprintf("%X", it.Value())] = it.Value()
+				hashesMap.add(ref)
 			}
 			// XXX: passing time in a ABCI Tags is not yet implemented
 			// case time.Time:
@@ -399,13 +699,53 @@ LOOP:
 			// 	}
 		}
 	}
-	hashes = make([][]byte, len(hashesMap))
-	i := 0
-	for _, h := range hashesMap {
-		hashes[i] = h
-		i++
+	for _, ref := range txi.matchTyped(r.key, r.conditions()) {
+		hashesMap.add(ref)
 	}
-	return
+	return hashesMap.refs()
+}
+
+// matchTyped returns the txs with a typed value of the tag matching all of the
+// conditions, which are either equalities with a string, or comparisons with
+// numbers or times. Only the keys between the bounds of the latter are read.
+func (txi *TxIndex) matchTyped(tag string, conditions []query.Condition) (hashes []txRef) {
+	var prefixes [][]byte
+	var it dbm.Iterator
+	if s, ok := conditions[0].Operand.(string); ok {
+		prefixes = [][]byte{
+			startKey(tag, tagvalue.StringPrefix+s),
+			startKey(tag, tagvalue.BoolPrefix+s),
+		}
+	} else if start, end, ok := tagvalue.Range(tag, conditions); ok {
+		it = txi.store.Iterator(start, end)
+	} else {
+		return nil
+	}
+
+	for _, prefix := range prefixes {
+		hashes = append(hashes, txi.matchTypedKeys(dbm.IteratePrefix(txi.store, prefix), tag, conditions)...)
+	}
+	if it != nil {
+		hashes = append(hashes, txi.matchTypedKeys(it, tag, conditions)...)
+	}
+	return hashes
+}
+
+func (txi *TxIndex) matchTypedKeys(it dbm.Iterator, tag string, conditions []query.Condition) (hashes []txRef) {
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		if !isTagKey(it.Key()) {
+			continue
+		}
+		ref, ok := refForTagKey(it.Key(), it.Value())
+		if !ok {
+			continue
+		}
+		if tagvalue.Matches(tag, valueFromKey(it.Key(), tag), conditions) {
+			hashes = append(hashes, ref)
+		}
+	}
+	return hashes
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -415,20 +755,84 @@ func isTagKey(key []byte) bool {
 	return strings.Count(string(key), tagKeySeparator) == 3
 }
 
+// refForTagKey returns the tx a tag key points to. The height and index are
+// the last two parts of the key.
+func refForTagKey(key, hash []byte) (txRef, bool) {
+	parts := strings.Split(string(key), tagKeySeparator)
+	if len(parts) < 4 {
+		return txRef{}, false
+	}
+	height, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+	if err != nil {
+		return txRef{}, false
+	}
+	index, err := strconv.ParseUint(parts[len(parts)-1], 10, 32)
+	if err != nil {
+		return txRef{}, false
+	}
+	return txRef{hash: hash, height: height, index: uint32(index)}, true
+}
+
 func extractValueFromKey(key []byte) string {
 	parts := strings.SplitN(string(key), tagKeySeparator, 3)
 	return parts[1]
 }
 
+// valueFromKey returns the (encoded) value of the tag in a tag key.
+func valueFromKey(key []byte, tag string) string {
+	value := strings.TrimPrefix(string(key), tag+tagKeySeparator)
+	for i := 0; i < 2; i++ {
+		if j := strings.LastIndex(value, tagKeySeparator); j >= 0 {
+			value = value[:j]
+		}
+	}
+	return value
+}
+
 func keyForTag(tag cmn.KVPair, result *types.TxResult) []byte {
 	return []byte(fmt.Sprintf("%s/%s/%d/%d",
 		tag.Key,
-		tag.Value,
+		tagvalue.Encode(tag),
 		result.Height,
 		result.Index,
 	))
 }
 
+// keyForIndexKeys returns the key of the list of the keys indexing the tx at
+// the height and index of the result. Unlike the tag keys, it has three parts,
+// and the height and index are fixed-width hex numbers, so that the keys sort
+// in the order of the txs.
+func keyForIndexKeys(result *types.TxResult) []byte {
+	return []byte(fmt.Sprintf("%s/%016x/%08x",
+		indexKeysPrefix,
+		result.Height,
+		result.Index,
+	))
+}
+
+// refForIndexKeys returns the tx a list of index keys belongs to, or false if
+// the key isn't the key of such a list (e.g. it is a tag key of a "tx.keys"
+// tag).
+func refForIndexKeys(key, value []byte) (txRef, bool, error) {
+	parts := strings.Split(string(key), tagKeySeparator)
+	if len(parts) != 3 || len(parts[1]) != 16 || len(parts[2]) != 8 {
+		return txRef{}, false, nil
+	}
+	height, err := strconv.ParseInt(parts[1], 16, 64)
+	if err != nil {
+		return txRef{}, false, nil
+	}
+	index, err := strconv.ParseUint(parts[2], 16, 32)
+	if err != nil {
+		return txRef{}, false, nil
+	}
+	var entry txKeys
+	if err := cdc.UnmarshalBinaryBare(value, &entry); err != nil {
+		return txRef{}, false, fmt.Errorf("Error reading index keys: %v", err)
+	}
+	return txRef{hash: entry.Hash, height: height, index: uint32(index)}, true, nil
+}
+
 func keyForHeight(result *types.TxResult) []byte {
 	return []byte(fmt.Sprintf("%s/%d/%d/%d",
 		types.TxHeightKey,
@@ -456,14 +860,227 @@ func startKey(fields ...interface{}) []byte {
 ///////////////////////////////////////////////////////////////////////////////
 // Utils
 
-func intersect(as, bs [][]byte) [][]byte {
-	i := make([][]byte, 0, cmn.MinInt(len(as), len(bs)))
+// txKeys is stored for each indexed tx, under a key sorting in the order of
+// the txs: its hash and the keys indexing it.
+type txKeys struct {
+	Hash []byte
+	Keys [][]byte
+}
+
+// txRef identifies an indexed tx by its hash and position, which is all
+// Search needs to know about a tx until it has to load it.
+type txRef struct {
+	hash   []byte
+	height int64
+	index  uint32
+}
+
+func refForResult(hash []byte, result *types.TxResult) txRef {
+	return txRef{hash: hash, height: result.Height, index: result.Index}
+}
+
+// refSet is a set of txs, keyed by the string conversion of their hashes.
+type refSet map[string]txRef
+
+func newRefSet(refs []txRef) refSet {
+	set := make(refSet, len(refs))
+	for _, r := range refs {
+		set.add(r)
+	}
+	return set
+}
+
+func (s refSet) add(ref txRef) {
+	s[string(ref.hash)] = ref
+}
+
+func (s refSet) union(other refSet) {
+	for k, r := range other {
+		s[k] = r
+	}
+}
+
+func (s refSet) intersect(other refSet) {
+	for k := range s {
+		if _, ok := other[k]; !ok {
+			delete(s, k)
+		}
+	}
+}
+
+func (s refSet) refs() []txRef {
+	refs := make([]txRef, 0, len(s))
+	for _, r := range s {
+		refs = append(refs, r)
+	}
+	return refs
+}
+
+func intersect(as, bs []txRef) []txRef {
+	i := make([]txRef, 0, cmn.MinInt(len(as), len(bs)))
 	for _, a := range as {
 		for _, b := range bs {
-			if bytes.Equal(a, b) {
+			if bytes.Equal(a.hash, b.hash) {
 				i = append(i, a)
 			}
 		}
 	}
 	return i
 }
+
+// resultIterator loads the txs of a page of search results one at a time.
+type resultIterator struct {
+	txi        *TxIndex
+	refs       []txRef
+	totalCount int
+
+	result *types.TxResult
+	err    error
+}
+
+var _ txindex.ResultIterator = (*resultIterator)(nil)
+
+func (it *resultIterator) Next() bool {
+	if it.err != nil || len(it.refs) == 0 {
+		return false
+	}
+	hash := it.refs[0].hash
+	it.refs = it.refs[1:]
+
+	it.result, it.err = it.txi.load(hash)
+	return it.err == nil
+}
+
+func (it *resultIterator) Result() *types.TxResult { return it.result }
+
+func (it *resultIterator) Err() error { return it.err }
+
+func (it *resultIterator) TotalCount() int { return it.totalCount }
+
+func (it *resultIterator) Close() error {
+	it.refs = nil
+	return nil
+}
+
+// walkIterator walks the indexed txs in order, and loads the txs matching the
+// query which are on the requested page. Only the hashes of the other txs are
+// read.
+type walkIterator struct {
+	txi        *TxIndex
+	match      func(txRef) bool
+	opts       txindex.SearchOptions
+	it         dbm.Iterator
+	skipped    int
+	returned   int
+	totalCount int // -1 until counted
+
+	result *types.TxResult
+	err    error
+}
+
+var _ txindex.ResultIterator = (*walkIterator)(nil)
+
+func newWalkIterator(txi *TxIndex, match func(txRef) bool, opts txindex.SearchOptions) *walkIterator {
+	return &walkIterator{
+		txi:        txi,
+		match:      match,
+		opts:       opts,
+		it:         txi.iterateTxs(opts.OrderBy == txindex.OrderDesc),
+		totalCount: -1,
+	}
+}
+
+func (w *walkIterator) Next() bool {
+	if w.err != nil || (w.opts.Limit > 0 && w.returned == w.opts.Limit) {
+		return false
+	}
+	for {
+		ref, ok, err := w.nextMatch(w.it)
+		if err != nil {
+			w.err = err
+			return false
+		}
+		if !ok {
+			return false
+		}
+		if w.skipped < w.opts.Offset {
+			w.skipped++
+			continue
+		}
+		w.returned++
+
+		w.result, w.err = w.txi.load(ref.hash)
+		return w.err == nil
+	}
+}
+
+// nextMatch advances the iterator past the next tx matching the query and
+// returns it, or false if there are no more.
+func (w *walkIterator) nextMatch(it dbm.Iterator) (txRef, bool, error) {
+	for ; it.Valid(); it.Next() {
+		ref, ok, err := refForIndexKeys(it.Key(), it.Value())
+		if err != nil {
+			return txRef{}, false, err
+		}
+		if ok && w.match(ref) {
+			it.Next()
+			return ref, true, nil
+		}
+	}
+	return txRef{}, false, nil
+}
+
+func (w *walkIterator) Result() *types.TxResult { return w.result }
+
+func (w *walkIterator) Err() error { return w.err }
+
+// TotalCount walks all the txs once, without loading them.
+func (w *walkIterator) TotalCount() int {
+	if w.totalCount >= 0 {
+		return w.totalCount
+	}
+	it := w.txi.iterateTxs(false)
+	defer it.Close()
+
+	w.totalCount = 0
+	for {
+		_, ok, err := w.nextMatch(it)
+		if err != nil {
+			w.err = err
+			break
+		}
+		if !ok {
+			break
+		}
+		w.totalCount++
+	}
+	return w.totalCount
+}
+
+func (w *walkIterator) Close() error {
+	w.it.Close()
+	return nil
+}
+
+// iterateTxs returns an iterator over the lists of the keys indexing the txs,
+// in the order of the txs.
+func (txi *TxIndex) iterateTxs(desc bool) dbm.Iterator {
+	start := []byte(indexKeysPrefix + tagKeySeparator)
+	end := []byte(indexKeysPrefix + "0") // '0' follows the separator
+	if desc {
+		return txi.store.ReverseIterator(start, end)
+	}
+	return txi.store.Iterator(start, end)
+}
+
+// load gets a tx which has been found in the index.
+func (txi *TxIndex) load(hash []byte) (*types.TxResult, error) {
+	result, err := txi.Get(hash)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get Tx{%X}", hash)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("Tx{%X} is indexed but not stored", hash)
+	}
+	return result, nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	db "github.com/tendermint/tendermint/libs/db"
 
@@ -93,11 +94,31 @@ func TestTxSearch(t *testing.T) {
 		{"account.owner CONTAINS 'Vlad'", 0},
 		// search using the wrong tag (of numeric type) using CONTAINS
 		{"account.number CONTAINS 'Iv'", 0},
+		// search using IN
+		{"account.owner IN ('Vlad', 'Ivan')", 1},
+		// search for non existing values using IN
+		{"account.number IN (2, 3)", 0},
+		// search by hash using IN
+		{fmt.Sprintf("tx.hash IN ('%X', 'ABCD')", hash), 1},
+		// search using EXISTS
+		{"account.owner EXISTS", 1},
+		// search using EXISTS on a not existing tag
+		{"account.date EXISTS", 0},
+		// search using OR
+		{"account.owner = 'Vlad' OR account.number = 1", 1},
+		// search using OR (no match)
+		{"account.owner = 'Vlad' OR account.number = 2", 0},
+		// search using NOT
+		{"NOT account.owner = 'Vlad'", 1},
+		// search using NOT (no match)
+		{"account.number = 1 AND NOT account.owner = 'Ivan'", 0},
+		// search using parentheses
+		{"(account.number = 2 OR account.owner = 'Ivan') AND account.number <= 5", 1},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.q, func(t *testing.T) {
-			results, err := indexer.Search(query.MustParse(tc.q))
+			results, err := search(indexer, tc.q)
 			assert.NoError(t, err)
 
 			assert.Len(t, results, tc.resultsLength)
@@ -120,7 +141,7 @@ func TestTxSearchOneTxWithMultipleSameTagsButDifferentValues(t *testing.T) {
 	err := indexer.Index(txResult)
 	require.NoError(t, err)
 
-	results, err := indexer.Search(query.MustParse("account.number >= 1"))
+	results, err := search(indexer, "account.number >= 1")
 	assert.NoError(t, err)
 
 	assert.Len(t, results, 1)
@@ -173,11 +194,201 @@ func TestTxSearchMultipleTxs(t *testing.T) {
 	err = indexer.Index(txResult4)
 	require.NoError(t, err)
 
-	results, err := indexer.Search(query.MustParse("account.number >= 1"))
+	results, err := search(indexer, "account.number >= 1")
 	assert.NoError(t, err)
 
 	require.Len(t, results, 3)
 	assert.Equal(t, []*types.TxResult{txResult3, txResult2, txResult}, results)
+
+	testCases := []struct {
+		opts    txindex.SearchOptions
+		results []*types.TxResult
+	}{
+		{txindex.SearchOptions{OrderBy: txindex.OrderDesc}, []*types.TxResult{txResult, txResult2, txResult3}},
+		{txindex.SearchOptions{Offset: 1}, []*types.TxResult{txResult2, txResult}},
+		{txindex.SearchOptions{Limit: 2}, []*types.TxResult{txResult3, txResult2}},
+		{txindex.SearchOptions{OrderBy: txindex.OrderDesc, Offset: 1, Limit: 1}, []*types.TxResult{txResult2}},
+		{txindex.SearchOptions{Offset: 3}, []*types.TxResult{}},
+	}
+	for _, tc := range testCases {
+		it, err := indexer.Search(query.MustParse("account.number >= 1"), tc.opts)
+		require.NoError(t, err)
+		assert.Equal(t, 3, it.TotalCount(), "%+v", tc.opts)
+		results, err := txindex.Collect(it)
+		require.NoError(t, err)
+		assert.Equal(t, tc.results, results, "%+v", tc.opts)
+	}
+
+	_, err = indexer.Search(query.MustParse("account.number >= 1"), txindex.SearchOptions{OrderBy: "random"})
+	assert.Error(t, err)
+}
+
+func TestTxSearchExpr(t *testing.T) {
+	indexer := NewTxIndex(db.NewMemDB(), IndexAllTags())
+
+	txResults := make([]*types.TxResult, 4)
+	for i, owner := range []string{"Ivan", "Vlad", "Anton", ""} {
+		tags := []cmn.KVPair{{Key: []byte("account.number"), Value: []byte(fmt.Sprintf("%d", i+1))}}
+		if owner != "" {
+			tags = append(tags, cmn.KVPair{Key: []byte("account.owner"), Value: []byte(owner)})
+		}
+		txResult := txResultWithTags(tags)
+		txResult.Tx = types.Tx(fmt.Sprintf("account %d", i+1))
+		txResult.Height = int64(i + 1)
+		txResults[i] = txResult
+		require.NoError(t, indexer.Index(txResult))
+	}
+
+	testCases := []struct {
+		q       string
+		results []int
+	}{
+		{"account.owner = 'Ivan' OR account.owner = 'Anton'", []int{0, 2}},
+		{"account.owner IN ('Ivan', 'Anton')", []int{0, 2}},
+		{"account.number > 2 OR account.owner = 'Ivan'", []int{0, 2, 3}},
+		{"NOT account.owner = 'Vlad'", []int{0, 2, 3}},
+		{"NOT account.owner EXISTS", []int{3}},
+		{"account.owner EXISTS AND NOT account.owner IN ('Ivan', 'Vlad')", []int{2}},
+		{"account.number >= 2 AND NOT (account.owner = 'Vlad' OR account.owner = 'Anton')", []int{3}},
+		{"NOT (account.number < 2 OR account.number > 3)", []int{1, 2}},
+		{"tx.height = 1 OR tx.height = 4", []int{0, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.q, func(t *testing.T) {
+			results, err := search(indexer, tc.q)
+			require.NoError(t, err)
+
+			expected := make([]*types.TxResult, len(tc.results))
+			for i, j := range tc.results {
+				expected[i] = txResults[j]
+			}
+			assert.Equal(t, expected, results)
+		})
+	}
+}
+
+func TestTxSearchExprPage(t *testing.T) {
+	store := &countingDB{DB: db.NewMemDB()}
+	indexer := NewTxIndex(store, IndexAllTags())
+
+	txResults := make([]*types.TxResult, 10)
+	for i := range txResults {
+		tags := []cmn.KVPair{{Key: []byte("account.number"), Value: []byte(fmt.Sprintf("%d", i+1))}}
+		if i%2 == 1 {
+			tags = append(tags, cmn.KVPair{Key: []byte("account.owner"), Value: []byte("Ivan")})
+		}
+		txResult := txResultWithTags(tags)
+		txResult.Tx = types.Tx(fmt.Sprintf("account %d", i+1))
+		txResult.Height = int64(i + 1)
+		txResults[i] = txResult
+		require.NoError(t, indexer.Index(txResult))
+	}
+
+	testCases := []struct {
+		q          string
+		opts       txindex.SearchOptions
+		totalCount int
+		results    []int
+	}{
+		{"NOT account.owner = 'Ivan'", txindex.SearchOptions{Offset: 1, Limit: 2}, 5, []int{2, 4}},
+		{"NOT account.owner = 'Ivan'", txindex.SearchOptions{OrderBy: txindex.OrderDesc, Offset: 1, Limit: 2}, 5, []int{6, 4}},
+		{"NOT account.owner = 'Ivan'", txindex.SearchOptions{Offset: 4, Limit: 2}, 5, []int{8}},
+		{"account.number > 2 AND NOT account.owner = 'Ivan'", txindex.SearchOptions{OrderBy: txindex.OrderDesc, Offset: 1, Limit: 2}, 4, []int{6, 4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %+v", tc.q, tc.opts), func(t *testing.T) {
+			store.txGets = 0
+
+			it, err := indexer.Search(query.MustParse(tc.q), tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.totalCount, it.TotalCount())
+			results, err := txindex.Collect(it)
+			require.NoError(t, err)
+
+			expected := make([]*types.TxResult, len(tc.results))
+			for i, j := range tc.results {
+				expected[i] = txResults[j]
+			}
+			assert.Equal(t, expected, results)
+			// only the txs on the page are loaded
+			assert.Equal(t, len(tc.results), store.txGets)
+		})
+	}
+}
+
+func TestTxSearchTypedTags(t *testing.T) {
+	indexer := NewTxIndex(db.NewMemDB(), IndexAllTags())
+
+	tagSets := [][]cmn.KVPair{
+		{
+			{Key: []byte("amount"), Value: []byte("-20"), Type: cmn.KVPair_INT},
+			{Key: []byte("memo"), Value: []byte("7"), Type: cmn.KVPair_STRING},
+			{Key: []byte("time"), Value: []byte("2013-05-03T14:45:00Z"), Type: cmn.KVPair_TIME},
+		},
+		{
+			{Key: []byte("amount"), Value: []byte("-2.5"), Type: cmn.KVPair_DECIMAL},
+			{Key: []byte("memo"), Value: []byte("8"), Type: cmn.KVPair_STRING},
+			{Key: []byte("time"), Value: []byte("2013-05-03T16:45:00.5+02:00"), Type: cmn.KVPair_TIME},
+			{Key: []byte("paid"), Value: []byte("true"), Type: cmn.KVPair_BOOL},
+		},
+		{
+			{Key: []byte("amount"), Value: []byte("7"), Type: cmn.KVPair_INT},
+			{Key: []byte("memo"), Value: []byte("7"), Type: cmn.KVPair_UNTYPED},
+			{Key: []byte("time"), Value: []byte("1969-12-31T23:59:59Z"), Type: cmn.KVPair_TIME},
+		},
+		{
+			{Key: []byte("amount"), Value: []byte("9007199254740993"), Type: cmn.KVPair_INT},
+			{Key: []byte("memo"), Value: []byte("2013"), Type: cmn.KVPair_TIME}, // invalid, not indexed
+		},
+	}
+	txResults := make([]*types.TxResult, len(tagSets))
+	for i, tags := range tagSets {
+		txResult := txResultWithTags(tags)
+		txResult.Tx = types.Tx(fmt.Sprintf("tx %d", i))
+		txResult.Height = int64(i + 1)
+		txResults[i] = txResult
+		require.NoError(t, indexer.Index(txResult))
+	}
+
+	testCases := []struct {
+		q       string
+		results []int
+	}{
+		{"amount < 0", []int{0, 1}},
+		{"amount > -3 AND amount < 8", []int{1, 2}},
+		{"amount >= -2.5", []int{1, 2, 3}},
+		{"amount = -20", []int{0}},
+		{"amount = 7.0", []int{2}},
+		{"amount = 9007199254740993", []int{3}},
+		{"amount > 9007199254740992", []int{3}},
+		{"amount IN (7, -2.5)", []int{1, 2}},
+		{"amount = '7'", []int{}},
+		{"memo = '7'", []int{0, 2}},
+		{"memo = 7", []int{2}},
+		{"memo > 6", []int{2}},
+		{"memo CONTAINS '8'", []int{1}},
+		{"memo EXISTS", []int{0, 1, 2}},
+		{"time = TIME 2013-05-03T14:45:00Z", []int{0}},
+		{"time > TIME 2013-05-03T14:45:00Z", []int{1}},
+		{"time < DATE 2013-05-03", []int{2}},
+		{"paid = 'true'", []int{1}},
+		{"NOT paid = 'true' AND amount > -100", []int{0, 2, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.q, func(t *testing.T) {
+			results, err := search(indexer, tc.q)
+			require.NoError(t, err)
+
+			expected := make([]*types.TxResult, len(tc.results))
+			for i, j := range tc.results {
+				expected[i] = txResults[j]
+			}
+			assert.Equal(t, expected, results)
+		})
+	}
 }
 
 func TestIndexAllTags(t *testing.T) {
@@ -191,17 +402,117 @@ func TestIndexAllTags(t *testing.T) {
 	err := indexer.Index(txResult)
 	require.NoError(t, err)
 
-	results, err := indexer.Search(query.MustParse("account.number >= 1"))
+	results, err := search(indexer, "account.number >= 1")
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, []*types.TxResult{txResult}, results)
 
-	results, err = indexer.Search(query.MustParse("account.owner = 'Ivan'"))
+	results, err = search(indexer, "account.owner = 'Ivan'")
 	assert.NoError(t, err)
 	assert.Len(t, results, 1)
 	assert.Equal(t, []*types.TxResult{txResult}, results)
 }
 
+func TestReindex(t *testing.T) {
+	store := db.NewMemDB()
+	txResult := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number"), Value: []byte("1")},
+		{Key: []byte("account.owner"), Value: []byte("Ivan")},
+	})
+
+	indexer := NewTxIndex(store, IndexTags([]string{"account.number"}))
+	require.NoError(t, indexer.Index(txResult))
+
+	// index account.owner instead of account.number
+	indexer = NewTxIndex(store, IndexTags([]string{"account.owner"}))
+	batch := txindex.NewBatch(1)
+	require.NoError(t, batch.Add(txResult))
+	require.NoError(t, indexer.Reindex(batch))
+
+	results, err := search(indexer, "account.owner = 'Ivan'")
+	require.NoError(t, err)
+	assert.Equal(t, []*types.TxResult{txResult}, results)
+
+	results, err = search(indexer, "account.number = 1")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestReindexAfterEncodingChange(t *testing.T) {
+	store := db.NewMemDB()
+	txResult := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number"), Value: []byte("1"), Type: cmn.KVPair_INT},
+		{Key: []byte("account.owner"), Value: []byte("Ivan"), Type: cmn.KVPair_STRING},
+	})
+	hash := txResult.Tx.Hash()
+
+	// indexed before the values were typed, and the keys of the txs stored
+	indexer := NewTxIndex(store, IndexAllTags())
+	require.NoError(t, indexer.Index(txResult))
+	store.Delete(keyForIndexKeys(txResult))
+	for _, tag := range txResult.Result.Tags {
+		store.Delete(keyForTag(tag, txResult))
+		tag.Type = cmn.KVPair_UNTYPED
+		store.Set(keyForTag(tag, txResult), hash)
+	}
+
+	indexer = NewTxIndex(store, IndexTags([]string{"account.owner"}))
+	batch := txindex.NewBatch(1)
+	require.NoError(t, batch.Add(txResult))
+	require.NoError(t, indexer.Reindex(batch))
+	assert.Equal(t, []string{string(keyForTag(txResult.Result.Tags[1], txResult))}, tagKeys(store))
+
+	results, err := search(indexer, "account.owner = 'Ivan'")
+	require.NoError(t, err)
+	assert.Equal(t, []*types.TxResult{txResult}, results)
+
+	// the tag set changes again
+	indexer = NewTxIndex(store, IndexTags([]string{"account.number", types.TxHeightKey}))
+	require.NoError(t, indexer.Reindex(batch))
+	assert.Equal(t, []string{string(keyForTag(txResult.Result.Tags[0], txResult)), "tx.height/1/1/0"}, tagKeys(store))
+
+	results, err = search(indexer, "account.owner = 'Ivan'")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	results, err = search(indexer, "account.number = 1")
+	require.NoError(t, err)
+	assert.Equal(t, []*types.TxResult{txResult}, results)
+}
+
+// tagKeys returns the tag and height keys of the store.
+func tagKeys(store db.DB) []string {
+	var keys []string
+	it := store.Iterator(nil, nil)
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		if isTagKey(it.Key()) {
+			keys = append(keys, string(it.Key()))
+		}
+	}
+	return keys
+}
+
+// countingDB counts the txs read from the store.
+type countingDB struct {
+	db.DB
+	txGets int
+}
+
+func (cdb *countingDB) Get(key []byte) []byte {
+	if len(key) == tmhash.Size {
+		cdb.txGets++
+	}
+	return cdb.DB.Get(key)
+}
+
+func search(indexer txindex.TxIndexer, q string) ([]*types.TxResult, error) {
+	it, err := indexer.Search(query.MustParse(q), txindex.SearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return txindex.Collect(it)
+}
+
 func txResultWithTags(tags []cmn.KVPair) *types.TxResult {
 	tx := types.Tx("HELLO WORLD")
 	return &types.TxResult{
@@ -28,6 +28,15 @@ func (txi *TxIndex) Index(result *types.TxResult) error {
 	return nil
 }
 
-func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
-	return []*types.TxResult{}, nil
+// Search always returns no results.
+func (txi *TxIndex) Search(q *query.Query, opts txindex.SearchOptions) (txindex.ResultIterator, error) {
+	return emptyIterator{}, nil
 }
+
+type emptyIterator struct{}
+
+func (emptyIterator) Next() bool              { return false }
+func (emptyIterator) Result() *types.TxResult { return nil }
+func (emptyIterator) Err() error              { return nil }
+func (emptyIterator) TotalCount() int         { return 0 }
+func (emptyIterator) Close() error            { return nil }
@@ -0,0 +1,85 @@
+package sql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// DriverSQLite is the database/sql driver name of SQLite.
+	DriverSQLite = "sqlite3"
+	// DriverPostgres is the database/sql driver name of Postgres.
+	DriverPostgres = "postgres"
+)
+
+// dialect captures the differences between the supported databases.
+type dialect struct {
+	blobType    string
+	placeholder func(n int) string
+	// noLimit is the LIMIT which doesn't limit anything, required by SQLite
+	// to use OFFSET.
+	noLimit string
+}
+
+var dialects = map[string]dialect{
+	DriverSQLite: {
+		blobType:    "BLOB",
+		placeholder: func(int) string { return "?" },
+		noLimit:     "-1",
+	},
+	DriverPostgres: {
+		blobType:    "BYTEA",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+		noLimit:     "ALL",
+	},
+}
+
+func dialectFor(driver string) (dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return dialect{}, fmt.Errorf("unsupported SQL driver %q (want %q or %q)", driver, DriverSQLite, DriverPostgres)
+	}
+	return d, nil
+}
+
+// schema returns the statements creating the tables and indexes used by
+// TxIndex. They are safe to run against an already initialized database.
+//
+// tx_results has a row for each tx with its DeliverTx result, and tx_tags a
+// row for each indexed tag of a tx, with the type of its value (KVPair.Type).
+// num_value is only set when the tag value is a number, and time_value (in
+// nanoseconds since the epoch) when it is a time, so that they can be
+// compared numerically and chronologically.
+func (d dialect) schema() []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS tx_results (
+			height     BIGINT NOT NULL,
+			tx_index   INTEGER NOT NULL,
+			tx_hash    VARCHAR(64) NOT NULL,
+			tx         %[1]s NOT NULL,
+			code       INTEGER NOT NULL,
+			data       %[1]s,
+			log        TEXT NOT NULL,
+			info       TEXT NOT NULL,
+			gas_wanted BIGINT NOT NULL,
+			gas_used   BIGINT NOT NULL,
+			result     %[1]s NOT NULL,
+			PRIMARY KEY (height, tx_index)
+		)`, d.blobType),
+		`CREATE INDEX IF NOT EXISTS tx_results_tx_hash ON tx_results (tx_hash)`,
+		`CREATE TABLE IF NOT EXISTS tx_tags (
+			height    BIGINT NOT NULL,
+			tx_index  INTEGER NOT NULL,
+			tag_key    TEXT NOT NULL,
+			tag_type   INTEGER NOT NULL,
+			tag_value  TEXT NOT NULL,
+			num_value  DOUBLE PRECISION,
+			time_value BIGINT,
+			FOREIGN KEY (height, tx_index) REFERENCES tx_results (height, tx_index) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS tx_tags_tx ON tx_tags (height, tx_index)`,
+		`CREATE INDEX IF NOT EXISTS tx_tags_key_value ON tx_tags (tag_key, tag_value)`,
+		`CREATE INDEX IF NOT EXISTS tx_tags_key_num_value ON tx_tags (tag_key, num_value)`,
+		`CREATE INDEX IF NOT EXISTS tx_tags_key_time_value ON tx_tags (tag_key, time_value)`,
+	}
+}
@@ -0,0 +1,6 @@
+package sql
+
+import (
+	// register the Postgres driver
+	_ "github.com/lib/pq"
+)
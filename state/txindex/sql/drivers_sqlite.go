@@ -0,0 +1,8 @@
+// +build cgo
+
+package sql
+
+import (
+	// register the SQLite driver, which requires cgo
+	_ "github.com/mattn/go-sqlite3"
+)
@@ -0,0 +1,526 @@
+package sql
+
+import (
+	gosql "database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+var _ txindex.TxIndexer = (*TxIndex)(nil)
+
+// TxIndex is an indexer backed by a relational database (SQLite or
+// Postgres). Unlike the kv indexer, the data it writes can be queried
+// directly with SQL, and searches are evaluated by the database.
+type TxIndex struct {
+	db           *gosql.DB
+	dialect      dialect
+	tagsToIndex  []string
+	indexAllTags bool
+}
+
+// NewTxIndex creates new SQL indexer, using db opened with the given driver
+// (DriverSQLite or DriverPostgres). It creates the schema if necessary.
+func NewTxIndex(db *gosql.DB, driver string, options ...func(*TxIndex)) (*TxIndex, error) {
+	d, err := dialectFor(driver)
+	if err != nil {
+		return nil, err
+	}
+	txi := &TxIndex{db: db, dialect: d, tagsToIndex: make([]string, 0), indexAllTags: false}
+	for _, o := range options {
+		o(txi)
+	}
+
+	for _, stmt := range d.schema() {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, errors.Wrap(err, "failed to create schema")
+		}
+	}
+	return txi, nil
+}
+
+// IndexTags is an option for setting which tags to index.
+func IndexTags(tags []string) func(*TxIndex) {
+	return func(txi *TxIndex) {
+		txi.tagsToIndex = tags
+	}
+}
+
+// IndexAllTags is an option for indexing all tags.
+func IndexAllTags() func(*TxIndex) {
+	return func(txi *TxIndex) {
+		txi.indexAllTags = true
+	}
+}
+
+// Close closes the database.
+func (txi *TxIndex) Close() error {
+	return txi.db.Close()
+}
+
+// Get gets transaction from the database and returns it or nil if the
+// transaction is not found. If the same tx was included in several blocks,
+// the latest one is returned.
+func (txi *TxIndex) Get(hash []byte) (*types.TxResult, error) {
+	if len(hash) == 0 {
+		return nil, txindex.ErrorEmptyHash
+	}
+
+	b := txi.newBuilder()
+	stmt := fmt.Sprintf("SELECT result FROM tx_results WHERE tx_hash = %s ORDER BY height DESC LIMIT 1",
+		b.arg(fmt.Sprintf("%X", hash)))
+
+	var rawBytes []byte
+	err := txi.db.QueryRow(stmt, b.args...).Scan(&rawBytes)
+	if err == gosql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return decodeTxResult(rawBytes)
+}
+
+// AddBatch indexes a batch of transactions using the given list of tags.
+func (txi *TxIndex) AddBatch(b *txindex.Batch) error {
+	return txi.withTx(func(tx *gosql.Tx) error {
+		for _, result := range b.Ops {
+			if err := txi.index(tx, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Index indexes a single transaction using the given list of tags.
+func (txi *TxIndex) Index(result *types.TxResult) error {
+	return txi.withTx(func(tx *gosql.Tx) error {
+		return txi.index(tx, result)
+	})
+}
+
+// index replaces whatever was stored for the result's height and index.
+func (txi *TxIndex) index(tx *gosql.Tx, result *types.TxResult) error {
+	rawBytes, err := cdc.MarshalBinaryBare(result)
+	if err != nil {
+		return err
+	}
+
+	b := txi.newBuilder()
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM tx_tags WHERE height = %s AND tx_index = %s",
+		b.arg(result.Height), b.arg(result.Index)), b.args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete previous tags")
+	}
+
+	b = txi.newBuilder()
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM tx_results WHERE height = %s AND tx_index = %s",
+		b.arg(result.Height), b.arg(result.Index)), b.args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete previous result")
+	}
+
+	b = txi.newBuilder()
+	r := result.Result
+	_, err = tx.Exec(fmt.Sprintf(`INSERT INTO tx_results
+		(height, tx_index, tx_hash, tx, code, data, log, info, gas_wanted, gas_used, result)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, %s, %s)`,
+		b.arg(result.Height), b.arg(result.Index), b.arg(fmt.Sprintf("%X", result.Tx.Hash())),
+		b.arg([]byte(result.Tx)), b.arg(r.Code), b.arg(r.Data), b.arg(r.Log), b.arg(r.Info),
+		b.arg(r.GasWanted), b.arg(r.GasUsed), b.arg(rawBytes)), b.args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert result")
+	}
+
+	for _, tag := range r.Tags {
+		if !txi.indexAllTags && !cmn.StringInSlice(string(tag.Key), txi.tagsToIndex) {
+			continue
+		}
+		if tag.ValidateValue() != nil {
+			continue
+		}
+
+		numValue, timeValue := typedValues(tag)
+		b = txi.newBuilder()
+		_, err = tx.Exec(fmt.Sprintf(`INSERT INTO tx_tags
+			(height, tx_index, tag_key, tag_type, tag_value, num_value, time_value)
+			VALUES (%s, %s, %s, %s, %s, %s, %s)`,
+			b.arg(result.Height), b.arg(result.Index), b.arg(string(tag.Key)), b.arg(int32(tag.Type)),
+			b.arg(string(tag.Value)), b.arg(numValue), b.arg(timeValue)), b.args...)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert tag")
+		}
+	}
+	return nil
+}
+
+// typedValues returns the num_value and time_value of the tag, which are NULL
+// unless its type is a number or a time. Like the subscriptions and the kv
+// indexer, untyped values are compared as whatever they can be converted to.
+func typedValues(tag cmn.KVPair) (numValue, timeValue interface{}) {
+	value := string(tag.Value)
+	switch tag.Type {
+	case cmn.KVPair_INT, cmn.KVPair_DECIMAL:
+		f, _ := strconv.ParseFloat(value, 64)
+		return f, nil
+	case cmn.KVPair_TIME:
+		t, _ := time.Parse(time.RFC3339Nano, value)
+		return nil, t.UnixNano()
+	case cmn.KVPair_UNTYPED:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			numValue = f
+		}
+		layout := query.DateLayout
+		if strings.ContainsAny(value, "T") {
+			layout = query.TimeLayout
+		}
+		if t, err := time.Parse(layout, value); err == nil {
+			timeValue = t.UnixNano()
+		}
+	}
+	return numValue, timeValue
+}
+
+// Search performs a search using the given query. Each condition (like
+// "tx.height > 5") is translated into a SQL predicate, AND, OR and NOT are
+// kept as they are, and the database returns the matching txs, sorted by
+// height & index.
+//
+// "tx.hash" and "tx.height" match the columns of tx_results. Other tags are
+// matched against tx_tags according to the type of their values: numbers are
+// compared numerically, times chronologically, strings (and bools) as they
+// are, and CONTAINS becomes LIKE.
+//
+// The order, offset and limit of the options are part of the statement, and
+// the returned iterator reads the rows as it goes. The total count is
+// computed by a separate COUNT query.
+func (txi *TxIndex) Search(q *query.Query, opts txindex.SearchOptions) (txindex.ResultIterator, error) {
+	if err := opts.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	stmt, args, err := txi.countStatement(q.Expr())
+	if err != nil {
+		return nil, err
+	}
+	var totalCount int
+	if err := txi.db.QueryRow(stmt, args...).Scan(&totalCount); err != nil {
+		return nil, errors.Wrap(err, "failed to count txs")
+	}
+
+	stmt, args, err = txi.searchStatement(q.Expr(), opts)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := txi.db.Query(stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search txs")
+	}
+	return &resultIterator{rows: rows, totalCount: totalCount}, nil
+}
+
+func (txi *TxIndex) searchStatement(e *query.Expr, opts txindex.SearchOptions) (string, []interface{}, error) {
+	where, args, err := txi.whereClause(e)
+	if err != nil {
+		return "", nil, err
+	}
+
+	stmt := "SELECT r.result FROM tx_results r" + where
+	if opts.OrderBy == txindex.OrderDesc {
+		stmt += " ORDER BY r.height DESC, r.tx_index DESC"
+	} else {
+		stmt += " ORDER BY r.height, r.tx_index"
+	}
+	if opts.Limit > 0 {
+		stmt += " LIMIT " + strconv.Itoa(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		if opts.Limit == 0 {
+			stmt += " LIMIT " + txi.dialect.noLimit
+		}
+		stmt += " OFFSET " + strconv.Itoa(opts.Offset)
+	}
+	return stmt, args, nil
+}
+
+func (txi *TxIndex) countStatement(e *query.Expr) (string, []interface{}, error) {
+	where, args, err := txi.whereClause(e)
+	if err != nil {
+		return "", nil, err
+	}
+	return "SELECT COUNT(*) FROM tx_results r" + where, args, nil
+}
+
+func (txi *TxIndex) whereClause(e *query.Expr) (string, []interface{}, error) {
+	b := txi.newBuilder()
+	where, err := b.expr(e, true)
+	if err != nil {
+		return "", nil, err
+	}
+	if where == "" {
+		return "", nil, nil
+	}
+	return " WHERE " + where, b.args, nil
+}
+
+func (txi *TxIndex) withTx(fn func(tx *gosql.Tx) error) error {
+	tx, err := txi.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback() // nolint: errcheck
+		return err
+	}
+	return tx.Commit()
+}
+
+func (txi *TxIndex) newBuilder() *builder {
+	return &builder{dialect: txi.dialect}
+}
+
+func decodeTxResult(rawBytes []byte) (*types.TxResult, error) {
+	txResult := new(types.TxResult)
+	err := cdc.UnmarshalBinaryBare(rawBytes, &txResult)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading TxResult: %v", err)
+	}
+	return txResult, nil
+}
+
+// resultIterator decodes the rows of a search as it goes.
+type resultIterator struct {
+	rows       *gosql.Rows
+	totalCount int
+
+	result *types.TxResult
+	err    error
+}
+
+var _ txindex.ResultIterator = (*resultIterator)(nil)
+
+func (it *resultIterator) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	var rawBytes []byte
+	if it.err = it.rows.Scan(&rawBytes); it.err != nil {
+		return false
+	}
+	it.result, it.err = decodeTxResult(rawBytes)
+	return it.err == nil
+}
+
+func (it *resultIterator) Result() *types.TxResult { return it.result }
+
+func (it *resultIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+func (it *resultIterator) TotalCount() int { return it.totalCount }
+
+func (it *resultIterator) Close() error { return it.rows.Close() }
+
+///////////////////////////////////////////////////////////////////////////////
+// Statements
+
+// builder collects the arguments of a statement, handing out the matching
+// placeholders.
+type builder struct {
+	dialect dialect
+	args    []interface{}
+}
+
+func (b *builder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return b.dialect.placeholder(len(b.args))
+}
+
+// expr translates the expression into a SQL predicate. Nested AND and OR
+// expressions are parenthesized; root ones don't need to be.
+func (b *builder) expr(e *query.Expr, root bool) (string, error) {
+	switch e.Type {
+	case query.ExprCondition:
+		return b.predicate(e.Condition)
+	case query.ExprNot:
+		p, err := b.expr(e.Args[0], false)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", p), nil
+	case query.ExprAnd, query.ExprOr:
+		sep := " AND "
+		if e.Type == query.ExprOr {
+			sep = " OR "
+		}
+		predicates := make([]string, 0, len(e.Args))
+		for _, arg := range e.Args {
+			p, err := b.expr(arg, false)
+			if err != nil {
+				return "", err
+			}
+			predicates = append(predicates, p)
+		}
+		if root {
+			return strings.Join(predicates, sep), nil
+		}
+		return "(" + strings.Join(predicates, sep) + ")", nil
+	default:
+		return "", fmt.Errorf("unknown expression type %v", e.Type)
+	}
+}
+
+func (b *builder) predicate(c query.Condition) (string, error) {
+	switch c.Tag {
+	case types.TxHashKey:
+		return b.hashPredicate(c)
+	case types.TxHeightKey:
+		return b.heightPredicate(c)
+	}
+
+	key := b.arg(c.Tag)
+	var valuePredicate string
+	switch c.Op {
+	case query.OpExists:
+		return fmt.Sprintf("EXISTS (SELECT 1 FROM tx_tags t WHERE t.height = r.height AND t.tx_index = r.tx_index AND t.tag_key = %s)",
+			key), nil
+	case query.OpIn:
+		operands := c.Operand.([]interface{})
+		predicates := make([]string, 0, len(operands))
+		for _, operand := range operands {
+			p, err := b.valuePredicate(c.Tag, query.OpEqual, operand)
+			if err != nil {
+				return "", err
+			}
+			predicates = append(predicates, p)
+		}
+		valuePredicate = "(" + strings.Join(predicates, " OR ") + ")"
+	default:
+		p, err := b.valuePredicate(c.Tag, c.Op, c.Operand)
+		if err != nil {
+			return "", err
+		}
+		valuePredicate = p
+	}
+
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM tx_tags t WHERE t.height = r.height AND t.tx_index = r.tx_index AND t.tag_key = %s AND %s)",
+		key, valuePredicate), nil
+}
+
+func (b *builder) hashPredicate(c query.Condition) (string, error) {
+	var operands []interface{}
+	switch c.Op {
+	case query.OpEqual:
+		operands = []interface{}{c.Operand}
+	case query.OpIn:
+		operands = c.Operand.([]interface{})
+	default:
+		return "", fmt.Errorf("%s only supports = and IN", types.TxHashKey)
+	}
+
+	placeholders := make([]string, 0, len(operands))
+	for _, operand := range operands {
+		s, ok := operand.(string)
+		if !ok {
+			return "", fmt.Errorf("%s must be compared with a string", types.TxHashKey)
+		}
+		placeholders = append(placeholders, b.arg(strings.ToUpper(s)))
+	}
+	if c.Op == query.OpEqual {
+		return fmt.Sprintf("r.tx_hash = %s", placeholders[0]), nil
+	}
+	return fmt.Sprintf("r.tx_hash IN (%s)", strings.Join(placeholders, ", ")), nil
+}
+
+func (b *builder) heightPredicate(c query.Condition) (string, error) {
+	if c.Op == query.OpExists {
+		return "r.height IS NOT NULL", nil
+	}
+
+	operands := []interface{}{c.Operand}
+	if c.Op == query.OpIn {
+		operands = c.Operand.([]interface{})
+	}
+	placeholders := make([]string, 0, len(operands))
+	for _, operand := range operands {
+		switch operand.(type) {
+		case int64, float64:
+		default:
+			return "", fmt.Errorf("%s only supports numbers", types.TxHeightKey)
+		}
+		placeholders = append(placeholders, b.arg(operand))
+	}
+	if c.Op == query.OpIn {
+		return fmt.Sprintf("r.height IN (%s)", strings.Join(placeholders, ", ")), nil
+	}
+
+	op, err := sqlOperator(c.Op)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("r.height %s %s", op, placeholders[0]), nil
+}
+
+// stringTypes are the types of the values compared with string operands.
+var stringTypes = fmt.Sprintf("%d, %d, %d", cmn.KVPair_UNTYPED, cmn.KVPair_STRING, cmn.KVPair_BOOL)
+
+// valuePredicate compares the value of a tag (t.tag_value, t.num_value or
+// t.time_value) with the operand.
+func (b *builder) valuePredicate(tag string, op query.Operator, operand interface{}) (string, error) {
+	sqlOp, err := sqlOperator(op)
+	if err != nil {
+		return "", err
+	}
+
+	switch operand := operand.(type) {
+	case string:
+		if op == query.OpContains {
+			return fmt.Sprintf(`(t.tag_type IN (%s) AND t.tag_value LIKE %s ESCAPE '\')`,
+				stringTypes, b.arg("%"+escapeLike(operand)+"%")), nil
+		}
+		return fmt.Sprintf("(t.tag_type IN (%s) AND t.tag_value %s %s)", stringTypes, sqlOp, b.arg(operand)), nil
+	case int64:
+		return fmt.Sprintf("t.num_value %s %s", sqlOp, b.arg(float64(operand))), nil
+	case float64:
+		return fmt.Sprintf("t.num_value %s %s", sqlOp, b.arg(operand)), nil
+	case time.Time:
+		return fmt.Sprintf("t.time_value %s %s", sqlOp, b.arg(operand.UnixNano())), nil
+	default:
+		return "", fmt.Errorf("unexpected operand type %T (tag %s)", operand, tag)
+	}
+}
+
+func sqlOperator(op query.Operator) (string, error) {
+	switch op {
+	case query.OpLessEqual:
+		return "<=", nil
+	case query.OpGreaterEqual:
+		return ">=", nil
+	case query.OpLess:
+		return "<", nil
+	case query.OpGreater:
+		return ">", nil
+	case query.OpEqual:
+		return "=", nil
+	case query.OpContains:
+		return "LIKE", nil
+	default:
+		return "", fmt.Errorf("unknown operator %v", op)
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,312 @@
+// +build cgo
+
+package sql
+
+import (
+	gosql "database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+func newTestTxIndex(t *testing.T, options ...func(*TxIndex)) *TxIndex {
+	db, err := gosql.Open(DriverSQLite, ":memory:")
+	require.NoError(t, err)
+	// every connection to ":memory:" gets its own database
+	db.SetMaxOpenConns(1)
+
+	indexer, err := NewTxIndex(db, DriverSQLite, options...)
+	require.NoError(t, err)
+	return indexer
+}
+
+func TestTxIndex(t *testing.T) {
+	indexer := newTestTxIndex(t)
+
+	tx := types.Tx("HELLO WORLD")
+	txResult := &types.TxResult{Height: 1, Index: 0, Tx: tx, Result: abci.ResponseDeliverTx{Data: []byte{0}, Code: abci.CodeTypeOK, Log: "", Tags: nil}}
+	hash := tx.Hash()
+
+	batch := txindex.NewBatch(1)
+	if err := batch.Add(txResult); err != nil {
+		t.Error(err)
+	}
+	err := indexer.AddBatch(batch)
+	require.NoError(t, err)
+
+	loadedTxResult, err := indexer.Get(hash)
+	require.NoError(t, err)
+	assert.Equal(t, txResult, loadedTxResult)
+
+	tx2 := types.Tx("BYE BYE WORLD")
+	txResult2 := &types.TxResult{Height: 1, Index: 0, Tx: tx2, Result: abci.ResponseDeliverTx{Data: []byte{0}, Code: abci.CodeTypeOK, Log: "", Tags: nil}}
+	hash2 := tx2.Hash()
+
+	// replaces the tx at the same height and index
+	err = indexer.Index(txResult2)
+	require.NoError(t, err)
+
+	loadedTxResult2, err := indexer.Get(hash2)
+	require.NoError(t, err)
+	assert.Equal(t, txResult2, loadedTxResult2)
+
+	loadedTxResult, err = indexer.Get(hash)
+	require.NoError(t, err)
+	assert.Nil(t, loadedTxResult)
+}
+
+func TestTxSearch(t *testing.T) {
+	allowedTags := []string{"account.number", "account.owner", "account.date"}
+	indexer := newTestTxIndex(t, IndexTags(allowedTags))
+
+	txResult := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number"), Value: []byte("1")},
+		{Key: []byte("account.owner"), Value: []byte("Ivan")},
+		{Key: []byte("not_allowed"), Value: []byte("Vlad")},
+	})
+	hash := txResult.Tx.Hash()
+
+	err := indexer.Index(txResult)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		q             string
+		resultsLength int
+	}{
+		// search by hash
+		{fmt.Sprintf("tx.hash = '%X'", hash), 1},
+		// search by hash (lower case)
+		{fmt.Sprintf("tx.hash = '%x'", hash), 1},
+		// search by height
+		{"tx.height = 1", 1},
+		// search by exact match (one tag)
+		{"account.number = 1", 1},
+		// search by exact match (two tags)
+		{"account.number = 1 AND account.owner = 'Ivan'", 1},
+		// search by exact match (two tags)
+		{"account.number = 1 AND account.owner = 'Vlad'", 0},
+		// search using a prefix of the stored value
+		{"account.owner = 'Iv'", 0},
+		// search by range
+		{"account.number >= 1 AND account.number <= 5", 1},
+		// search by range (lower bound)
+		{"account.number >= 1", 1},
+		// search by range (upper bound)
+		{"account.number <= 5", 1},
+		// search by range (float)
+		{"account.number < 1.5", 1},
+		// search using not allowed tag
+		{"not_allowed = 'boom'", 0},
+		// search for not existing tx result
+		{"account.number >= 2 AND account.number <= 5", 0},
+		// search using CONTAINS
+		{"account.owner CONTAINS 'an'", 1},
+		// search using CONTAINS with a LIKE wildcard
+		{"account.owner CONTAINS '%'", 0},
+		// search for non existing value using CONTAINS
+		{"account.owner CONTAINS 'Vlad'", 0},
+		// search using the wrong tag (of numeric type) using CONTAINS
+		{"account.number CONTAINS 'Iv'", 0},
+		// search using IN
+		{"account.owner IN ('Vlad', 'Ivan')", 1},
+		// search using EXISTS
+		{"account.owner EXISTS", 1},
+		{"account.date EXISTS", 0},
+		{"account.date >= TIME 2013-05-03T14:45:00Z", 0},
+		// search using OR
+		{"account.owner = 'Vlad' OR account.number = 1", 1},
+		// search using NOT
+		{"NOT account.owner = 'Vlad'", 1},
+		{"account.number = 1 AND NOT account.owner = 'Ivan'", 0},
+		// search using parentheses
+		{"(account.number = 2 OR account.owner = 'Ivan') AND account.number <= 5", 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.q, func(t *testing.T) {
+			results, err := search(indexer, tc.q)
+			assert.NoError(t, err)
+
+			assert.Len(t, results, tc.resultsLength)
+			if tc.resultsLength > 0 {
+				assert.Equal(t, []*types.TxResult{txResult}, results)
+			}
+		})
+	}
+}
+
+func TestTxSearchTypedTags(t *testing.T) {
+	indexer := newTestTxIndex(t, IndexAllTags())
+
+	tagSets := [][]cmn.KVPair{
+		{
+			{Key: []byte("amount"), Value: []byte("-20"), Type: cmn.KVPair_INT},
+			{Key: []byte("memo"), Value: []byte("7"), Type: cmn.KVPair_STRING},
+			{Key: []byte("time"), Value: []byte("2013-05-03T14:45:00Z"), Type: cmn.KVPair_TIME},
+		},
+		{
+			{Key: []byte("amount"), Value: []byte("-2.5"), Type: cmn.KVPair_DECIMAL},
+			{Key: []byte("memo"), Value: []byte("8"), Type: cmn.KVPair_STRING},
+			{Key: []byte("time"), Value: []byte("2013-05-03T16:45:00.5+02:00"), Type: cmn.KVPair_TIME},
+			{Key: []byte("paid"), Value: []byte("true"), Type: cmn.KVPair_BOOL},
+		},
+		{
+			{Key: []byte("amount"), Value: []byte("7"), Type: cmn.KVPair_INT},
+			{Key: []byte("memo"), Value: []byte("7"), Type: cmn.KVPair_UNTYPED},
+			{Key: []byte("time"), Value: []byte("1969-12-31T23:59:59Z"), Type: cmn.KVPair_TIME},
+		},
+		{
+			{Key: []byte("amount"), Value: []byte("100"), Type: cmn.KVPair_INT},
+			{Key: []byte("memo"), Value: []byte("2013"), Type: cmn.KVPair_TIME}, // invalid, not indexed
+		},
+	}
+	txResults := make([]*types.TxResult, len(tagSets))
+	for i, tags := range tagSets {
+		txResult := txResultWithTags(tags)
+		txResult.Tx = types.Tx(fmt.Sprintf("tx %d", i))
+		txResult.Height = int64(i + 1)
+		txResults[i] = txResult
+		require.NoError(t, indexer.Index(txResult))
+	}
+
+	testCases := []struct {
+		q       string
+		results []int
+	}{
+		{"amount < 0", []int{0, 1}},
+		{"amount > -3 AND amount < 8", []int{1, 2}},
+		{"amount >= -2.5", []int{1, 2, 3}},
+		{"amount = -20", []int{0}},
+		{"amount = 7.0", []int{2}},
+		{"amount IN (7, -2.5)", []int{1, 2}},
+		{"amount = '7'", []int{}},
+		{"memo = '7'", []int{0, 2}},
+		{"memo = 7", []int{2}},
+		{"memo > 6", []int{2}},
+		{"memo CONTAINS '8'", []int{1}},
+		{"memo EXISTS", []int{0, 1, 2}},
+		{"time = TIME 2013-05-03T14:45:00Z", []int{0}},
+		{"time > TIME 2013-05-03T14:45:00Z", []int{1}},
+		{"time < DATE 2013-05-03", []int{2}},
+		{"time = '2013-05-03T14:45:00Z'", []int{}},
+		{"paid = 'true'", []int{1}},
+		{"NOT paid = 'true' AND amount > -100", []int{0, 2, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.q, func(t *testing.T) {
+			results, err := search(indexer, tc.q)
+			require.NoError(t, err)
+
+			expected := make([]*types.TxResult, len(tc.results))
+			for i, j := range tc.results {
+				expected[i] = txResults[j]
+			}
+			assert.Equal(t, expected, results)
+		})
+	}
+}
+
+func TestTxSearchMultipleTxs(t *testing.T) {
+	allowedTags := []string{"account.number", "account.number.id"}
+	indexer := newTestTxIndex(t, IndexTags(allowedTags))
+
+	// indexed first, but bigger height (to test the order of transactions)
+	txResult := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number"), Value: []byte("1")},
+	})
+	txResult.Tx = types.Tx("Bob's account")
+	txResult.Height = 2
+	txResult.Index = 1
+	err := indexer.Index(txResult)
+	require.NoError(t, err)
+
+	// indexed second, but smaller height (to test the order of transactions)
+	txResult2 := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number"), Value: []byte("2")},
+	})
+	txResult2.Tx = types.Tx("Alice's account")
+	txResult2.Height = 1
+	txResult2.Index = 2
+	err = indexer.Index(txResult2)
+	require.NoError(t, err)
+
+	// indexed third (to test the order of transactions)
+	txResult3 := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number"), Value: []byte("3")},
+	})
+	txResult3.Tx = types.Tx("Jack's account")
+	txResult3.Height = 1
+	txResult3.Index = 1
+	err = indexer.Index(txResult3)
+	require.NoError(t, err)
+
+	// indexed fourth (to test we don't include txs with similar tags)
+	txResult4 := txResultWithTags([]cmn.KVPair{
+		{Key: []byte("account.number.id"), Value: []byte("1")},
+	})
+	txResult4.Tx = types.Tx("Mike's account")
+	txResult4.Height = 2
+	txResult4.Index = 2
+	err = indexer.Index(txResult4)
+	require.NoError(t, err)
+
+	results, err := search(indexer, "account.number >= 1")
+	assert.NoError(t, err)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, []*types.TxResult{txResult3, txResult2, txResult}, results)
+
+	testCases := []struct {
+		opts    txindex.SearchOptions
+		results []*types.TxResult
+	}{
+		{txindex.SearchOptions{OrderBy: txindex.OrderDesc}, []*types.TxResult{txResult, txResult2, txResult3}},
+		{txindex.SearchOptions{Offset: 1}, []*types.TxResult{txResult2, txResult}},
+		{txindex.SearchOptions{Limit: 2}, []*types.TxResult{txResult3, txResult2}},
+		{txindex.SearchOptions{OrderBy: txindex.OrderDesc, Offset: 1, Limit: 1}, []*types.TxResult{txResult2}},
+		{txindex.SearchOptions{Offset: 3}, []*types.TxResult{}},
+	}
+	for _, tc := range testCases {
+		it, err := indexer.Search(query.MustParse("account.number >= 1"), tc.opts)
+		require.NoError(t, err)
+		assert.Equal(t, 3, it.TotalCount(), "%+v", tc.opts)
+		results, err := txindex.Collect(it)
+		require.NoError(t, err)
+		assert.Equal(t, tc.results, results, "%+v", tc.opts)
+	}
+
+	_, err = indexer.Search(query.MustParse("account.number >= 1"), txindex.SearchOptions{OrderBy: "random"})
+	assert.Error(t, err)
+}
+
+func search(indexer txindex.TxIndexer, q string) ([]*types.TxResult, error) {
+	it, err := indexer.Search(query.MustParse(q), txindex.SearchOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return txindex.Collect(it)
+}
+
+func txResultWithTags(tags []cmn.KVPair) *types.TxResult {
+	tx := types.Tx("HELLO WORLD")
+	return &types.TxResult{
+		Height: 1,
+		Index:  0,
+		Tx:     tx,
+		Result: abci.ResponseDeliverTx{
+			Data: []byte{0},
+			Code: abci.CodeTypeOK,
+			Log:  "",
+			Tags: tags,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/txindex"
+)
+
+func TestSearchStatement(t *testing.T) {
+	const tagPredicate = "EXISTS (SELECT 1 FROM tx_tags t WHERE t.height = r.height AND t.tx_index = r.tx_index AND t.tag_key = "
+	const stringPredicate = "(t.tag_type IN (0, 1, 5) AND t.tag_value"
+
+	testCases := []struct {
+		q     string
+		where string
+		args  []interface{}
+	}{
+		{"tx.hash = 'abcd'", "r.tx_hash = $1", []interface{}{"ABCD"}},
+		{"tx.height > 5", "r.height > $1", []interface{}{int64(5)}},
+		{"account.number <= 5", tagPredicate + "$1 AND t.num_value <= $2)", []interface{}{"account.number", float64(5)}},
+		{
+			"account.date >= DATE 2013-05-03",
+			tagPredicate + "$1 AND t.time_value >= $2)",
+			[]interface{}{"account.date", time.Date(2013, 5, 3, 0, 0, 0, 0, time.UTC).UnixNano()},
+		},
+		{"account.owner = 'Ivan'", tagPredicate + "$1 AND " + stringPredicate + " = $2))", []interface{}{"account.owner", "Ivan"}},
+		{"account.owner CONTAINS 'a_%'", tagPredicate + "$1 AND " + stringPredicate + ` LIKE $2 ESCAPE '\'))`, []interface{}{"account.owner", `%a\_\%%`}},
+		{
+			"tx.height = 1 AND account.owner = 'Ivan'",
+			"r.height = $1 AND " + tagPredicate + "$2 AND " + stringPredicate + " = $3))",
+			[]interface{}{int64(1), "account.owner", "Ivan"},
+		},
+		{"tx.hash IN ('ab', 'cd')", "r.tx_hash IN ($1, $2)", []interface{}{"AB", "CD"}},
+		{"tx.height IN (1, 2)", "r.height IN ($1, $2)", []interface{}{int64(1), int64(2)}},
+		{
+			"account.owner IN ('Ivan', 1)",
+			tagPredicate + "$1 AND (" + stringPredicate + " = $2) OR t.num_value = $3))",
+			[]interface{}{"account.owner", "Ivan", float64(1)},
+		},
+		{"account.owner EXISTS", tagPredicate + "$1)", []interface{}{"account.owner"}},
+		{
+			"tx.height = 1 OR tx.height > 5",
+			"r.height = $1 OR r.height > $2",
+			[]interface{}{int64(1), int64(5)},
+		},
+		{"NOT tx.height = 1", "NOT (r.height = $1)", []interface{}{int64(1)}},
+		{
+			"tx.height > 1 AND NOT (tx.height = 3 OR account.owner EXISTS)",
+			"r.height > $1 AND NOT ((r.height = $2 OR " + tagPredicate + "$3)))",
+			[]interface{}{int64(1), int64(3), "account.owner"},
+		},
+	}
+
+	txi := &TxIndex{dialect: dialects[DriverPostgres]}
+	for _, tc := range testCases {
+		stmt, args, err := txi.searchStatement(query.MustParse(tc.q).Expr(), txindex.SearchOptions{})
+		require.NoError(t, err, tc.q)
+		assert.Equal(t, "SELECT r.result FROM tx_results r WHERE "+tc.where+" ORDER BY r.height, r.tx_index", stmt, tc.q)
+		assert.Equal(t, tc.args, args, tc.q)
+	}
+}
+
+func TestSearchStatementOptions(t *testing.T) {
+	testCases := []struct {
+		driver string
+		opts   txindex.SearchOptions
+		suffix string
+	}{
+		{DriverPostgres, txindex.SearchOptions{}, " ORDER BY r.height, r.tx_index"},
+		{DriverPostgres, txindex.SearchOptions{OrderBy: txindex.OrderDesc}, " ORDER BY r.height DESC, r.tx_index DESC"},
+		{DriverPostgres, txindex.SearchOptions{Limit: 10}, " ORDER BY r.height, r.tx_index LIMIT 10"},
+		{DriverPostgres, txindex.SearchOptions{Limit: 10, Offset: 20}, " ORDER BY r.height, r.tx_index LIMIT 10 OFFSET 20"},
+		{DriverPostgres, txindex.SearchOptions{Offset: 20}, " ORDER BY r.height, r.tx_index LIMIT ALL OFFSET 20"},
+		{DriverSQLite, txindex.SearchOptions{Offset: 20}, " ORDER BY r.height, r.tx_index LIMIT -1 OFFSET 20"},
+	}
+
+	for _, tc := range testCases {
+		txi := &TxIndex{dialect: dialects[tc.driver]}
+		stmt, _, err := txi.searchStatement(query.MustParse("tx.height > 5").Expr(), tc.opts)
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT r.result FROM tx_results r WHERE r.height > "+txi.dialect.placeholder(1)+tc.suffix, stmt, "%+v", tc.opts)
+	}
+}
+
+func TestSearchStatementErrors(t *testing.T) {
+	txi := &TxIndex{dialect: dialects[DriverSQLite]}
+	for _, q := range []string{
+		"tx.hash CONTAINS 'ab'",
+		"tx.height = 'one'",
+		"tx.hash EXISTS",
+		"account.owner = 'Ivan' OR tx.height IN (1, 'two')",
+	} {
+		_, _, err := txi.searchStatement(query.MustParse(q).Expr(), txindex.SearchOptions{})
+		assert.Error(t, err, q)
+	}
+}
@@ -71,17 +71,29 @@ func (b *EventBus) Publish(eventType string, eventData TMEventData) error {
 	return nil
 }
 
-func (b *EventBus) validateAndStringifyTags(tags []cmn.KVPair, logger log.Logger) map[string]string {
+// validateAndStringifyTags returns the values and the types of the tags,
+// skipping the invalid ones.
+func (b *EventBus) validateAndStringifyTags(tags []cmn.KVPair, logger log.Logger) (map[string]string, map[string]cmn.KVPair_Type) {
 	result := make(map[string]string)
+	types := make(map[string]cmn.KVPair_Type)
 	for _, tag := range tags {
 		// basic validation
 		if len(tag.Key) == 0 {
 			logger.Debug("Got tag with an empty key (skipping)", "tag", tag)
 			continue
 		}
+		if err := tag.ValidateValue(); err != nil {
+			logger.Info("Got tag with an invalid value (skipping)", "tag", tag, "err", err)
+			continue
+		}
 		result[string(tag.Key)] = string(tag.Value)
+		if tag.Type != cmn.KVPair_UNTYPED {
+			types[string(tag.Key)] = tag.Type
+		} else {
+			delete(types, string(tag.Key))
+		}
 	}
-	return result
+	return result, types
 }
 
 func (b *EventBus) PublishEventNewBlock(data EventDataNewBlock) error {
@@ -89,13 +101,14 @@ func (b *EventBus) PublishEventNewBlock(data EventDataNewBlock) error {
 	ctx := context.Background()
 
 	resultTags := append(data.ResultBeginBlock.Tags, data.ResultEndBlock.Tags...)
-	tags := b.validateAndStringifyTags(resultTags, b.Logger.With("block", data.Block.StringShort()))
+	tags, tagTypes := b.validateAndStringifyTags(resultTags, b.Logger.With("block", data.Block.StringShort()))
 
 	// add predefined tags
 	logIfTagExists(EventTypeKey, tags, b.Logger)
+	delete(tagTypes, EventTypeKey)
 	tags[EventTypeKey] = EventNewBlock
 
-	b.pubsub.PublishWithTags(ctx, data, tmpubsub.NewTagMap(tags))
+	b.pubsub.PublishWithTags(ctx, data, tmpubsub.NewTypedTagMap(tags, tagTypes))
 	return nil
 }
 
@@ -105,13 +118,14 @@ func (b *EventBus) PublishEventNewBlockHeader(data EventDataNewBlockHeader) erro
 
 	resultTags := append(data.ResultBeginBlock.Tags, data.ResultEndBlock.Tags...)
 	// TODO: Create StringShort method for Header and use it in logger.
-	tags := b.validateAndStringifyTags(resultTags, b.Logger.With("header", data.Header))
+	tags, tagTypes := b.validateAndStringifyTags(resultTags, b.Logger.With("header", data.Header))
 
 	// add predefined tags
 	logIfTagExists(EventTypeKey, tags, b.Logger)
+	delete(tagTypes, EventTypeKey)
 	tags[EventTypeKey] = EventNewBlockHeader
 
-	b.pubsub.PublishWithTags(ctx, data, tmpubsub.NewTagMap(tags))
+	b.pubsub.PublishWithTags(ctx, data, tmpubsub.NewTypedTagMap(tags, tagTypes))
 	return nil
 }
 
@@ -130,19 +144,22 @@ func (b *EventBus) PublishEventTx(data EventDataTx) error {
 	// no explicit deadline for publishing events
 	ctx := context.Background()
 
-	tags := b.validateAndStringifyTags(data.Result.Tags, b.Logger.With("tx", data.Tx))
+	tags, tagTypes := b.validateAndStringifyTags(data.Result.Tags, b.Logger.With("tx", data.Tx))
 
 	// add predefined tags
 	logIfTagExists(EventTypeKey, tags, b.Logger)
+	delete(tagTypes, EventTypeKey)
 	tags[EventTypeKey] = EventTx
 
 	logIfTagExists(TxHashKey, tags, b.Logger)
+	delete(tagTypes, TxHashKey)
 	tags[TxHashKey] = fmt.Sprintf("%X", data.Tx.Hash())
 
 	logIfTagExists(TxHeightKey, tags, b.Logger)
+	delete(tagTypes, TxHeightKey)
 	tags[TxHeightKey] = fmt.Sprintf("%d", data.Height)
 
-	b.pubsub.PublishWithTags(ctx, data, tmpubsub.NewTagMap(tags))
+	b.pubsub.PublishWithTags(ctx, data, tmpubsub.NewTypedTagMap(tags, tagTypes))
 	return nil
 }
 
@@ -58,6 +58,38 @@ func TestEventBusPublishEventTx(t *testing.T) {
 	}
 }
 
+func TestEventBusPublishEventTxTypedTags(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	defer eventBus.Stop()
+
+	result := abci.ResponseDeliverTx{Tags: []cmn.KVPair{
+		{Key: []byte("amount"), Value: []byte("7"), Type: cmn.KVPair_INT},
+		{Key: []byte("memo"), Value: []byte("7"), Type: cmn.KVPair_STRING},
+		{Key: []byte("fee"), Value: []byte("free"), Type: cmn.KVPair_INT},
+		{Key: []byte("tx.height"), Value: []byte("one"), Type: cmn.KVPair_STRING},
+	}}
+
+	txEventsCh := make(chan interface{}, 1)
+
+	// the string "7" is not a number, the invalid fee is skipped and the
+	// predefined tx.height is untyped
+	query := "amount > 5 AND memo = '7' AND NOT memo > 5 AND NOT fee EXISTS AND tx.height = 1"
+	err = eventBus.Subscribe(context.Background(), "test", tmquery.MustParse(query), txEventsCh)
+	require.NoError(t, err)
+
+	err = eventBus.PublishEventTx(EventDataTx{TxResult{Height: 1, Tx: Tx("foo"), Result: result}})
+	assert.NoError(t, err)
+
+	select {
+	case e := <-txEventsCh:
+		assert.Equal(t, result, e.(EventDataTx).Result)
+	case <-time.After(1 * time.Second):
+		t.Fatal("did not receive a transaction after 1 sec.")
+	}
+}
+
 func TestEventBusPublishEventNewBlock(t *testing.T) {
 	eventBus := NewEventBus()
 	err := eventBus.Start()
@@ -127,6 +127,9 @@ const (
 	// TxHeightKey is a reserved key, used to specify transaction block's height.
 	// see EventBus#PublishEventTx
 	TxHeightKey = "tx.height"
+	// BlockHeightKey is a reserved key, used to search blocks by height.
+	// see blockindex.BlockIndexer#Search
+	BlockHeightKey = "block.height"
 )
 
 var (
@@ -64,20 +64,30 @@ func (txs Txs) IndexByHash(hash []byte) int {
 
 // Proof returns a simple merkle proof for this node.
 // Panics if i < 0 or i >= len(txs)
-// TODO: optimize this!
+// NOTE: the whole merkle tree is computed, use Proofs to get the proofs of
+// several txs.
 func (txs Txs) Proof(i int) TxProof {
-	l := len(txs)
-	bzs := make([][]byte, l)
-	for i := 0; i < l; i++ {
+	return txs.Proofs()[i]
+}
+
+// Proofs returns the simple merkle proofs of all the txs, computing the
+// merkle tree once.
+func (txs Txs) Proofs() []TxProof {
+	bzs := make([][]byte, len(txs))
+	for i := 0; i < len(txs); i++ {
 		bzs[i] = txs[i]
 	}
 	root, proofs := merkle.SimpleProofsFromByteSlices(bzs)
 
-	return TxProof{
-		RootHash: root,
-		Data:     txs[i],
-		Proof:    *proofs[i],
+	txProofs := make([]TxProof, len(txs))
+	for i := range txs {
+		txProofs[i] = TxProof{
+			RootHash: root,
+			Data:     txs[i],
+			Proof:    *proofs[i],
+		}
 	}
+	return txProofs
 }
 
 // TxProof represents a Merkle proof of the presence of a transaction in the Merkle tree.
@@ -64,11 +64,14 @@ func TestValidTxProof(t *testing.T) {
 	for h, tc := range cases {
 		txs := tc.txs
 		root := txs.Hash()
+		proofs := txs.Proofs()
+		assert.Len(t, proofs, len(txs))
 		// make sure valid proof for every tx
 		for i := range txs {
 			leaf := txs[i]
 			leafHash := leaf.Hash()
 			proof := txs.Proof(i)
+			assert.Equal(t, proof, proofs[i], "%d: %d", h, i)
 			assert.Equal(t, i, proof.Proof.Index, "%d: %d", h, i)
 			assert.Equal(t, len(txs), proof.Proof.Total, "%d: %d", h, i)
 			assert.EqualValues(t, root, proof.RootHash, "%d: %d", h, i)